@@ -9,6 +9,9 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/donovanhide/eventsource"
 	"github.com/satori/go.uuid"
@@ -21,14 +24,23 @@ import (
 type Client struct {
 	client *http.Client
 
+	// deadline, if set, is when requests made by this client give up.
+	deadline time.Time
+
+	// dryRun, if set, makes Do refuse any request that would change
+	// something.
+	dryRun bool
+
 	Orgs         *OrgsClient
 	Users        *UsersClient
 	Machines     *MachinesClient
 	Profiles     *ProfilesClient
 	Teams        *TeamsClient
+	Roles        *RolesClient
 	Memberships  *MembershipsClient
 	Invites      *InvitesClient
 	Keypairs     *KeypairsClient
+	Keyrings     *KeyringsClient
 	Session      *SessionClient
 	Services     *ServicesClient
 	Policies     *PoliciesClient
@@ -37,6 +49,7 @@ type Client struct {
 	Credentials  *CredentialsClient
 	Worklog      *WorklogClient
 	Version      *VersionClient
+	Daemon       *DaemonClient
 }
 
 // NewClient returns a new Client.
@@ -51,14 +64,28 @@ func NewClient(cfg *config.Config) *Client {
 		},
 	}
 
+	// The timeout runs from when the Client is made, and is shared by every
+	// request made through it rather than applied to each request.
+	if cfg.Timeout > 0 {
+		c.deadline = time.Now().Add(cfg.Timeout)
+	}
+
+	c.dryRun = cfg.DryRun
+
+	if cfg.Verbose {
+		c.client.Transport = &verboseTransport{next: c.client.Transport, w: os.Stderr}
+	}
+
 	c.Orgs = &OrgsClient{client: c}
 	c.Users = &UsersClient{client: c}
 	c.Machines = &MachinesClient{client: c}
 	c.Profiles = &ProfilesClient{client: c}
 	c.Teams = &TeamsClient{client: c}
+	c.Roles = &RolesClient{client: c}
 	c.Memberships = &MembershipsClient{client: c}
 	c.Invites = &InvitesClient{client: c}
 	c.Keypairs = &KeypairsClient{client: c}
+	c.Keyrings = &KeyringsClient{client: c}
 	c.Session = &SessionClient{client: c}
 	c.Projects = &ProjectsClient{client: c}
 	c.Services = &ServicesClient{client: c}
@@ -67,6 +94,7 @@ func NewClient(cfg *config.Config) *Client {
 	c.Policies = &PoliciesClient{client: c}
 	c.Worklog = &WorklogClient{client: c}
 	c.Version = &VersionClient{client: c}
+	c.Daemon = &DaemonClient{client: c}
 
 	return c
 }
@@ -113,12 +141,47 @@ func (c *Client) NewRequest(method, path string, query *url.Values, body interfa
 	return req, requestID, nil
 }
 
+// ErrDryRun is returned by Do for a request that would change something,
+// when the client was created for a dry run.
+var ErrDryRun = errors.New("refusing to make changes during a dry run")
+
+// readOnly reports whether r can be made during a dry run. Logging in only
+// changes the daemon's session, which a dry run may still need.
+func readOnly(r *http.Request) bool {
+	switch r.Method {
+	case "GET", "HEAD":
+		return true
+	case "POST":
+		return r.URL.Path == "/v1/login"
+	default:
+		return false
+	}
+}
+
 // Do executes an http.Request, populating v with the JSON response
 // on success.
 //
 // If the request errors with a JSON formatted response body, it will be
-// unmarshaled into the returned error.
+// unmarshaled into the returned error. If ctx, or the client's timeout,
+// expires first, a request_timeout error is returned.
 func (c *Client) Do(ctx context.Context, r *http.Request, v interface{}, reqID *string, progress *ProgressFunc) (*http.Response, error) {
+	if c.dryRun && !readOnly(r) {
+		return nil, ErrDryRun
+	}
+
+	if !c.deadline.IsZero() {
+		var cancelFunc context.CancelFunc
+		ctx, cancelFunc = context.WithDeadline(ctx, c.deadline)
+		defer cancelFunc()
+	}
+	r = r.WithContext(ctx)
+
+	// The daemon bounds its calls to the registry by the time left, rather
+	// than by its own default.
+	if deadline, ok := ctx.Deadline(); ok {
+		r.Header.Set("X-Torus-Timeout", time.Until(deadline).String())
+	}
+
 	done := make(chan bool)
 	if progress != nil {
 		version := "v1"
@@ -161,7 +224,7 @@ func (c *Client) Do(ctx context.Context, r *http.Request, v interface{}, reqID *
 	resp, err := c.client.Do(r)
 	close(done)
 	if err != nil {
-		return nil, err
+		return nil, timeoutErr(ctx, err)
 	}
 
 	defer resp.Body.Close()
@@ -175,19 +238,37 @@ func (c *Client) Do(ctx context.Context, r *http.Request, v interface{}, reqID *
 		dec := json.NewDecoder(resp.Body)
 		err = dec.Decode(v)
 		if err != nil {
-			return nil, err
+			return nil, timeoutErr(ctx, err)
 		}
 	}
 
 	return resp, nil
 }
 
+// timeoutErr replaces err with a request_timeout error if it was caused by
+// ctx's deadline passing.
+func timeoutErr(ctx context.Context, err error) error {
+	if ctx.Err() != context.DeadlineExceeded {
+		return err
+	}
+
+	return &apitypes.Error{
+		StatusCode: http.StatusRequestTimeout,
+		Type:       "request_timeout",
+		Err:        []string{"Request timed out"},
+	}
+}
+
 func checkResponseCode(r *http.Response) error {
 	if r.StatusCode >= 200 && r.StatusCode < 300 {
 		return nil
 	}
 
 	rErr := &apitypes.Error{StatusCode: r.StatusCode}
+	if r.StatusCode == http.StatusTooManyRequests {
+		rErr.RetryAt = retryAt(r.Header, time.Now())
+	}
+
 	if r.ContentLength != 0 {
 		dec := json.NewDecoder(r.Body)
 		err := dec.Decode(rErr)
@@ -198,5 +279,32 @@ func checkResponseCode(r *http.Response) error {
 		return apitypes.FormatError(rErr)
 	}
 
+	if r.StatusCode == http.StatusTooManyRequests {
+		return apitypes.FormatError(rErr)
+	}
+
 	return errors.New("Error from daemon. Check status code.")
 }
+
+// retryAt returns when a rate limited request may be retried, from the
+// registry's Retry-After or X-RateLimit-Reset headers passed along by the
+// daemon. It returns the zero time if neither is usable.
+func retryAt(h http.Header, now time.Time) time.Time {
+	if v := h.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			return now.Add(time.Duration(secs) * time.Second)
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return t
+		}
+	}
+
+	if secs, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		if secs > apitypes.RateLimitEpochThreshold {
+			return time.Unix(secs, 0)
+		}
+		return now.Add(time.Duration(secs) * time.Second)
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,16 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/manifoldco/torus-cli/identity"
+)
+
+// newID returns the ID of body, failing the test if it can't be derived.
+func newID(t testing.TB, body identity.Mutable) *identity.ID {
+	id, err := identity.NewMutable(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &id
+}
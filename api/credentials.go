@@ -1,12 +1,23 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"net/url"
+	"strings"
 
 	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/pathexp"
+)
+
+// Errors returned when setting a credential at a pathexp whose org or project
+// doesn't exist.
+var (
+	ErrOrgNotFound     = errors.New("org not found")
+	ErrProjectNotFound = errors.New("project not found")
 )
 
 // CredentialsClient provides access to unencrypted credentials for viewing,
@@ -15,25 +26,62 @@ type CredentialsClient struct {
 	client *Client
 }
 
+// credentialsResp is the daemon's response when retrieving credentials with
+// failures reported.
+type credentialsResp struct {
+	Credentials []apitypes.CredentialResp    `json:"credentials"`
+	Failures    []apitypes.CredentialFailure `json:"failures"`
+}
+
+// UnmarshalJSON also accepts the bare list of credentials returned by daemons
+// that predate reporting failures.
+func (r *credentialsResp) UnmarshalJSON(b []byte) error {
+	if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 && trimmed[0] == '[' {
+		return json.Unmarshal(trimmed, &r.Credentials)
+	}
+
+	type resp credentialsResp
+	return json.Unmarshal(b, (*resp)(r))
+}
+
 // Search returns all credentials at the given pathexp.
+//
+// If some credentials could not be decrypted, the rest are returned along with
+// a *apitypes.DecryptionFailuresError.
 func (c *CredentialsClient) Search(ctx context.Context, pathexp string) ([]apitypes.CredentialEnvelope, error) {
 	v := &url.Values{}
 	v.Set("pathexp", pathexp)
 
+	return c.list(ctx, v)
+}
+
+// Get returns all credentials at the given path.
+//
+// If some credentials could not be decrypted, the rest are returned along with
+// a *apitypes.DecryptionFailuresError.
+func (c *CredentialsClient) Get(ctx context.Context, path string) ([]apitypes.CredentialEnvelope, error) {
+	v := &url.Values{}
+	v.Set("path", path)
+
+	return c.list(ctx, v)
+}
+
+func (c *CredentialsClient) list(ctx context.Context, v *url.Values) ([]apitypes.CredentialEnvelope, error) {
+	v.Set("failures", "report")
 	req, _, err := c.client.NewRequest("GET", "/credentials", v, nil, false)
 	if err != nil {
 		return nil, err
 	}
 
-	resp := []apitypes.CredentialResp{}
+	resp := credentialsResp{}
 
 	_, err = c.client.Do(ctx, req, &resp, nil, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	creds := make([]apitypes.CredentialEnvelope, len(resp))
-	for i, c := range resp {
+	creds := make([]apitypes.CredentialEnvelope, len(resp.Credentials))
+	for i, c := range resp.Credentials {
 		v, err := createEnvelopeFromResp(c)
 		if err != nil {
 			return nil, err
@@ -41,56 +89,193 @@ func (c *CredentialsClient) Search(ctx context.Context, pathexp string) ([]apity
 		creds[i] = *v
 	}
 
-	return creds, err
+	if len(resp.Failures) > 0 {
+		return creds, &apitypes.DecryptionFailuresError{Failures: resp.Failures}
+	}
+
+	return creds, nil
 }
 
-// Get returns all credentials at the given path.
-func (c *CredentialsClient) Get(ctx context.Context, path string) ([]apitypes.CredentialEnvelope, error) {
-	v := &url.Values{}
-	v.Set("path", path)
+// Create creates the given credential
+func (c *CredentialsClient) Create(ctx context.Context, cred *apitypes.Credential,
+	progress *ProgressFunc) (*apitypes.CredentialEnvelope, error) {
 
-	req, _, err := c.client.NewRequest("GET", "/credentials", v, nil, false)
+	// Check the name before the daemon does the work of encrypting the value
+	err := apitypes.ValidCredentialName((*cred).GetName())
 	if err != nil {
 		return nil, err
 	}
 
-	resp := []apitypes.CredentialResp{}
+	env := apitypes.CredentialEnvelope{Version: 2, Body: cred}
+	req, reqID, err := c.client.NewRequest("POST", "/credentials", nil, &env, false)
+	if err != nil {
+		return nil, err
+	}
 
-	_, err = c.client.Do(ctx, req, &resp, nil, nil)
+	resp := apitypes.CredentialResp{}
+	_, err = c.client.Do(ctx, req, &resp, &reqID, progress)
 	if err != nil {
 		return nil, err
 	}
 
-	creds := make([]apitypes.CredentialEnvelope, len(resp))
-	for i, c := range resp {
-		v, err := createEnvelopeFromResp(c)
+	out, err := createEnvelopeFromResp(resp)
+	return out, err
+}
+
+// Set creates a new version of the named credential at the given pathexp,
+// resolving the pathexp's org and project. The daemon encrypts the value for
+// the keyring at the pathexp. Setting an unset value unsets the credential.
+//
+// The description and tags are stored unencrypted. If neither is given, the
+// previous version's are kept.
+//
+// If expectedVersion is not nil, the credential is only set if its current
+// version matches, with 0 meaning it doesn't exist yet. Otherwise an error
+// satisfying apitypes.IsVersionConflictError is returned. The daemon checks
+// the version just before writing, so this is best-effort: a write made
+// through another daemon at the same moment can still be replaced.
+func (c *CredentialsClient) Set(ctx context.Context, pe *pathexp.PathExp, name string,
+	value *apitypes.CredentialValue, description string, tags []string,
+	expectedVersion *int, progress *ProgressFunc) (*apitypes.CredentialEnvelope, error) {
+
+	org, err := c.client.Orgs.GetByName(ctx, pe.Org())
+	if err != nil {
+		return nil, err
+	}
+	if org == nil {
+		return nil, ErrOrgNotFound
+	}
+
+	projects, err := c.client.Projects.List(ctx, &[]*identity.ID{org.ID}, &[]string{pe.Project()})
+	if err != nil {
+		return nil, err
+	}
+	if len(projects) != 1 {
+		return nil, ErrProjectNotFound
+	}
+
+	state := "set"
+	if value.IsUnset() {
+		state = "unset"
+		value = nil
+	}
+
+	var cred apitypes.Credential = &apitypes.CredentialV2{
+		BaseCredential: apitypes.BaseCredential{
+			OrgID:     org.ID,
+			ProjectID: projects[0].ID,
+			Name:      strings.ToLower(name),
+			PathExp:   pe,
+			Value:     value,
+		},
+		State:           state,
+		Description:     description,
+		Tags:            tags,
+		ExpectedVersion: expectedVersion,
+	}
+
+	return c.Create(ctx, &cred, progress)
+}
+
+// Unset marks the named credential at the given pathexp as unset.
+func (c *CredentialsClient) Unset(ctx context.Context, pe *pathexp.PathExp, name string,
+	progress *ProgressFunc) (*apitypes.CredentialEnvelope, error) {
+
+	return c.Set(ctx, pe, name, apitypes.NewUnsetCredentialValue(), "", nil, nil, progress)
+}
+
+// CreateBatch creates all of the given credentials in a single request. All
+// credentials must share the same pathexp.
+//
+// The daemon saves the credentials one at a time unless a new keyring is
+// needed, so an error may leave some of them saved. The error then lists
+// those that were.
+func (c *CredentialsClient) CreateBatch(ctx context.Context, creds []apitypes.Credential,
+	progress *ProgressFunc) ([]apitypes.CredentialEnvelope, error) {
+
+	envs := make([]apitypes.CredentialEnvelope, len(creds))
+	for i := range creds {
+		err := apitypes.ValidCredentialName(creds[i].GetName())
 		if err != nil {
 			return nil, err
 		}
-		creds[i] = *v
+		envs[i] = apitypes.CredentialEnvelope{Version: 2, Body: &creds[i]}
+	}
+
+	req, reqID, err := c.client.NewRequest("POST", "/credentials/batch", nil, envs, false)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := []apitypes.CredentialResp{}
+	_, err = c.client.Do(ctx, req, &resp, &reqID, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]apitypes.CredentialEnvelope, len(resp))
+	for i, r := range resp {
+		v, err := createEnvelopeFromResp(r)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = *v
 	}
 
-	return creds, err
+	return out, nil
 }
 
-// Create creates the given credential
-func (c *CredentialsClient) Create(ctx context.Context, cred *apitypes.Credential,
-	progress *ProgressFunc) (*apitypes.CredentialEnvelope, error) {
+// History returns every version of the named credential at the given
+// pathexp, oldest first. Values are decrypted only if reveal is true.
+func (c *CredentialsClient) History(ctx context.Context, pathexp, name string,
+	reveal bool) ([]apitypes.CredentialVersion, error) {
 
-	env := apitypes.CredentialEnvelope{Version: 2, Body: cred}
-	req, reqID, err := c.client.NewRequest("POST", "/credentials", nil, &env, false)
+	v := &url.Values{}
+	v.Set("pathexp", pathexp)
+	v.Set("name", name)
+	if reveal {
+		v.Set("reveal", "true")
+	}
+
+	req, _, err := c.client.NewRequest("GET", "/credentials/history", v, nil, false)
 	if err != nil {
 		return nil, err
 	}
 
-	resp := apitypes.CredentialResp{}
-	_, err = c.client.Do(ctx, req, &resp, &reqID, progress)
+	resp := []apitypes.CredentialVersion{}
+	_, err = c.client.Do(ctx, req, &resp, nil, nil)
+	return resp, err
+}
+
+// ListNames returns the names, pathexps and metadata of the credentials
+// matching the given pathexp, sorted by name. Values are neither returned nor
+// decrypted, so this is much faster than Search.
+//
+// A name is returned once for each pathexp it is set at.
+func (c *CredentialsClient) ListNames(ctx context.Context, pathexp string) ([]apitypes.CredentialKey, error) {
+	v := &url.Values{}
+	v.Set("pathexp", pathexp)
+
+	req, _, err := c.client.NewRequest("GET", "/credentials/keys", v, nil, false)
 	if err != nil {
 		return nil, err
 	}
 
-	out, err := createEnvelopeFromResp(resp)
-	return out, err
+	keys := []apitypes.CredentialKey{}
+	_, err = c.client.Do(ctx, req, &keys, nil, nil)
+	return keys, err
+}
+
+// FlushCache drops the credentials cached by the daemon, so the next
+// retrieval fetches and decrypts them all again.
+func (c *CredentialsClient) FlushCache(ctx context.Context) error {
+	req, _, err := c.client.NewRequest("DELETE", "/credentials/cache", nil, nil, false)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.client.Do(ctx, req, nil, nil, nil)
+	return err
 }
 
 func createEnvelopeFromResp(c apitypes.CredentialResp) (*apitypes.CredentialEnvelope, error) {
@@ -0,0 +1,275 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/config"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/pathexp"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+// mockDaemon serves the org, project and credential endpoints used when
+// setting credentials, for an org named "acme" holding a project named "api".
+// Credentials posted to it are recorded and echoed back, unless they expect a
+// version other than version.
+type mockDaemon struct {
+	orgID     identity.ID
+	projectID identity.ID
+	version   int
+	posted    []map[string]interface{}
+}
+
+func (m *mockDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	enc := json.NewEncoder(w)
+
+	switch r.URL.Path {
+	case "/proxy/orgs":
+		orgs := []OrgResult{}
+		if q.Get("name") == "acme" {
+			orgs = append(orgs, OrgResult{ID: &m.orgID, Body: &primitive.Org{Name: "acme"}})
+		}
+		enc.Encode(orgs)
+	case "/proxy/projects":
+		projects := []ProjectResult{}
+		if q.Get("org_id") == m.orgID.String() && q.Get("name") == "api" {
+			projects = append(projects, ProjectResult{
+				ID:   &m.projectID,
+				Body: &primitive.Project{Name: "api", OrgID: &m.orgID},
+			})
+		}
+		enc.Encode(projects)
+	case "/v1/credentials":
+		raw := json.RawMessage{}
+		err := json.NewDecoder(r.Body).Decode(&raw)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		posted := map[string]interface{}{}
+		json.Unmarshal(raw, &posted)
+		m.posted = append(m.posted, posted)
+
+		body, _ := posted["body"].(map[string]interface{})
+		if expected, ok := body["expected_version"].(float64); ok && int(expected) != m.version {
+			w.WriteHeader(http.StatusConflict)
+			enc.Encode(&apitypes.Error{Type: apitypes.VersionConflictError, Err: []string{"version mismatch"}})
+			return
+		}
+
+		w.Write(raw)
+	case "/v1/credentials/keys":
+		keys := []apitypes.CredentialKey{}
+		if q.Get("pathexp") == "/acme/api/*/*/*/*" {
+			pe, _ := pathexp.Parse("/acme/api/dev/*/*/*")
+			keys = append(keys,
+				apitypes.CredentialKey{Name: "password", PathExp: pe, Version: 2, Tags: []string{"db"}},
+				apitypes.CredentialKey{Name: "token", PathExp: pe, Version: 1},
+			)
+		}
+		enc.Encode(keys)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newMockDaemon(t *testing.T) (*mockDaemon, *Client, func()) {
+	dir, err := ioutil.TempDir("", "torus-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	socketPath := filepath.Join(dir, "daemon.socket")
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &mockDaemon{}
+	m.orgID, err = identity.NewMutable(&primitive.Org{Name: "acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.projectID, err = identity.NewMutable(&primitive.Project{Name: "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(m)
+	srv.Listener.Close()
+	srv.Listener = l
+	srv.Start()
+
+	client := NewClient(&config.Config{SocketPath: socketPath})
+	return m, client, func() {
+		srv.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestCredentialsSet(t *testing.T) {
+	m, client, done := newMockDaemon(t)
+	defer done()
+
+	c := context.Background()
+	pe, err := pathexp.Parse("/acme/api/dev/default/*/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("set", func(t *testing.T) {
+		m.posted = nil
+		_, err := client.Credentials.Set(c, pe, "DB_PASSWORD",
+			apitypes.NewStringCredentialValue("hunter2"), "", nil, nil, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		if len(m.posted) != 1 {
+			t.Fatalf("Expected 1 credential posted, got %d", len(m.posted))
+		}
+		body := m.posted[0]["body"].(map[string]interface{})
+		if body["name"] != "db_password" || body["state"] != "set" {
+			t.Errorf("Unexpected credential: %v", body)
+		}
+		if body["org_id"] != m.orgID.String() || body["project_id"] != m.projectID.String() {
+			t.Errorf("Credential not placed in the right org and project: %v", body)
+		}
+		if body["value"] == nil {
+			t.Error("Expected the credential to have a value")
+		}
+	})
+
+	t.Run("unset", func(t *testing.T) {
+		m.posted = nil
+		_, err := client.Credentials.Unset(c, pe, "db_password", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		body := m.posted[0]["body"].(map[string]interface{})
+		if body["state"] != "unset" || body["value"] != nil {
+			t.Errorf("Expected an unset credential without a value: %v", body)
+		}
+	})
+
+	t.Run("expected version", func(t *testing.T) {
+		m.posted = nil
+		m.version = 3
+
+		expected := 3
+		_, err := client.Credentials.Set(c, pe, "db_password",
+			apitypes.NewStringCredentialValue("hunter2"), "", nil, &expected, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		body := m.posted[0]["body"].(map[string]interface{})
+		if body["expected_version"] != float64(3) {
+			t.Errorf("Expected the expected version to be posted: %v", body)
+		}
+	})
+
+	t.Run("concurrent version bump", func(t *testing.T) {
+		m.posted = nil
+		m.version = 3
+
+		// Someone else sets the credential after version 3 was read.
+		expected := 3
+		m.version++
+
+		_, err := client.Credentials.Set(c, pe, "db_password",
+			apitypes.NewStringCredentialValue("hunter2"), "", nil, &expected, nil)
+		if !apitypes.IsVersionConflictError(err) {
+			t.Errorf("Expected a version conflict error, got %v", err)
+		}
+	})
+
+	t.Run("missing org or project", func(t *testing.T) {
+		m.posted = nil
+		for raw, expected := range map[string]error{
+			"/other/api/dev/default/*/*":  ErrOrgNotFound,
+			"/acme/other/dev/default/*/*": ErrProjectNotFound,
+		} {
+			pe, err := pathexp.Parse(raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = client.Credentials.Set(c, pe, "db_password",
+				apitypes.NewStringCredentialValue("hunter2"), "", nil, nil, nil)
+			if err != expected {
+				t.Errorf("%s: expected %v, got %v", raw, expected, err)
+			}
+		}
+
+		if len(m.posted) != 0 {
+			t.Errorf("Expected nothing to be posted, got %d credentials", len(m.posted))
+		}
+	})
+}
+
+func TestCredentialsListNames(t *testing.T) {
+	_, client, done := newMockDaemon(t)
+	defer done()
+
+	keys, err := client.Credentials.ListNames(context.Background(), "/acme/api/*/*/*/*")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(keys) != 2 {
+		t.Fatalf("Expected 2 keys, got %d", len(keys))
+	}
+	if keys[0].Name != "password" || keys[0].Version != 2 || !apitypes.HasTags(&keys[0], []string{"db"}) {
+		t.Errorf("Unexpected first key: %+v", keys[0])
+	}
+	if keys[1].PathExp.String() != "/acme/api/dev/*/*/*" {
+		t.Errorf("Unexpected pathexp: %s", keys[1].PathExp)
+	}
+
+	keys, err = client.Credentials.ListNames(context.Background(), "/acme/web/*/*/*/*")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("Expected no keys, got %d", len(keys))
+	}
+}
+
+func TestCredentialsRespUnmarshal(t *testing.T) {
+	t.Run("with failures", func(t *testing.T) {
+		raw := `{"credentials":[{"version":2,"body":{}}],"failures":[{"name":"token","reason":"bad"}]}`
+		resp := credentialsResp{}
+		err := json.Unmarshal([]byte(raw), &resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Credentials) != 1 || len(resp.Failures) != 1 || resp.Failures[0].Name != "token" {
+			t.Errorf("Unexpected response: %+v", resp)
+		}
+	})
+
+	t.Run("bare list from an older daemon", func(t *testing.T) {
+		raw := ` [{"version":2,"body":{}},{"version":2,"body":{}}]`
+		resp := credentialsResp{}
+		err := json.Unmarshal([]byte(raw), &resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Credentials) != 2 || len(resp.Failures) != 0 {
+			t.Errorf("Unexpected response: %+v", resp)
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"context"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+)
+
+// DaemonClient provides access to the daemon's /v1/health and /v1/stop
+// endpoints, for inspecting and stopping the daemon itself.
+type DaemonClient struct {
+	client *Client
+}
+
+// Health returns the daemon's liveness details.
+func (d *DaemonClient) Health(ctx context.Context) (*apitypes.Health, error) {
+	req, _, err := d.client.NewRequest("GET", "/health", nil, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	health := &apitypes.Health{}
+	_, err = d.client.Do(ctx, req, health, nil, nil)
+	return health, err
+}
+
+// Stop asks the daemon to shut down gracefully. It returns before the daemon
+// has stopped; the daemon first waits for in-flight requests to finish.
+func (d *DaemonClient) Stop(ctx context.Context) error {
+	req, _, err := d.client.NewRequest("POST", "/stop", nil, nil, false)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.client.Do(ctx, req, nil, nil, nil)
+	return err
+}
@@ -131,3 +131,14 @@ func (i *InvitesClient) Approve(ctx context.Context, inviteID identity.ID, outpu
 
 	return nil
 }
+
+// Revoke deletes an outstanding invite, so it can no longer be accepted
+func (i *InvitesClient) Revoke(ctx context.Context, inviteID identity.ID) error {
+	req, _, err := i.client.NewRequest("DELETE", "/org-invites/"+inviteID.String(), nil, nil, true)
+	if err != nil {
+		return err
+	}
+
+	_, err = i.client.Do(ctx, req, nil, nil, nil)
+	return err
+}
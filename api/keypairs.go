@@ -25,8 +25,23 @@ type KeypairResult struct {
 	} `json:"private_key"`
 	Claims *[]struct {
 		ID   *identity.ID     `json:"id"`
-		Body *primitive.Claim `json:"claims"`
+		Body *primitive.Claim `json:"body"`
+	} `json:"claims"`
+}
+
+// Revoked returns whether the keypair has been revoked.
+func (k *KeypairResult) Revoked() bool {
+	if k.Claims == nil {
+		return false
 	}
+
+	for _, claim := range *k.Claims {
+		if claim.Body != nil && claim.Body.KeyType == primitive.RevocationClaimType {
+			return true
+		}
+	}
+
+	return false
 }
 
 type keypairsGenerateRequest struct {
@@ -48,6 +63,22 @@ func (k *KeypairsClient) Generate(ctx context.Context, orgID *identity.ID,
 	return err
 }
 
+// Regenerate replaces the user's keypairs in the given org with new ones,
+// re-encrypting their keyring memberships and revoking the old keypairs.
+func (k *KeypairsClient) Regenerate(ctx context.Context, orgID *identity.ID,
+	output *ProgressFunc) error {
+
+	kpgr := keypairsGenerateRequest{OrgID: orgID}
+
+	req, reqID, err := k.client.NewRequest("POST", "/keypairs/regenerate", nil, &kpgr, false)
+	if err != nil {
+		return err
+	}
+
+	_, err = k.client.Do(ctx, req, nil, &reqID, output)
+	return err
+}
+
 // List retrieves relevant keypairs by orgID
 func (k *KeypairsClient) List(ctx context.Context, orgID *identity.ID) ([]KeypairResult, error) {
 	v := &url.Values{}
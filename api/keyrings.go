@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/pathexp"
+)
+
+// KeyringsClient makes requests to the daemon's keyrings endpoints
+type KeyringsClient struct {
+	client *Client
+}
+
+type keyringsRotateRequest struct {
+	OrgID *identity.ID `json:"org_id"`
+}
+
+// Rotate creates new versions of every keyring in the org that a removed
+// member belonged to, re-encrypting their secrets for the remaining members.
+// The PathExps of the rotated keyrings are returned.
+func (k *KeyringsClient) Rotate(ctx context.Context, orgID *identity.ID,
+	output *ProgressFunc) ([]*pathexp.PathExp, error) {
+
+	krr := keyringsRotateRequest{OrgID: orgID}
+
+	req, reqID, err := k.client.NewRequest("POST", "/keyrings/rotate", nil, &krr, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var rotated []*pathexp.PathExp
+	_, err = k.client.Do(ctx, req, &rotated, &reqID, output)
+	return rotated, err
+}
+
+type keyringsRepairRequest struct {
+	OrgID     *identity.ID `json:"org_id"`
+	ProjectID *identity.ID `json:"project_id"`
+}
+
+// Repair adds the keyring memberships that are missing for members of the
+// org, for the keyrings in the given project. Every missing membership is
+// returned, along with whether it could be added.
+func (k *KeyringsClient) Repair(ctx context.Context, orgID, projectID *identity.ID,
+	output *ProgressFunc) ([]apitypes.KeyringRepair, error) {
+
+	krr := keyringsRepairRequest{OrgID: orgID, ProjectID: projectID}
+
+	req, reqID, err := k.client.NewRequest("POST", "/keyrings/repair", nil, &krr, false)
+	if err != nil {
+		return nil, err
+	}
+
+	repairs := []apitypes.KeyringRepair{}
+	_, err = k.client.Do(ctx, req, &repairs, &reqID, output)
+	return repairs, err
+}
+
+// Verify checks the integrity of the org's credential tree: the signatures on
+// its keyrings, keyring memberships and credentials, and that every member of
+// the org belongs to the keyrings holding active secrets. The problems found
+// are returned, most severe first.
+func (k *KeyringsClient) Verify(ctx context.Context, orgID *identity.ID,
+	output *ProgressFunc) ([]apitypes.KeyringFinding, error) {
+
+	v := &url.Values{}
+	v.Set("org_id", orgID.String())
+
+	req, reqID, err := k.client.NewRequest("GET", "/keyrings/verify", v, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	findings := []apitypes.KeyringFinding{}
+	_, err = k.client.Do(ctx, req, &findings, &reqID, output)
+	return findings, err
+}
+
+// Members returns who can decrypt the secrets in each keyring contained within
+// the given path expression.
+func (k *KeyringsClient) Members(ctx context.Context, pathexp string) ([]apitypes.KeyringAccess, error) {
+	v := &url.Values{}
+	v.Set("pathexp", pathexp)
+
+	req, _, err := k.client.NewRequest("GET", "/keyrings/members", v, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	access := []apitypes.KeyringAccess{}
+	_, err = k.client.Do(ctx, req, &access, nil, nil)
+	return access, err
+}
@@ -3,15 +3,21 @@ package api
 import (
 	"context"
 	"crypto/rand"
+	"errors"
 	"net/url"
 
 	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/base64"
 	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
 )
 
 const tokenSecretSize = 18
 
+// ErrMachineDestroyed is returned when destroying a machine that has already
+// been destroyed.
+var ErrMachineDestroyed = errors.New("machine is already destroyed")
+
 // MachinesClient makes requests to the Daemon on behalf of the user to
 // manipulate Machine resources.
 type MachinesClient struct {
@@ -48,6 +54,36 @@ func (m *MachinesClient) Create(ctx context.Context, orgID, teamID *identity.ID,
 	return result, secret, nil
 }
 
+// RotateToken replaces the given token of a machine with a newly generated
+// one, returning the new token and its secret.
+func (m *MachinesClient) RotateToken(ctx context.Context, machineID, tokenID *identity.ID,
+	output *ProgressFunc) (*apitypes.MachineTokenSegment, *base64.Value, error) {
+
+	secret, err := createTokenSecret()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mtr := apitypes.MachineTokenRotateRequest{
+		MachineID: machineID,
+		TokenID:   tokenID,
+		Secret:    secret,
+	}
+
+	req, reqID, err := m.client.NewRequest("POST", "/machines/tokens/rotate", nil, &mtr, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := &apitypes.MachineTokenSegment{}
+	_, err = m.client.Do(ctx, req, result, &reqID, output)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return result, secret, nil
+}
+
 func createTokenSecret() (*base64.Value, error) {
 	value := make([]byte, tokenSecretSize)
 	_, err := rand.Read(value)
@@ -65,6 +101,9 @@ func (m *MachinesClient) List(ctx context.Context, orgID *identity.ID, state *st
 		v.Add("org_id", (*orgID).String())
 	}
 	if state != nil {
+		if *state != primitive.MachineActiveState && *state != primitive.MachineDestroyedState {
+			return nil, errors.New("unknown machine state: " + *state)
+		}
 		v.Add("state", *state)
 	}
 	if teamID != nil {
@@ -88,16 +127,46 @@ func (m *MachinesClient) List(ctx context.Context, orgID *identity.ID, state *st
 	return results, nil
 }
 
-// Destroy machine by ID
-func (m *MachinesClient) Destroy(ctx context.Context, machineID *identity.ID) error {
+// Destroy revokes each of the machine's active tokens, and then destroys the
+// machine. It returns the number of tokens revoked.
+//
+// Revoking the tokens revokes their keyring memberships, so the machine can't
+// decrypt anything once the keyrings are rotated.
+func (m *MachinesClient) Destroy(ctx context.Context, machineID *identity.ID) (int, error) {
+	machine, err := m.Get(ctx, machineID)
+	if err != nil {
+		return 0, err
+	}
+	if machine.Machine.Body.State == primitive.MachineDestroyedState {
+		return 0, ErrMachineDestroyed
+	}
+
+	revoked := 0
+	for _, t := range machine.Tokens {
+		if t.Token.Body.State != primitive.MachineTokenActiveState {
+			continue
+		}
+
+		path := "/machines/" + machineID.String() + "/tokens/" + t.Token.ID.String()
+		req, reqID, err := m.client.NewRequest("DELETE", path, nil, nil, true)
+		if err != nil {
+			return revoked, err
+		}
+
+		_, err = m.client.Do(ctx, req, nil, &reqID, nil)
+		if err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+
 	req, reqID, err := m.client.NewRequest("DELETE", "/machines/"+machineID.String(), nil, nil, true)
 	if err != nil {
-		return err
+		return revoked, err
 	}
 
 	_, err = m.client.Do(ctx, req, nil, &reqID, nil)
-
-	return err
+	return revoked, err
 }
 
 // Get machine by ID
@@ -4,9 +4,12 @@ import (
 	"context"
 	"errors"
 	"net/url"
+	"sort"
+	"time"
 
 	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/pagination"
 	"github.com/manifoldco/torus-cli/primitive"
 )
 
@@ -28,6 +31,12 @@ type orgCreateRequest struct {
 	} `json:"body"`
 }
 
+type orgUpdateRequest struct {
+	Body struct {
+		Name string `json:"name"`
+	} `json:"body"`
+}
+
 // OrgTreeSegment is the payload returns for an org tree
 type OrgTreeSegment struct {
 	Org      *primitive.Org      `json:"org"`
@@ -55,6 +64,77 @@ func (o *OrgsClient) Create(ctx context.Context, name string) (*OrgResult, error
 	return &res, err
 }
 
+// Update renames the org with the given id. It returns the updated org.
+func (o *OrgsClient) Update(ctx context.Context, orgID identity.ID, name string) (*OrgResult, error) {
+	org := orgUpdateRequest{}
+	org.Body.Name = name
+
+	req, _, err := o.client.NewRequest("PATCH", "/orgs/"+orgID.String(), nil, &org, true)
+	if err != nil {
+		return nil, err
+	}
+
+	res := OrgResult{}
+	_, err = o.client.Do(ctx, req, &res, nil, nil)
+	return &res, err
+}
+
+// Delete deletes the org with the given id, along with everything in it.
+func (o *OrgsClient) Delete(ctx context.Context, orgID identity.ID) error {
+	req, _, err := o.client.NewRequest("DELETE", "/orgs/"+orgID.String(), nil, nil, true)
+	if err != nil {
+		return err
+	}
+
+	_, err = o.client.Do(ctx, req, nil, nil, nil)
+	return err
+}
+
+// AuditLogOptions filters the events returned by AuditLog. Zero values are
+// not used to filter.
+type AuditLogOptions struct {
+	Since time.Time
+	Until time.Time
+	Actor string
+
+	Limit  int
+	Offset int
+}
+
+// AuditLog returns a page of the org's audit log, oldest first, along with a
+// pagination.Cursor for requesting the next page.
+func (o *OrgsClient) AuditLog(ctx context.Context, orgID *identity.ID,
+	opts *AuditLogOptions) ([]apitypes.AuditEvent, *pagination.Cursor, error) {
+
+	v := &url.Values{}
+	if opts == nil {
+		opts = &AuditLogOptions{}
+	}
+	if !opts.Since.IsZero() {
+		v.Set("since", opts.Since.UTC().Format(time.RFC3339))
+	}
+	if !opts.Until.IsZero() {
+		v.Set("until", opts.Until.UTC().Format(time.RFC3339))
+	}
+	if opts.Actor != "" {
+		v.Set("actor", opts.Actor)
+	}
+	pagination.SetPage(v, opts.Limit, opts.Offset)
+
+	req, _, err := o.client.NewRequest("GET", "/orgs/"+orgID.String()+"/audit", v, nil, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	events := []apitypes.AuditEvent{}
+	resp, err := o.client.Do(ctx, req, &events, nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return events, pagination.NewCursor(resp, opts.Limit, opts.Offset, len(events)), nil
+}
+
 // GetByName retrieves an org by its named
 func (o *OrgsClient) GetByName(ctx context.Context, name string) (*OrgResult, error) {
 	v := &url.Values{}
@@ -92,6 +172,103 @@ func (o *OrgsClient) List(ctx context.Context) ([]OrgResult, error) {
 	return orgs, err
 }
 
+// OrgRole is an org along with the signed-in user or machine's role within
+// it. Users have the highest of the owner, admin and member system teams they
+// belong to; machines have the name of their machine role team.
+type OrgRole struct {
+	Org  OrgResult
+	Role string
+}
+
+// ListWithRoles returns the orgs the given session has access to, with its
+// role in each. Machine sessions only have access to the org their machine
+// belongs to.
+func (o *OrgsClient) ListWithRoles(ctx context.Context, session *Session) ([]OrgRole, error) {
+	orgs, err := o.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make([]OrgRole, 0, len(orgs))
+	for _, org := range orgs {
+		if orgID := session.OrgID(); orgID != nil && *orgID != *org.ID {
+			continue
+		}
+
+		teams, err := o.client.Teams.GetByOrg(ctx, org.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		memberships, err := o.client.Memberships.List(ctx, org.ID, session.ID(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		roles = append(roles, OrgRole{
+			Org:  org,
+			Role: orgRole(session.Type(), teams, memberships),
+		})
+	}
+
+	return roles, nil
+}
+
+// Role returns the given session's role in the org with the given ID.
+func (o *OrgsClient) Role(ctx context.Context, session *Session, orgID *identity.ID) (string, error) {
+	teams, err := o.client.Teams.GetByOrg(ctx, orgID)
+	if err != nil {
+		return "", err
+	}
+
+	memberships, err := o.client.Memberships.List(ctx, orgID, session.ID(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	return orgRole(session.Type(), teams, memberships), nil
+}
+
+// userRoles lists the system teams that make up a user's role, highest first.
+var userRoles = []string{
+	primitive.OwnerTeamName, primitive.AdminTeamName, primitive.MemberTeamName,
+}
+
+// orgRole derives a role from the teams in an org and the memberships held
+// in them by a user or machine.
+func orgRole(sessionType string, teams []TeamResult, memberships []MembershipResult) string {
+	byID := make(map[identity.ID]*primitive.Team, len(teams))
+	for _, t := range teams {
+		byID[*t.ID] = t.Body
+	}
+
+	held := make(map[string]bool, len(memberships))
+	for _, m := range memberships {
+		t, ok := byID[*m.Body.TeamID]
+		if !ok {
+			continue
+		}
+
+		if sessionType == apitypes.MachineSession && t.TeamType == primitive.MachineTeam {
+			return t.Name
+		}
+		if t.TeamType == primitive.SystemTeam {
+			held[t.Name] = true
+		}
+	}
+
+	if sessionType == apitypes.MachineSession {
+		return primitive.MachineTeamName
+	}
+
+	for _, name := range userRoles {
+		if held[name] {
+			return name
+		}
+	}
+	return primitive.MemberTeamName
+}
+
 // RemoveMember removes a user from an org
 func (o *OrgsClient) RemoveMember(ctx context.Context, orgID identity.ID,
 	userID identity.ID) error {
@@ -119,3 +296,99 @@ func (o *OrgsClient) GetTree(ctx context.Context, orgID identity.ID) ([]OrgTreeS
 	_, err = o.client.Do(ctx, req, &segments, nil, nil)
 	return segments, err
 }
+
+// Types of org member.
+const (
+	MemberTypeUser    = "user"
+	MemberTypeMachine = "machine"
+)
+
+// OrgMember is a user or machine belonging to an org, along with the teams it
+// is a member of. Machines have no username or email.
+type OrgMember struct {
+	ID       *identity.ID
+	Type     string
+	Name     string
+	Username string
+	Email    string
+	Teams    []*apitypes.Team
+}
+
+// Members returns the users and machines in the given org, joined with their
+// team memberships. Users are listed first, sorted by username, followed by
+// machines sorted by name.
+func (o *OrgsClient) Members(ctx context.Context, orgID identity.ID) ([]OrgMember, error) {
+	trees, err := o.GetTree(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if len(trees) < 1 {
+		return nil, errors.New("org tree not found")
+	}
+	tree := trees[0]
+
+	machines, err := o.client.Machines.List(ctx, &orgID, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[identity.ID]*OrgMember)
+	for _, p := range tree.Profiles {
+		members[*p.ID] = &OrgMember{
+			ID:       p.ID,
+			Type:     MemberTypeUser,
+			Name:     p.Body.Name,
+			Username: p.Body.Username,
+			Email:    p.Body.Email,
+		}
+	}
+	for _, m := range machines {
+		members[*m.Machine.ID] = &OrgMember{
+			ID:   m.Machine.ID,
+			Type: MemberTypeMachine,
+			Name: m.Machine.Body.Name,
+		}
+	}
+
+	// Only those with a membership in one of the org's teams are members.
+	var result []OrgMember
+	found := make(map[identity.ID]bool)
+	for _, t := range tree.Teams {
+		if t.Memberships == nil {
+			continue
+		}
+		for _, membership := range *t.Memberships {
+			member, ok := members[*membership.Body.OwnerID]
+			if !ok {
+				continue
+			}
+			member.Teams = append(member.Teams, t.Team)
+			found[*member.ID] = true
+		}
+	}
+
+	for id, member := range members {
+		if found[id] {
+			result = append(result, *member)
+		}
+	}
+	sort.Sort(orgMembers(result))
+
+	return result, nil
+}
+
+// orgMembers implements sort.Interface, ordering users before machines, and
+// each by username or name.
+type orgMembers []OrgMember
+
+func (m orgMembers) Len() int      { return len(m) }
+func (m orgMembers) Swap(i, j int) { m[i], m[j] = m[j], m[i] }
+func (m orgMembers) Less(i, j int) bool {
+	if m[i].Type != m[j].Type {
+		return m[i].Type == MemberTypeUser
+	}
+	if m[i].Type == MemberTypeUser {
+		return m[i].Username < m[j].Username
+	}
+	return m[i].Name < m[j].Name
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+func TestOrgRole(t *testing.T) {
+	orgID := newID(t, &primitive.Org{Name: "acme"})
+
+	var teams []TeamResult
+	team := func(name, teamType string) TeamResult {
+		body := &primitive.Team{Name: name, OrgID: orgID, TeamType: teamType}
+		tr := TeamResult{ID: newID(t, body), Body: body}
+		teams = append(teams, tr)
+		return tr
+	}
+
+	owner := team(primitive.OwnerTeamName, primitive.SystemTeam)
+	admin := team(primitive.AdminTeamName, primitive.SystemTeam)
+	member := team(primitive.MemberTeamName, primitive.SystemTeam)
+	machine := team(primitive.MachineTeamName, primitive.SystemTeam)
+	deploy := team("deploy", primitive.MachineTeam)
+	eng := team("eng", primitive.UserTeam)
+
+	memberships := func(ts ...TeamResult) []MembershipResult {
+		res := make([]MembershipResult, len(ts))
+		for i, t := range ts {
+			res[i] = MembershipResult{Body: &primitive.Membership{OrgID: orgID, TeamID: t.ID}}
+		}
+		return res
+	}
+
+	tcs := []struct {
+		name        string
+		sessionType string
+		memberships []MembershipResult
+		role        string
+	}{
+		{"owner", apitypes.UserSession, memberships(member, eng, owner, admin), "owner"},
+		{"admin", apitypes.UserSession, memberships(member, admin), "admin"},
+		{"member", apitypes.UserSession, memberships(eng, member), "member"},
+		{"no memberships", apitypes.UserSession, nil, "member"},
+		{"machine role", apitypes.MachineSession, memberships(machine, deploy), "deploy"},
+		{"machine without role", apitypes.MachineSession, memberships(machine), "machine"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			role := orgRole(tc.sessionType, teams, tc.memberships)
+			if role != tc.role {
+				t.Errorf("Expected role %q, got %q", tc.role, role)
+			}
+		})
+	}
+}
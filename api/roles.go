@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+// ErrRoleExists is returned when creating a machine role with the name of a
+// team that already exists in the org.
+var ErrRoleExists = errors.New("Role already exists")
+
+// RolesClient makes proxied requests to the registry to manage machine roles,
+// the teams that machines belong to.
+type RolesClient struct {
+	client *Client
+}
+
+// Role is a machine role, along with the number of active machines assigned
+// to it. System is true for the machine team every machine belongs to.
+type Role struct {
+	ID       *identity.ID `json:"id"`
+	Name     string       `json:"name"`
+	System   bool         `json:"system"`
+	Machines int          `json:"machines"`
+}
+
+// List returns the machine roles in the given org, in the order the registry
+// returns them.
+func (r *RolesClient) List(ctx context.Context, orgID *identity.ID) ([]Role, error) {
+	teams, err := r.client.Teams.List(ctx, orgID, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	state := primitive.MachineActiveState
+	machines, err := r.client.Machines.List(ctx, orgID, &state, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return machineRoles(teams, machines), nil
+}
+
+// Create creates a machine role with the given name in the given org. It
+// returns ErrRoleExists if a team of that name already exists.
+func (r *RolesClient) Create(ctx context.Context, orgID *identity.ID, name string) (*apitypes.Team, error) {
+	if orgID == nil {
+		return nil, errors.New("invalid org")
+	}
+
+	teams, err := r.client.Teams.List(ctx, orgID, name, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range teams {
+		if strings.EqualFold(t.Body.Name, name) {
+			return nil, ErrRoleExists
+		}
+	}
+
+	team, err := r.client.Teams.Create(ctx, orgID, name, primitive.MachineTeam)
+	if err != nil && (apitypes.IsConflictError(err) || apitypes.HasMessage(err, "resource exists")) {
+		return nil, ErrRoleExists
+	}
+
+	return team, err
+}
+
+// machineRoles returns the machine roles among teams, counting the machines
+// with a membership in each.
+func machineRoles(teams []TeamResult, machines []*apitypes.MachineSegment) []Role {
+	counts := make(map[identity.ID]int)
+	for _, m := range machines {
+		for _, membership := range m.Memberships {
+			counts[*membership.Body.TeamID]++
+		}
+	}
+
+	roles := []Role{}
+	for _, t := range teams {
+		system := t.Body.TeamType == primitive.SystemTeam && t.Body.Name == primitive.MachineTeamName
+		if t.Body.TeamType != primitive.MachineTeam && !system {
+			continue
+		}
+
+		roles = append(roles, Role{
+			ID:       t.ID,
+			Name:     t.Body.Name,
+			System:   system,
+			Machines: counts[*t.ID],
+		})
+	}
+
+	return roles
+}
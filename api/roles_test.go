@@ -0,0 +1,51 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+func TestMachineRoles(t *testing.T) {
+	team := func(name, teamType string) TeamResult {
+		body := &primitive.Team{Name: name, TeamType: teamType}
+		return TeamResult{ID: newID(t, body), Body: body}
+	}
+
+	member := team(primitive.MemberTeamName, primitive.SystemTeam)
+	machine := team(primitive.MachineTeamName, primitive.SystemTeam)
+	deploy := team("deploy", primitive.MachineTeam)
+	ci := team("ci", primitive.MachineTeam)
+	eng := team("eng", primitive.UserTeam)
+
+	segment := func(name string, teams ...TeamResult) *apitypes.MachineSegment {
+		m := &apitypes.MachineSegment{}
+		for _, t := range teams {
+			m.Memberships = append(m.Memberships, &struct {
+				ID   *identity.ID          `json:"id"`
+				Body *primitive.Membership `json:"body"`
+			}{Body: &primitive.Membership{TeamID: t.ID}})
+		}
+		return m
+	}
+
+	machines := []*apitypes.MachineSegment{
+		segment("web", machine, deploy),
+		segment("worker", machine, deploy),
+		segment("builder", machine),
+	}
+
+	roles := machineRoles([]TeamResult{member, machine, deploy, ci, eng}, machines)
+
+	expected := []Role{
+		{ID: machine.ID, Name: primitive.MachineTeamName, System: true, Machines: 3},
+		{ID: deploy.ID, Name: "deploy", Machines: 2},
+		{ID: ci.ID, Name: "ci", Machines: 0},
+	}
+	if !reflect.DeepEqual(roles, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, roles)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net/url"
+	"sync"
 
 	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/identity"
@@ -82,3 +83,77 @@ func (s *ServicesClient) Create(ctx context.Context, orgID, projectID *identity.
 	_, err = s.client.Do(ctx, req, nil, nil, nil)
 	return err
 }
+
+type serviceUpdateRequest struct {
+	Body struct {
+		Name string `json:"name"`
+	} `json:"body"`
+}
+
+// Update renames the service with the given id. It returns the updated
+// service.
+func (s *ServicesClient) Update(ctx context.Context, serviceID identity.ID, name string) (*ServiceResult, error) {
+	service := serviceUpdateRequest{}
+	service.Body.Name = name
+
+	req, _, err := s.client.NewRequest("PATCH", "/services/"+serviceID.String(), nil, &service, true)
+	if err != nil {
+		return nil, err
+	}
+
+	res := ServiceResult{}
+	_, err = s.client.Do(ctx, req, &res, nil, nil)
+	return &res, err
+}
+
+// Delete deletes the service with the given id.
+func (s *ServicesClient) Delete(ctx context.Context, serviceID identity.ID) error {
+	req, _, err := s.client.NewRequest("DELETE", "/services/"+serviceID.String(), nil, nil, true)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Do(ctx, req, nil, nil, nil)
+	return err
+}
+
+// serviceCreateConcurrency bounds the number of services CreateBatch creates
+// at once. The registry has no batch endpoint for services.
+const serviceCreateConcurrency = 4
+
+// ServiceCreateResult is the outcome of creating a single service as part of
+// a batch. Err is nil if the service was created.
+type ServiceCreateResult struct {
+	Name string
+	Err  error
+}
+
+// CreateBatch creates a service for each of the given names. A failure to
+// create one service does not stop the others from being created; the
+// result for each name is returned, in the order the names were given.
+func (s *ServicesClient) CreateBatch(ctx context.Context, orgID, projectID *identity.ID, names []string) ([]ServiceCreateResult, error) {
+	if orgID == nil || projectID == nil {
+		return nil, errors.New("invalid org or project")
+	}
+
+	results := make([]ServiceCreateResult, len(names))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, serviceCreateConcurrency)
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i] = ServiceCreateResult{
+				Name: name,
+				Err:  s.Create(ctx, orgID, projectID, name),
+			}
+		}(i, name)
+	}
+	wg.Wait()
+
+	return results, nil
+}
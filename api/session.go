@@ -1,12 +1,14 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"io/ioutil"
+	"unicode/utf8"
 
 	"github.com/manifoldco/torus-cli/apitypes"
-	"github.com/manifoldco/torus-cli/base64"
 	"github.com/manifoldco/torus-cli/envelope"
 	"github.com/manifoldco/torus-cli/identity"
 	"github.com/manifoldco/torus-cli/primitive"
@@ -44,6 +46,15 @@ func (s *Session) Username() string {
 	return s.identity.Body.(*primitive.User).Username
 }
 
+// OrgID returns the id of the org a machine belongs to, or nil for a user
+func (s *Session) OrgID() *identity.ID {
+	if s.sessionType == apitypes.MachineSession {
+		return s.identity.Body.(*primitive.Machine).OrgID
+	}
+
+	return nil
+}
+
 // Name returns the fullname of the user or the machine name
 func (s *Session) Name() string {
 	if s.sessionType == apitypes.MachineSession {
@@ -100,6 +111,17 @@ type SessionClient struct {
 
 // Who returns the Session object for the current authenticated user or machine
 func (s *SessionClient) Who(ctx context.Context) (*Session, error) {
+	resp, err := s.Self(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSession(resp)
+}
+
+// Self returns the raw identity and auth objects for the current
+// authenticated user or machine
+func (s *SessionClient) Self(ctx context.Context) (*apitypes.Self, error) {
 	req, _, err := s.client.NewRequest("GET", "/self", nil, nil, false)
 	if err != nil {
 		return nil, err
@@ -111,7 +133,7 @@ func (s *SessionClient) Who(ctx context.Context) (*Session, error) {
 		return nil, err
 	}
 
-	return NewSession(resp)
+	return resp, nil
 }
 
 // Get returns the status of the user's session.
@@ -130,40 +152,79 @@ func (s *SessionClient) Get(ctx context.Context) (*apitypes.SessionStatus, error
 	return resp, nil
 }
 
-// UserLogin logs the user in using the provided email and passphrase
-func (s *SessionClient) UserLogin(ctx context.Context, email, passphrase string) error {
-	// Package up login credentials for the user
-	login := apitypes.UserLogin{
-		Email:    email,
-		Password: passphrase,
+// UserLogin logs the user in using the provided email and passphrase.
+//
+// The request body is built directly from passphrase, and zeroed once it has
+// been sent, so the caller can zero passphrase knowing no other copy remains.
+func (s *SessionClient) UserLogin(ctx context.Context, email string, passphrase []byte) error {
+	body, err := userLoginBody(email, passphrase)
+	if err != nil {
+		return err
 	}
+	defer zero(body)
 
-	rawLogin, err := json.Marshal(login)
+	req, _, err := s.client.NewRequest("POST", "/login", nil, nil, false)
 	if err != nil {
 		return err
 	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
 
-	return performLogin(ctx, s, "user", rawLogin)
+	_, err = s.client.Do(ctx, req, nil, nil, nil)
+	return err
 }
 
-// MachineLogin logs the user in using the provided token id and secret
-func (s *SessionClient) MachineLogin(ctx context.Context, tokenID, tokenSecret string) error {
+// userLoginBody returns the JSON encoded apitypes.Login for a user login,
+// without copying passphrase into a string that couldn't be zeroed. The
+// slice is allocated at its largest possible size up front, so that no
+// partial copies are left behind by appending to it.
+//
+// The passphrase is escaped as encoding/json would decode it. JSON strings
+// must be valid UTF-8, so a passphrase that isn't is refused rather than
+// silently altered.
+func userLoginBody(email string, passphrase []byte) ([]byte, error) {
+	if !utf8.Valid(passphrase) {
+		return nil, errors.New("passphrase must be valid UTF-8")
+	}
 
-	ID, err := identity.DecodeFromString(tokenID)
+	rawEmail, err := json.Marshal(email)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	secret, err := base64.NewValueFromString(tokenSecret)
-	if err != nil {
-		return err
+	const prefix = `{"type":"user","credentials":{"email":`
+	const middle = `,"passphrase":"`
+	const suffix = `"}}`
+
+	// Each byte of the passphrase takes at most 6 when escaped, as \u00XX.
+	b := make([]byte, 0, len(prefix)+len(rawEmail)+len(middle)+6*len(passphrase)+len(suffix))
+	b = append(b, prefix...)
+	b = append(b, rawEmail...)
+	b = append(b, middle...)
+
+	const hex = "0123456789abcdef"
+	for _, c := range passphrase {
+		switch {
+		case c == '"' || c == '\\':
+			b = append(b, '\\', c)
+		case c < 0x20:
+			b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		default:
+			b = append(b, c)
+		}
 	}
 
-	login := apitypes.MachineLogin{
-		TokenID: &ID,
-		Secret:  secret,
+	return append(b, suffix...), nil
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
 	}
+}
 
+// MachineLogin logs in as a machine using the provided token id and secret
+func (s *SessionClient) MachineLogin(ctx context.Context, login *apitypes.MachineLogin) error {
 	rawLogin, err := json.Marshal(login)
 	if err != nil {
 		return err
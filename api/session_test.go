@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+)
+
+func TestUserLoginBody(t *testing.T) {
+	passphrases := []string{
+		"hunter22",
+		`quo"te\back\slash`,
+		"tab\tnew\nline\x00",
+		"ünïcödé ☃",
+		"<html> & \x7f \u2028",
+	}
+
+	for _, passphrase := range passphrases {
+		b, err := userLoginBody(`jo"e@example.com`, []byte(passphrase))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		login := apitypes.Login{}
+		if err := json.Unmarshal(b, &login); err != nil {
+			t.Fatalf("%q: invalid body %s: %s", passphrase, b, err)
+		}
+		user := apitypes.UserLogin{}
+		if err := json.Unmarshal(login.Credentials, &user); err != nil {
+			t.Fatalf("%q: invalid credentials %s: %s", passphrase, login.Credentials, err)
+		}
+
+		if login.Type != "user" || user.Email != `jo"e@example.com` || user.Password != passphrase {
+			t.Errorf("%q: got %s", passphrase, b)
+		}
+	}
+}
+
+func TestUserLoginBodyInvalidUTF8(t *testing.T) {
+	_, err := userLoginBody("joe@example.com", []byte("bad\xffbyte"))
+	if err == nil {
+		t.Error("Expected a passphrase that isn't valid UTF-8 to be refused")
+	}
+}
@@ -114,3 +114,15 @@ func (t *TeamsClient) Create(
 	_, err = t.client.Do(ctx, req, teamResult, nil, nil)
 	return teamResult, err
 }
+
+// Delete performs a request to delete a team. The team's memberships must be
+// removed first.
+func (t *TeamsClient) Delete(ctx context.Context, teamID *identity.ID) error {
+	req, _, err := t.client.NewRequest("DELETE", "/teams/"+teamID.String(), nil, nil, true)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.client.Do(ctx, req, nil, nil, nil)
+	return err
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// verboseTransport wraps an http.RoundTripper, writing the method, path,
+// status and elapsed time of each request to w once its response body has
+// been consumed. The daemon is asked to report the registry requests it makes
+// on our behalf, which are written out after the request they belong to.
+type verboseTransport struct {
+	next http.RoundTripper
+	w    io.Writer
+}
+
+func (t *verboseTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	// The event stream stays open for the length of another request, and
+	// would only add noise.
+	if r.URL.Path == "/v1/observe" {
+		return t.next.RoundTrip(r)
+	}
+
+	// RoundTrippers must not modify the request they are given.
+	req := new(http.Request)
+	*req = *r
+	req.Header = make(http.Header, len(r.Header)+1)
+	for k, v := range r.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("X-Torus-Verbose", "true")
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		fmt.Fprintf(t.w, "%s %s error: %s %s\n", r.Method, redactQuery(r.URL), err,
+			roundMillis(time.Since(start)))
+		return nil, err
+	}
+
+	resp.Body = &verboseBody{
+		ReadCloser: resp.Body,
+		report: func() {
+			fmt.Fprintf(t.w, "%s %s %s %s\n", r.Method, redactQuery(r.URL),
+				resp.Status, roundMillis(time.Since(start)))
+			for _, line := range resp.Trailer["X-Torus-Trace"] {
+				fmt.Fprintf(t.w, "    registry: %s\n", line)
+			}
+		},
+	}
+
+	return resp, nil
+}
+
+// verboseBody reports on its request when closed. Anything left unread is
+// consumed first, as trailers are only available once the body is.
+type verboseBody struct {
+	io.ReadCloser
+	once   sync.Once
+	report func()
+}
+
+func (b *verboseBody) Close() error {
+	b.once.Do(func() {
+		io.Copy(ioutil.Discard, b.ReadCloser)
+		b.report()
+	})
+	return b.ReadCloser.Close()
+}
+
+// redactQuery returns the path and query of u, with every query value
+// replaced, so that verbose output can be shared without the names, paths and
+// tokens passed in it.
+func redactQuery(u *url.URL) string {
+	q := u.Query()
+	if len(q) == 0 {
+		return u.EscapedPath()
+	}
+
+	for key := range q {
+		q.Set(key, "REDACTED")
+	}
+	return u.EscapedPath() + "?" + q.Encode()
+}
+
+func roundMillis(d time.Duration) time.Duration {
+	return d - d%time.Millisecond
+}
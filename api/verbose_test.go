@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRedactQuery(t *testing.T) {
+	u, err := url.Parse("/v1/credentials?path=/acme/api/prod&token=abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := redactQuery(u)
+	if !strings.HasPrefix(out, "/v1/credentials?") {
+		t.Errorf("Expected the path to be kept, got %q", out)
+	}
+	for _, value := range []string{"acme", "abc123"} {
+		if strings.Contains(out, value) {
+			t.Errorf("Expected %q to be redacted, got %q", value, out)
+		}
+	}
+
+	u.RawQuery = ""
+	if out := redactQuery(u); out != "/v1/credentials" {
+		t.Errorf("Expected just the path, got %q", out)
+	}
+}
@@ -3,7 +3,11 @@ package apitypes
 
 import (
 	"encoding/json"
+	"fmt"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/manifoldco/torus-cli/base64"
 	"github.com/manifoldco/torus-cli/envelope"
@@ -17,11 +21,14 @@ type ErrorType string
 
 // These are the possible error types.
 const (
-	BadRequestError     = "bad_request"
-	UnauthorizedError   = "unauthorized"
-	NotFoundError       = "not_found"
-	InternalServerError = "internal_server"
-	NotImplementedError = "not_implemented"
+	BadRequestError      = "bad_request"
+	UnauthorizedError    = "unauthorized"
+	NotFoundError        = "not_found"
+	ConflictError        = "conflict"
+	VersionConflictError = "version_conflict"
+	InternalServerError  = "internal_server"
+	NotImplementedError  = "not_implemented"
+	TooManyRequestsError = "too_many_requests"
 )
 
 // Error represents standard formatted API errors from the daemon or registry.
@@ -30,8 +37,15 @@ type Error struct {
 
 	Type string   `json:"type"`
 	Err  []string `json:"error"`
+
+	// RetryAt is when a rate limited request may be retried, if known.
+	RetryAt time.Time `json:"-"`
 }
 
+// RateLimitEpochThreshold separates X-RateLimit-Reset values given as a Unix
+// time, which are larger, from those given as a number of seconds from now.
+const RateLimitEpochThreshold = 365 * 24 * 60 * 60
+
 // Error implements the error interface for formatted API errors.
 func (e *Error) Error() string {
 	segments := strings.Split(e.Type, "_")
@@ -39,31 +53,119 @@ func (e *Error) Error() string {
 	return strings.Title(errType) + ": " + strings.Join(e.Err, " ")
 }
 
+// ErrorType returns the type of the error, such as NotFoundError.
+func (e *Error) ErrorType() string {
+	return e.Type
+}
+
+// HTTPStatus returns the status code of the response the error was read
+// from, or 0 if it is unknown.
+func (e *Error) HTTPStatus() int {
+	return e.StatusCode
+}
+
 // FormatError updates an error to contain more context
 func FormatError(err error) error {
+	return formatError(err, time.Now())
+}
+
+func formatError(err error, now time.Time) error {
 	if err == nil {
 		return nil
 	}
 
-	if apiErr, ok := err.(*Error); ok {
-		if apiErr.Type == UnauthorizedError {
-			for _, m := range apiErr.Err {
-				if strings.Contains(m, "wrong identity state: unverified") {
-					return NewUnverifiedError()
-				}
-			}
+	apiErr, ok := err.(*Error)
+	if !ok {
+		return err
+	}
 
-			return &Error{
-				StatusCode: 401,
-				Type:       UnauthorizedError,
-				Err:        []string{"You are unauthorized to perform this action."},
+	if apiErr.StatusCode == 429 || apiErr.Type == TooManyRequestsError {
+		msg := "Too many requests have been made to Torus."
+		if wait := apiErr.RetryAt.Sub(now); !apiErr.RetryAt.IsZero() && wait > 0 {
+			secs := int((wait + time.Second - 1) / time.Second)
+			msg += fmt.Sprintf(" Please retry in %d second%s.", secs, plural(secs))
+		} else {
+			msg += " Please wait a moment and retry."
+		}
+
+		return &Error{
+			StatusCode: 429,
+			Type:       TooManyRequestsError,
+			Err:        []string{msg},
+			RetryAt:    apiErr.RetryAt,
+		}
+	}
+
+	switch apiErr.Type {
+	case UnauthorizedError:
+		for _, m := range apiErr.Err {
+			if strings.Contains(m, "wrong identity state: unverified") {
+				return NewUnverifiedError()
 			}
 		}
+
+		return &Error{
+			StatusCode: 401,
+			Type:       UnauthorizedError,
+			Err:        []string{"You are unauthorized to perform this action."},
+		}
+	case NotFoundError:
+		msg := joinMessages(apiErr.Err)
+		if msg == "" {
+			msg = "The requested resource could not be found."
+		}
+
+		return &Error{StatusCode: apiErr.StatusCode, Type: NotFoundError, Err: []string{msg}}
+	case NotImplementedError:
+		msg := "This action is not supported by the Torus registry you are connected to."
+		if detail := joinMessages(apiErr.Err); detail != "" {
+			msg += " " + detail
+		}
+
+		return &Error{StatusCode: apiErr.StatusCode, Type: NotImplementedError, Err: []string{msg}}
+	}
+
+	if len(apiErr.Err) > 1 {
+		return &Error{
+			StatusCode: apiErr.StatusCode,
+			Type:       apiErr.Type,
+			Err:        []string{joinMessages(apiErr.Err)},
+		}
 	}
 
 	return err
 }
 
+// joinMessages collapses the messages of an error into a single sentence or
+// more, dropping blank and repeated messages.
+func joinMessages(msgs []string) string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, m := range msgs {
+		m = strings.TrimSpace(m)
+		if m == "" || seen[m] {
+			continue
+		}
+		seen[m] = true
+
+		r, size := utf8.DecodeRuneInString(m)
+		m = string(unicode.ToUpper(r)) + m[size:]
+		if !strings.HasSuffix(m, ".") && !strings.HasSuffix(m, "!") && !strings.HasSuffix(m, "?") {
+			m += "."
+		}
+		out = append(out, m)
+	}
+
+	return strings.Join(out, " ")
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
 // NewUnverifiedError returns a message telling the user to verify their account before continuing
 func NewUnverifiedError() *Error {
 	return &Error{
@@ -75,6 +177,17 @@ func NewUnverifiedError() *Error {
 	}
 }
 
+// IsUnverifiedError returns whether or not an error is the result of the
+// account's email address not yet being verified.
+func IsUnverifiedError(err error) bool {
+	apiErr, ok := err.(*Error)
+	if !ok || apiErr.Type != UnauthorizedError || len(apiErr.Err) != 1 {
+		return false
+	}
+
+	return apiErr.Err[0] == NewUnverifiedError().Err[0]
+}
+
 // IsNotFoundError returns whether or not an error is a 404 result from the api.
 func IsNotFoundError(err error) bool {
 	if err == nil {
@@ -88,6 +201,55 @@ func IsNotFoundError(err error) bool {
 	return false
 }
 
+// IsConflictError returns whether or not an error is the result of a write
+// conflicting with the registry's state, such as creating a resource that
+// already exists.
+func IsConflictError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if apiErr, ok := err.(*Error); ok {
+		return apiErr.Type == ConflictError || apiErr.StatusCode == 409
+	}
+
+	return false
+}
+
+// IsVersionConflictError returns whether or not an error is the result of a
+// credential having been set by someone else since the version a write
+// expected to replace was read.
+func IsVersionConflictError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if apiErr, ok := err.(*Error); ok {
+		return apiErr.Type == VersionConflictError
+	}
+
+	return false
+}
+
+// HasMessage returns whether or not an error is an api error with a message
+// containing msg, ignoring case. Use it rather than matching the text of
+// Error, as FormatError may reword the messages it is given.
+func HasMessage(err error, msg string) bool {
+	apiErr, ok := err.(*Error)
+	if !ok {
+		return false
+	}
+
+	msg = strings.ToLower(msg)
+	for _, m := range apiErr.Err {
+		if strings.Contains(strings.ToLower(m), msg) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // A session can represent either a machine or a user
 const (
 	MachineSession = "machine"
@@ -107,6 +269,15 @@ type Version struct {
 	Version string `json:"version"`
 }
 
+// Health contains the liveness details of the daemon.
+type Health struct {
+	Status  string `json:"status"`
+	PID     int    `json:"pid"`
+	Version string `json:"version"`
+	Session bool   `json:"session"`
+	Uptime  int64  `json:"uptime"` // seconds
+}
+
 // SessionStatus contains details about the user's daemon session.
 type SessionStatus struct {
 	Token      bool `json:"token"`
@@ -161,6 +332,23 @@ type MachineLogin struct {
 	Secret  *base64.Value `json:"secret"`
 }
 
+// NewMachineLogin returns a MachineLogin for the given encoded token id and
+// secret, such as those in the TORUS_TOKEN_ID and TORUS_TOKEN_SECRET
+// environment variables.
+func NewMachineLogin(tokenID, tokenSecret string) (*MachineLogin, error) {
+	id, err := identity.DecodeFromString(tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := base64.NewValueFromString(tokenSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MachineLogin{TokenID: &id, Secret: secret}, nil
+}
+
 // Type returns the type of the login request
 func (m *MachineLogin) Type() string {
 	return MachineSession
@@ -187,6 +375,7 @@ type Profile struct {
 	Body *struct {
 		Name     string `json:"name"`
 		Username string `json:"username"`
+		Email    string `json:"email,omitempty"`
 	} `json:"body"`
 }
 
@@ -243,7 +432,26 @@ type Membership struct {
 	Body    *primitive.Membership `json:"body"`
 }
 
+// ProfileUpdate contains the fields of a user's profile to change. Empty
+// fields are left as they are.
+type ProfileUpdate struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
 // VerifyEmail contains email verification code
 type VerifyEmail struct {
 	Code string `json:"code"`
 }
+
+// AuditEvent is an entry in an org's audit log, recording an action taken by
+// a user or machine.
+type AuditEvent struct {
+	ID      *identity.ID `json:"id"`
+	OrgID   *identity.ID `json:"org_id"`
+	ActorID *identity.ID `json:"actor_id"`
+	Actor   string       `json:"actor"`
+	Action  string       `json:"action"`
+	Target  string       `json:"target"`
+	Created time.Time    `json:"created_at"`
+}
@@ -0,0 +1,167 @@
+package apitypes
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFormatError(t *testing.T) {
+	now := time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tcs := []struct {
+		name string
+		in   error
+		out  string
+	}{
+		{
+			name: "unverified",
+			in: &Error{StatusCode: 401, Type: UnauthorizedError,
+				Err: []string{"wrong identity state: unverified"}},
+			out: NewUnverifiedError().Error(),
+		},
+		{
+			name: "unauthorized",
+			in:   &Error{StatusCode: 401, Type: UnauthorizedError, Err: []string{"bad token"}},
+			out:  "Unauthorized: You are unauthorized to perform this action.",
+		},
+		{
+			name: "not found with message",
+			in:   &Error{StatusCode: 404, Type: NotFoundError, Err: []string{"org not found"}},
+			out:  "Not Found: Org not found.",
+		},
+		{
+			name: "not found without message",
+			in:   &Error{StatusCode: 404, Type: NotFoundError},
+			out:  "Not Found: The requested resource could not be found.",
+		},
+		{
+			name: "not implemented",
+			in:   &Error{StatusCode: 501, Type: NotImplementedError},
+			out: "Not Implemented: This action is not supported by the Torus " +
+				"registry you are connected to.",
+		},
+		{
+			name: "rate limited with reset",
+			in: &Error{StatusCode: 429, Type: "rate_limited",
+				RetryAt: now.Add(1500 * time.Millisecond)},
+			out: "Too Many Requests: Too many requests have been made to Torus. " +
+				"Please retry in 2 seconds.",
+		},
+		{
+			name: "rate limited without reset",
+			in:   &Error{StatusCode: 429},
+			out: "Too Many Requests: Too many requests have been made to Torus. " +
+				"Please wait a moment and retry.",
+		},
+		{
+			name: "collapses messages",
+			in: &Error{StatusCode: 400, Type: BadRequestError,
+				Err: []string{"name is invalid", " ", "name is invalid", "value is required."}},
+			out: "Bad Request: Name is invalid. Value is required.",
+		},
+		{
+			name: "single message unchanged",
+			in:   &Error{StatusCode: 500, Type: InternalServerError, Err: []string{"oops"}},
+			out:  "Internal Server: oops",
+		},
+		{
+			name: "not an api error",
+			in:   errors.New("plain"),
+			out:  "plain",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			out := formatError(tc.in, now)
+			if out.Error() != tc.out {
+				t.Errorf("Expected %q, got %q", tc.out, out.Error())
+			}
+		})
+	}
+
+	if formatError(nil, now) != nil {
+		t.Error("Expected nil error to stay nil")
+	}
+}
+
+func TestIsUnverifiedError(t *testing.T) {
+	raw := &Error{StatusCode: 401, Type: UnauthorizedError,
+		Err: []string{"wrong identity state: unverified"}}
+
+	if !IsUnverifiedError(FormatError(raw)) {
+		t.Error("Expected a formatted unverified error to be detected")
+	}
+	if IsUnverifiedError(FormatError(&Error{StatusCode: 401, Type: UnauthorizedError})) {
+		t.Error("Expected other unauthorized errors not to be detected")
+	}
+	if IsUnverifiedError(errors.New("plain")) || IsUnverifiedError(nil) {
+		t.Error("Expected non api errors not to be detected")
+	}
+}
+
+func TestIsConflictError(t *testing.T) {
+	if !IsConflictError(FormatError(&Error{StatusCode: 409, Type: ConflictError})) {
+		t.Error("Expected a conflict error to be detected")
+	}
+	if !IsConflictError(&Error{StatusCode: 409, Type: BadRequestError}) {
+		t.Error("Expected a 409 response to be detected as a conflict")
+	}
+	if IsConflictError(&Error{StatusCode: 400, Type: BadRequestError}) {
+		t.Error("Expected other errors not to be detected")
+	}
+	if IsConflictError(errors.New("plain")) || IsConflictError(nil) {
+		t.Error("Expected non api errors not to be detected")
+	}
+}
+
+func TestIsVersionConflictError(t *testing.T) {
+	err := FormatError(&Error{StatusCode: 409, Type: VersionConflictError})
+	if !IsVersionConflictError(err) {
+		t.Error("Expected a version conflict error to be detected")
+	}
+	if !IsConflictError(err) {
+		t.Error("Expected a version conflict to also be a conflict")
+	}
+	if IsVersionConflictError(&Error{StatusCode: 409, Type: ConflictError}) {
+		t.Error("Expected other conflicts not to be detected")
+	}
+	if IsVersionConflictError(errors.New("plain")) || IsVersionConflictError(nil) {
+		t.Error("Expected non api errors not to be detected")
+	}
+}
+
+func TestHasMessage(t *testing.T) {
+	err := FormatError(&Error{
+		StatusCode: 400,
+		Type:       BadRequestError,
+		Err:        []string{"resource exists", "user is a member of the team"},
+	})
+	if !HasMessage(err, "resource exists") || !HasMessage(err, "member of the") {
+		t.Errorf("Expected the messages to be found in %q", err)
+	}
+	if HasMessage(err, "cannot remove") {
+		t.Error("Expected other messages not to be found")
+	}
+	if HasMessage(errors.New("resource exists"), "resource exists") || HasMessage(nil, "") {
+		t.Error("Expected non api errors not to be detected")
+	}
+}
+
+func TestNewMachineLogin(t *testing.T) {
+	login, err := NewMachineLogin("04100000000000000000000000001", "c2VjcmV0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !login.Valid() || string(login.Passphrase()) != "secret" {
+		t.Errorf("Unexpected login: %+v", login)
+	}
+
+	if _, err := NewMachineLogin("not-an-id", "c2VjcmV0"); err == nil {
+		t.Error("Expected an error for an invalid token id")
+	}
+	if _, err := NewMachineLogin("04100000000000000000000000001", "!!"); err == nil {
+		t.Error("Expected an error for an invalid secret")
+	}
+}
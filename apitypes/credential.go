@@ -3,8 +3,10 @@ package apitypes
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 
 	"github.com/manifoldco/torus-cli/identity"
 	"github.com/manifoldco/torus-cli/pathexp"
@@ -19,6 +21,74 @@ const (
 	floatCV
 )
 
+// Bounds on the length of a credential name.
+const (
+	MinCredentialNameLength = 1
+	MaxCredentialNameLength = 64
+)
+
+// ValidCredentialName returns an error describing the first rule that name
+// breaks, or nil if it is a valid credential name. Names must be between
+// MinCredentialNameLength and MaxCredentialNameLength characters, start with
+// a lowercase letter, and otherwise contain only lowercase letters, digits,
+// hyphens and underscores.
+func ValidCredentialName(name string) error {
+	if len(name) < MinCredentialNameLength || len(name) > MaxCredentialNameLength {
+		return fmt.Errorf("Invalid secret name %q: names must be between %d and %d characters long, not %d",
+			name, MinCredentialNameLength, MaxCredentialNameLength, len(name))
+	}
+
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z':
+			continue
+		case i == 0:
+			return fmt.Errorf("Invalid secret name %q: names must start with a lowercase letter, not %q",
+				name, r)
+		case r >= '0' && r <= '9', r == '-', r == '_':
+			continue
+		default:
+			return fmt.Errorf("Invalid secret name %q: character %q at position %d is not allowed; use a-z, 0-9, hyphens and underscores",
+				name, r, i+1)
+		}
+	}
+
+	return nil
+}
+
+// CredentialFailure describes a credential the daemon could not decrypt. Name
+// is the credential's ID if the credential itself could not be read.
+type CredentialFailure struct {
+	ID      *identity.ID     `json:"id"`
+	Name    string           `json:"name"`
+	PathExp *pathexp.PathExp `json:"pathexp"`
+	Reason  string           `json:"reason"`
+}
+
+// DecryptionFailuresError is returned along with the credentials that could be
+// decrypted, when some could not be.
+type DecryptionFailuresError struct {
+	Failures []CredentialFailure
+}
+
+func (e *DecryptionFailuresError) Error() string {
+	msgs := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		name := f.Name
+		if f.PathExp != nil {
+			name = f.PathExp.String() + "/" + f.Name
+		}
+		msgs[i] = name + ": " + f.Reason
+	}
+
+	noun := "secrets"
+	if len(e.Failures) == 1 {
+		noun = "secret"
+	}
+	return fmt.Sprintf("%d %s could not be decrypted: %s", len(e.Failures), noun,
+		strings.Join(msgs, "; "))
+}
+
 // CredentialEnvelope is an unencrypted credential object with a
 // deserialized body
 type CredentialEnvelope struct {
@@ -41,6 +111,8 @@ type Credential interface {
 	GetPathExp() *pathexp.PathExp
 	GetProjectID() *identity.ID
 	GetValue() *CredentialValue
+	GetDescription() string
+	GetTags() []string
 }
 
 // BaseCredential is the body of an unencrypted Credential
@@ -80,10 +152,58 @@ func (c *BaseCredential) GetValue() *CredentialValue {
 	return c.Value
 }
 
+// GetDescription returns the description. v1 credentials have none.
+func (c *BaseCredential) GetDescription() string {
+	return ""
+}
+
+// GetTags returns the tags. v1 credentials have none.
+func (c *BaseCredential) GetTags() []string {
+	return nil
+}
+
 // CredentialV2 is the body of an unencrypted Credential
 type CredentialV2 struct {
 	BaseCredential
-	State string `json:"state"`
+	State       string   `json:"state"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+
+	// ExpectedVersion is the version of the credential the new one replaces,
+	// or 0 if it must not exist yet. The write is refused with a conflict if
+	// the credential has since changed. It is not checked if nil.
+	ExpectedVersion *int `json:"expected_version,omitempty"`
+}
+
+// GetDescription returns the description
+func (c *CredentialV2) GetDescription() string {
+	return c.Description
+}
+
+// GetTags returns the tags
+func (c *CredentialV2) GetTags() []string {
+	return c.Tags
+}
+
+// HasTags returns whether cred is tagged with every one of tags.
+func HasTags(cred interface {
+	GetTags() []string
+}, tags []string) bool {
+	have := cred.GetTags()
+	for _, tag := range tags {
+		found := false
+		for _, t := range have {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
 }
 
 // GetValue returns the value object, unless unset then returns nil
@@ -251,3 +371,33 @@ func NewFloatCredentialValue(f float64) *CredentialValue {
 		raw:    f,
 	}
 }
+
+// CredentialVersion is a single version of a credential in its history.
+// Value is nil unless the history was requested with values revealed.
+type CredentialVersion struct {
+	ID       *identity.ID     `json:"id"`
+	Version  int              `json:"version"`
+	State    string           `json:"state"`
+	AuthorID *identity.ID     `json:"author_id"`
+	Value    *CredentialValue `json:"value"`
+}
+
+// CredentialKey describes a set credential without its value: its name, the
+// PathExp it is set at, and its unencrypted metadata.
+type CredentialKey struct {
+	Name        string           `json:"name"`
+	PathExp     *pathexp.PathExp `json:"pathexp"`
+	Version     int              `json:"version"`
+	Description string           `json:"description,omitempty"`
+	Tags        []string         `json:"tags,omitempty"`
+}
+
+// GetDescription returns the description
+func (k *CredentialKey) GetDescription() string {
+	return k.Description
+}
+
+// GetTags returns the tags
+func (k *CredentialKey) GetTags() []string {
+	return k.Tags
+}
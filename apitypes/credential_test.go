@@ -2,6 +2,7 @@ package apitypes
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 )
 
@@ -116,3 +117,72 @@ func TestCredentialValueUnmarshalJSON(t *testing.T) {
 
 	})
 }
+
+func TestValidCredentialName(t *testing.T) {
+	valid := []string{"a", "db_password", "api-key2", strings.Repeat("a", 64)}
+	for _, name := range valid {
+		if err := ValidCredentialName(name); err != nil {
+			t.Errorf("Expected %q to be valid, got: %s", name, err)
+		}
+	}
+
+	invalid := map[string]string{
+		"":                      "between 1 and 64 characters",
+		strings.Repeat("a", 65): "between 1 and 64 characters",
+		"1password":             "start with a lowercase letter",
+		"_secret":               "start with a lowercase letter",
+		"Secret":                "start with a lowercase letter",
+		"db.password":           "character '.' at position 3",
+		"apiKey":                "character 'K' at position 4",
+	}
+	for name, rule := range invalid {
+		err := ValidCredentialName(name)
+		if err == nil {
+			t.Errorf("Expected %q to be invalid", name)
+			continue
+		}
+		if !strings.Contains(err.Error(), rule) {
+			t.Errorf("Expected error for %q to mention %q, got: %s", name, rule, err)
+		}
+	}
+}
+
+func TestDecryptionFailuresError(t *testing.T) {
+	err := &DecryptionFailuresError{Failures: []CredentialFailure{
+		{Name: "db_password", Reason: "decryption failed"},
+	}}
+	expected := "1 secret could not be decrypted: db_password: decryption failed"
+	if err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestCredentialV2Metadata(t *testing.T) {
+	old := CredentialV2{}
+	err := json.Unmarshal([]byte(`{"name":"a","state":"set"}`), &old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old.GetDescription() != "" || old.GetTags() != nil {
+		t.Errorf("Expected no metadata, got %q %v", old.Description, old.Tags)
+	}
+
+	b, err := json.Marshal(&CredentialV2{State: "set"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "description") || strings.Contains(string(b), "tags") {
+		t.Errorf("Expected empty metadata to be omitted, got %s", b)
+	}
+
+	var cred Credential = &CredentialV2{Tags: []string{"db", "prod"}}
+	if !HasTags(cred, []string{"prod"}) || !HasTags(cred, nil) {
+		t.Error("Expected credential to have its tags")
+	}
+	if HasTags(cred, []string{"db", "staging"}) {
+		t.Error("Expected credential missing a tag not to match")
+	}
+	if HasTags(&BaseCredential{}, []string{"db"}) {
+		t.Error("Expected v1 credential to have no tags")
+	}
+}
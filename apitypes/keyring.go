@@ -0,0 +1,41 @@
+package apitypes
+
+import (
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/pathexp"
+)
+
+// KeyringAccess lists who can decrypt the secrets held in the keyring for a
+// PathExp. MemberIDs holds user IDs and, for machines, machine token IDs.
+type KeyringAccess struct {
+	PathExp   *pathexp.PathExp `json:"pathexp"`
+	MemberIDs []*identity.ID   `json:"member_ids"`
+}
+
+// KeyringRepair is a keyring membership that was missing for a member of an
+// org. Repaired is false, with the Reason given, if it could not be added.
+type KeyringRepair struct {
+	PathExp  *pathexp.PathExp `json:"pathexp"`
+	OwnerID  *identity.ID     `json:"owner_id"`
+	Repaired bool             `json:"repaired"`
+	Reason   string           `json:"reason,omitempty"`
+}
+
+// Severities of the problems found when verifying keyrings. Critical
+// findings suggest the credential tree has been tampered with; warnings are
+// problems that can be repaired.
+const (
+	FindingCritical = "critical"
+	FindingWarning  = "warning"
+)
+
+// KeyringFinding is a problem found when verifying the keyrings of an org.
+// It concerns the credential named CredentialName, or the membership of
+// OwnerID, in the keyring for PathExp, if either is set.
+type KeyringFinding struct {
+	Severity       string           `json:"severity"`
+	PathExp        *pathexp.PathExp `json:"pathexp"`
+	CredentialName string           `json:"credential_name,omitempty"`
+	OwnerID        *identity.ID     `json:"owner_id,omitempty"`
+	Detail         string           `json:"detail"`
+}
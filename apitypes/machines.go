@@ -16,13 +16,16 @@ type MachineSegment struct {
 		ID   *identity.ID          `json:"id"`
 		Body *primitive.Membership `json:"body"`
 	} `json:"memberships"`
-	Tokens []*struct {
-		Token *struct {
-			ID   *identity.ID            `json:"id"`
-			Body *primitive.MachineToken `json:"body"`
-		} `json:"token"`
-		Keypairs []PublicKeySegment `json:"keypairs"`
-	} `json:"tokens"`
+	Tokens []*MachineTokenSegment `json:"tokens"`
+}
+
+// MachineTokenSegment represents a machine token and its connected keypairs
+type MachineTokenSegment struct {
+	Token *struct {
+		ID   *identity.ID            `json:"id"`
+		Body *primitive.MachineToken `json:"body"`
+	} `json:"token"`
+	Keypairs []PublicKeySegment `json:"keypairs"`
 }
 
 // MachinesCreateRequest represents a request by a client to create a machine
@@ -33,3 +36,11 @@ type MachinesCreateRequest struct {
 	TeamID *identity.ID  `json:"team_id"`
 	Secret *base64.Value `json:"secret"`
 }
+
+// MachineTokenRotateRequest represents a request by a client to replace an
+// existing machine token with a new one derived from the given secret.
+type MachineTokenRotateRequest struct {
+	MachineID *identity.ID  `json:"machine_id"`
+	TokenID   *identity.ID  `json:"token_id"`
+	Secret    *base64.Value `json:"secret"`
+}
@@ -14,6 +14,10 @@ type WorklogType byte
 // The enumberated byte types of WorklogItems
 const (
 	SecretRotateWorklogType WorklogType = 1 << iota
+	InviteApproveWorklogType
+	InvitePendingWorklogType
+	MachineTokenWorklogType
+	KeyringRotateWorklogType
 )
 
 // WorklogResult result states.
@@ -74,11 +78,23 @@ func (t WorklogType) String() string {
 	switch t {
 	case SecretRotateWorklogType:
 		return "secret"
+	case InviteApproveWorklogType, InvitePendingWorklogType:
+		return "invite"
+	case MachineTokenWorklogType:
+		return "machine"
+	case KeyringRotateWorklogType:
+		return "keyring"
 	default:
 		return "n/a"
 	}
 }
 
+// Automatic returns whether items of this type can be resolved by the daemon
+// without the user performing some other action.
+func (t WorklogType) Automatic() bool {
+	return t == InviteApproveWorklogType || t == KeyringRotateWorklogType
+}
+
 // CreateID creates and populates a WorklogID for the WorklogItem based on the
 // given type and its subject.
 func (w *WorklogItem) CreateID(worklogType WorklogType) {
@@ -3,23 +3,334 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"text/tabwriter"
 
+	"github.com/chzyer/readline"
 	"github.com/urfave/cli"
 
 	"github.com/manifoldco/torus-cli/api"
 	"github.com/manifoldco/torus-cli/config"
+	"github.com/manifoldco/torus-cli/errs"
+	"github.com/manifoldco/torus-cli/prefs"
+	"github.com/manifoldco/torus-cli/promptui"
 )
 
 // Cmds is the list of all cli commands
 var Cmds []cli.Command
 
+// GlobalFlags are the flags accepted before any command
+var GlobalFlags = []cli.Flag{
+	cli.BoolFlag{
+		Name:   "verbose",
+		Usage:  "Log the method, path, status and timing of each request to stderr",
+		EnvVar: "TORUS_VERBOSE",
+	},
+	cli.BoolFlag{
+		Name:   "quiet, q",
+		Usage:  "Suppress banners, blank separators and success messages",
+		EnvVar: "TORUS_QUIET",
+	},
+	cli.StringFlag{
+		Name:   "profile",
+		Usage:  "Use the registry and session of this profile",
+		EnvVar: "TORUS_PROFILE",
+	},
+	cli.DurationFlag{
+		Name:   "timeout",
+		Usage:  "Give up on requests to the registry after this long (e.g. 30s)",
+		EnvVar: "TORUS_TIMEOUT",
+	},
+	cli.BoolFlag{
+		Name:   "no-color",
+		Usage:  "Disable colored output; also disabled by NO_COLOR, or when stdout isn't a terminal",
+		EnvVar: "TORUS_NO_COLOR",
+	},
+	cli.BoolFlag{
+		Name:   "dry-run",
+		Usage:  "Show what import, secrets copy and other changing commands would do, without doing it",
+		EnvVar: "TORUS_DRY_RUN",
+	},
+	cli.BoolFlag{
+		Name:   "json-errors",
+		Usage:  "Write errors to stderr as JSON objects with type, message and code fields",
+		EnvVar: "TORUS_JSON_ERRORS",
+	},
+	cli.BoolFlag{
+		Name:   "strict-version",
+		Usage:  "Fail instead of warning when the daemon version doesn't match the cli",
+		EnvVar: "TORUS_STRICT_VERSION",
+	},
+}
+
+// Before runs ahead of every command. Global flags are exported to the
+// environment, where config.LoadConfig picks them up.
+func Before(ctx *cli.Context) error {
+	if ctx.GlobalBool("verbose") {
+		if err := os.Setenv("TORUS_VERBOSE", "true"); err != nil {
+			return err
+		}
+	}
+
+	if ctx.GlobalBool("quiet") {
+		if err := os.Setenv("TORUS_QUIET", "true"); err != nil {
+			return err
+		}
+	}
+
+	if profile := ctx.GlobalString("profile"); profile != "" {
+		if err := os.Setenv("TORUS_PROFILE", profile); err != nil {
+			return err
+		}
+	}
+
+	if timeout := ctx.GlobalDuration("timeout"); timeout > 0 {
+		if err := os.Setenv("TORUS_TIMEOUT", timeout.String()); err != nil {
+			return err
+		}
+	}
+
+	if ctx.GlobalBool("no-color") {
+		if err := os.Setenv("TORUS_NO_COLOR", "true"); err != nil {
+			return err
+		}
+	}
+	promptui.NoColor = !colorEnabled()
+
+	if ctx.GlobalBool("dry-run") {
+		if err := os.Setenv("TORUS_DRY_RUN", "true"); err != nil {
+			return err
+		}
+	}
+
+	if ctx.GlobalBool("json-errors") {
+		if err := os.Setenv("TORUS_JSON_ERRORS", "true"); err != nil {
+			return err
+		}
+	}
+
+	if ctx.GlobalBool("strict-version") {
+		return os.Setenv("TORUS_STRICT_VERSION", "true")
+	}
+
+	return nil
+}
+
+// ReportErrors arranges for every failure of app to be written to stderr as
+// an errs.JSONError with --json-errors, instead of as text: errors returned by
+// commands and their subcommands, by app.Before, from parsing flags, and for
+// unknown commands. The exit code is unchanged. It must be called once app's
+// Commands and Before are set.
+func ReportErrors(app *cli.App) {
+	app.Commands = reportCommandErrors(app.Commands)
+	app.OnUsageError = reportUsageError
+	app.CommandNotFound = reportCommandNotFound
+
+	if before := app.Before; before != nil {
+		report := reportErrors(before)
+		app.Before = func(ctx *cli.Context) error {
+			err := report(ctx)
+			if err != nil && jsonErrors(ctx) {
+				// Exit now; cli would otherwise print the app's help.
+				cli.HandleExitCoder(err)
+			}
+			return err
+		}
+	}
+}
+
+func reportCommandErrors(cmds []cli.Command) []cli.Command {
+	wrapped := make([]cli.Command, len(cmds))
+	for i, c := range cmds {
+		if fn, ok := c.Action.(func(*cli.Context) error); ok {
+			c.Action = reportErrors(fn)
+		}
+		c.OnUsageError = reportUsageError
+		c.Subcommands = reportCommandErrors(c.Subcommands)
+		wrapped[i] = c
+	}
+
+	return wrapped
+}
+
+// jsonErrors reports whether errors should be written as JSON. The global
+// flag is checked as well as the environment, as Before may not have run.
+func jsonErrors(ctx *cli.Context) bool {
+	return os.Getenv("TORUS_JSON_ERRORS") != "" || ctx.GlobalBool("json-errors")
+}
+
+func reportErrors(fn func(*cli.Context) error) func(*cli.Context) error {
+	return func(ctx *cli.Context) error {
+		err := fn(ctx)
+		if err == nil || !jsonErrors(ctx) {
+			return err
+		}
+
+		return reportError(err)
+	}
+}
+
+// reportError writes err to stderr as JSON, and returns an error that exits
+// with the same code.
+func reportError(err error) error {
+	writeJSONError(os.Stderr, err)
+
+	// An empty message keeps cli from printing the error again.
+	return cli.NewExitError("", errs.ExitCode(err))
+}
+
+// reportUsageError handles flags that couldn't be parsed. Without
+// --json-errors, it prints the error and help as cli does by default.
+func reportUsageError(ctx *cli.Context, err error, isSubcommand bool) error {
+	if jsonErrors(ctx) {
+		return reportError(errs.NewCodedExitError(err.Error(), errs.ExitUsage))
+	}
+
+	fmt.Fprintf(ctx.App.Writer, "Incorrect Usage. %s\n\n", err)
+	switch {
+	case ctx.Command.Name != "":
+		cli.ShowCommandHelp(ctx, ctx.Command.Name)
+	case isSubcommand:
+		cli.ShowSubcommandHelp(ctx)
+	default:
+		cli.ShowAppHelp(ctx)
+	}
+	return err
+}
+
+// reportCommandNotFound exits with a usage error naming the unknown command.
+func reportCommandNotFound(ctx *cli.Context, command string) {
+	err := errs.NewCodedExitError("No help topic for '"+command+"'", errs.ExitUsage)
+	if jsonErrors(ctx) {
+		err = reportError(err)
+	}
+	cli.HandleExitCoder(err)
+}
+
+// writeJSONError writes err to w as an errs.JSONError, on a line of its own.
+func writeJSONError(w io.Writer, err error) {
+	enc := json.NewEncoder(w)
+	if encErr := enc.Encode(errs.NewJSONError(err)); encErr != nil {
+		fmt.Fprintln(w, err)
+	}
+}
+
+// quiet reports whether decorative output should be suppressed.
+func quiet() bool {
+	return os.Getenv("TORUS_QUIET") != ""
+}
+
+// colorEnabled reports whether output may be colored. It isn't when
+// --no-color or the NO_COLOR environment variable is set, or when stdout isn't
+// a terminal, such as when it is piped into a file.
+func colorEnabled() bool {
+	if os.Getenv("TORUS_NO_COLOR") != "" || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	if noColorPref() {
+		return false
+	}
+
+	return readline.IsTerminal(int(os.Stdout.Fd()))
+}
+
+var noColorOnce sync.Once
+var noColor bool
+
+// noColorPref reports whether the no_color preference is set. The prefs file
+// is only read the first time, rather than on every colored line.
+func noColorPref() bool {
+	noColorOnce.Do(func() {
+		preferences, err := prefs.NewPreferences(true)
+		noColor = err == nil && preferences.Core.NoColor
+	})
+	return noColor
+}
+
+// warn prints msg to stderr, marked as a warning.
+func warn(msg string) {
+	fmt.Fprintln(os.Stderr, promptui.Warning(msg))
+}
+
+// decorate prints a line of non-essential output, such as a blank separator
+// or a success message, unless --quiet was given.
+func decorate(a ...interface{}) {
+	if !quiet() {
+		fmt.Println(a...)
+	}
+}
+
+// decoratef is like decorate, with a format string.
+func decoratef(format string, a ...interface{}) {
+	if !quiet() {
+		fmt.Printf(format, a...)
+	}
+}
+
+// dryRun reports whether commands should only show the changes they would
+// make. The api client refuses to make changes regardless, as a safety net.
+func dryRun() bool {
+	return config.DryRun()
+}
+
+// planStep is a change a command would make, shown instead of being made
+// during a dry run.
+type planStep struct {
+	Action string
+	Target string
+}
+
+// printPlan shows the changes a command would have made if not for
+// --dry-run.
+func printPlan(steps []planStep) {
+	if len(steps) == 0 {
+		fmt.Println("Dry run: nothing would be changed.")
+		return
+	}
+
+	fmt.Println("Dry run: nothing was changed. Without --dry-run, this would:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, s := range steps {
+		fmt.Fprintf(w, "  %s\t%s\n", s.Action, s.Target)
+	}
+	w.Flush()
+}
+
 var progress api.ProgressFunc = func(evt *api.Event, err error) {
 	if evt != nil {
 		fmt.Println(evt.Message)
 	}
 }
 
+// interruptContext returns a context that is cancelled when the user
+// interrupts the command (Ctrl-C), so that a long operation in the daemon
+// stops early rather than running to completion. The returned func stops
+// listening for the interrupt, and must be called once the operation is done.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		select {
+		case <-sig:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(sig)
+		cancel()
+	}
+}
+
 // NewAPIClient loads config and creates a new api client
 func NewAPIClient(ctx *context.Context, client *api.Client) (context.Context, *api.Client, error) {
 	if client == nil {
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/errs"
+	"github.com/manifoldco/torus-cli/identity"
+)
+
+func TestReportErrors(t *testing.T) {
+	// runApp runs a cli with a failing command, returning what was written
+	// to stderr and the first exit code.
+	runApp := func(t *testing.T, args ...string) (string, int) {
+		app := cli.NewApp()
+		app.Writer = ioutil.Discard
+		app.Flags = []cli.Flag{
+			cli.BoolFlag{Name: "json-errors"},
+			cli.BoolFlag{Name: "fail-before"},
+		}
+		app.Before = func(ctx *cli.Context) error {
+			if ctx.GlobalBool("fail-before") {
+				return errs.NewCodedExitError("Not logged in", errs.ExitAuth)
+			}
+			return nil
+		}
+		app.Commands = []cli.Command{{
+			Name: "parent",
+			Subcommands: []cli.Command{{
+				Name: "child",
+				Action: func(ctx *cli.Context) error {
+					return errs.NewNotFoundExitError("Project not found")
+				},
+			}},
+		}}
+		ReportErrors(app)
+
+		code := -1
+		exiter := cli.OsExiter
+		cli.OsExiter = func(c int) {
+			if code == -1 {
+				code = c
+			}
+		}
+		defer func() { cli.OsExiter = exiter }()
+		errWriter := cli.ErrWriter
+		cli.ErrWriter = ioutil.Discard
+		defer func() { cli.ErrWriter = errWriter }()
+
+		f, err := ioutil.TempFile("", "torus-stderr")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(f.Name())
+		stderr := os.Stderr
+		os.Stderr = f
+		app.Run(append([]string{"torus"}, args...))
+		os.Stderr = stderr
+		f.Close()
+
+		written, err := ioutil.ReadFile(f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(written), code
+	}
+
+	os.Unsetenv("TORUS_JSON_ERRORS")
+	tcs := []struct {
+		name string
+		args []string
+		json string
+		code int
+	}{
+		{"text errors", []string{"parent", "child"}, "", errs.ExitNotFound},
+		{"command", []string{"--json-errors", "parent", "child"}, `"type":"not_found"`, errs.ExitNotFound},
+		{"global flags", []string{"--json-errors", "--bogus"}, `"type":"usage"`, errs.ExitUsage},
+		{"command flags", []string{"--json-errors", "parent", "child", "--bogus"}, `"type":"usage"`, errs.ExitUsage},
+		{"before", []string{"--json-errors", "--fail-before", "parent", "child"}, `"type":"unauthorized"`, errs.ExitAuth},
+		{"unknown command", []string{"--json-errors", "bogus"}, `"type":"usage"`, errs.ExitUsage},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			stderr, code := runApp(t, tc.args...)
+			if code != tc.code {
+				t.Errorf("Expected exit code %d, got %d", tc.code, code)
+			}
+			if tc.json == "" && stderr != "" {
+				t.Errorf("Expected no JSON, got %q", stderr)
+			}
+			if tc.json != "" && !strings.Contains(stderr, tc.json) {
+				t.Errorf("Expected the error to be written as JSON, got %q", stderr)
+			}
+		})
+	}
+}
+
+func TestWriteJSONError(t *testing.T) {
+	buf := &bytes.Buffer{}
+	writeJSONError(buf, errs.NewCodedExitError("Not logged in", errs.ExitAuth))
+
+	got := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Expected JSON, got %q: %s", buf.String(), err)
+	}
+	if got["type"] != "unauthorized" || got["message"] != "Not logged in." || got["code"] != float64(errs.ExitAuth) {
+		t.Errorf("Unexpected error: %v", got)
+	}
+}
+
+// newID returns the ID of body, failing the test if it can't be derived.
+func newID(t testing.TB, body identity.Mutable) *identity.ID {
+	id, err := identity.NewMutable(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &id
+}
@@ -0,0 +1,404 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/config"
+	"github.com/manifoldco/torus-cli/errs"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/pathexp"
+)
+
+// completionTimeout bounds how long a completion lookup may wait on the
+// daemon, so a slow or wedged daemon doesn't hang the user's shell.
+const completionTimeout = 2 * time.Second
+
+func init() {
+	completion := cli.Command{
+		Name:      "completion",
+		Usage:     "Output a shell completion script for bash, zsh or fish",
+		ArgsUsage: "<bash|zsh|fish>",
+		Category:  "SYSTEM",
+		Action:    completionCmd,
+	}
+
+	// complete is invoked by the completion scripts to look up candidate
+	// names. It never prints errors; no output means no suggestions.
+	complete := cli.Command{
+		Name:      "__complete",
+		ArgsUsage: "<orgs|projects|services|secrets>",
+		Hidden:    true,
+		Flags: []cli.Flag{
+			cli.StringFlag{Name: "org", EnvVar: "TORUS_ORG"},
+			cli.StringFlag{Name: "project", EnvVar: "TORUS_PROJECT"},
+			cli.StringSliceFlag{Name: "environment", EnvVar: "TORUS_ENVIRONMENT"},
+			cli.StringSliceFlag{Name: "service", EnvVar: "TORUS_SERVICE"},
+		},
+		Action: completeNamesCmd,
+	}
+
+	Cmds = append(Cmds, completion, complete)
+}
+
+func completionCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) < 1 {
+		return errs.NewUsageExitError("A shell is required", ctx)
+	}
+	if len(args) > 1 {
+		return errs.NewUsageExitError("Too many arguments", ctx)
+	}
+
+	var tmpl string
+	switch args[0] {
+	case "bash":
+		tmpl = bashCompletion
+	case "zsh":
+		tmpl = zshCompletion
+	case "fish":
+		tmpl = fishCompletion
+	default:
+		return errs.NewUsageExitError("Unknown shell '"+args[0]+"'", ctx)
+	}
+
+	fmt.Printf(tmpl, strings.Join(completionCommandNames(), " "),
+		strings.Join(valueFlags, "|"), strings.Join(valueFlags, " "))
+	return nil
+}
+
+// valueFlags are the flags of secrets set, view and unset that take a value,
+// which the completion scripts skip over when looking for the name argument.
+var valueFlags = []string{
+	"--org", "-o", "--project", "-p", "--environment", "-e", "--service", "-s",
+	"--user", "-u", "--machine", "-m", "--instance", "-i", "--from-file", "--to-file",
+}
+
+// completionCommandNames returns the names of all visible top level commands.
+func completionCommandNames() []string {
+	var names []string
+	for _, c := range Cmds {
+		if c.Hidden {
+			continue
+		}
+		names = append(names, c.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func completeNamesCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) != 1 {
+		return nil
+	}
+
+	// Values for --org and --project not given on the command line come
+	// from the linked directory or the preferences defaults, as they would
+	// for the command being completed. Failures here just leave them unset.
+	if loadDirPrefs(ctx) == nil {
+		loadPrefDefaults(ctx)
+	}
+
+	names, err := completeNames(ctx, args[0])
+	if err != nil {
+		return nil
+	}
+
+	for _, name := range names {
+		fmt.Println(name)
+	}
+	return nil
+}
+
+func completeNames(ctx *cli.Context, kind string) ([]string, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	c, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+
+	client := api.NewClient(cfg)
+
+	var names []string
+	switch kind {
+	case "orgs":
+		orgs, err := client.Orgs.List(c)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range orgs {
+			names = append(names, o.Body.Name)
+		}
+	case "projects":
+		org, err := completionOrg(c, client, ctx.String("org"))
+		if err != nil {
+			return nil, err
+		}
+		projects, err := client.Projects.List(c, &[]*identity.ID{org.ID}, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range projects {
+			names = append(names, p.Body.Name)
+		}
+	case "services":
+		org, err := completionOrg(c, client, ctx.String("org"))
+		if err != nil {
+			return nil, err
+		}
+		projectName := ctx.String("project")
+		if projectName == "" {
+			return nil, nil
+		}
+		projects, err := client.Projects.List(c, &[]*identity.ID{org.ID}, &[]string{projectName})
+		if err != nil || len(projects) != 1 {
+			return nil, err
+		}
+		services, err := client.Services.List(c, nil, &[]*identity.ID{projects[0].ID}, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range services {
+			names = append(names, s.Body.Name)
+		}
+	case "secrets":
+		// Names are read from the credential graphs without decrypting
+		// anything, and the path uses the org's name, so no lookups are
+		// needed beforehand.
+		pe, err := completionPathExp(ctx.String("org"), ctx.String("project"),
+			ctx.StringSlice("environment"), ctx.StringSlice("service"))
+		if err != nil {
+			return nil, err
+		}
+		keys, err := client.Credentials.ListNames(c, pe.String())
+		if err != nil {
+			return nil, err
+		}
+		for i, k := range keys {
+			if i == 0 || k.Name != keys[i-1].Name {
+				names = append(names, k.Name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// completionOrg looks up the org named by --org. A missing name or org is
+// reported as an error so that no candidates are suggested.
+func completionOrg(c context.Context, client *api.Client, name string) (*api.OrgResult, error) {
+	if name == "" {
+		return nil, errs.NewExitError("Org not given")
+	}
+
+	org, err := client.Orgs.GetByName(c, name)
+	if err != nil {
+		return nil, err
+	}
+	if org == nil {
+		return nil, errs.NewNotFoundExitError("Org not found")
+	}
+
+	return org, nil
+}
+
+// completionPathExp builds the path expression whose secret names are
+// suggested. Any environment or service not given matches all of them.
+func completionPathExp(org, project string, envs, services []string) (*pathexp.PathExp, error) {
+	if org == "" || project == "" {
+		return nil, errs.NewExitError("Org and project not given")
+	}
+
+	if len(envs) == 0 {
+		envs = []string{"*"}
+	}
+	if len(services) == 0 {
+		services = []string{"*"}
+	}
+
+	return pathexp.New(org, project, envs, services, []string{"*"}, []string{"*"})
+}
+
+const bashCompletion = `# torus bash completion. Load it with:
+#   source <(torus completion bash)
+
+# _torus_wants_secret_name succeeds when the word being completed is the name
+# argument of torus secrets set, view or unset.
+_torus_wants_secret_name() {
+    local i
+    [ "$COMP_CWORD" -ge 3 ] && [ "${COMP_WORDS[1]}" = secrets ] || return 1
+    case "${COMP_WORDS[2]}" in
+        set|view|unset) ;;
+        *) return 1 ;;
+    esac
+    case "$cur" in
+        -*) return 1 ;;
+    esac
+
+    for ((i = 3; i < COMP_CWORD; i++)); do
+        case "${COMP_WORDS[i]}" in
+            %[2]s) ((i++)) ;;
+            -*) ;;
+            *) return 1 ;;
+        esac
+    done
+}
+
+_torus_complete() {
+    local cur prev kind i
+    local -a args
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+
+    case "$prev" in
+        --org|-o) kind=orgs ;;
+        --project|-p) kind=projects ;;
+        --service|-s) kind=services ;;
+        *)
+            if [ "$COMP_CWORD" -eq 1 ]; then
+                COMPREPLY=( $(compgen -W "%[1]s" -- "$cur") )
+                return 0
+            fi
+            _torus_wants_secret_name || return 0
+            kind=secrets
+            ;;
+    esac
+
+    for ((i = 1; i < COMP_CWORD - 1; i++)); do
+        case "${COMP_WORDS[i]}" in
+            --org|-o) args+=(--org "${COMP_WORDS[i+1]}") ;;
+            --project|-p) args+=(--project "${COMP_WORDS[i+1]}") ;;
+            --environment|-e) args+=(--environment "${COMP_WORDS[i+1]}") ;;
+            --service|-s) args+=(--service "${COMP_WORDS[i+1]}") ;;
+        esac
+    done
+
+    COMPREPLY=( $(compgen -W "$(torus __complete "${args[@]}" "$kind" 2>/dev/null)" -- "$cur") )
+}
+
+complete -o default -F _torus_complete torus
+`
+
+const zshCompletion = `#compdef torus
+# torus zsh completion. Load it with:
+#   source <(torus completion zsh)
+
+# _torus_wants_secret_name succeeds when the word being completed is the name
+# argument of torus secrets set, view or unset.
+_torus_wants_secret_name() {
+    local i
+    (( CURRENT >= 4 )) && [[ "${words[2]}" == secrets ]] || return 1
+    case "${words[3]}" in
+        set|view|unset) ;;
+        *) return 1 ;;
+    esac
+    [[ "${words[CURRENT]}" == -* ]] && return 1
+
+    for ((i = 4; i < CURRENT; i++)); do
+        case "${words[i]}" in
+            %[2]s) ((i++)) ;;
+            -*) ;;
+            *) return 1 ;;
+        esac
+    done
+    return 0
+}
+
+_torus() {
+    local kind i
+    local -a args candidates
+
+    case "${words[CURRENT-1]}" in
+        --org|-o) kind=orgs ;;
+        --project|-p) kind=projects ;;
+        --service|-s) kind=services ;;
+        *)
+            if (( CURRENT == 2 )); then
+                compadd -- %[1]s
+                return
+            fi
+            if ! _torus_wants_secret_name; then
+                _files
+                return
+            fi
+            kind=secrets
+            ;;
+    esac
+
+    for ((i = 2; i < CURRENT - 1; i++)); do
+        case "${words[i]}" in
+            --org|-o) args+=(--org "${words[i+1]}") ;;
+            --project|-p) args+=(--project "${words[i+1]}") ;;
+            --environment|-e) args+=(--environment "${words[i+1]}") ;;
+            --service|-s) args+=(--service "${words[i+1]}") ;;
+        esac
+    done
+
+    candidates=(${(f)"$(torus __complete "${args[@]}" "$kind" 2>/dev/null)"})
+    compadd -a candidates
+}
+
+compdef _torus torus
+`
+
+const fishCompletion = `# torus fish completion. Load it with:
+#   torus completion fish | source
+
+function __torus_complete
+    set -l tokens (commandline -opc)
+    set -l args
+    for i in (seq 2 (math (count $tokens) - 1))
+        switch $tokens[$i]
+            case --org -o
+                set args $args --org $tokens[(math $i + 1)]
+            case --project -p
+                set args $args --project $tokens[(math $i + 1)]
+            case --environment -e
+                set args $args --environment $tokens[(math $i + 1)]
+            case --service -s
+                set args $args --service $tokens[(math $i + 1)]
+        end
+    end
+    torus __complete $args $argv[1] 2>/dev/null
+end
+
+# __torus_wants_secret_name succeeds when the word being completed is the name
+# argument of torus secrets set, view or unset.
+function __torus_wants_secret_name
+    set -l tokens (commandline -opc)
+    test (count $tokens) -ge 3; or return 1
+    test $tokens[2] = secrets; or return 1
+    contains -- $tokens[3] set view unset; or return 1
+
+    set -l skip 0
+    for t in $tokens[4..-1]
+        if test $skip -eq 1
+            set skip 0
+            continue
+        end
+        switch $t
+            case %[3]s
+                set skip 1
+            case '-*'
+            case '*'
+                return 1
+        end
+    end
+end
+
+complete -c torus -n __fish_use_subcommand -f -a "%[1]s"
+complete -c torus -l org -s o -x -a "(__torus_complete orgs)"
+complete -c torus -l project -s p -x -a "(__torus_complete projects)"
+complete -c torus -l service -s s -x -a "(__torus_complete services)"
+complete -c torus -n __torus_wants_secret_name -f -a "(__torus_complete secrets)"
+`
@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/config"
+	"github.com/manifoldco/torus-cli/errs"
+	"github.com/manifoldco/torus-cli/prefs"
+)
+
+// configKey is a setting that can be viewed and changed with 'torus config'.
+// Settings are stored in the core section of the torusrc file, under the
+// same name, so 'torus prefs set core.<key>' changes them too.
+type configKey struct {
+	usage    string
+	validate func(string) error
+	daemon   bool // the running daemon is told to reload its config
+	restart  bool // the daemon only picks up a change when it next starts
+}
+
+var configKeys = map[string]configKey{
+	"registry_uri": {
+		usage:    "URL of the Torus registry",
+		validate: validateConfigURL,
+		restart:  true,
+	},
+	"proxy": {
+		usage:    "URL of the proxy used for registry requests",
+		validate: validateConfigURL,
+		daemon:   true,
+	},
+	"timeout": {
+		usage:    "How long commands wait on the daemon and registry, such as 30s",
+		validate: validateConfigDuration,
+	},
+	"idle_conn_timeout": {
+		usage:    "Seconds an idle registry connection is kept open",
+		validate: validateConfigPositiveInt,
+		daemon:   true,
+	},
+	"retry_attempts": {
+		usage:    "Maximum attempts for idempotent registry requests",
+		validate: validateConfigPositiveInt,
+		daemon:   true,
+	},
+	"retry_base_delay": {
+		usage:    "Milliseconds to wait before retrying a registry request",
+		validate: validateConfigPositiveInt,
+		daemon:   true,
+	},
+	"shutdown_grace_period": {
+		usage:    "Seconds the daemon waits for requests to finish when stopping",
+		validate: validateConfigPositiveInt,
+		daemon:   true,
+	},
+	"log_format": {
+		usage:    "Format of the daemon's log, text or json",
+		validate: validateConfigLogFormat,
+		daemon:   true,
+	},
+	"no_color": {
+		usage:    "Turn off colored output, true or false",
+		validate: validateConfigBool,
+	},
+}
+
+func init() {
+	cfgCmd := cli.Command{
+		Name:     "config",
+		Usage:    "View and set the configuration of Torus and its daemon",
+		Category: "SYSTEM",
+		Subcommands: []cli.Command{
+			{
+				Name:      "get",
+				Usage:     "Show the value of a configuration key",
+				ArgsUsage: "<key>",
+				Action:    configGetCmd,
+			},
+			{
+				Name:      "set",
+				Usage:     "Set the value of a configuration key",
+				ArgsUsage: "<key> <value>",
+				Action:    configSetCmd,
+			},
+		},
+	}
+
+	Cmds = append(Cmds, cfgCmd)
+}
+
+func configGetCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) != 1 {
+		return errs.NewUsageExitError("A key is required", ctx)
+	}
+
+	key := args[0]
+	if _, err := lookupConfigKey(key); err != nil {
+		return err
+	}
+
+	preferences, err := prefs.NewPreferences(false)
+	if err != nil {
+		return errs.NewErrorExitError("Failed to load prefs.", err)
+	}
+
+	value := coreValue(preferences, key)
+	if value == "" {
+		return errs.NewExitError(key + " is not set; the default is used.")
+	}
+
+	fmt.Println(value)
+	return nil
+}
+
+func configSetCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) != 2 {
+		return errs.NewUsageExitError("A key and value are required", ctx)
+	}
+
+	key, value := args[0], args[1]
+	ck, err := lookupConfigKey(key)
+	if err != nil {
+		return err
+	}
+
+	err = savePref("core."+key, value)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s set to %s.\n", key, value)
+	if key == "registry_uri" {
+		if err := warnProfileRegistry(value); err != nil {
+			return err
+		}
+	}
+	if !ck.daemon && !ck.restart {
+		return nil
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	proc, err := findDaemon(cfg)
+	if err != nil {
+		return err
+	}
+	if proc == nil {
+		return nil
+	}
+
+	// The daemon holds the session in memory, so restarting it to pick up
+	// the change would log the user out. Leave that to them.
+	if ck.restart {
+		fmt.Println("The running daemon keeps using the old value until it is restarted with " +
+			"'torus daemon stop', which will log you out.")
+		return nil
+	}
+
+	err = proc.Signal(syscall.SIGHUP)
+	if err != nil {
+		return errs.NewErrorExitError("Could not tell the daemon to reload its config.", err)
+	}
+
+	return nil
+}
+
+// warnProfileRegistry tells the user when the registry_uri they set in
+// [core] isn't used, because the active profile sets its own.
+func warnProfileRegistry(value string) error {
+	preferences, err := prefs.NewPreferences(false)
+	if err != nil {
+		return errs.NewErrorExitError("Failed to load prefs.", err)
+	}
+
+	name := preferences.ActiveProfile()
+	if name == prefs.DefaultProfile {
+		return nil
+	}
+
+	profile, err := prefs.LoadProfile(preferences, name)
+	if err != nil {
+		return err
+	}
+	if profile.RegistryURI != value {
+		fmt.Printf("This sets the registry of the default profile. The %s profile in use has its "+
+			"own, %s, set in the [profile %s] section of your torusrc.\n",
+			name, profile.RegistryURI, name)
+	}
+
+	return nil
+}
+
+// lookupConfigKey returns the configKey for key, or an error listing the
+// valid keys if there is none.
+func lookupConfigKey(key string) (configKey, error) {
+	ck, ok := configKeys[key]
+	if ok {
+		return ck, nil
+	}
+
+	var keys []string
+	for k, ck := range configKeys {
+		keys = append(keys, fmt.Sprintf("  %-22s %s", k, ck.usage))
+	}
+	sort.Strings(keys)
+
+	return ck, errs.NewExitError(fmt.Sprintf("Unknown key %q. Valid keys are:\n%s",
+		key, strings.Join(keys, "\n")))
+}
+
+// coreValue returns the value of the core preference with the given ini
+// name, or an empty string if it is unset.
+func coreValue(preferences *prefs.Preferences, key string) string {
+	core := reflect.ValueOf(preferences.Core)
+	for i := 0; i < core.NumField(); i++ {
+		name := strings.Split(core.Type().Field(i).Tag.Get("ini"), ",")[0]
+		if name != key {
+			continue
+		}
+
+		field := core.Field(i)
+		if field.Interface() == reflect.Zero(field.Type()).Interface() {
+			return ""
+		}
+		return fmt.Sprint(field.Interface())
+	}
+
+	return ""
+}
+
+func validateConfigURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http or https URL", value)
+	}
+	return nil
+}
+
+func validateConfigDuration(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return fmt.Errorf("%q is not a duration, such as 30s or 2m", value)
+	}
+	return nil
+}
+
+func validateConfigPositiveInt(value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return fmt.Errorf("%q is not a positive whole number", value)
+	}
+	return nil
+}
+
+func validateConfigBool(value string) error {
+	if value != "true" && value != "false" {
+		return fmt.Errorf("%q is not true or false", value)
+	}
+	return nil
+}
+
+func validateConfigLogFormat(value string) error {
+	if value != "text" && value != "json" {
+		return fmt.Errorf("%q is not text or json", value)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/manifoldco/torus-cli/prefs"
+)
+
+func TestConfigKeysValidate(t *testing.T) {
+	tcs := []struct {
+		key   string
+		value string
+		valid bool
+	}{
+		{"registry_uri", "https://registry.example.com", true},
+		{"registry_uri", "registry.example.com", false},
+		{"proxy", "http://proxy:3128", true},
+		{"proxy", "socks5://proxy:1080", false},
+		{"timeout", "30s", true},
+		{"timeout", "30", false},
+		{"timeout", "-1s", false},
+		{"retry_attempts", "3", true},
+		{"retry_attempts", "0", false},
+		{"no_color", "true", true},
+		{"no_color", "yes", false},
+		{"log_format", "json", true},
+		{"log_format", "xml", false},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.key+"="+tc.value, func(t *testing.T) {
+			ck, err := lookupConfigKey(tc.key)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+
+			err = ck.validate(tc.value)
+			if tc.valid && err != nil {
+				t.Errorf("Expected %s to be valid, got %s", tc.value, err)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("Expected %s to be invalid", tc.value)
+			}
+		})
+	}
+}
+
+func TestLookupConfigKeyUnknown(t *testing.T) {
+	_, err := lookupConfigKey("registry")
+	if err == nil {
+		t.Fatal("Expected an error for an unknown key")
+	}
+
+	for key := range configKeys {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("Expected the error to list %s, got %s", key, err)
+		}
+	}
+}
+
+func TestCoreValue(t *testing.T) {
+	p := &prefs.Preferences{Core: prefs.Core{Timeout: "1m", RetryAttempts: 4}}
+
+	if v := coreValue(p, "timeout"); v != "1m" {
+		t.Errorf("Expected 1m, got %q", v)
+	}
+	if v := coreValue(p, "retry_attempts"); v != "4" {
+		t.Errorf("Expected 4, got %q", v)
+	}
+	if v := coreValue(p, "proxy"); v != "" {
+		t.Errorf("Expected an unset value, got %q", v)
+	}
+}
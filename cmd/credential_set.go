@@ -1,39 +1,51 @@
 package cmd
 
 import (
+	"errors"
 	"sort"
+	"strings"
 
 	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/pathexp"
 )
 
 // credentialSet represents a set of credentials.
 // It ensures credentials are unique by name. The credential with the most
-// specific PathExp wins. If two credentials have the same specificity level,
-// the first one added wins.
+// specific PathExp wins, as chosen by pathexp.MostSpecific. Two credentials
+// of the same name set at different, equally specific PathExps are an error.
 //
 // credentials are returned in lexicographically sorted order, by name.
 type credentialSet map[string]*apitypes.CredentialEnvelope
 
 // Add adds a credential to the credentialSet, replacing an existing credential
-// of the same name, if the new credential is more specific.
+// of the same name, if the new credential is more specific. An error is
+// returned if neither is more specific, rather than picking one.
 //
 // If the added credential is an unset value, it is summarily ignored.
-func (c credentialSet) Add(cred apitypes.CredentialEnvelope) {
+func (c credentialSet) Add(cred apitypes.CredentialEnvelope) error {
 	if (*cred.Body).GetValue() == nil {
-		return
+		return nil
 	}
 
 	name := (*cred.Body).GetName()
 	if existing, ok := c[name]; ok {
+		eBody := *existing.Body
+		best, err := pathexp.MostSpecific([]*pathexp.PathExp{
+			eBody.GetPathExp(), (*cred.Body).GetPathExp(),
+		})
+		if err != nil {
+			return errors.New("Cannot choose a value for " + name + ". " + err.Error())
+		}
+
 		// The new credential is either as specific, or less specific than
 		// the existing one. Keep the existing one.
-		eBody := *existing.Body
-		if (*cred.Body).GetPathExp().CompareSpecificity(eBody.GetPathExp()) != 1 {
-			return
+		if best == 0 {
+			return nil
 		}
 	}
 
 	c[name] = &cred
+	return nil
 }
 
 // ToSlice returns a slice of the credentials in the set, in lexicographically
@@ -61,3 +73,23 @@ func (c credSorter) Less(i, j int) bool {
 	b := *c[j].Body
 	return a.GetName() < b.GetName()
 }
+
+// credentialMetadata formats the description and tags of cred for display,
+// as "# description [tag, tag]". It is empty if cred has neither.
+func credentialMetadata(cred interface {
+	GetDescription() string
+	GetTags() []string
+}) string {
+	var parts []string
+	if d := cred.GetDescription(); d != "" {
+		parts = append(parts, d)
+	}
+	if tags := cred.GetTags(); len(tags) > 0 {
+		parts = append(parts, "["+strings.Join(tags, ", ")+"]")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+
+	return "# " + strings.Join(parts, " ")
+}
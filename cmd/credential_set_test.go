@@ -201,4 +201,55 @@ func TestCredentialSet(t *testing.T) {
 			t.Error("credentials not sorted")
 		}
 	})
+
+	t.Run("equally specific is an error", func(t *testing.T) {
+		makeCred := func(exp string, value *apitypes.CredentialValue) apitypes.CredentialEnvelope {
+			path, _ := pathexp.Parse(exp)
+			var cBody apitypes.Credential = &apitypes.CredentialV2{
+				State: "set",
+				BaseCredential: apitypes.BaseCredential{
+					Name:    "1",
+					PathExp: path,
+					Value:   value,
+				},
+			}
+			return apitypes.CredentialEnvelope{Body: &cBody}
+		}
+
+		cset := credentialSet{}
+		if err := cset.Add(makeCred("/o/p/e/[s|t]/*/i", astring)); err != nil {
+			t.Fatal(err)
+		}
+		if err := cset.Add(makeCred("/o/p/e/s/*/i", bstring)); err != nil {
+			t.Fatal(err)
+		}
+		if err := cset.Add(makeCred("/o/p/e/s/*/i", astring)); err != nil {
+			t.Error("Expected the same path expression not to be an error, got", err)
+		}
+		if err := cset.Add(makeCred("/o/p/e/s/[u|v]/i", astring)); err != nil {
+			t.Fatal(err)
+		}
+		if err := cset.Add(makeCred("/o/p/e/s/[u|w]/i", bstring)); err == nil {
+			t.Error("Expected an error for equally specific path expressions")
+		}
+	})
+}
+
+func TestCredentialMetadata(t *testing.T) {
+	tcs := []struct {
+		cred     apitypes.Credential
+		expected string
+	}{
+		{&apitypes.BaseCredential{}, ""},
+		{&apitypes.CredentialV2{}, ""},
+		{&apitypes.CredentialV2{Description: "Main db"}, "# Main db"},
+		{&apitypes.CredentialV2{Tags: []string{"db", "prod"}}, "# [db, prod]"},
+		{&apitypes.CredentialV2{Description: "Main db", Tags: []string{"db"}}, "# Main db [db]"},
+	}
+
+	for _, tc := range tcs {
+		if got := credentialMetadata(tc.cred); got != tc.expected {
+			t.Errorf("Expected %q, got %q", tc.expected, got)
+		}
+	}
 }
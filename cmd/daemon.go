@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
@@ -10,6 +11,7 @@ import (
 	"path"
 	"runtime"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/kardianos/osext"
@@ -22,6 +24,7 @@ import (
 	"github.com/manifoldco/torus-cli/errs"
 
 	"github.com/manifoldco/torus-cli/daemon"
+	"github.com/manifoldco/torus-cli/daemon/logging"
 )
 
 func init() {
@@ -66,6 +69,8 @@ func init() {
 	Cmds = append(Cmds, daemon)
 }
 
+const daemonNotRunning = "Daemon is not running."
+
 func daemonStatus(ctx *cli.Context) error {
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -78,19 +83,35 @@ func daemonStatus(ctx *cli.Context) error {
 	}
 
 	if proc == nil {
-		fmt.Println("Daemon is not running.")
-		return nil
+		return errs.NewExitError(daemonNotRunning)
 	}
 
 	client := api.NewClient(cfg)
-	v, err := client.Version.Get(context.Background())
+	health, err := client.Daemon.Health(context.Background())
 	if err != nil {
-		return errs.NewErrorExitError("Error communicating with the daemon", err)
+		return errs.NewExitError(fmt.Sprintf(
+			"Daemon is running (pid %d) but is not responding.", proc.Pid))
+	}
+
+	pid := health.PID
+	if pid == 0 { // daemons predating the pid in /v1/health
+		pid = proc.Pid
 	}
 
-	fmt.Printf("Daemon is running. pid: %d version: v%s\n", proc.Pid, v.Version)
+	session := "none"
+	if health.Session {
+		session = "logged in"
+	}
 
-	return nil
+	fmt.Println("Daemon is running.")
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "PID:\t%d\n", pid)
+	fmt.Fprintf(w, "Version:\tv%s\n", health.Version)
+	fmt.Fprintf(w, "Uptime:\t%s\n", time.Duration(health.Uptime)*time.Second)
+	fmt.Fprintf(w, "Session:\t%s\n", session)
+	return w.Flush()
 }
 
 func spawnDaemonCmd() error {
@@ -147,26 +168,28 @@ func startDaemon(ctx *cli.Context) error {
 		return errs.NewErrorExitError("Failed to initialize Torus root dir.", err)
 	}
 
+	cfg, err := config.NewConfig(torusRoot)
+	if err != nil {
+		return errs.NewErrorExitError("Failed to load config.", err)
+	}
+
+	var logOutput io.Writer = os.Stderr
 	if ctx.Bool("daemonize") {
-		log.SetOutput(&lumberjack.Logger{
+		logOutput = &lumberjack.Logger{
 			Filename:   path.Join(torusRoot, "daemon.log"),
 			MaxSize:    10, // megabytes
 			MaxBackups: 3,
 			MaxAge:     28, // days
-		})
-	}
-
-	cfg, err := config.NewConfig(torusRoot)
-	if err != nil {
-		return errs.NewErrorExitError("Failed to load config.", err)
+		}
 	}
+	logging.Setup(logOutput, cfg.LogFormat)
 
 	daemon, err := daemon.New(cfg)
 	if err != nil {
 		return errs.NewErrorExitError("Failed to create daemon.", err)
 	}
 
-	go watch(daemon)
+	go watch(daemon, logOutput)
 	defer daemon.Shutdown()
 
 	log.Printf("v%s of the Daemon is now listening on %s", cfg.Version, daemon.Addr())
@@ -178,13 +201,37 @@ func startDaemon(ctx *cli.Context) error {
 	return err
 }
 
-func watch(daemon *daemon.Daemon) {
+// watch shuts the daemon down when it is interrupted or asked to stop, and
+// reloads its config on SIGHUP, as sent by 'torus config set'.
+func watch(daemon *daemon.Daemon, logOutput io.Writer) {
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
-	s := <-c
+	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+
+	for {
+		select {
+		case s := <-c:
+			if s == syscall.SIGHUP {
+				reload(daemon, logOutput)
+				continue
+			}
+			log.Printf("Caught a signal: %s", s)
+		case <-daemon.StopRequested():
+		}
 
-	log.Printf("Caught a signal: %s", s)
-	shutdown(daemon)
+		shutdown(daemon)
+		return
+	}
+}
+
+func reload(daemon *daemon.Daemon, logOutput io.Writer) {
+	cfg, err := daemon.Reload()
+	if err != nil {
+		log.Printf("Could not reload config.\n%s", err)
+		return
+	}
+
+	logging.Setup(logOutput, cfg.LogFormat)
+	log.Printf("Reloaded config")
 }
 
 func shutdown(daemon *daemon.Daemon) {
@@ -211,11 +258,18 @@ func stopDaemonCmd(ctx *cli.Context) error {
 	}
 
 	if proc == nil {
-		fmt.Println("Daemon is not running.")
-		return nil
+		return errs.NewExitError(daemonNotRunning)
 	}
 
-	graceful, err := stopDaemon(proc)
+	// Ask the daemon to stop over its socket, so it can let in-flight
+	// requests finish. Daemons that don't answer are signalled instead.
+	var graceful bool
+	client := api.NewClient(cfg)
+	if stopErr := client.Daemon.Stop(context.Background()); stopErr == nil {
+		graceful, err = awaitDaemonStop(proc, cfg.GracePeriod+3*time.Second)
+	} else {
+		graceful, err = stopDaemon(proc)
+	}
 	if err != nil {
 		return err
 	}
@@ -230,6 +284,26 @@ func stopDaemonCmd(ctx *cli.Context) error {
 	return nil
 }
 
+// awaitDaemonStop waits up to timeout for a daemon that has been asked to
+// stop to exit, killing it if it hasn't. It returns a bool indicating if the
+// shutdown was graceful.
+func awaitDaemonStop(proc *os.Process, timeout time.Duration) (bool, error) {
+	increment := 50 * time.Millisecond
+	for d := time.Duration(0); d < timeout; d += increment {
+		if _, err := findProcess(proc.Pid); err != nil {
+			return true, nil
+		}
+		time.Sleep(increment)
+	}
+
+	err := proc.Kill()
+	if err != nil {
+		return false, errs.NewErrorExitError("Could not stop daemon.", err)
+	}
+
+	return false, nil
+}
+
 // stopDaemon stops the daemon process. It returns a bool indicating if the
 // shutdown was graceful.
 func stopDaemon(proc *os.Process) (bool, error) {
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"strings"
 	"text/tabwriter"
 	"time"
 
@@ -75,7 +74,7 @@ func debugInfoCmd(ctx *cli.Context) error {
 	session, uErr := client.Session.Who(c)
 	loggedIn := true
 	if uErr != nil {
-		if strings.Contains(uErr.Error(), "invalid login") {
+		if apitypes.HasMessage(uErr, "invalid login") {
 			loggedIn = false
 		} else {
 			return errs.NewErrorExitError("Could not retrieve user", err)
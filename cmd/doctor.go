@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/config"
+	"github.com/manifoldco/torus-cli/daemon/socket"
+	"github.com/manifoldco/torus-cli/errs"
+	"github.com/manifoldco/torus-cli/prefs"
+)
+
+// doctorTimeout bounds each of the network checks made by doctor.
+const doctorTimeout = 10 * time.Second
+
+// maxClockSkew is the largest difference between the local clock and the
+// registry's that doctor accepts.
+const maxClockSkew = time.Minute
+
+func init() {
+	doctor := cli.Command{
+		Name:     "doctor",
+		Usage:    "Check your configuration and connectivity to the daemon and registry",
+		Category: "SYSTEM",
+		// The daemon and session are checked by doctorCmd, so it can
+		// report on them rather than fail or prompt.
+		Action: doctorCmd,
+	}
+	Cmds = append(Cmds, doctor)
+}
+
+// doctorResult is the outcome of a single check. A check is skipped if one
+// it depends on failed.
+type doctorResult struct {
+	name    string
+	detail  string
+	err     error
+	hint    string
+	skipped bool
+}
+
+func doctorCmd(ctx *cli.Context) error {
+	var results []doctorResult
+	add := func(r doctorResult) bool {
+		results = append(results, r)
+		return r.err == nil && !r.skipped
+	}
+
+	cfg, configOK := doctorConfig(add)
+
+	daemonOK := false
+	if configOK {
+		var health bool
+		health, daemonOK = doctorDaemon(cfg, add)
+		if daemonOK {
+			r := doctorResult{name: "Session", detail: "logged in"}
+			if !health {
+				r.err = errors.New("not logged in")
+				r.hint = "Log in with 'torus login'."
+			}
+			add(r)
+		}
+	}
+	if !daemonOK {
+		add(doctorResult{name: "Session", skipped: true})
+	}
+
+	if configOK {
+		doctorRegistry(cfg, add)
+	} else {
+		add(doctorResult{name: "Registry", skipped: true})
+		add(doctorResult{name: "Clock", skipped: true})
+	}
+
+	failed := false
+	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+	for _, r := range results {
+		switch {
+		case r.skipped:
+			fmt.Fprintf(w, "-\t%s\tskipped\n", r.name)
+		case r.err != nil:
+			failed = true
+			fmt.Fprintf(w, "✗\t%s\t%s\n", r.name, r.err)
+			if r.hint != "" {
+				fmt.Fprintf(w, "\t\t%s\n", r.hint)
+			}
+		default:
+			fmt.Fprintf(w, "✔\t%s\t%s\n", r.name, r.detail)
+		}
+	}
+	w.Flush()
+
+	if failed {
+		return errs.NewExitError("\nSome checks failed.")
+	}
+
+	return nil
+}
+
+// doctorConfig checks that the torusrc file can be read, and that the
+// config derived from it loads.
+func doctorConfig(add func(doctorResult) bool) (*config.Config, bool) {
+	rcPath, err := prefs.RcPath()
+	if err == nil {
+		_, err = prefs.NewPreferences(true)
+	}
+	ok := add(doctorResult{
+		name:   "Preferences",
+		detail: rcPath,
+		err:    err,
+		hint:   "Fix or remove " + rcPath + ". It must be a readable ini file.",
+	})
+	if !ok {
+		add(doctorResult{name: "Config", skipped: true})
+		return nil, false
+	}
+
+	cfg, err := config.LoadConfig()
+	r := doctorResult{
+		name: "Config",
+		err:  err,
+		hint: "Check the registry_uri and ca_bundle_file settings in " + rcPath + ".",
+	}
+	if err == nil {
+		r.detail = "profile " + cfg.Profile
+	}
+
+	return cfg, add(r)
+}
+
+// doctorDaemon checks that the daemon is running and responding on its
+// socket, returning whether it has a session.
+func doctorDaemon(cfg *config.Config, add func(doctorResult) bool) (bool, bool) {
+	startHint := "Start the daemon with 'torus daemon start'."
+
+	proc, err := findDaemon(cfg)
+	if err == nil && proc == nil {
+		err = errors.New("not running")
+	}
+	if err != nil {
+		return false, add(doctorResult{name: "Daemon", err: err, hint: startHint})
+	}
+
+	c, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+
+	client := api.NewClient(cfg)
+	health, err := client.Daemon.Health(c)
+	if err != nil {
+		return false, add(doctorResult{
+			name: "Daemon",
+			err:  err,
+			hint: "The daemon isn't responding on " + cfg.SocketPath + ". Restart it with 'torus daemon stop' and 'torus daemon start'.",
+		})
+	}
+
+	detail := fmt.Sprintf("running as pid %d, version %s", health.PID, health.Version)
+	return health.Session, add(doctorResult{name: "Daemon", detail: detail})
+}
+
+// doctorRegistry checks that the registry is reachable, and that its TLS
+// certificate is trusted, directly rather than through the daemon. The
+// registry's Date header is compared with the local clock.
+func doctorRegistry(cfg *config.Config, add func(doctorResult) bool) {
+	client := &http.Client{
+		Transport: socket.CreateHTTPTransport(cfg),
+		Timeout:   doctorTimeout,
+	}
+
+	resp, err := client.Get(cfg.RegistryURI.String() + "/version")
+	if err != nil {
+		add(doctorResult{name: "Registry", err: err, hint: registryHint(err)})
+		add(doctorResult{name: "Clock", skipped: true})
+		return
+	}
+	resp.Body.Close()
+
+	add(doctorResult{name: "Registry", detail: cfg.RegistryURI.String()})
+
+	skew, err := clockSkew(resp.Header.Get("Date"), time.Now())
+	r := doctorResult{name: "Clock", err: err, detail: fmt.Sprintf("within %s of the registry", skew)}
+	if err != nil {
+		r.hint = "Synchronize your clock, for example by enabling NTP."
+	}
+	add(r)
+}
+
+// registryHint suggests how to fix an error reaching the registry.
+func registryHint(err error) string {
+	if uerr, ok := err.(*url.Error); ok {
+		err = uerr.Err
+	}
+
+	switch err.(type) {
+	case x509.UnknownAuthorityError, x509.HostnameError, x509.CertificateInvalidError:
+		return "The registry's certificate could not be verified. If it uses a private CA, set " +
+			"TORUS_CA_BUNDLE or extra_ca_bundle_file in your preferences to the CA's certificate."
+	default:
+		return "Check your network connection, and the registry_uri and proxy settings in your preferences."
+	}
+}
+
+// clockSkew returns how far now is from the time in date, an HTTP Date
+// header. It is an error if the difference is more than maxClockSkew.
+func clockSkew(date string, now time.Time) (time.Duration, error) {
+	if date == "" {
+		return 0, errors.New("the registry did not report its time")
+	}
+
+	t, err := http.ParseTime(date)
+	if err != nil {
+		return 0, err
+	}
+
+	skew := now.Sub(t)
+	if skew < 0 {
+		skew = -skew
+	}
+
+	// Date headers only have second precision.
+	skew = (skew + time.Second/2) / time.Second * time.Second
+	if skew > maxClockSkew {
+		return skew, fmt.Errorf("clock differs from the registry's by %s", skew)
+	}
+
+	return skew, nil
+}
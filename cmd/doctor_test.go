@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClockSkew(t *testing.T) {
+	now := time.Date(2017, 3, 1, 12, 0, 0, 500, time.UTC)
+
+	skew, err := clockSkew(now.Add(-30*time.Second).Format(http.TimeFormat), now)
+	if err != nil {
+		t.Errorf("Expected a small skew to pass, got %s", err)
+	}
+	if skew != 30*time.Second {
+		t.Errorf("Expected 30s of skew, got %s", skew)
+	}
+
+	skew, err = clockSkew(now.Add(5*time.Minute).Format(http.TimeFormat), now)
+	if err == nil {
+		t.Error("Expected a large skew to fail")
+	}
+	if skew != 5*time.Minute {
+		t.Errorf("Expected 5m of skew, got %s", skew)
+	}
+
+	if _, err := clockSkew("", now); err == nil {
+		t.Error("Expected a missing date to fail")
+	}
+	if _, err := clockSkew("yesterday", now); err == nil {
+		t.Error("Expected an invalid date to fail")
+	}
+}
+
+func TestRegistryHint(t *testing.T) {
+	tlsErr := &url.Error{Op: "Get", URL: "https://registry.torus.sh", Err: x509.UnknownAuthorityError{}}
+	if !strings.Contains(registryHint(tlsErr), "certificate") {
+		t.Errorf("Expected a certificate hint, got %q", registryHint(tlsErr))
+	}
+
+	netErr := &url.Error{Op: "Get", URL: "https://registry.torus.sh", Err: errors.New("no route to host")}
+	if !strings.Contains(registryHint(netErr), "network") {
+		t.Errorf("Expected a network hint, got %q", registryHint(netErr))
+	}
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/errs"
+)
+
+var envMapFlag = newPlaceholder("env-map", "FILE",
+	"Rename, prefix or exclude secrets using the mapping in this JSON file. "+
+		"Mapped names are still uppercased, except by tfvars", "", "", false)
+
+// envMap describes how secret names are turned into the names they are
+// exposed as by run and export. It is read from a JSON file such as:
+//
+//	{
+//	  "prefix": "APP_",
+//	  "rename": {"db_password": "DATABASE_PASSWORD"},
+//	  "exclude": ["signing_key"]
+//	}
+//
+// Renamed secrets take the given name in place of their own; the prefix is
+// only added to secrets that are not renamed. Excluded secrets are left out
+// entirely. Like any other name, a mapped name is uppercased by run and by the
+// dotenv and json export formats, and kept as given by tfvars.
+type envMap struct {
+	Prefix  string            `json:"prefix"`
+	Rename  map[string]string `json:"rename"`
+	Exclude []string          `json:"exclude"`
+}
+
+// secretVar is the value of a secret along with the name it is exposed as.
+type secretVar struct {
+	Name  string
+	Value string
+}
+
+func loadEnvMap(path string) (*envMap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m := &envMap{}
+	dec := json.NewDecoder(f)
+	err = dec.Decode(m)
+	if err != nil {
+		return nil, err
+	}
+
+	for from, to := range m.Rename {
+		if to == "" || strings.ContainsAny(to, "= \t\n") {
+			return nil, fmt.Errorf("invalid name %q for %s", to, from)
+		}
+	}
+	if strings.ContainsAny(m.Prefix, "= \t\n") {
+		return nil, fmt.Errorf("invalid prefix %q", m.Prefix)
+	}
+
+	return m, nil
+}
+
+// apply returns the secrets with their mapped names, in the order given.
+// It is an error for two secrets to end up with the same name, ignoring
+// case, as they would overwrite one another.
+func (m *envMap) apply(secrets []apitypes.CredentialEnvelope) ([]secretVar, error) {
+	exclude := make(map[string]bool, len(m.Exclude))
+	for _, name := range m.Exclude {
+		exclude[strings.ToLower(name)] = true
+	}
+
+	rename := make(map[string]string, len(m.Rename))
+	for from, to := range m.Rename {
+		rename[strings.ToLower(from)] = to
+	}
+
+	vars := make([]secretVar, 0, len(secrets))
+	sources := make(map[string][]string)
+	for _, secret := range secrets {
+		name := (*secret.Body).GetName()
+		if exclude[name] {
+			continue
+		}
+
+		mapped, ok := rename[name]
+		if !ok {
+			mapped = m.Prefix + name
+		}
+
+		key := strings.ToUpper(mapped)
+		sources[key] = append(sources[key], name)
+		vars = append(vars, secretVar{
+			Name:  mapped,
+			Value: (*secret.Body).GetValue().String(),
+		})
+	}
+
+	var collisions []string
+	for key, names := range sources {
+		if len(names) > 1 {
+			collisions = append(collisions, fmt.Sprintf("%s would be set by %s", key,
+				strings.Join(names, ", ")))
+		}
+	}
+	if len(collisions) > 0 {
+		sort.Strings(collisions)
+		return nil, fmt.Errorf("secret names collide after mapping:\n\t%s",
+			strings.Join(collisions, "\n\t"))
+	}
+
+	return vars, nil
+}
+
+// getSecretVars returns the secrets for the command's context, mapped
+// according to the --env-map file if one was given. With --redact, matching
+// secrets are redacted, and with --expand, references between secrets are
+// expanded.
+func getSecretVars(ctx *cli.Context) ([]secretVar, error) {
+	m := &envMap{}
+	if path := ctx.String("env-map"); path != "" {
+		var err error
+		m, err = loadEnvMap(path)
+		if err != nil {
+			return nil, errs.NewErrorExitError("Could not read env map "+path, err)
+		}
+	}
+
+	secrets, _, err := getSecrets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	vars, err := m.apply(secrets)
+	if err != nil {
+		return nil, errs.NewErrorExitError("Could not map secrets.", err)
+	}
+
+	// Secrets are redacted before they are expanded, so a redacted value can't
+	// be read through a secret that refers to it.
+	vars = redactSecrets(vars, ctx.StringSlice("redact"))
+
+	if !ctx.Bool("expand") {
+		return vars, nil
+	}
+
+	vars, missing, err := expandSecretVars(vars)
+	if err != nil {
+		return nil, errs.NewErrorExitError("Could not expand secrets.", err)
+	}
+	if len(missing) > 0 {
+		msg := "secrets refer to secrets that don't exist: " + strings.Join(missing, ", ")
+		if ctx.Bool("strict") {
+			return nil, errs.NewExitError("Could not expand secrets: " + msg)
+		}
+		warn(msg + ". The references were left as they are.")
+	}
+
+	return vars, nil
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+)
+
+func TestEnvMapApply(t *testing.T) {
+	makeCred := func(name, value string) apitypes.CredentialEnvelope {
+		v, err := interfaceToCredentialValue(t, map[string]interface{}{
+			"version": 2,
+			"body":    map[string]interface{}{"type": "string", "value": value},
+		})
+		if err != nil {
+			t.Fatal("Unable to decode credential value: " + err.Error())
+		}
+
+		var cBody apitypes.Credential = &apitypes.CredentialV2{
+			State:          "set",
+			BaseCredential: apitypes.BaseCredential{Name: name, Value: v},
+		}
+		return apitypes.CredentialEnvelope{Body: &cBody}
+	}
+
+	secrets := []apitypes.CredentialEnvelope{
+		makeCred("db_password", "hunter2"),
+		makeCred("port", "8080"),
+		makeCred("signing_key", "secret"),
+	}
+
+	t.Run("no mapping", func(t *testing.T) {
+		vars, err := (&envMap{}).apply(secrets)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		expected := []secretVar{
+			{Name: "db_password", Value: "hunter2"},
+			{Name: "port", Value: "8080"},
+			{Name: "signing_key", Value: "secret"},
+		}
+		if !reflect.DeepEqual(vars, expected) {
+			t.Errorf("Expected %v, got %v", expected, vars)
+		}
+	})
+
+	t.Run("rename, prefix and exclude", func(t *testing.T) {
+		m := &envMap{
+			Prefix:  "APP_",
+			Rename:  map[string]string{"DB_PASSWORD": "DATABASE_PASSWORD"},
+			Exclude: []string{"signing_key"},
+		}
+		vars, err := m.apply(secrets)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		expected := []secretVar{
+			{Name: "DATABASE_PASSWORD", Value: "hunter2"},
+			{Name: "APP_port", Value: "8080"},
+		}
+		if !reflect.DeepEqual(vars, expected) {
+			t.Errorf("Expected %v, got %v", expected, vars)
+		}
+	})
+
+	t.Run("collision", func(t *testing.T) {
+		m := &envMap{Rename: map[string]string{"db_password": "PORT"}}
+		_, err := m.apply(secrets)
+		if err == nil {
+			t.Fatal("Expected an error for colliding names")
+		}
+		if !strings.Contains(err.Error(), "PORT would be set by db_password, port") {
+			t.Errorf("Unexpected error: %s", err)
+		}
+	})
+}
+
+func TestLoadEnvMap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "torus-envmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(contents string) string {
+		path := filepath.Join(dir, "map.json")
+		err := ioutil.WriteFile(path, []byte(contents), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	m, err := loadEnvMap(write(`{"prefix": "APP_", "rename": {"a": "B"}, "exclude": ["c"]}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if m.Prefix != "APP_" || m.Rename["a"] != "B" || len(m.Exclude) != 1 {
+		t.Errorf("Unexpected map: %+v", m)
+	}
+
+	_, err = loadEnvMap(write(`{"rename": {"a": "B=C"}}`))
+	if err == nil {
+		t.Error("Expected an error for an invalid name")
+	}
+}
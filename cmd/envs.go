@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"unicode/utf8"
@@ -10,6 +11,7 @@ import (
 	"github.com/urfave/cli"
 
 	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/config"
 	"github.com/manifoldco/torus-cli/errs"
 	"github.com/manifoldco/torus-cli/identity"
@@ -77,11 +79,11 @@ func createEnv(ctx *cli.Context) error {
 		return handleSelectError(err, "Org selection failed.")
 	}
 	if org == nil && !newOrg {
-		fmt.Println("")
+		decorate()
 		return errs.NewExitError("Org not found.")
 	}
 	if newOrg && oName == "" {
-		fmt.Println("")
+		decorate()
 		return errs.NewExitError("Invalid org name.")
 	}
 
@@ -96,11 +98,11 @@ func createEnv(ctx *cli.Context) error {
 		return handleSelectError(err, "Project selection failed.")
 	}
 	if project == nil && !newProject {
-		fmt.Println("")
+		decorate()
 		return errs.NewExitError("Project not found.")
 	}
 	if newProject && pName == "" {
-		fmt.Println("")
+		decorate()
 		return errs.NewExitError("Invalid project name.")
 	}
 
@@ -115,7 +117,7 @@ func createEnv(ctx *cli.Context) error {
 	if org == nil && newOrg {
 		org, err = createOrgByName(c, ctx, client, oName)
 		if err != nil {
-			fmt.Println("")
+			decorate()
 			return err
 		}
 		orgID = org.ID
@@ -125,22 +127,22 @@ func createEnv(ctx *cli.Context) error {
 	if project == nil && newProject {
 		project, err = createProjectByName(c, client, orgID, pName)
 		if err != nil {
-			fmt.Println("")
+			decorate()
 			return err
 		}
 	}
 
 	// Create our new environment
-	fmt.Println("")
+	decorate()
 	err = client.Environments.Create(c, orgID, project.ID, environmentName)
 	if err != nil {
-		if strings.Contains(err.Error(), "resource exists") {
+		if apitypes.HasMessage(err, "resource exists") {
 			return errs.NewExitError("Environment already exists.")
 		}
 		return errs.NewExitError(envCreateFailed)
 	}
 
-	fmt.Println("Environment " + environmentName + " created.")
+	decorate("Environment " + environmentName + " created.")
 	return nil
 }
 
@@ -151,8 +153,11 @@ func listEnvsCmd(ctx *cli.Context) error {
 		if len(ctx.String("project")) < 1 {
 			return errs.NewUsageExitError("Missing flags: --project", ctx)
 		}
+	} else {
+		if len(ctx.String("project")) > 0 {
+			return errs.NewUsageExitError("Cannot use --project flag with --all", ctx)
+		}
 	}
-	// TODO: Error when profile flag is used with --all
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -173,7 +178,7 @@ func listEnvsCmd(ctx *cli.Context) error {
 	}
 
 	// Identify which projects to list envs for
-	var projectID identity.ID
+	var projectID *identity.ID
 	var projects []api.ProjectResult
 	if ctx.Bool("all") {
 		// Pull all projects for the given orgID
@@ -190,32 +195,31 @@ func listEnvsCmd(ctx *cli.Context) error {
 			return errs.NewExitError(envListFailed)
 		}
 		if len(projects) == 1 {
-			projectID = *projects[0].ID
+			projectID = projects[0].ID
 		} else {
 			return errs.NewExitError("Project not found.")
 		}
 	}
 
 	// Retrieve envs for targeted org and project
-	envs, err := listEnvs(&c, client, org.ID, &projectID, nil)
+	envs, err := listEnvs(&c, client, org.ID, projectID, nil)
 	if err != nil {
 		return errs.NewErrorExitError(envListFailed, err)
 	}
 
 	// Build map of envs to project
-	pMap := make(map[string]api.ProjectResult)
-	for _, project := range projects {
-		pMap[project.ID.String()] = project
-	}
 	eMap := make(map[string][]api.EnvironmentResult)
 	for _, env := range envs {
 		ID := env.Body.ProjectID.String()
 		eMap[ID] = append(eMap[ID], env)
 	}
 
+	sort.Sort(projectsByName(projects))
+
 	// Build output of projects/envs
 	fmt.Println("")
-	for projectID, project := range pMap {
+	for _, project := range projects {
+		projectID := project.ID.String()
 		count := strconv.Itoa(len(eMap[projectID]))
 		title := project.Body.Name + " (" + count + ")"
 		fmt.Println(title)
@@ -232,7 +236,7 @@ func listEnvsCmd(ctx *cli.Context) error {
 func listEnvs(ctx *context.Context, client *api.Client, orgID, projID *identity.ID, name *string) ([]api.EnvironmentResult, error) {
 	c, client, err := NewAPIClient(ctx, client)
 	if err != nil {
-		return nil, cli.NewExitError(envListFailed, -1)
+		return nil, errs.NewErrorExitError(envListFailed, err)
 	}
 
 	var orgIDs []*identity.ID
@@ -256,7 +260,15 @@ func listEnvs(ctx *context.Context, client *api.Client, orgID, projID *identity.
 func listEnvsByProjectID(ctx *context.Context, client *api.Client, projectIDs []*identity.ID) ([]api.EnvironmentResult, error) {
 	c, client, err := NewAPIClient(ctx, client)
 	if err != nil {
-		return nil, cli.NewExitError(envListFailed, -1)
+		return nil, errs.NewErrorExitError(envListFailed, err)
 	}
 	return client.Environments.List(c, nil, &projectIDs, nil)
 }
+
+// projectsByName implements sort.Interface, for sorting projects
+// lexicographically by name.
+type projectsByName []api.ProjectResult
+
+func (p projectsByName) Len() int           { return len(p) }
+func (p projectsByName) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p projectsByName) Less(i, j int) bool { return p[i].Body.Name < p[j].Body.Name }
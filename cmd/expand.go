@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// expandFlag turns on the expansion of references to other secrets in secret
+// values, for run and export.
+var expandFlag = cli.BoolFlag{
+	Name: "expand",
+	Usage: "Replace ${NAME} in secret values with the value of the secret exposed as NAME, " +
+		"and $$ with $. With --strict, referring to a missing secret is an error",
+}
+
+// secretExpander expands ${NAME} references between secrets. Secrets are
+// looked up by the name they are exposed as, ignoring case.
+type secretExpander struct {
+	values   map[string]string
+	expanded map[string]string
+	visiting map[string]bool
+	missing  map[string]bool
+}
+
+// expandSecretVars returns a copy of secrets with references in their values
+// replaced by the values of the secrets they name, themselves expanded. $$ is
+// replaced by a single $, and a $ starting neither is left alone.
+//
+// References to secrets that don't exist are left as they are, and their
+// names returned, sorted. It is an error for secrets to refer to each other
+// in a cycle.
+func expandSecretVars(secrets []secretVar) ([]secretVar, []string, error) {
+	e := &secretExpander{
+		values:   make(map[string]string, len(secrets)),
+		expanded: make(map[string]string, len(secrets)),
+		visiting: make(map[string]bool),
+		missing:  make(map[string]bool),
+	}
+	for _, secret := range secrets {
+		e.values[strings.ToLower(secret.Name)] = secret.Value
+	}
+
+	out := make([]secretVar, len(secrets))
+	for i, secret := range secrets {
+		value, err := e.expand(strings.ToLower(secret.Name), []string{secret.Name})
+		if err != nil {
+			return nil, nil, err
+		}
+		out[i] = secretVar{Name: secret.Name, Value: value}
+	}
+
+	var missing []string
+	for name := range e.missing {
+		missing = append(missing, name)
+	}
+	sort.Strings(missing)
+
+	return out, missing, nil
+}
+
+// expand returns the expanded value of the secret with the given lower case
+// name. path holds the names of the secrets being expanded that lead to it.
+func (e *secretExpander) expand(key string, path []string) (string, error) {
+	if value, ok := e.expanded[key]; ok {
+		return value, nil
+	}
+	if e.visiting[key] {
+		return "", fmt.Errorf("secrets refer to each other in a cycle: %s", strings.Join(path, " -> "))
+	}
+
+	e.visiting[key] = true
+	defer delete(e.visiting, key)
+
+	raw := e.values[key]
+	buf := &bytes.Buffer{}
+	for i := 0; i < len(raw); {
+		if raw[i] != '$' || i+1 == len(raw) {
+			buf.WriteByte(raw[i])
+			i++
+			continue
+		}
+
+		switch raw[i+1] {
+		case '$':
+			buf.WriteByte('$')
+			i += 2
+			continue
+		case '{':
+			end := strings.IndexByte(raw[i+2:], '}')
+			if end <= 0 {
+				break
+			}
+
+			ref := raw[i+2 : i+2+end]
+			if _, ok := e.values[strings.ToLower(ref)]; !ok {
+				e.missing[ref] = true
+				buf.WriteString(raw[i : i+3+end])
+			} else {
+				value, err := e.expand(strings.ToLower(ref), append(path, ref))
+				if err != nil {
+					return "", err
+				}
+				buf.WriteString(value)
+			}
+
+			i += 3 + end
+			continue
+		}
+
+		buf.WriteByte(raw[i])
+		i++
+	}
+
+	value := buf.String()
+	e.expanded[key] = value
+	return value, nil
+}
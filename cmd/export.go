@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/errs"
+)
+
+func init() {
+	export := cli.Command{
+		Name:     "export",
+		Usage:    "Export secrets for the current service and environment",
+		Category: "SECRETS",
+		Flags: []cli.Flag{
+			stdOrgFlag,
+			stdProjectFlag,
+			stdEnvFlag,
+			serviceFlag("Use this service.", "default", true),
+			userFlag("Use this user.", false),
+			machineFlag("Use this machine.", false),
+			stdInstanceFlag,
+			formatFlag("dotenv", "Format used to export secrets (dotenv, json, tfvars)"),
+			envMapFlag,
+			expandFlag,
+			strictFlag,
+			newPlaceholder("output, o", "PATH",
+				"Write secrets to this file, readable only by you, instead of stdout", "", "", false),
+			cli.BoolFlag{
+				Name:  "force",
+				Usage: "Overwrite the --output file if it already exists",
+			},
+			newSlicePlaceholder("redact", "PATTERN",
+				"Replace the values of secrets whose names match this glob with "+redactedValue,
+				"", "", false),
+		},
+		Action: chain(
+			ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+			setUserEnv, checkRequiredFlags, exportCmd,
+		),
+	}
+
+	Cmds = append(Cmds, export)
+}
+
+var exporters = map[string]func(io.Writer, []secretVar) error{
+	"dotenv": exportDotenv,
+	"json":   exportJSON,
+	"tfvars": exportTfvars,
+}
+
+func exportCmd(ctx *cli.Context) error {
+	exporter, ok := exporters[ctx.String("format")]
+	if !ok {
+		return errs.NewUsageExitError("Unknown format: "+ctx.String("format"), ctx)
+	}
+
+	redact := ctx.StringSlice("redact")
+	for _, pattern := range redact {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errs.NewUsageExitError("Invalid --redact pattern: "+pattern, ctx)
+		}
+	}
+
+	output := ctx.String("output")
+	if output != "" && !ctx.Bool("force") {
+		if _, err := os.Stat(output); err == nil {
+			return errs.NewExitError(output + " already exists. Use --force to overwrite it.")
+		}
+	}
+
+	// getSecrets layers the credentials by path expression specificity, the
+	// same way they are resolved for run. The secrets arrive all at once, so
+	// the counter shows no count, only that the export is under way.
+	counter := newProgressCounter("Exporting secrets", 0)
+	if ctx.String("format") == "json" {
+		counter.hide()
+	}
+	counter.draw()
+	secrets, err := getSecretVars(ctx)
+	counter.clear()
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		return exporter(os.Stdout, secrets)
+	}
+
+	err = exportFile(output, exporter, secrets)
+	if err != nil {
+		return errs.NewErrorExitError("Could not write "+output+".", err)
+	}
+
+	if !quiet() {
+		fmt.Fprintf(os.Stderr, "Exported %d secrets to %s.\n", len(secrets), output)
+	}
+	return nil
+}
+
+// redactedValue replaces the values of secrets matching a --redact pattern.
+const redactedValue = "***"
+
+// redactSecrets returns a copy of secrets in which the value of each secret
+// whose exported name matches one of the glob patterns, ignoring case, is
+// replaced by redactedValue. Patterns are expected to be valid.
+func redactSecrets(secrets []secretVar, patterns []string) []secretVar {
+	if len(patterns) == 0 {
+		return secrets
+	}
+
+	redacted := make([]secretVar, len(secrets))
+	for i, secret := range secrets {
+		redacted[i] = secret
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(secret.Name)); ok {
+				redacted[i].Value = redactedValue
+				break
+			}
+		}
+	}
+
+	return redacted
+}
+
+// exportFile writes secrets to path with mode 0600, ending in a newline.
+func exportFile(path string, exporter func(io.Writer, []secretVar) error, secrets []secretVar) error {
+	buf := &bytes.Buffer{}
+	err := exporter(buf, secrets)
+	if err != nil {
+		return err
+	}
+	if buf.Len() > 0 && !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		buf.WriteString("\n")
+	}
+
+	return writePrivateFile(path, buf.Bytes())
+}
+
+// writePrivateFile writes b to path with mode 0600. It is written to a
+// temporary file alongside path which is then renamed, so that path is never
+// left partially written.
+func writePrivateFile(path string, b []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // fails harmlessly once renamed
+
+	_, err = f.Write(b)
+	if err == nil {
+		err = f.Chmod(0600)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+func exportDotenv(w io.Writer, secrets []secretVar) error {
+	for _, secret := range secrets {
+		key := strings.ToUpper(secret.Name)
+		_, err := fmt.Fprintf(w, "%s=%s\n", key, shellQuote(secret.Value))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func exportJSON(w io.Writer, secrets []secretVar) error {
+	values := make(map[string]string, len(secrets))
+	for _, secret := range secrets {
+		values[strings.ToUpper(secret.Name)] = secret.Value
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(values)
+}
+
+func exportTfvars(w io.Writer, secrets []secretVar) error {
+	for _, secret := range secrets {
+		_, err := fmt.Fprintf(w, "%s = %s\n", secret.Name, hclQuote(secret.Value))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// shellQuote returns value unchanged if it is safe to use unquoted in a
+// shell or dotenv file, otherwise wrapping it in single quotes, within which
+// nothing is special. A single quote within value closes the quotes, is
+// escaped with a backslash, and opens them again.
+func shellQuote(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n\r\"'\\$`#;&|<>(){}*?!~") {
+		return value
+	}
+
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+// hclQuote returns value as a double quoted HCL string.
+func hclQuote(value string) string {
+	r := strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		"\n", `\n`,
+		"\r", `\r`,
+		"\t", `\t`,
+		"${", "$${",
+	)
+	return `"` + r.Replace(value) + `"`
+}
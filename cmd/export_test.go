@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	testCases := []struct {
+		in  string
+		out string
+	}{
+		{in: "plain", out: "plain"},
+		{in: "", out: `''`},
+		{in: "with space", out: `'with space'`},
+		{in: `say "hi"`, out: `'say "hi"'`},
+		{in: "it's", out: `'it'\''s'`},
+		{in: "line\nbreak", out: "'line\nbreak'"},
+		{in: "$HOME", out: `'$HOME'`},
+		{in: `back\slash`, out: `'back\slash'`},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.in, func(t *testing.T) {
+			out := shellQuote(test.in)
+			if out != test.out {
+				t.Errorf("Expected %s, got %s", test.out, out)
+			}
+		})
+	}
+}
+
+func TestShellQuoteRoundTrip(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not found")
+	}
+
+	values := []string{
+		"plain", "", "with space", `say "hi"`, "it's", "'", "line\nbreak",
+		"$HOME", "$(id)", "`id`", `back\slash`, `trailing\`, "tab\there", "ünïcödé ☃",
+	}
+
+	for _, v := range values {
+		out, err := exec.Command(sh, "-c", "printf %s "+shellQuote(v)).Output()
+		if err != nil {
+			t.Fatalf("%q: %s", v, err)
+		}
+		if string(out) != v {
+			t.Errorf("Expected %q from sh, got %q", v, out)
+		}
+	}
+}
+
+func TestHCLQuote(t *testing.T) {
+	testCases := []struct {
+		in  string
+		out string
+	}{
+		{in: "plain", out: `"plain"`},
+		{in: `say "hi"`, out: `"say \"hi\""`},
+		{in: "line\nbreak", out: `"line\nbreak"`},
+		{in: "${var.x}", out: `"$${var.x}"`},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.in, func(t *testing.T) {
+			out := hclQuote(test.in)
+			if out != test.out {
+				t.Errorf("Expected %s, got %s", test.out, out)
+			}
+		})
+	}
+}
+
+func TestExportFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "torus-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, ".env")
+	secrets := []secretVar{{Name: "port", Value: "3000"}}
+	noNewline := func(w io.Writer, _ []secretVar) error {
+		_, err := io.WriteString(w, "PORT=3000")
+		return err
+	}
+
+	err = exportFile(path, noNewline, secrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "PORT=3000\n" {
+		t.Errorf("Expected trailing newline, got %q", b)
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %s", fi.Mode().Perm())
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("Expected only the exported file, found %d files", len(files))
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	secrets := []secretVar{
+		{Name: "port", Value: "3000"},
+		{Name: "db_password", Value: "hunter2"},
+		{Name: "API_KEY", Value: "abc123"},
+	}
+
+	redacted := redactSecrets(secrets, []string{"*_PASSWORD", "api_*"})
+
+	expected := []secretVar{
+		{Name: "port", Value: "3000"},
+		{Name: "db_password", Value: redactedValue},
+		{Name: "API_KEY", Value: redactedValue},
+	}
+	if !reflect.DeepEqual(redacted, expected) {
+		t.Errorf("Expected %v, got %v", expected, redacted)
+	}
+
+	if secrets[1].Value != "hunter2" {
+		t.Error("Expected the original secrets to be left unchanged")
+	}
+}
+
+func TestRedactSecretsBeforeExpanding(t *testing.T) {
+	secrets := []secretVar{
+		{Name: "db_password", Value: "hunter2"},
+		{Name: "db_url", Value: "postgres://app:${DB_PASSWORD}@db/app"},
+	}
+
+	expanded, _, err := expandSecretVars(redactSecrets(secrets, []string{"*_password"}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	for _, secret := range expanded {
+		if strings.Contains(secret.Value, "hunter2") {
+			t.Errorf("Expected the redacted value not to appear in %s, got %q", secret.Name, secret.Value)
+		}
+	}
+}
@@ -85,6 +85,11 @@ func destroyedFlag() cli.Flag {
 	}
 }
 
+// formatFlag creates a new --format cli.Flag with custom usage string.
+func formatFlag(defaultValue, usage string) cli.Flag {
+	return newPlaceholder("format, f", "FORMAT", usage, defaultValue, "", false)
+}
+
 // placeHolderStringSliceFlag is a StringSliceFlag that has been extended to use a
 // specific placedholder value in the usage, without parsing it out of the
 // usage string.
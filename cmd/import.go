@@ -0,0 +1,581 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/config"
+	"github.com/manifoldco/torus-cli/errs"
+	"github.com/manifoldco/torus-cli/pathexp"
+)
+
+func init() {
+	importFlags := append([]cli.Flag{
+		newPlaceholder("file", "FILE", "Import secrets from this file", "", "", true),
+		formatFlag("", "Format of the imported file (dotenv, json). Detected from the file extension if omitted"),
+		cli.BoolFlag{
+			Name:  "overwrite",
+			Usage: "Replace the values of secrets that are already set",
+		},
+		cli.BoolFlag{
+			Name:  "resume",
+			Usage: "Skip secrets already imported by an earlier, interrupted run of this import",
+		},
+	}, setUnsetFlags...)
+
+	imp := cli.Command{
+		Name:     "import",
+		Usage:    "Import secrets from a dotenv or json file",
+		Category: "SECRETS",
+		Flags:    importFlags,
+		Action: chain(
+			ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+			setSliceDefaults, setUserEnv, checkRequiredFlags, importCmd,
+		),
+	}
+
+	Cmds = append(Cmds, imp)
+}
+
+var importers = map[string]func(io.Reader) (map[string]string, error){
+	"dotenv": importDotenv,
+	"json":   importJSON,
+}
+
+func importCmd(ctx *cli.Context) error {
+	file := ctx.String("file")
+	format := ctx.String("format")
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".json":
+			format = "json"
+		default:
+			format = "dotenv"
+		}
+	}
+
+	importer, ok := importers[format]
+	if !ok {
+		return errs.NewUsageExitError("Unknown format: "+format, ctx)
+	}
+
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return errs.NewErrorExitError("Could not open "+file, err)
+	}
+
+	// The importers validate the whole file, names included, before anything
+	// is written, reporting every problem at once.
+	values, err := importer(bytes.NewReader(raw))
+	if err != nil {
+		return errs.NewErrorExitError("Could not import "+file, err)
+	}
+
+	if len(values) == 0 {
+		fmt.Println("No secrets found in " + file)
+		return nil
+	}
+
+	pe, err := flagPathExp(ctx)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	org, err := client.Orgs.GetByName(c, pe.Org())
+	if org == nil || err != nil {
+		return errs.NewExitError("Org not found")
+	}
+
+	pName := pe.Project()
+	projects, err := listProjects(&c, client, org.ID, &pName)
+	if len(projects) != 1 || err != nil {
+		return errs.NewExitError("Project not found")
+	}
+	project := projects[0]
+
+	existing, err := client.Credentials.Search(c, pe.String())
+	if err != nil {
+		return errs.NewErrorExitError("Could not retrieve existing secrets", err)
+	}
+
+	set := make(map[string]bool)
+	current := make(map[string]string)
+	for _, cred := range existing {
+		body := *cred.Body
+		if body.GetValue() != nil && body.GetPathExp().Equal(pe) {
+			set[body.GetName()] = true
+			current[body.GetName()] = body.GetValue().String()
+		}
+	}
+
+	journal := newImportJournal(cfg.TorusRoot, pe.String())
+	if ctx.Bool("resume") {
+		err = journal.load()
+		if err != nil {
+			return errs.NewErrorExitError("Could not read import progress.", err)
+		}
+	}
+
+	names := make([]string, 0, len(values))
+	for key := range values {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+
+	var creds []apitypes.Credential
+	var keys []string
+	var skipped []string
+	resumed := 0
+	for _, key := range names {
+		name := strings.ToLower(key)
+		// Only skip a secret imported by the failed run if it still holds
+		// the value from the file, which may have changed since.
+		if journal.done(name) && current[name] == parseCredentialValue(values[key]).String() {
+			resumed++
+			continue
+		}
+		if set[name] && !ctx.Bool("overwrite") {
+			skipped = append(skipped, name)
+			continue
+		}
+
+		keys = append(keys, key)
+		creds = append(creds, &apitypes.CredentialV2{
+			BaseCredential: apitypes.BaseCredential{
+				OrgID:     org.ID,
+				ProjectID: project.ID,
+				Name:      name,
+				PathExp:   pe,
+				Value:     parseCredentialValue(values[key]),
+			},
+			State: "set",
+		})
+	}
+
+	if dryRun() {
+		var steps []planStep
+		for _, cred := range creds {
+			action := "create"
+			if set[cred.GetName()] {
+				action = "overwrite"
+			}
+			steps = append(steps, planStep{action, pe.String() + "/" + cred.GetName()})
+		}
+		for _, name := range skipped {
+			steps = append(steps, planStep{"skip (already set)", pe.String() + "/" + name})
+		}
+		printPlan(steps)
+		return nil
+	}
+
+	// Create the secrets in batches, recording each completed batch so an
+	// interrupted import can be resumed without repeating work.
+	// While the counter is shown, it stands in for the daemon's own progress
+	// messages.
+	ic, stop := interruptContext()
+	defer stop()
+	counter := newProgressCounter("Importing secrets", len(creds)+resumed)
+	output := &progress
+	if counter.visible {
+		output = nil
+	}
+	counter.add(resumed)
+	for start := 0; start < len(creds); start += importBatchSize {
+		end := start + importBatchSize
+		if end > len(creds) {
+			end = len(creds)
+		}
+
+		_, err = client.Credentials.CreateBatch(ic, creds[start:end], output)
+		if err != nil {
+			counter.clear()
+
+			// The daemon saves a batch one secret at a time, so part of it
+			// may have been saved before the failure.
+			imported := start + resumed + recordSaved(c, client, pe, journal, keys[start:end], values)
+			if imported > 0 {
+				fmt.Printf("\n%d of %d secrets were imported before the failure. Run the "+
+					"same command with --resume to import the rest.\n",
+					imported, len(creds)+resumed)
+			}
+			return errs.NewErrorExitError("Could not import secrets.", err)
+		}
+
+		for _, key := range keys[start:end] {
+			journal.record(strings.ToLower(key))
+		}
+		err = journal.save()
+		if err != nil {
+			counter.clear()
+			return errs.NewErrorExitError("Could not record import progress.", err)
+		}
+		counter.add(end - start)
+	}
+	counter.clear()
+
+	err = journal.remove()
+	if err != nil {
+		return errs.NewErrorExitError("Could not remove import progress.", err)
+	}
+
+	if resumed > 0 {
+		fmt.Printf("\nResumed import, %d secrets were already imported\n", resumed)
+	}
+	fmt.Printf("\n%d secrets created, %d skipped at %s\n", len(creds), len(skipped), pe)
+	if len(skipped) > 0 {
+		fmt.Printf("Skipped secrets that are already set (use --overwrite to replace them):\n\t%s\n",
+			strings.Join(skipped, "\n\t"))
+	}
+
+	return nil
+}
+
+// importBatchSize is the number of secrets created per request during an
+// import. Progress is recorded after each batch.
+const importBatchSize = 25
+
+// recordSaved records in journal those of keys whose secret at pe already
+// holds the imported value, saving the journal and returning how many there
+// were. It is used after a failed batch, to find the secrets saved before the
+// failure. Nothing is recorded if the secrets can't be retrieved.
+func recordSaved(ctx context.Context, client *api.Client, pe *pathexp.PathExp,
+	journal *importJournal, keys []string, values map[string]string) int {
+
+	creds, err := client.Credentials.Search(ctx, pe.String())
+	if err != nil {
+		return 0
+	}
+
+	current := make(map[string]string)
+	for _, cred := range creds {
+		body := *cred.Body
+		if body.GetValue() != nil && body.GetPathExp().Equal(pe) {
+			current[body.GetName()] = body.GetValue().String()
+		}
+	}
+
+	saved := 0
+	for _, key := range keys {
+		name := strings.ToLower(key)
+		value, ok := current[name]
+		if !ok || value != parseCredentialValue(values[key]).String() {
+			continue
+		}
+		journal.record(name)
+		saved++
+	}
+
+	if saved > 0 && journal.save() != nil {
+		return 0
+	}
+	return saved
+}
+
+// importJournal records which secrets an import has created, so that a failed
+// import can be resumed with --resume.
+//
+// There is one journal per target path expression, kept in the torus root.
+// Only the names of the imported secrets are stored; nothing derived from
+// their values is written to disk. On resume, a recorded secret is skipped
+// only if it still holds the value in the file.
+type importJournal struct {
+	path     string
+	Imported map[string]bool `json:"imported"`
+}
+
+func newImportJournal(torusRoot, pathexp string) *importJournal {
+	h := sha256.Sum256([]byte(pathexp))
+	name := "import-" + hex.EncodeToString(h[:])[:32] + ".json"
+
+	return &importJournal{
+		path:     filepath.Join(torusRoot, name),
+		Imported: make(map[string]bool),
+	}
+}
+
+// done reports whether name was already imported.
+func (j *importJournal) done(name string) bool {
+	return j.Imported[name]
+}
+
+// record notes that name was imported.
+func (j *importJournal) record(name string) {
+	j.Imported[name] = true
+}
+
+// load reads previously recorded progress. A missing journal is not an error;
+// there is simply nothing to resume.
+func (j *importJournal) load() error {
+	b, err := ioutil.ReadFile(j.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(b, j)
+	if j.Imported == nil {
+		j.Imported = make(map[string]bool)
+	}
+	return err
+}
+
+// save writes the journal, replacing it atomically so an interrupted write
+// never leaves a partial journal behind.
+func (j *importJournal) save() error {
+	b, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+
+	tmp := j.path + ".tmp"
+	err = ioutil.WriteFile(tmp, b, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, j.path)
+}
+
+// remove deletes the journal once an import has completed.
+func (j *importJournal) remove() error {
+	err := os.Remove(j.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// importErrors collects every problem found in an imported file, so they can
+// all be fixed at once.
+type importErrors []string
+
+func (e importErrors) Error() string {
+	return strings.Join(e, "\n")
+}
+
+func (e *importErrors) add(format string, a ...interface{}) {
+	*e = append(*e, fmt.Sprintf(format, a...))
+}
+
+// importNames tracks the names seen in an imported file. Names are stored
+// lowercased, so two keys differing only in case would set the same secret.
+type importNames map[string]string
+
+// check returns why key can't be imported, or an empty string if it can.
+// where describes where key was first seen, for reporting duplicates.
+func (n importNames) check(key, where string) string {
+	name := strings.ToLower(key)
+	if err := apitypes.ValidCredentialName(name); err != nil {
+		return err.Error()
+	}
+	if first, ok := n[name]; ok {
+		return fmt.Sprintf("%s sets the same secret as %s", key, first)
+	}
+	n[name] = where
+	return ""
+}
+
+// importDotenv parses KEY=VALUE lines. Blank lines, comments and a leading
+// "export" are ignored. Quoted values are unquoted as a shell would, mirroring
+// shellQuote; a single quoted value may span several lines.
+//
+// Every line is checked, and all of the problems found are returned together
+// as importErrors.
+func importDotenv(r io.Reader) (map[string]string, error) {
+	values := make(map[string]string)
+	names := make(importNames)
+	var problems importErrors
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		text := scanner.Text()
+		line := strings.TrimSpace(text)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		idx := strings.Index(line, "=")
+		if idx < 1 {
+			problems.add("line %d: expected KEY=VALUE", lineNum)
+			continue
+		}
+
+		key := strings.TrimSpace(line[:idx])
+		value := strings.TrimSpace(line[idx+1:])
+
+		if reason := names.check(key, fmt.Sprintf("line %d", lineNum)); reason != "" {
+			problems.add("line %d: %s", lineNum, reason)
+			continue
+		}
+
+		if len(value) > 0 && (value[0] == '"' || value[0] == '\'') {
+			start := lineNum
+			unquoted, ok := shellUnquote(value)
+
+			// A single quoted value may hold newlines, and so span lines.
+			quoted := strings.TrimLeftFunc(text[strings.Index(text, "=")+1:], unicode.IsSpace)
+			for !ok && value[0] == '\'' && scanner.Scan() {
+				lineNum++
+				quoted += "\n" + scanner.Text()
+				unquoted, ok = shellUnquote(strings.TrimRightFunc(quoted, unicode.IsSpace))
+			}
+			if !ok {
+				problems.add("line %d: the value of %s is missing its closing %c", start, key, value[0])
+				continue
+			}
+			value = unquoted
+		}
+
+		values[key] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return nil, problems
+	}
+
+	return values, nil
+}
+
+// importJSON parses a flat JSON object of names to string, number or boolean
+// values. Names with a null value are left out of the import.
+//
+// Every name and value is checked, and all of the problems found are
+// returned together as importErrors.
+func importJSON(r io.Reader) (map[string]string, error) {
+	var doc interface{}
+
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	err := dec.Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after the JSON object")
+	}
+
+	raw, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("expected a JSON object of secret names to values")
+	}
+
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := make(map[string]string, len(raw))
+	names := make(importNames)
+	var problems importErrors
+	for _, key := range keys {
+		if reason := names.check(key, key); reason != "" {
+			problems.add("%s", reason)
+			continue
+		}
+
+		switch t := raw[key].(type) {
+		case string:
+			values[key] = t
+		case json.Number:
+			values[key] = t.String()
+		case bool:
+			values[key] = fmt.Sprintf("%t", t)
+		case nil:
+			continue
+		case map[string]interface{}:
+			problems.add("value for %s must be a string, number, boolean or null, not an object", key)
+		case []interface{}:
+			problems.add("value for %s must be a string, number, boolean or null, not a list", key)
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, problems
+	}
+
+	return values, nil
+}
+
+// shellUnquote returns the value of word, a shell word such as those written
+// by shellQuote. Single quoted parts are taken literally. Outside quotes, a
+// backslash escapes the next character; within double quotes, it escapes $, `,
+// " and \, and \n and \r are read as a newline and carriage return, as
+// written by earlier versions of export. ok is false if a quote is left open.
+func shellUnquote(word string) (value string, ok bool) {
+	var b bytes.Buffer
+	for i := 0; i < len(word); i++ {
+		switch c := word[i]; c {
+		case '\'':
+			end := strings.IndexByte(word[i+1:], '\'')
+			if end < 0 {
+				return "", false
+			}
+			b.WriteString(word[i+1 : i+1+end])
+			i += end + 1
+		case '"':
+			for i++; i < len(word) && word[i] != '"'; i++ {
+				if word[i] == '\\' && i+1 < len(word) {
+					switch word[i+1] {
+					case '$', '`', '"', '\\':
+						i++
+					case 'n':
+						b.WriteByte('\n')
+						i++
+						continue
+					case 'r':
+						b.WriteByte('\r')
+						i++
+						continue
+					}
+				}
+				b.WriteByte(word[i])
+			}
+			if i >= len(word) {
+				return "", false
+			}
+		case '\\':
+			if i+1 < len(word) {
+				i++
+			}
+			b.WriteByte(word[i])
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String(), true
+}
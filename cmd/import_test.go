@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportDotenv(t *testing.T) {
+	in := `# comment
+export PLAIN=value
+
+QUOTED="say \"hi\" to \$HOME"
+SINGLE='it is $literal'
+  SPACED = padded
+EMPTY=
+`
+
+	values, err := importDotenv(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := map[string]string{
+		"PLAIN":  "value",
+		"QUOTED": `say "hi" to $HOME`,
+		"SINGLE": "it is $literal",
+		"SPACED": "padded",
+		"EMPTY":  "",
+	}
+
+	if len(values) != len(expected) {
+		t.Errorf("Expected %d values, got %d", len(expected), len(values))
+	}
+
+	for k, v := range expected {
+		if values[k] != v {
+			t.Errorf("Expected %s=%q, got %q", k, v, values[k])
+		}
+	}
+}
+
+func TestImportDotenvInvalidLine(t *testing.T) {
+	_, err := importDotenv(strings.NewReader("NOEQUALS\n"))
+	if err == nil {
+		t.Error("Expected an error for a line without =")
+	}
+}
+
+func TestImportDotenvReportsAllProblems(t *testing.T) {
+	in := `GOOD=1
+NOEQUALS
+1BAD=2
+UNCLOSED="value
+good=3
+`
+
+	_, err := importDotenv(strings.NewReader(in))
+	problems, ok := err.(importErrors)
+	if !ok {
+		t.Fatalf("Expected importErrors, got %#v", err)
+	}
+
+	expected := []string{
+		"line 2: expected KEY=VALUE",
+		"line 3: Invalid secret name",
+		"line 4: the value of UNCLOSED is missing its closing \"",
+		"line 5: good sets the same secret as line 1",
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %d: %v", len(expected), len(problems), problems)
+	}
+	for i, e := range expected {
+		if !strings.HasPrefix(problems[i], e) {
+			t.Errorf("Expected problem %d to start with %q, got %q", i, e, problems[i])
+		}
+	}
+}
+
+func TestImportJSON(t *testing.T) {
+	t.Run("values", func(t *testing.T) {
+		in := `{"STR": "a", "NUM": 1.5, "BOOL": true, "NULL": null}`
+		values, err := importJSON(strings.NewReader(in))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		expected := map[string]string{"STR": "a", "NUM": "1.5", "BOOL": "true"}
+		if len(values) != len(expected) {
+			t.Errorf("Expected %d values, got %v", len(expected), values)
+		}
+		for k, v := range expected {
+			if values[k] != v {
+				t.Errorf("Expected %s=%q, got %q", k, v, values[k])
+			}
+		}
+	})
+
+	t.Run("reports all problems", func(t *testing.T) {
+		in := `{"OBJ": {"a": 1}, "LIST": [1], "9BAD": "x", "OK": "y", "ok": "z"}`
+		_, err := importJSON(strings.NewReader(in))
+		problems, ok := err.(importErrors)
+		if !ok {
+			t.Fatalf("Expected importErrors, got %#v", err)
+		}
+
+		expected := []string{
+			"Invalid secret name \"9bad\"",
+			"value for LIST must be a string, number, boolean or null, not a list",
+			"value for OBJ must be a string, number, boolean or null, not an object",
+			"ok sets the same secret as OK",
+		}
+		if len(problems) != len(expected) {
+			t.Fatalf("Expected %d problems, got %d: %v", len(expected), len(problems), problems)
+		}
+		for i, e := range expected {
+			if !strings.HasPrefix(problems[i], e) {
+				t.Errorf("Expected problem %d to start with %q, got %q", i, e, problems[i])
+			}
+		}
+	})
+
+	t.Run("not an object", func(t *testing.T) {
+		if _, err := importJSON(strings.NewReader(`["A"]`)); err == nil {
+			t.Error("Expected an error for a JSON list")
+		}
+	})
+}
+
+func TestShellUnquoteRoundTrip(t *testing.T) {
+	values := []string{"plain", "", `say "hi"`, "it's", "line\nbreak", "$HOME", `back\slash`, "tick`"}
+
+	for _, v := range values {
+		out, ok := shellUnquote(shellQuote(v))
+		if !ok || out != v {
+			t.Errorf("Expected %q, got %q", v, out)
+		}
+	}
+}
+
+func TestShellUnquote(t *testing.T) {
+	testCases := []struct {
+		in  string
+		out string
+		ok  bool
+	}{
+		{in: `'it'\''s'`, out: "it's", ok: true},
+		{in: `"say \"hi\" to \$HOME"`, out: `say "hi" to $HOME`, ok: true},
+		{in: `"line\nbreak"`, out: "line\nbreak", ok: true},
+		{in: `'open`, ok: false},
+		{in: `"open`, ok: false},
+	}
+
+	for _, test := range testCases {
+		out, ok := shellUnquote(test.in)
+		if ok != test.ok || out != test.out {
+			t.Errorf("%s: expected %q, %t, got %q, %t", test.in, test.out, test.ok, out, ok)
+		}
+	}
+}
+
+func TestImportDotenvExported(t *testing.T) {
+	secrets := []secretVar{
+		{Name: "quote", Value: "it's"},
+		{Name: "lines", Value: "first\n  second\n"},
+		{Name: "plain", Value: "value"},
+	}
+
+	var buf bytes.Buffer
+	if err := exportDotenv(&buf, secrets); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := importDotenv(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, secret := range secrets {
+		key := strings.ToUpper(secret.Name)
+		if values[key] != secret.Value {
+			t.Errorf("Expected %s=%q, got %q", key, secret.Value, values[key])
+		}
+	}
+}
+
+func TestImportJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "torus-import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	j := newImportJournal(dir, "/org/proj/dev/*/*/*")
+
+	t.Run("missing journal loads empty", func(t *testing.T) {
+		err := j.load()
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if len(j.Imported) != 0 {
+			t.Errorf("Expected no imported secrets, got %v", j.Imported)
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		j.record("a")
+		err := j.save()
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		info, err := os.Stat(j.path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("Expected the journal to be private, got %s", info.Mode())
+		}
+
+		loaded := newImportJournal(dir, "/org/proj/dev/*/*/*")
+		err = loaded.load()
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if !loaded.done("a") || len(loaded.Imported) != 1 {
+			t.Errorf("Expected only a to be imported, got %v", loaded.Imported)
+		}
+		if loaded.done("b") {
+			t.Error("Expected b not to be imported")
+		}
+
+		b, err := ioutil.ReadFile(j.path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if string(b) != `{"imported":{"a":true}}` {
+			t.Errorf("Expected only names to be stored, got %s", b)
+		}
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		err := j.remove()
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if _, err := os.Stat(j.path); !os.IsNotExist(err) {
+			t.Errorf("Expected journal to be removed, got %v", err)
+		}
+		if err := j.remove(); err != nil {
+			t.Errorf("Expected removing a missing journal to succeed, got %s", err)
+		}
+	})
+}
+
+func TestImportJournalPath(t *testing.T) {
+	root := "/home/user/.torus"
+	pathexp := "/org/proj/dev/*/*/*"
+
+	base := newImportJournal(root, pathexp).path
+	if filepath.Dir(base) != root {
+		t.Errorf("Expected the journal to be kept in %s, got %s", root, base)
+	}
+	if again := newImportJournal(root, pathexp).path; again != base {
+		t.Errorf("Expected the same journal for the same target, got %s and %s", base, again)
+	}
+	if other := newImportJournal(root, "/org/proj/prod/*/*/*").path; other == base {
+		t.Error("Expected a different target path to use a different journal")
+	}
+}
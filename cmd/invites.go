@@ -10,11 +10,13 @@ func init() {
 		Subcommands: []cli.Command{
 			{
 				Name:      "send",
-				Usage:     "Send an invitation to join an organization to an email address",
-				ArgsUsage: "<email>",
+				Usage:     "Send an invitation to join an organization to an email address, or to each address in a file",
+				ArgsUsage: "<email> | --file FILE",
 				Flags: []cli.Flag{
 					orgFlag("org to invite user to", true),
 					newSlicePlaceholder("team, t", "TEAM", "team to add user to", "member", "", true),
+					newPlaceholder("file", "FILE",
+						"Invite each email address in this file, one or more to a line", "", "", false),
 				},
 				Action: chain(
 					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
@@ -30,6 +32,10 @@ func init() {
 						Name:  "approved",
 						Usage: "Show only approved invites",
 					},
+					cli.StringSliceFlag{
+						Name:  "state",
+						Usage: "Show only invites in this state (pending, associated, accepted, approved)",
+					},
 				},
 				Action: chain(
 					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
@@ -48,12 +54,26 @@ func init() {
 					loadPrefDefaults, setUserEnv, checkRequiredFlags, invitesApprove,
 				),
 			},
+			{
+				Name:      "revoke",
+				Usage:     "Revoke a pending invitation previously sent to an email address",
+				ArgsUsage: "<email>",
+				Flags: []cli.Flag{
+					orgFlag("org to revoke invite for", true),
+				},
+				Action: chain(
+					ensureDaemon, ensureSession, loadDirPrefs,
+					loadPrefDefaults, setUserEnv, checkRequiredFlags, invitesRevoke,
+				),
+			},
 			{
 				Name:      "accept",
 				Usage:     "Accept an invitation to join an organization",
-				ArgsUsage: "<email> <code>",
+				ArgsUsage: "[<email> <code>]",
 				Flags: []cli.Flag{
 					orgFlag("org to approve invite for", true),
+					newPlaceholder("email", "EMAIL", "Email address the invitation was sent to", "", "", false),
+					newPlaceholder("code", "CODE", "Code from the invitation email", "", "", false),
 				},
 				Action: chain(
 					ensureDaemon, loadDirPrefs,
@@ -7,19 +7,36 @@ import (
 	"github.com/urfave/cli"
 
 	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/config"
 	"github.com/manifoldco/torus-cli/errs"
+	"github.com/manifoldco/torus-cli/primitive"
 	"github.com/manifoldco/torus-cli/promptui"
 )
 
 const acceptInviteFailed = "Could not accept invitation to org, please try again."
 
 func invitesAccept(ctx *cli.Context) error {
+	email := ctx.String("email")
+	code := ctx.String("code")
+
 	args := ctx.Args()
-	if len(args) < 2 {
+	if len(args) > 2 {
+		return errs.NewUsageExitError("Too many arguments", ctx)
+	}
+	if len(args) > 0 {
+		email = args[0]
+	}
+	if len(args) > 1 {
+		code = args[1]
+	}
+
+	if email == "" || code == "" {
 		var text string
-		if len(args) < 1 {
+		if email == "" && code == "" {
 			text = "Missing email and code"
+		} else if email == "" {
+			text = "Missing email"
 		} else {
 			text = "Missing code"
 		}
@@ -64,14 +81,23 @@ func invitesAccept(ctx *cli.Context) error {
 		fmt.Println("")
 	}
 
-	email := args[0]
-	code := args[1]
 	err = validateInviteCode(code)
 	if err != nil {
 		return err
 	}
 
-	invite, err := client.Invites.Associate(c, ctx.String("org"), email, code)
+	var invite *api.InviteResult
+	err = withVerification(ctx, func() error {
+		var err error
+		invite, err = client.Invites.Associate(c, ctx.String("org"), email, code)
+		return err
+	})
+	if inviteAlreadyAccepted(err) ||
+		(err == nil && invite != nil && invite.Body != nil && inviteAlreadyAcceptedState(invite.Body.State)) {
+		fmt.Println("You have already accepted this invitation.")
+		fmt.Println("\nYou will be added to the org once the administrator has approved your invite.")
+		return nil
+	}
 	if err != nil || invite == nil {
 		return errs.NewExitError(acceptInviteFailed)
 	}
@@ -82,7 +108,12 @@ func invitesAccept(ctx *cli.Context) error {
 		return errs.NewExitError(acceptInviteFailed)
 	}
 
-	err = client.Invites.Accept(c, ctx.String("org"), email, code)
+	err = withVerification(ctx, func() error {
+		return client.Invites.Accept(c, ctx.String("org"), email, code)
+	})
+	if inviteAlreadyAccepted(err) {
+		err = nil
+	}
 	if err != nil {
 		return errs.NewExitError(acceptInviteFailed)
 	}
@@ -91,3 +122,52 @@ func invitesAccept(ctx *cli.Context) error {
 	fmt.Println("\nYou will be added to the org once the administrator has approved your invite.")
 	return nil
 }
+
+// withVerification runs fn, and if it fails because the account's email
+// address has not been verified, asks for the verification code and runs fn
+// again once verified.
+func withVerification(ctx *cli.Context, fn func() error) error {
+	err := fn()
+	if !apitypes.IsUnverifiedError(err) {
+		return err
+	}
+
+	fmt.Println("Your email address must be verified before accepting the invitation.")
+	fmt.Println("Check your email for a verification code.")
+	fmt.Println("")
+
+	code, err := VerificationPrompt()
+	if err != nil {
+		return err
+	}
+
+	err = verifyEmail(ctx, &code, true)
+	if err != nil {
+		return err
+	}
+	fmt.Println("")
+
+	return fn()
+}
+
+// inviteAlreadyAccepted returns whether err is the registry refusing, as a
+// conflict, to accept an invite that has already been accepted or approved.
+func inviteAlreadyAccepted(err error) bool {
+	if !apitypes.IsConflictError(err) {
+		return false
+	}
+
+	for _, msg := range err.(*apitypes.Error).Err {
+		switch msg {
+		case "wrong invite state: " + primitive.OrgInviteAcceptedState,
+			"wrong invite state: " + primitive.OrgInviteApprovedState:
+			return true
+		}
+	}
+
+	return false
+}
+
+func inviteAlreadyAcceptedState(state string) bool {
+	return state == primitive.OrgInviteAcceptedState || state == primitive.OrgInviteApprovedState
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+)
+
+func TestInviteAlreadyAccepted(t *testing.T) {
+	conflict := func(msg string) error {
+		return &apitypes.Error{StatusCode: 409, Type: apitypes.ConflictError, Err: []string{msg}}
+	}
+
+	tcs := []struct {
+		name     string
+		err      error
+		accepted bool
+	}{
+		{"accepted", conflict("wrong invite state: accepted"), true},
+		{"approved", conflict("wrong invite state: approved"), true},
+		{"revoked", conflict("wrong invite state: revoked"), false},
+		{"other type", &apitypes.Error{StatusCode: 400, Type: apitypes.BadRequestError,
+			Err: []string{"wrong invite state: accepted"}}, false},
+		{"untyped", errors.New("wrong invite state: accepted"), false},
+		{"nil", nil, false},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := inviteAlreadyAccepted(tc.err); got != tc.accepted {
+				t.Errorf("Expected %t, got %t", tc.accepted, got)
+			}
+		})
+	}
+}
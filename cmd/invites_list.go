@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
 	"time"
 
@@ -15,6 +16,13 @@ import (
 	"github.com/manifoldco/torus-cli/identity"
 )
 
+var validInviteStates = map[string]bool{
+	"pending":    true,
+	"associated": true,
+	"accepted":   true,
+	"approved":   true,
+}
+
 func invitesList(ctx *cli.Context) error {
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -32,9 +40,17 @@ func invitesList(ctx *cli.Context) error {
 	}
 
 	var states []string
-	if ctx.Bool("approved") {
+	switch {
+	case len(ctx.StringSlice("state")) > 0:
+		states = ctx.StringSlice("state")
+		for _, state := range states {
+			if !validInviteStates[state] {
+				return errs.NewUsageExitError("Unknown invite state: "+state, ctx)
+			}
+		}
+	case ctx.Bool("approved"):
 		states = []string{"approved"}
-	} else {
+	default:
 		states = []string{"pending", "associated", "accepted"}
 	}
 
@@ -76,9 +92,12 @@ func invitesList(ctx *cli.Context) error {
 	}
 
 	fmt.Println("")
-	if ctx.Bool("approved") {
+	switch {
+	case len(ctx.StringSlice("state")) > 0:
+		fmt.Println("Listing " + strings.Join(states, ", ") + " invitations for the " + ctx.String("org") + " org")
+	case ctx.Bool("approved"):
 		fmt.Println("Listing approved invitations for the " + ctx.String("org") + " org")
-	} else {
+	default:
 		fmt.Println("Listing all pending and accepted invitations for the " + ctx.String("org") + " org")
 	}
 	fmt.Println("")
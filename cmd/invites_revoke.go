@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/config"
+	"github.com/manifoldco/torus-cli/errs"
+)
+
+const revokeInviteFailed = "Could not revoke invitation to org, please try again."
+
+func invitesRevoke(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) < 1 {
+		return errs.NewUsageExitError("Missing email", ctx)
+	}
+	email := ctx.Args()[0]
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	org, err := client.Orgs.GetByName(c, ctx.String("org"))
+	if err != nil {
+		return errs.NewExitError(revokeInviteFailed)
+	}
+	if org == nil {
+		return errs.NewExitError("Org not found.")
+	}
+
+	// Look through every state, so an invite that has moved past pending
+	// can be reported as such rather than as missing.
+	states := []string{"pending", "associated", "accepted", "approved"}
+	invites, err := client.Invites.List(c, org.ID, states)
+	if err != nil {
+		return errs.NewExitError("Failed to retrieve invites, please try again.")
+	}
+
+	var target *api.InviteResult
+	for i, invite := range invites {
+		if invite.Body.Email == email {
+			target = &invites[i]
+			if invite.Body.State == "pending" || invite.Body.State == "associated" {
+				break
+			}
+		}
+	}
+	if target == nil {
+		return errs.NewExitError("Invite not found.")
+	}
+
+	switch target.Body.State {
+	case "pending", "associated":
+	default:
+		return errs.NewExitError("The invite for " + email + " has already been " +
+			target.Body.State + " and can no longer be revoked.")
+	}
+
+	err = client.Invites.Revoke(c, *target.ID)
+	if err != nil {
+		return errs.NewErrorExitError(revokeInviteFailed, err)
+	}
+
+	fmt.Println("")
+	fmt.Println("You have revoked " + email + "'s invitation.")
+
+	return nil
+}
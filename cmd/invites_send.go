@@ -1,13 +1,18 @@
 package cmd
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 
+	"github.com/asaskevich/govalidator"
 	"github.com/urfave/cli"
 
 	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/config"
 	"github.com/manifoldco/torus-cli/errs"
 	"github.com/manifoldco/torus-cli/identity"
@@ -17,13 +22,38 @@ const orgInviteFailed = "Could not send invitation to org, please try again."
 
 func invitesSend(ctx *cli.Context) error {
 	args := ctx.Args()
-	if len(args) < 1 || args[0] == "" {
+	file := ctx.String("file")
+	if file != "" && len(args) > 0 {
+		return errs.NewUsageExitError("Give either an email or --file, not both", ctx)
+	}
+	if file == "" && (len(args) < 1 || args[0] == "") {
 		return errs.NewUsageExitError("Missing email", ctx)
 	}
 	if len(args) > 1 {
 		return errs.NewUsageExitError("Too many arguments", ctx)
 	}
-	email := args[0]
+
+	var emails []string
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return errs.NewErrorExitError("Could not open "+file, err)
+		}
+		defer f.Close()
+
+		var problems []string
+		emails, problems, err = readInviteEmails(f)
+		if err != nil {
+			return errs.NewErrorExitError("Could not read "+file, err)
+		}
+		if len(problems) > 0 {
+			return errs.NewExitError("No invitations were sent. Fix these problems in " + file +
+				" and try again:\n  " + strings.Join(problems, "\n  "))
+		}
+		if len(emails) == 0 {
+			return errs.NewExitError("No email addresses found in " + file + ".")
+		}
+	}
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -92,9 +122,14 @@ TeamSearch:
 		return errs.NewExitError(orgInviteFailed)
 	}
 
+	if file != "" {
+		return sendInvites(client, emails, org.Body.Name, *org.ID, *session.ID(), teamIDs, matchTeams)
+	}
+
+	email := args[0]
 	err = client.Invites.Send(context.Background(), email, *org.ID, *session.ID(), teamIDs)
 	if err != nil {
-		if strings.Contains(err.Error(), "resource exists") {
+		if apitypes.HasMessage(err, "resource exists") {
 			return errs.NewExitError(email + " has already been invited to the " + org.Body.Name + " org")
 		}
 		return errs.NewExitError(orgInviteFailed)
@@ -107,3 +142,83 @@ TeamSearch:
 
 	return nil
 }
+
+// sendInvites invites each of emails to the org, reporting the outcome for
+// each one. Addresses that have already been invited are skipped; other
+// failures don't stop the remaining invitations from being sent, but are
+// reported in the returned error.
+func sendInvites(client *api.Client, emails []string, orgName string, orgID, inviterID identity.ID,
+	teamIDs []identity.ID, teamNames []string) error {
+
+	sent, skipped, failed := 0, 0, 0
+	for _, email := range emails {
+		err := client.Invites.Send(context.Background(), email, orgID, inviterID, teamIDs)
+		switch {
+		case err == nil:
+			sent++
+			fmt.Printf("%s\tinvited\n", email)
+		case apitypes.HasMessage(err, "resource exists"):
+			skipped++
+			fmt.Printf("%s\talready invited\n", email)
+		default:
+			failed++
+			fmt.Printf("%s\tfailed: %s\n", email, err)
+		}
+	}
+
+	fmt.Printf("\nSent %d of %d invitations to join the %s organization", sent, len(emails), orgName)
+	if skipped > 0 {
+		fmt.Printf(", skipping %d already invited", skipped)
+	}
+	fmt.Println(".")
+	if sent > 0 {
+		fmt.Println("\nInvitees will be added to the following teams once their invite has been confirmed:")
+		fmt.Println("\n\t" + strings.Join(teamNames, "\n\t"))
+		fmt.Println("\nThey will receive an e-mail with instructions.")
+	}
+
+	if failed > 0 {
+		return errs.NewExitError(fmt.Sprintf("%d invitations could not be sent.", failed))
+	}
+	return nil
+}
+
+// readInviteEmails reads email addresses from r, one or more to a line
+// separated by commas or whitespace. Blank lines and lines starting with #
+// are ignored, and repeated addresses are only returned once, ignoring case.
+//
+// Every address is checked, and a problem is returned for each one that is
+// not a valid email address.
+func readInviteEmails(r io.Reader) ([]string, []string, error) {
+	var emails, problems []string
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		for _, email := range fields {
+			if !govalidator.IsEmail(email) {
+				problems = append(problems, fmt.Sprintf("line %d: %q is not a valid email address", lineNum, email))
+				continue
+			}
+
+			key := strings.ToLower(email)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			emails = append(emails, email)
+		}
+	}
+
+	return emails, problems, scanner.Err()
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadInviteEmails(t *testing.T) {
+	in := strings.Join([]string{
+		"# engineering",
+		"alice@example.com",
+		"",
+		"bob@example.com, carol@example.com",
+		"Alice@Example.com\tdave@example.com",
+		"not-an-email",
+	}, "\n")
+
+	emails, problems, err := readInviteEmails(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := []string{"alice@example.com", "bob@example.com", "carol@example.com", "dave@example.com"}
+	if !reflect.DeepEqual(emails, expected) {
+		t.Errorf("Expected %v, got %v", expected, emails)
+	}
+
+	if len(problems) != 1 || !strings.Contains(problems[0], "line 6") {
+		t.Errorf("Expected a problem on line 6, got %v", problems)
+	}
+}
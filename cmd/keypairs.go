@@ -18,7 +18,7 @@ import (
 func init() {
 	keypairs := cli.Command{
 		Name:     "keypairs",
-		Usage:    "View and generate organization keypairs",
+		Usage:    "View, generate and regenerate organization keypairs",
 		Category: "ORGANIZATIONS",
 		Subcommands: []cli.Command{
 			{
@@ -47,6 +47,18 @@ func init() {
 					setUserEnv, checkRequiredFlags, generateKeypairs,
 				),
 			},
+			{
+				Name:  "regenerate",
+				Usage: "Replace your keypairs for an organization, revoking the old ones",
+				Flags: []cli.Flag{
+					orgFlag("org to regenerate keypairs for", true),
+					stdAutoAcceptFlag,
+				},
+				Action: chain(
+					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+					setUserEnv, checkRequiredFlags, regenerateKeypairs,
+				),
+			},
 		},
 	}
 	Cmds = append(Cmds, keypairs)
@@ -81,10 +93,14 @@ func listKeypairs(ctx *cli.Context) error {
 	fmt.Println("")
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', 0)
-	fmt.Fprintln(w, "ID\tORG\tKEY TYPE\tVALID\tCREATION DATE")
+	fmt.Fprintln(w, "ID\tORG\tKEY TYPE\tSTATE\tCREATION DATE")
 	fmt.Fprintln(w, " \t \t \t \t ")
 	for _, keypair := range keypairs {
-		fmt.Fprintln(w, keypair.PublicKey.ID.String()+"\t"+org.Body.Name+"\t"+keypair.PublicKey.Body.KeyType+"\tYES\t"+keypair.PublicKey.Body.Created.Format(time.RFC3339))
+		state := "active"
+		if keypair.Revoked() {
+			state = "revoked"
+		}
+		fmt.Fprintln(w, keypair.PublicKey.ID.String()+"\t"+org.Body.Name+"\t"+keypair.PublicKey.Body.KeyType+"\t"+state+"\t"+keypair.PublicKey.Body.Created.Format(time.RFC3339))
 	}
 	w.Flush()
 	fmt.Println("")
@@ -141,6 +157,9 @@ func generateKeypairs(ctx *cli.Context) error {
 			break
 		}
 		for _, kp := range keypairs {
+			if kp.Revoked() {
+				continue
+			}
 			oID := kp.PublicKey.Body.OrgID.String()
 			if hasKey[oID] == nil {
 				hasKey[oID] = make(map[string]bool)
@@ -184,6 +203,50 @@ func generateKeypairs(ctx *cli.Context) error {
 	return nil
 }
 
+const keypairRegenerateFailed = "Could not regenerate keypairs, please try again."
+
+func regenerateKeypairs(ctx *cli.Context) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	orgName := ctx.String("org")
+	org, err := client.Orgs.GetByName(c, orgName)
+	if err != nil {
+		return errs.NewErrorExitError(keypairRegenerateFailed, err)
+	}
+	if org == nil {
+		return errs.NewExitError("Org '" + orgName + "' not found.")
+	}
+
+	preamble := fmt.Sprintf("You are about to replace your signing and encryption "+
+		"keypairs for the %s org.\n\nYour access to existing secrets will be "+
+		"re-encrypted for the new keys, and the old keys will be revoked. "+
+		"Any other copies of your old keys will no longer work.", org.Body.Name)
+	abortErr := ConfirmDialogue(ctx, nil, &preamble)
+	if abortErr != nil {
+		return abortErr
+	}
+
+	rc, stop := interruptContext()
+	err = client.Keypairs.Regenerate(rc, org.ID, &progress)
+	stop()
+	if rc.Err() == context.Canceled {
+		return errs.NewExitError("Keypair regeneration cancelled. If your new keypairs were " +
+			"generated, run 'torus keypairs regenerate' again to finish replacing the old ones.")
+	}
+	if err != nil {
+		return errs.NewErrorExitError(keypairRegenerateFailed, err)
+	}
+
+	fmt.Println("Keypair regeneration successful.")
+	return nil
+}
+
 func generateKeypairsForOrg(c context.Context, ctx *cli.Context, client *api.Client, orgID *identity.ID, lookupOrg bool) error {
 	var err error
 
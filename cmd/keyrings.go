@@ -0,0 +1,290 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/config"
+	"github.com/manifoldco/torus-cli/errs"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/pathexp"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+func init() {
+	keyrings := cli.Command{
+		Name:     "keyrings",
+		Usage:    "Inspect and repair the keyrings that secrets are encrypted with",
+		Category: "ACCESS CONTROL",
+		Subcommands: []cli.Command{
+			{
+				Name:  "repair",
+				Usage: "Give org members who should be able to read a project's secrets access to its keyrings",
+				Flags: []cli.Flag{
+					stdOrgFlag,
+					stdProjectFlag,
+				},
+				Action: chain(
+					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+					checkRequiredFlags, keyringsRepairCmd,
+				),
+			},
+			{
+				Name:  "verify",
+				Usage: "Check the signatures and memberships of an org's keyrings and secrets",
+				Flags: []cli.Flag{
+					stdOrgFlag,
+				},
+				Action: chain(
+					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+					checkRequiredFlags, keyringsVerifyCmd,
+				),
+			},
+		},
+	}
+	Cmds = append(Cmds, keyrings)
+}
+
+const keyringsRepairFailed = "Could not repair keyrings."
+
+func keyringsRepairCmd(ctx *cli.Context) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	org, err := getOrg(c, client, ctx.String("org"))
+	if err != nil {
+		return err
+	}
+
+	session, err := client.Session.Who(c)
+	if err != nil {
+		return errs.NewErrorExitError("Error fetching user details", err)
+	}
+
+	err = preflightRole(c, client, session, org.ID, org.Body.Name, primitive.AdminTeamName, "repair its keyrings")
+	if err != nil {
+		return err
+	}
+
+	projectName := ctx.String("project")
+	projects, err := listProjects(&c, client, org.ID, &projectName)
+	if err != nil {
+		return errs.NewErrorExitError(keyringsRepairFailed, err)
+	}
+	if len(projects) != 1 {
+		return errs.NewNotFoundExitError("Project not found.")
+	}
+
+	repairs, err := client.Keyrings.Repair(c, org.ID, projects[0].ID, &progress)
+	if err != nil {
+		return errs.NewErrorExitError(keyringsRepairFailed, err)
+	}
+
+	if len(repairs) == 0 {
+		fmt.Println("All keyring memberships are in place.")
+		return nil
+	}
+
+	members, err := client.Orgs.Members(c, *org.ID)
+	if err != nil {
+		return errs.NewErrorExitError("Could not retrieve org members.", err)
+	}
+
+	machines, err := client.Machines.List(c, org.ID, nil, nil, nil)
+	if err != nil {
+		return errs.NewErrorExitError("Could not retrieve machines.", err)
+	}
+
+	names := keyringOwnerNames(members, machines)
+
+	fmt.Println("")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
+	fmt.Fprintln(w, "KEYRING\tMEMBER\tRESULT\t")
+	failed := false
+	for _, r := range repairs {
+		name, ok := names[*r.OwnerID]
+		if !ok {
+			name = r.OwnerID.String()
+		}
+
+		result := "added"
+		if !r.Repaired {
+			failed = true
+			result = "skipped: " + r.Reason
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t\n", r.PathExp, name, result)
+	}
+	w.Flush()
+
+	if failed {
+		return errs.NewExitError("\nSome keyring memberships could not be added.")
+	}
+
+	return nil
+}
+
+const keyringsVerifyFailed = "Could not verify keyrings."
+
+func keyringsVerifyCmd(ctx *cli.Context) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	org, err := getOrg(c, client, ctx.String("org"))
+	if err != nil {
+		return err
+	}
+
+	session, err := client.Session.Who(c)
+	if err != nil {
+		return errs.NewErrorExitError("Error fetching user details", err)
+	}
+
+	err = preflightRole(c, client, session, org.ID, org.Body.Name, primitive.AdminTeamName, "verify its keyrings")
+	if err != nil {
+		return err
+	}
+
+	findings, err := client.Keyrings.Verify(c, org.ID, &progress)
+	if err != nil {
+		return errs.NewErrorExitError(keyringsVerifyFailed, err)
+	}
+
+	if len(findings) == 0 {
+		fmt.Println("No problems found.")
+		return nil
+	}
+
+	members, err := client.Orgs.Members(c, *org.ID)
+	if err != nil {
+		return errs.NewErrorExitError("Could not retrieve org members.", err)
+	}
+
+	machines, err := client.Machines.List(c, org.ID, nil, nil, nil)
+	if err != nil {
+		return errs.NewErrorExitError("Could not retrieve machines.", err)
+	}
+
+	names := keyringOwnerNames(members, machines)
+
+	critical, warnings := groupKeyringFindings(findings)
+	if len(critical) > 0 {
+		printKeyringFindings("CRITICAL", critical, names)
+	}
+	if len(warnings) > 0 {
+		printKeyringFindings("WARNINGS", warnings, names)
+		fmt.Println("\nRun 'torus keyrings repair' to fix missing memberships.")
+	}
+
+	if len(critical) > 0 {
+		return errs.NewExitError(fmt.Sprintf(
+			"\n%d critical problems found. Secrets in the affected keyrings may have been tampered with.",
+			len(critical)))
+	}
+
+	return nil
+}
+
+// groupKeyringFindings splits findings by severity, keeping their order.
+func groupKeyringFindings(findings []apitypes.KeyringFinding) ([]apitypes.KeyringFinding, []apitypes.KeyringFinding) {
+	var critical, warnings []apitypes.KeyringFinding
+	for _, f := range findings {
+		if f.Severity == apitypes.FindingCritical {
+			critical = append(critical, f)
+		} else {
+			warnings = append(warnings, f)
+		}
+	}
+
+	return critical, warnings
+}
+
+// printKeyringFindings prints findings in a table under heading. Each finding
+// is about a secret, a keyring member, or the keyring as a whole.
+func printKeyringFindings(heading string, findings []apitypes.KeyringFinding, names map[identity.ID]string) {
+	fmt.Println("")
+	fmt.Println(heading)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
+	fmt.Fprintln(w, "KEYRING\tSUBJECT\tPROBLEM\t")
+	for _, f := range findings {
+		subject := "-"
+		switch {
+		case f.CredentialName != "":
+			subject = f.CredentialName
+		case f.OwnerID != nil:
+			subject = f.OwnerID.String()
+			if name, ok := names[*f.OwnerID]; ok {
+				subject = name
+			}
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t\n", f.PathExp, subject, f.Detail)
+	}
+	w.Flush()
+}
+
+// keyringOwnerNames maps the IDs keyrings are owned by to display names.
+// Machines own keyring memberships by their tokens, which are named after
+// their machine.
+func keyringOwnerNames(members []api.OrgMember, machines []*apitypes.MachineSegment) map[identity.ID]string {
+	names := make(map[identity.ID]string, len(members))
+	for _, m := range members {
+		if m.Type == api.MemberTypeUser {
+			names[*m.ID] = m.Username
+		} else {
+			names[*m.ID] = m.Name
+		}
+	}
+	for _, m := range machines {
+		for _, t := range m.Tokens {
+			names[*t.Token.ID] = m.Machine.Body.Name
+		}
+	}
+
+	return names
+}
+
+// keyringRotateCancelled is shown when keyring rotation is interrupted. Each
+// keyring rotated before the interrupt is reported as it is rotated.
+const keyringRotateCancelled = "Keyring rotation cancelled. Keyrings rotated so far are " +
+	"listed above; run 'torus worklog list' to see the secrets still to be rotated."
+
+// rotateKeyrings rotates the org's keyrings after a member or machine loses
+// access, so it can't decrypt anything written from now on, and lists those
+// rotated. failed is the message shown if the rotation fails, ahead of the
+// daemon's, which names the keyrings that were and weren't rotated.
+func rotateKeyrings(client *api.Client, orgID *identity.ID, failed string) ([]*pathexp.PathExp, error) {
+	rc, stop := interruptContext()
+	rotated, err := client.Keyrings.Rotate(rc, orgID, &progress)
+	stop()
+	if rc.Err() == context.Canceled {
+		return nil, errs.NewExitError(keyringRotateCancelled)
+	}
+	if err != nil {
+		return nil, errs.NewErrorExitError(failed, err)
+	}
+
+	if len(rotated) > 0 {
+		fmt.Println("\nKeyrings rotated:")
+		for _, pe := range rotated {
+			fmt.Printf("\t%s\n", pe)
+		}
+	}
+
+	return rotated, nil
+}
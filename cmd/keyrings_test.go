@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+)
+
+func TestGroupKeyringFindings(t *testing.T) {
+	findings := []apitypes.KeyringFinding{
+		{Severity: apitypes.FindingCritical, CredentialName: "a"},
+		{Severity: apitypes.FindingWarning, CredentialName: "b"},
+		{Severity: apitypes.FindingCritical, CredentialName: "c"},
+	}
+
+	critical, warnings := groupKeyringFindings(findings)
+	if len(critical) != 2 || critical[0].CredentialName != "a" || critical[1].CredentialName != "c" {
+		t.Errorf("Unexpected critical findings: %+v", critical)
+	}
+	if len(warnings) != 1 || warnings[0].CredentialName != "b" {
+		t.Errorf("Unexpected warnings: %+v", warnings)
+	}
+}
@@ -23,10 +23,19 @@ func init() {
 		Usage:    "Link your current directory to Torus",
 		Category: "CONTEXT",
 		Flags: []cli.Flag{
+			orgFlag("Link to this org. It must already exist.", false),
+			projectFlag("Link to this project. It must already exist.", false),
+			// Unlike envFlag and serviceFlag, these don't read TORUS_ENVIRONMENT
+			// and TORUS_SERVICE, which would otherwise be saved into the link.
+			newPlaceholder("environment, e", "ENV",
+				"Use this environment by default in the linked directory.", "", "", false),
+			newPlaceholder("service, s", "SERVICE",
+				"Use this service by default in the linked directory.", "", "", false),
 			cli.BoolFlag{
 				Name:  "force, f",
-				Usage: "Overwrite existing organization and project links.",
+				Usage: "Overwrite existing organization, project, environment and service links.",
 			},
+			stdAutoAcceptFlag,
 			cli.BoolFlag{
 				Name:   "bare",
 				Usage:  "Skip creation of default service.",
@@ -51,11 +60,15 @@ func linkCmd(ctx *cli.Context) error {
 	}
 
 	if dPrefs != nil && dPrefs.Path != "" && !ctx.Bool("force") {
-		msg := fmt.Sprintf(
-			"This directory is already linked. Use '%s status' to view.",
-			ctx.App.Name,
+		preamble := fmt.Sprintf(
+			"This directory is already linked to the %s project in the %s org.",
+			dPrefs.Project, dPrefs.Organization,
 		)
-		return errs.NewExitError(msg)
+		label := "Overwrite the existing link"
+		abortErr := ConfirmDialogue(ctx, &label, &preamble)
+		if abortErr != nil {
+			return abortErr
+		}
 	}
 
 	cfg, err := config.LoadConfig()
@@ -143,6 +156,8 @@ func linkCmd(ctx *cli.Context) error {
 
 	dPrefs.Organization = oName
 	dPrefs.Project = pName
+	dPrefs.Environment = ctx.String("environment")
+	dPrefs.Service = ctx.String("service")
 	dPrefs.Path = filepath.Join(cwd, ".torus.json")
 
 	err = dPrefs.Save()
@@ -155,11 +170,17 @@ func linkCmd(ctx *cli.Context) error {
 	w := tabwriter.NewWriter(os.Stdout, 2, 0, 1, ' ', 0)
 	fmt.Fprintf(w, "Org:\t%s\n", oName)
 	fmt.Fprintf(w, "Project:\t%s\n", pName)
+	if dPrefs.Environment != "" {
+		fmt.Fprintf(w, "Environment:\t%s\n", dPrefs.Environment)
+	}
+	if dPrefs.Service != "" {
+		fmt.Fprintf(w, "Service:\t%s\n", dPrefs.Service)
+	}
 	w.Flush()
 	fmt.Printf("\nUse '%s status' to view your full working context.\n", ctx.App.Name)
 
 	if !preferences.Core.Context {
-		fmt.Printf("Warning: context is disabled. Use '%s prefs' to enable it.\n", ctx.App.Name)
+		warn(fmt.Sprintf("context is disabled. Use '%s prefs' to enable it.", ctx.App.Name))
 	}
 
 	return nil
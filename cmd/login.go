@@ -1,12 +1,19 @@
 package cmd
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 
+	"github.com/chzyer/readline"
 	"github.com/urfave/cli"
 
 	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/config"
 	"github.com/manifoldco/torus-cli/errs"
 )
@@ -16,13 +23,33 @@ func init() {
 		Name:     "login",
 		Usage:    "Log in to your Torus account",
 		Category: "ACCOUNT",
-		Action:   chain(ensureDaemon, login),
+		Flags: []cli.Flag{
+			newPlaceholder("email", "EMAIL", "Log in using this email address", "", "", false),
+			cli.BoolFlag{
+				Name:  "password-stdin",
+				Usage: "Read the password from stdin, requires --email",
+			},
+		},
+		Action: chain(ensureDaemon, login),
 	}
 	Cmds = append(Cmds, login)
 }
 
 func login(ctx *cli.Context) error {
-	email, err := EmailPrompt("")
+	if ctx.Bool("password-stdin") {
+		return loginWithStdin(ctx)
+	}
+
+	// Without a terminal to prompt on, such as in a container or CI runner,
+	// log in as the machine given by the environment, if any.
+	tokenID, hasTokenID := os.LookupEnv("TORUS_TOKEN_ID")
+	tokenSecret, hasTokenSecret := os.LookupEnv("TORUS_TOKEN_SECRET")
+	if hasTokenID && hasTokenSecret && ctx.String("email") == "" &&
+		!readline.IsTerminal(int(os.Stdin.Fd())) {
+		return loginWithToken(tokenID, tokenSecret)
+	}
+
+	email, err := EmailPrompt(ctx.String("email"))
 	if err != nil {
 		return err
 	}
@@ -40,15 +67,94 @@ func login(ctx *cli.Context) error {
 	client := api.NewClient(cfg)
 
 	c := context.Background()
-	err = performLogin(c, client, email, password)
+	err = performLogin(c, client, email, []byte(password))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// loginWithStdin logs the user in non-interactively, reading the password
+// from stdin. It refuses to read from a terminal, as it would otherwise wait
+// for input that is never prompted for.
+func loginWithStdin(ctx *cli.Context) error {
+	email := ctx.String("email")
+	if email == "" {
+		return errs.NewUsageExitError("--email is required with --password-stdin", ctx)
+	}
+
+	if readline.IsTerminal(int(os.Stdin.Fd())) {
+		return errs.NewUsageExitError("--password-stdin requires the password to be piped in", ctx)
+	}
+
+	password, err := readPassword(os.Stdin)
+	defer zero(password)
+	if err != nil {
+		return errs.NewErrorExitError("Could not read password from stdin.", err)
+	}
+	if len(password) == 0 {
+		return errs.NewExitError("No password provided on stdin.")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	return performLogin(context.Background(), client, email, password)
+}
+
+// loginWithToken logs in as the machine with the given token id and secret.
+func loginWithToken(tokenID, tokenSecret string) error {
+	cfg, err := config.LoadConfig()
 	if err != nil {
 		return err
 	}
 
+	client := api.NewClient(cfg)
+	err = machineLogin(context.Background(), client, tokenID, tokenSecret)
+	if err != nil {
+		return errs.NewErrorExitError("Login failed.", err)
+	}
+
+	fmt.Println("You are now authenticated as token " + tokenID + ".")
 	return nil
 }
 
-func performLogin(c context.Context, client *api.Client, email, password string) error {
+// machineLogin logs in as the machine with the given encoded token id and
+// secret, zeroing the decoded secret afterwards.
+func machineLogin(c context.Context, client *api.Client, tokenID, tokenSecret string) error {
+	login, err := apitypes.NewMachineLogin(tokenID, tokenSecret)
+	if err != nil || !login.Valid() {
+		return errors.New("TORUS_TOKEN_ID and TORUS_TOKEN_SECRET are not a valid machine token")
+	}
+	defer zero(*login.Secret)
+
+	return client.Session.MachineLogin(c, login)
+}
+
+// readPassword reads the first line from r, trimming a single trailing
+// newline (and carriage return).
+func readPassword(r io.Reader) ([]byte, error) {
+	line, err := bufio.NewReader(r).ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return nil, err
+	}
+
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	return line, nil
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+func performLogin(c context.Context, client *api.Client, email string, password []byte) error {
 	err := client.Session.UserLogin(context.Background(), email, password)
 	if err != nil {
 		return errs.NewErrorExitError("Login failed.", err)
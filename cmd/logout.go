@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
 
 	"github.com/urfave/cli"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/config"
 	"github.com/manifoldco/torus-cli/errs"
+	"github.com/manifoldco/torus-cli/prefs"
 )
 
 func init() {
@@ -17,30 +19,104 @@ func init() {
 		Name:     "logout",
 		Usage:    "Log out of your Torus account",
 		Category: "ACCOUNT",
-		Action:   chain(ensureDaemon, logoutCmd),
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "all",
+				Usage: "Log out of every profile, not just the active one",
+			},
+		},
+		Action: chain(ensureDaemon, logoutCmd),
 	}
 	Cmds = append(Cmds, logout)
 }
 
+const logoutFailed = "Logout failed."
+
 func logoutCmd(ctx *cli.Context) error {
+	if ctx.Bool("all") {
+		return logoutAllCmd()
+	}
+
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		return err
 	}
 
+	loggedOut, err := logout(cfg)
+	if err != nil {
+		return errs.NewErrorExitError(logoutFailed, err)
+	}
+
+	if loggedOut {
+		fmt.Println("You have successfully logged out. o/")
+	} else {
+		fmt.Println("Already logged out.")
+	}
+	return nil
+}
+
+// logoutAllCmd logs out of the daemon of each profile. Profiles without a
+// running daemon hold no session, so no daemon is started for them.
+func logoutAllCmd() error {
+	preferences, err := prefs.NewPreferences(true)
+	if err != nil {
+		return errs.NewErrorExitError("Failed to load prefs.", err)
+	}
+
+	profiles, err := prefs.LoadProfiles(preferences)
+	if err != nil {
+		return errs.NewErrorExitError("Failed to load profiles.", err)
+	}
+
+	active := os.Getenv("TORUS_PROFILE")
+	defer os.Setenv("TORUS_PROFILE", active)
+
+	for _, p := range profiles {
+		os.Setenv("TORUS_PROFILE", p.Name)
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return errs.NewErrorExitError(logoutFailed, err)
+		}
+
+		proc, err := findDaemon(cfg)
+		if err != nil {
+			return errs.NewErrorExitError(logoutFailed, err)
+		}
+
+		loggedOut := false
+		if proc != nil {
+			loggedOut, err = logout(cfg)
+			if err != nil {
+				return errs.NewErrorExitError("Logout of profile "+p.Name+" failed.", err)
+			}
+		}
+
+		if loggedOut {
+			fmt.Printf("%s: logged out\n", p.Name)
+		} else {
+			fmt.Printf("%s: already logged out\n", p.Name)
+		}
+	}
+
+	return nil
+}
+
+// logout asks the daemon for cfg to discard its session. It returns false if
+// the daemon held no session to discard.
+func logout(cfg *config.Config) (bool, error) {
 	client := api.NewClient(cfg)
 
-	err = client.Session.Logout(context.Background())
+	err := client.Session.Logout(context.Background())
 	if err != nil {
 		if herr, ok := err.(*apitypes.Error); ok {
-			if herr.StatusCode == 404 {
-				fmt.Println("You are not logged in.")
-				return nil
+			if herr.StatusCode == 401 || herr.StatusCode == 404 ||
+				herr.Type == apitypes.UnauthorizedError {
+				return false, nil
 			}
 		}
-		return errs.NewErrorExitError("Logout failed.", err)
+		return false, err
 	}
 
-	fmt.Println("You have successfully logged out. o/")
-	return nil
+	return true, nil
 }
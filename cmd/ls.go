@@ -1,12 +1,14 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"sort"
 
 	"github.com/urfave/cli"
 
 	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/errs"
 	"github.com/manifoldco/torus-cli/identity"
 	"github.com/manifoldco/torus-cli/pathexp"
@@ -31,6 +33,20 @@ func init() {
 				"*", "TORUS_MACHINE", false),
 			newSlicePlaceholder("instance, i", "INSTANCE", "Use this instance.",
 				"*", "TORUS_INSTANCE", false),
+			cli.BoolFlag{
+				Name:  "tree, t",
+				Usage: "Show the org, project, environment, service and secret hierarchy as a tree",
+			},
+			cli.IntFlag{
+				Name:  "depth",
+				Usage: "Limit the tree to this many levels, starting from orgs (0 for no limit)",
+			},
+			cli.BoolFlag{
+				Name:  "values",
+				Usage: "Reveal the values of listed secrets",
+			},
+			newSlicePlaceholder("tag", "TAG",
+				"Only list secrets with this tag. May be repeated to require several tags", "", "", false),
 		},
 		Action: chain(
 			ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
@@ -112,6 +128,13 @@ func listObjects(ctx *cli.Context) error {
 	if count > 3 && cpathObj.Project() == "*" {
 		return errs.NewUsageExitError("Project must be supplied to view secrets", ctx)
 	}
+	if ctx.Int("depth") < 0 {
+		return errs.NewUsageExitError("Depth must not be negative", ctx)
+	}
+
+	if ctx.Bool("tree") {
+		return listTree(cpathObj, count, ctx.Int("depth"), ctx.Bool("values"), ctx.StringSlice("tag"))
+	}
 
 	var paths []string
 	var pathErr error
@@ -147,7 +170,7 @@ func listObjects(ctx *cli.Context) error {
 		}
 	default:
 		showing = "Listing secrets within path: " + cpathObj.String()
-		paths, err = secretPaths(cpathObj)
+		paths, err = secretPaths(cpathObj, ctx.Bool("values"), ctx.StringSlice("tag"))
 		if err != nil {
 			pathErr = errs.NewExitError("Failed to list secrets.")
 		}
@@ -301,26 +324,124 @@ func servicePaths(cpathObj *pathexp.PathExp) ([]string, error) {
 	return paths, nil
 }
 
-func secretPaths(cpathObj *pathexp.PathExp) ([]string, error) {
-	var paths []string
-	c, client, err := NewAPIClient(nil, nil)
+// listedSecret is a secret as shown by ls. Value is empty unless values are
+// being listed.
+type listedSecret struct {
+	Name        string
+	PathExp     *pathexp.PathExp
+	Value       string
+	Description string
+	Tags        []string
+}
+
+// GetDescription returns the description
+func (s *listedSecret) GetDescription() string {
+	return s.Description
+}
+
+// GetTags returns the tags
+func (s *listedSecret) GetTags() []string {
+	return s.Tags
+}
+
+// listSecrets returns the secrets matching pe. Values are only retrieved, and
+// so decrypted, if values is true; otherwise just the names and metadata are
+// listed, which is much faster.
+func listSecrets(c context.Context, client *api.Client, pe *pathexp.PathExp, values bool) ([]listedSecret, error) {
+	var secrets []listedSecret
+	if !values {
+		keys, err := client.Credentials.ListNames(c, pe.String())
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			secrets = append(secrets, listedSecret{
+				Name:        k.Name,
+				PathExp:     k.PathExp,
+				Description: k.Description,
+				Tags:        k.Tags,
+			})
+		}
+		return secrets, nil
+	}
+
+	creds, err := client.Credentials.Search(c, pe.String())
 	if err != nil {
 		return nil, err
 	}
+	for _, cred := range creds {
+		body := *cred.Body
+		if body.GetValue() == nil {
+			continue
+		}
+		secrets = append(secrets, listedSecret{
+			Name:        body.GetName(),
+			PathExp:     body.GetPathExp(),
+			Value:       body.GetValue().String(),
+			Description: body.GetDescription(),
+			Tags:        body.GetTags(),
+		})
+	}
+
+	return secrets, nil
+}
 
-	creds, err := client.Credentials.Search(c, cpathObj.String())
+// mostSpecificSecrets returns one secret for each name among secrets, the
+// one set at the most specific PathExp, sorted by name. The secrets are only
+// being listed, not resolved for a single path, so the first of equally
+// specific secrets wins.
+func mostSpecificSecrets(secrets []listedSecret) []listedSecret {
+	byName := make(map[string]int, len(secrets))
+	var out []listedSecret
+	for _, s := range secrets {
+		if i, ok := byName[s.Name]; ok {
+			if s.PathExp.CompareSpecificity(out[i].PathExp) == 1 {
+				out[i] = s
+			}
+			continue
+		}
+		byName[s.Name] = len(out)
+		out = append(out, s)
+	}
+	sort.Sort(listedSecretsByName(out))
+
+	return out
+}
+
+// listedSecretsByName implements sort.Interface, ordering secrets by name.
+type listedSecretsByName []listedSecret
+
+func (s listedSecretsByName) Len() int           { return len(s) }
+func (s listedSecretsByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s listedSecretsByName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+
+// secretPaths returns the paths of the secrets matched by cpathObj that have
+// every one of tags, along with their descriptions and tags. Values are
+// included only if values is true.
+func secretPaths(cpathObj *pathexp.PathExp, values bool, tags []string) ([]string, error) {
+	var paths []string
+	c, client, err := NewAPIClient(nil, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	cset := credentialSet{}
-	for _, c := range creds {
-		cset.Add(c)
+	secrets, err := listSecrets(c, client, cpathObj, values)
+	if err != nil {
+		return nil, err
 	}
 
-	for _, cred := range cset {
-		body := *cred.Body
-		paths = append(paths, fmt.Sprintf("%s/%s", body.GetPathExp(), body.GetName()))
+	for _, s := range mostSpecificSecrets(secrets) {
+		if !apitypes.HasTags(&s, tags) {
+			continue
+		}
+		path := fmt.Sprintf("%s/%s", s.PathExp, s.Name)
+		if values {
+			path += "=" + s.Value
+		}
+		if meta := credentialMetadata(&s); meta != "" {
+			path += "  " + meta
+		}
+		paths = append(paths, path)
 	}
 	sort.Strings(paths)
 
@@ -362,3 +483,169 @@ func filterOrgs(orgs []api.OrgResult, pe *pathexp.PathExp) ([]*identity.ID, map[
 
 	return orgIDs, orgMapName, orgMapID, nil
 }
+
+// Levels of the tree printed by listTree
+const (
+	orgDepth = iota + 1
+	projectDepth
+	envDepth
+	serviceDepth
+	secretDepth
+)
+
+// listTree prints the orgs, projects, environments, services and secrets
+// matched by cpathObj as an indented tree, down to the given depth. A depth
+// of 0 prints every level. Secrets are shown under each service whose path
+// they apply to, with their values only if values is true. If tags are
+// given, only secrets with every one of them are shown.
+func listTree(cpathObj *pathexp.PathExp, count, depth int, values bool, tags []string) error {
+	within := func(level int) bool {
+		return depth == 0 || level <= depth
+	}
+
+	c, client, err := NewAPIClient(nil, nil)
+	if err != nil {
+		return err
+	}
+
+	orgs, _, err := orgsList()
+	if err != nil {
+		return errs.NewExitError("Failed to list orgs.")
+	}
+
+	orgIDs, _, orgMapID, err := filterOrgs(orgs, cpathObj)
+	if err != nil {
+		return err
+	}
+
+	var projects []api.ProjectResult
+	if within(projectDepth) {
+		projects, err = listProjectsByOrgID(&c, client, orgIDs)
+		if err != nil {
+			return errs.NewExitError("Failed to list projects.")
+		}
+	}
+
+	var projectIDs []*identity.ID
+	for _, project := range projects {
+		if cpathObj.Project() == "*" || project.Body.Name == cpathObj.Project() {
+			projectIDs = append(projectIDs, project.ID)
+		}
+	}
+
+	var envs []api.EnvironmentResult
+	var services []api.ServiceResult
+	if within(envDepth) && len(projectIDs) > 0 {
+		envs, err = listEnvsByProjectID(&c, client, projectIDs)
+		if err != nil {
+			return errs.NewExitError("Failed to list environments.")
+		}
+		if within(serviceDepth) {
+			services, err = listServicesByProjectID(&c, client, projectIDs)
+			if err != nil {
+				return errs.NewExitError("Failed to list services.")
+			}
+		}
+	}
+
+	projectsByOrg := make(map[identity.ID][]api.ProjectResult)
+	for _, project := range projects {
+		if cpathObj.Project() == "*" || project.Body.Name == cpathObj.Project() {
+			projectsByOrg[*project.Body.OrgID] = append(projectsByOrg[*project.Body.OrgID], project)
+		}
+	}
+	envsByProject := make(map[identity.ID][]string)
+	for _, env := range envs {
+		envsByProject[*env.Body.ProjectID] = append(envsByProject[*env.Body.ProjectID], env.Body.Name)
+	}
+	servicesByProject := make(map[identity.ID][]string)
+	for _, service := range services {
+		servicesByProject[*service.Body.ProjectID] = append(servicesByProject[*service.Body.ProjectID], service.Body.Name)
+	}
+
+	var orgNames []string
+	orgsByName := make(map[string]identity.ID)
+	for _, id := range orgIDs {
+		orgNames = append(orgNames, orgMapID[*id])
+		orgsByName[orgMapID[*id]] = *id
+	}
+	sort.Strings(orgNames)
+
+	for _, orgName := range orgNames {
+		fmt.Println(orgName)
+
+		orgProjects := projectsByOrg[orgsByName[orgName]]
+		sort.Sort(projectsByName(orgProjects))
+		for _, project := range orgProjects {
+			fmt.Println("  " + project.Body.Name)
+
+			var secrets []listedSecret
+			if within(secretDepth) {
+				projectPath, err := pathexp.New(orgName, project.Body.Name,
+					[]string{"*"}, []string{"*"}, []string{"*"}, []string{"*"})
+				if err != nil {
+					return err
+				}
+				secrets, err = listSecrets(c, client, projectPath, values)
+				if err != nil {
+					return errs.NewExitError("Failed to list secrets.")
+				}
+			}
+
+			envNames := envsByProject[*project.ID]
+			serviceNames := servicesByProject[*project.ID]
+			sort.Strings(envNames)
+			sort.Strings(serviceNames)
+
+			for _, envName := range envNames {
+				envPath, err := pathexp.New(orgName, project.Body.Name,
+					[]string{envName}, []string{"*"}, []string{"*"}, []string{"*"})
+				if err != nil {
+					return err
+				}
+				if count > 3 {
+					if _, ok := envPath.Intersect(cpathObj); !ok {
+						continue
+					}
+				}
+				fmt.Println("    " + envName)
+
+				for _, serviceName := range serviceNames {
+					servicePath, err := pathexp.New(orgName, project.Body.Name,
+						[]string{envName}, []string{serviceName}, []string{"*"}, []string{"*"})
+					if err != nil {
+						return err
+					}
+					if count > 3 {
+						if _, ok := servicePath.Intersect(cpathObj); !ok {
+							continue
+						}
+					}
+					fmt.Println("      " + serviceName)
+
+					var matched []listedSecret
+					for _, s := range secrets {
+						if _, ok := s.PathExp.Intersect(servicePath); ok {
+							matched = append(matched, s)
+						}
+					}
+					for _, s := range mostSpecificSecrets(matched) {
+						if !apitypes.HasTags(&s, tags) {
+							continue
+						}
+						line := "        " + s.Name
+						if values {
+							line += "=" + s.Value
+						}
+						if meta := credentialMetadata(&s); meta != "" {
+							line += "  " + meta
+						}
+						fmt.Println(line)
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
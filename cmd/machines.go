@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"crypto/rand"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
@@ -26,7 +27,32 @@ const (
 	machineCreateFailed   = "Could not create machine, please try again."
 )
 
+// rotateTokenCmd replaces a machine's token. It is found at machines roles
+// rotate, alongside the commands managing what machines can do; machines
+// rotate is kept as a hidden alias, as it is a machine's token that changes
+// rather than its role.
+var rotateTokenCmd = cli.Command{
+	Name:      "rotate",
+	Usage:     "Replace a machine's token with a newly generated one",
+	ArgsUsage: "<id|name>",
+	Flags: []cli.Flag{
+		orgFlag("Org the machine belongs to", true),
+		cli.StringFlag{
+			Name:  "token",
+			Usage: "ID of the token to rotate, required if the machine has more than one active token",
+		},
+		stdAutoAcceptFlag,
+	},
+	Action: chain(
+		ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+		checkRequiredFlags, rotateMachineTokenCmd,
+	),
+}
+
 func init() {
+	rotateAlias := rotateTokenCmd
+	rotateAlias.Hidden = true
+
 	machines := cli.Command{
 		Name:      "machines",
 		Usage:     "View and create machines within an organization",
@@ -59,7 +85,7 @@ func init() {
 			},
 			{
 				Name:      "destroy",
-				Usage:     "Destroy a machine in the specified organization",
+				Usage:     "Destroy a machine in the specified organization, revoking its tokens",
 				ArgsUsage: "<id|name>",
 				Flags: []cli.Flag{
 					orgFlag("Org the machine will belongs to", true),
@@ -70,13 +96,17 @@ func init() {
 					checkRequiredFlags, destroyMachineCmd,
 				),
 			},
+			rotateAlias,
 			{
 				Name:  "list",
 				Usage: "List machines for an organization",
 				Flags: []cli.Flag{
 					orgFlag("Org the machine belongs to", true),
 					roleFlag("List machines of this role", false),
+					newPlaceholder("state", "STATE", "List machines in this state (active, destroyed)",
+						primitive.MachineActiveState, "", false),
 					destroyedFlag(),
+					formatFlag("table", "Format used to display data (table, json)"),
 				},
 				Action: chain(
 					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
@@ -90,7 +120,7 @@ func init() {
 				Subcommands: []cli.Command{
 					{
 						Name:  "list",
-						Usage: "List all machine roles for an organization",
+						Usage: "List all machine roles for an organization, and how many machines have each",
 						Flags: []cli.Flag{
 							orgFlag("Org the machine roles belongs to", true),
 						},
@@ -111,6 +141,7 @@ func init() {
 							checkRequiredFlags, createMachineRole,
 						),
 					},
+					rotateTokenCmd,
 				},
 			},
 		},
@@ -147,12 +178,95 @@ func destroyMachineCmd(ctx *cli.Context) error {
 		return errs.NewExitError("Org not found.")
 	}
 
+	var machine *apitypes.MachineSegment
+	machineID, err := identity.DecodeFromString(args[0])
+	if err == nil {
+		machine, err = client.Machines.Get(c, &machineID)
+		if err != nil {
+			return errs.NewErrorExitError("Failed to retrieve machine", err)
+		}
+	} else {
+		name := args[0]
+		machines, lErr := client.Machines.List(c, org.ID, nil, &name, nil)
+		if lErr != nil {
+			return errs.NewErrorExitError("Failed to retrieve machine", lErr)
+		}
+		if len(machines) < 1 {
+			return errs.NewNotFoundExitError("Machine not found")
+		}
+		machine = machines[0]
+		machineID = *machine.Machine.ID
+	}
+
+	machineName := machine.Machine.Body.Name
+	if machine.Machine.Body.State == primitive.MachineDestroyedState {
+		fmt.Printf("Machine %s is already destroyed; nothing to do.\n", machineName)
+		return nil
+	}
+
+	preamble := fmt.Sprintf("You are about to destroy the machine %s, and revoke its tokens. "+
+		"This cannot be undone.", machineName)
+	abortErr := ConfirmDialogue(ctx, nil, &preamble)
+	if abortErr != nil {
+		return abortErr
+	}
+
+	revoked, err := client.Machines.Destroy(c, &machineID)
+	if err == api.ErrMachineDestroyed {
+		fmt.Printf("Machine %s is already destroyed; nothing to do.\n", machineName)
+		return nil
+	}
+	if err != nil {
+		return errs.NewErrorExitError("Failed to destroy machine", err)
+	}
+
+	fmt.Printf("Machine %s destroyed; %d token(s) revoked.\n", machineName, revoked)
+
+	_, err = rotateKeyrings(client, org.ID, "The machine was destroyed, but its keyrings "+
+		"were not all rotated. Run 'torus worklog resolve' to rotate the rest.")
+	return err
+}
+
+func rotateMachineTokenCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) > 1 {
+		return errs.NewUsageExitError("Too many arguments supplied.", ctx)
+	}
+	if len(args) < 1 {
+		return errs.NewUsageExitError("Name or ID is required", ctx)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	org, err := getOrg(c, client, ctx.String("org"))
+	if err != nil {
+		return errs.NewErrorExitError("Machine token rotation failed", err)
+	}
+	if org == nil {
+		return errs.NewExitError("Org not found.")
+	}
+
+	session, err := client.Session.Who(c)
+	if err != nil {
+		return errs.NewErrorExitError("Machine token rotation failed", err)
+	}
+	err = preflightRole(c, client, session, org.ID, org.Body.Name, primitive.AdminTeamName, "rotate machine tokens")
+	if err != nil {
+		return err
+	}
+
 	machineID, err := identity.DecodeFromString(args[0])
 	if err != nil {
 		name := args[0]
 		machines, lErr := client.Machines.List(c, org.ID, nil, &name, nil)
 		if lErr != nil {
-			return errs.NewErrorExitError("Failed to retrieve machine", err)
+			return errs.NewErrorExitError("Failed to retrieve machine", lErr)
 		}
 		if len(machines) < 1 {
 			return errs.NewExitError("Machine not found")
@@ -160,18 +274,59 @@ func destroyMachineCmd(ctx *cli.Context) error {
 		machineID = *machines[0].Machine.ID
 	}
 
-	preamble := "You are about to destroy a machine. This cannot be undone."
+	machineSegment, err := client.Machines.Get(c, &machineID)
+	if err != nil {
+		return errs.NewErrorExitError("Failed to retrieve machine", err)
+	}
+
+	var tokenID *identity.ID
+	if raw := ctx.String("token"); raw != "" {
+		id, err := identity.DecodeFromString(raw)
+		if err != nil {
+			return errs.NewUsageExitError("Invalid token ID", ctx)
+		}
+		tokenID = &id
+	} else {
+		var active []*identity.ID
+		for _, t := range machineSegment.Tokens {
+			if t.Token.Body.State == primitive.MachineTokenActiveState {
+				active = append(active, t.Token.ID)
+			}
+		}
+		switch len(active) {
+		case 0:
+			return errs.NewExitError("Machine has no active tokens.")
+		case 1:
+			tokenID = active[0]
+		default:
+			return errs.NewUsageExitError("Machine has multiple active tokens, --token is required", ctx)
+		}
+	}
+
+	if dryRun() {
+		printPlan([]planStep{{"rotate token", tokenID.String() + " of machine " + machineSegment.Machine.Body.Name}})
+		return nil
+	}
+
+	preamble := "You are about to rotate a machine token. The existing token will stop working immediately."
 	abortErr := ConfirmDialogue(ctx, nil, &preamble)
 	if abortErr != nil {
 		return abortErr
 	}
 
-	err = client.Machines.Destroy(c, &machineID)
+	token, tokenSecret, err := client.Machines.RotateToken(c, &machineID, tokenID, &progress)
 	if err != nil {
-		return errs.NewErrorExitError("Failed to destroy machine", err)
+		return errs.NewErrorExitError("Could not rotate machine token, please try again.", err)
 	}
 
-	fmt.Println("Machine destroyed.")
+	fmt.Print("\nYou will only be shown the secret once, please keep it safe.\n\n")
+
+	w := tabwriter.NewWriter(os.Stdout, 2, 0, 1, ' ', 0)
+	fmt.Fprintf(w, "Machine ID:\t%s\n", machineID)
+	fmt.Fprintf(w, "Machine Token ID:\t%s\n", token.Token.ID)
+	fmt.Fprintf(w, "Machine Token Secret:\t%s\n", tokenSecret)
+	w.Flush()
+
 	return nil
 }
 
@@ -329,14 +484,22 @@ func listMachinesCmd(ctx *cli.Context) error {
 	}
 	orgID := org.ID
 
-	state := primitive.MachineActiveState
+	state := ctx.String("state")
 	if ctx.Bool("destroyed") {
 		state = primitive.MachineDestroyedState
 	}
+	if state != primitive.MachineActiveState && state != primitive.MachineDestroyedState {
+		return errs.NewUsageExitError("Unknown state: "+state, ctx)
+	}
+
+	format := ctx.String("format")
+	if format != "table" && format != "json" {
+		return errs.NewUsageExitError("Unknown format: "+format, ctx)
+	}
 
-	if ctx.String("role") != "" && ctx.Bool("destroyed") {
+	if ctx.String("role") != "" && state == primitive.MachineDestroyedState {
 		return errs.NewExitError(
-			"Cannot specify --destroyed and --role at the same time")
+			"Cannot specify a destroyed state and --role at the same time")
 	}
 
 	teams, err := client.Teams.List(c, org.ID, ctx.String("role"), primitive.MachineTeam)
@@ -364,12 +527,16 @@ func listMachinesCmd(ctx *cli.Context) error {
 		}
 	}
 
-	fmt.Println("")
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', 0)
-	fmt.Fprintln(w, "ID\tNAME\tSTATE\tROLE\tCREATION DATE")
-	fmt.Fprintln(w, " \t \t \t \t ")
-	for _, machine := range machines {
-		mID := machine.Machine.ID.String()
+	type machineRow struct {
+		ID      *identity.ID `json:"id"`
+		Name    string       `json:"name"`
+		State   string       `json:"state"`
+		Role    string       `json:"role"`
+		Created time.Time    `json:"created_at"`
+	}
+
+	rows := make([]machineRow, len(machines))
+	for i, machine := range machines {
 		m := machine.Machine.Body
 		teamName := "-"
 		for _, m := range machine.Memberships {
@@ -378,7 +545,27 @@ func listMachinesCmd(ctx *cli.Context) error {
 				teamName = team.Name
 			}
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", mID, m.Name, m.State, teamName, m.Created.Format(time.RFC3339))
+		rows[i] = machineRow{
+			ID:      machine.Machine.ID,
+			Name:    m.Name,
+			State:   m.State,
+			Role:    teamName,
+			Created: m.Created,
+		}
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	}
+
+	fmt.Println("")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSTATE\tROLE\tCREATION DATE")
+	fmt.Fprintln(w, " \t \t \t \t ")
+	for _, r := range rows {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.ID, r.Name, r.State, r.Role, r.Created.Format(time.RFC3339))
 	}
 	w.Flush()
 	fmt.Println("")
@@ -408,23 +595,20 @@ func listMachineRoles(ctx *cli.Context) error {
 		return errs.NewExitError("Org not found.")
 	}
 
-	teams, err := client.Teams.List(c, org.ID, "", "")
+	roles, err := client.Roles.List(c, org.ID)
 	if err != nil {
 		return errs.NewErrorExitError("Failed to retrieve roles", err)
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 20, 0, 1, ' ', 0)
-	for _, t := range teams {
-		if !isMachineTeam(t.Body) {
-			continue
-		}
-
-		displayTeamType := ""
-		if t.Body.TeamType == primitive.SystemTeam && t.Body.Name == primitive.MachineTeamName {
-			displayTeamType = "[system]"
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
+	fmt.Fprintln(w, "ROLE\tMACHINES\t")
+	for _, r := range roles {
+		name := r.Name
+		if r.System {
+			name += " [system]"
 		}
 
-		fmt.Fprintf(w, "%s\t%s\n", t.Body.Name, displayTeamType)
+		fmt.Fprintf(w, "%s\t%d\t\n", name, r.Machines)
 	}
 
 	w.Flush()
@@ -483,12 +667,11 @@ func createMachineRole(ctx *cli.Context) error {
 	}
 
 	fmt.Println("")
-	_, err = client.Teams.Create(c, orgID, teamName, primitive.MachineTeam)
+	_, err = client.Roles.Create(c, orgID, teamName)
+	if err == api.ErrRoleExists {
+		return errs.NewExitError("Role already exists")
+	}
 	if err != nil {
-		if strings.Contains(err.Error(), "resource exists") {
-			return errs.NewExitError("Role already exists")
-		}
-
 		return errs.NewErrorExitError("Role creation failed.", err)
 	}
 
@@ -559,7 +742,10 @@ func createMachine(ctx *cli.Context) error {
 	}
 
 	if newTeam {
-		team, err := client.Teams.Create(c, orgID, teamName, primitive.MachineTeam)
+		team, err := client.Roles.Create(c, orgID, teamName)
+		if err == api.ErrRoleExists {
+			return errs.NewExitError("Role already exists")
+		}
 		if err != nil {
 			return errs.NewErrorExitError("Could not create machine role", err)
 		}
@@ -592,7 +778,7 @@ func createMachineByName(c context.Context, client *api.Client,
 	machine, tokenSecret, err := client.Machines.Create(
 		c, orgID, teamID, name, &progress)
 	if err != nil {
-		if strings.Contains(err.Error(), "resource exists") {
+		if apitypes.HasMessage(err, "resource exists") {
 			return nil, nil, errs.NewExitError("Machine already exists")
 		}
 
@@ -35,11 +35,21 @@ func chain(funcs ...func(*cli.Context) error) func(*cli.Context) error {
 	}
 }
 
+// daemonChecked records that ensureDaemon has already found a running,
+// compatible daemon in this process, so later calls can skip the round trip.
+var daemonChecked bool
+
 // ensureDaemon ensures that the daemon is running, and is the correct version,
 // before a command is exeucted.
 // the daemon will be started/restarted once, to try and launch the latest
-// version.
+// version. If the daemon's version still differs from the cli's in its major
+// or minor version, a warning is printed, or with --strict-version, an error
+// is returned.
 func ensureDaemon(ctx *cli.Context) error {
+	if daemonChecked {
+		return nil
+	}
+
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		return err
@@ -78,11 +88,26 @@ func ensureDaemon(ctx *cli.Context) error {
 	}
 
 	if v.Version == cfg.Version {
+		daemonChecked = true
 		return nil
 	}
 
 	if spawned {
-		return errs.NewExitError("The daemon version is incorrect. Check for stale processes.")
+		if sameMinorVersion(v.Version, cfg.Version) {
+			daemonChecked = true
+			return nil
+		}
+
+		msg := fmt.Sprintf("The daemon version (%s) does not match the cli version (%s).\n"+
+			"Check for stale processes, then restart the daemon with '%s daemon stop'.",
+			v.Version, cfg.Version, ctx.App.Name)
+		if os.Getenv("TORUS_STRICT_VERSION") != "" {
+			return errs.NewExitError(msg)
+		}
+
+		warn(msg)
+		daemonChecked = true
+		return nil
 	}
 
 	fmt.Println("The daemon version is out of date and is being restarted.")
@@ -96,6 +121,23 @@ func ensureDaemon(ctx *cli.Context) error {
 	return ensureDaemon(ctx)
 }
 
+// sameMinorVersion reports whether versions a and b share their major and
+// minor version numbers. Versions that aren't dotted numbers only match
+// themselves.
+func sameMinorVersion(a, b string) bool {
+	if a == b {
+		return true
+	}
+
+	ap := strings.SplitN(a, ".", 3)
+	bp := strings.SplitN(b, ".", 3)
+	if len(ap) < 2 || len(bp) < 2 {
+		return false
+	}
+
+	return ap[0] == bp[0] && ap[1] == bp[1]
+}
+
 // ensureSession ensures that the user is logged in with the daemon and has a
 // valid session. If not, it will attempt to log the user in via environment
 // variables. If they do not exist, of the login fails, it will abort the
@@ -134,7 +176,7 @@ func ensureSession(ctx *cli.Context) error {
 	if hasEmail && hasPassword {
 		fmt.Println("Attempting to login with email: " + email)
 
-		err := client.Session.UserLogin(bgCtx, email, password)
+		err := client.Session.UserLogin(bgCtx, email, []byte(password))
 		if err != nil {
 			fmt.Println("Could not log in.\n" + err.Error())
 		} else {
@@ -145,7 +187,7 @@ func ensureSession(ctx *cli.Context) error {
 	if hasTokenID && hasTokenSecret {
 		fmt.Println("Attempting to login with token id: " + tokenID)
 
-		err := client.Session.MachineLogin(bgCtx, tokenID, tokenSecret)
+		err := machineLogin(bgCtx, client, tokenID, tokenSecret)
 		if err != nil {
 			fmt.Println("Could not log in\n" + err.Error())
 		} else {
@@ -174,14 +216,64 @@ func loadDirPrefs(ctx *cli.Context) error {
 }
 
 // loadPrefDefaults loads default argument values from the .torusrc
-// preferences file defaults section, inserting them into any unset flag values
+// preferences file defaults section, inserting them into any unset flag values.
+// If a required org flag is still unset, and the user belongs to exactly one
+// org, that org is used.
 func loadPrefDefaults(ctx *cli.Context) error {
 	p, err := prefs.NewPreferences(true)
 	if err != nil {
 		return err
 	}
 
-	return reflectArgs(ctx, p, p.Defaults, "ini")
+	err = reflectArgs(ctx, p, p.Defaults, "ini")
+	if err != nil {
+		return err
+	}
+
+	if p.Core.DisableAutoOrg {
+		return nil
+	}
+
+	return setSingleOrg(ctx)
+}
+
+// setSingleOrg populates the org argument, if it is required and unset, with
+// the name of the only org the user belongs to. If they belong to more than
+// one org, the argument is left unset so they must choose.
+func setSingleOrg(ctx *cli.Context) error {
+	argName := "org"
+	required := false
+	for _, f := range ctx.Command.Flags {
+		if pf, ok := f.(placeHolderStringFlag); ok &&
+			strings.SplitN(pf.GetName(), ",", 2)[0] == argName {
+			required = pf.Required
+			break
+		}
+	}
+	if !required || ctx.String(argName) != "" {
+		return nil
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	orgs, err := client.Orgs.List(context.Background())
+	if err != nil {
+		return errs.NewErrorExitError("Could not retrieve your orgs.", err)
+	}
+	if len(orgs) != 1 {
+		return nil
+	}
+
+	name := orgs[0].Body.Name
+	if cfg.Verbose {
+		fmt.Fprintf(os.Stderr, "Using org %s, the only org you belong to.\n", name)
+	}
+
+	return ctx.Set(argName, name)
 }
 
 func reflectArgs(ctx *cli.Context, p *prefs.Preferences, i interface{},
@@ -201,7 +293,7 @@ func reflectArgs(ctx *cli.Context, p *prefs.Preferences, i interface{},
 	flags := make(map[string]bool)
 	for _, flagName := range ctx.FlagNames() {
 		// This value is already set via arguments or env vars. skip it.
-		if isSet(ctx, flagName) {
+		if isSet(ctx, flagName) && !usesFlagDefault(ctx, flagName) {
 			continue
 		}
 
@@ -279,6 +371,32 @@ func setSliceDefaults(ctx *cli.Context) error {
 	return nil
 }
 
+// prefsOverrideDefault are the flags whose declared default value gives way
+// to a value from prefs or a linked directory.
+var prefsOverrideDefault = map[string]bool{
+	"environment": true,
+	"service":     true,
+}
+
+// usesFlagDefault returns whether the named flag holds the default value it
+// was declared with, rather than one given on the command line, through an
+// env var, or by earlier middleware. Only the flags in prefsOverrideDefault
+// are considered; others are never reported as using their default.
+func usesFlagDefault(ctx *cli.Context, name string) bool {
+	if !prefsOverrideDefault[name] || ctx.IsSet(name) {
+		return false
+	}
+
+	for _, f := range ctx.Command.Flags {
+		if pf, ok := f.(placeHolderStringFlag); ok &&
+			strings.SplitN(pf.GetName(), ",", 2)[0] == name {
+			return pf.Value != "" && ctx.String(name) == pf.Value
+		}
+	}
+
+	return false
+}
+
 func isSet(ctx *cli.Context, name string) bool {
 	value := ctx.Generic(name)
 	if value != nil {
@@ -135,6 +135,54 @@ func TestReflectArgs(t *testing.T) {
 			t.Error("loadPrefDefaults did not set argument")
 		}
 	})
+
+	t.Run("Overwrites a flag's default value", func(t *testing.T) {
+		cmd := cli.Command{
+			Flags: []cli.Flag{serviceFlag("", "default", true)},
+		}
+		p := &prefs.Preferences{
+			Core:     prefs.Core{Context: true},
+			Defaults: prefs.Defaults{Service: "api"},
+		}
+
+		flagset := flag.NewFlagSet("", flag.ContinueOnError)
+		flagset.String("service", "default", "")
+		ctx := cli.NewContext(nil, flagset, nil)
+		ctx.Command = cmd
+
+		err := reflectArgs(ctx, p, p.Defaults, "ini")
+		if err != nil {
+			t.Error("loadPrefDefaults errored: " + err.Error())
+		}
+
+		if ctx.String("service") != "api" {
+			t.Error("loadPrefDefaults did not replace the flag's default value")
+		}
+	})
+
+	t.Run("Keeps other flags' default values", func(t *testing.T) {
+		cmd := cli.Command{
+			Flags: []cli.Flag{instanceFlag("", true)},
+		}
+		p := &prefs.Preferences{Core: prefs.Core{Context: true}}
+		defaults := struct {
+			Instance string `ini:"instance"`
+		}{"2"}
+
+		flagset := flag.NewFlagSet("", flag.ContinueOnError)
+		flagset.String("instance", "1", "")
+		ctx := cli.NewContext(nil, flagset, nil)
+		ctx.Command = cmd
+
+		err := reflectArgs(ctx, p, defaults, "ini")
+		if err != nil {
+			t.Error("loadPrefDefaults errored: " + err.Error())
+		}
+
+		if ctx.String("instance") != "1" {
+			t.Error("loadPrefDefaults replaced the default value of instance")
+		}
+	})
 }
 
 func TestCheckRequiredFlags(t *testing.T) {
@@ -183,3 +231,24 @@ func TestCheckRequiredFlags(t *testing.T) {
 		}
 	})
 }
+
+func TestSameMinorVersion(t *testing.T) {
+	testCases := []struct {
+		a, b string
+		same bool
+	}{
+		{a: "0.16.0", b: "0.16.0", same: true},
+		{a: "0.16.0", b: "0.16.2", same: true},
+		{a: "0.16.0", b: "0.16.0-rc1", same: true},
+		{a: "0.16.0", b: "0.17.0", same: false},
+		{a: "0.16.0", b: "1.16.0", same: false},
+		{a: "alpha", b: "alpha", same: true},
+		{a: "alpha", b: "0.16.0", same: false},
+	}
+
+	for _, tc := range testCases {
+		if res := sameMinorVersion(tc.a, tc.b); res != tc.same {
+			t.Errorf("Expected sameMinorVersion(%s, %s) = %t", tc.a, tc.b, tc.same)
+		}
+	}
+}
@@ -2,16 +2,25 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"text/tabwriter"
+	"time"
 
 	"github.com/urfave/cli"
 
 	"github.com/manifoldco/torus-cli/api"
 	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/config"
+	"github.com/manifoldco/torus-cli/dirprefs"
 	"github.com/manifoldco/torus-cli/errs"
 	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
 )
 
 func init() {
@@ -27,10 +36,72 @@ func init() {
 				Action:    chain(ensureDaemon, orgsCreate),
 			},
 			{
-				Name:   "list",
-				Usage:  "List organizations associated with your account",
+				Name:  "list",
+				Usage: "List organizations associated with your account, and your role in each",
+				Flags: []cli.Flag{
+					formatFlag("table", "Output format (table or json)"),
+				},
 				Action: chain(ensureDaemon, ensureSession, orgsListCmd),
 			},
+			{
+				Name:      "rename",
+				Usage:     "Rename an organization",
+				ArgsUsage: "<old> <new>",
+				Action:    chain(ensureDaemon, ensureSession, orgsRename),
+			},
+			{
+				Name:      "delete",
+				Usage:     "Delete an organization and everything in it",
+				ArgsUsage: "<name>",
+				Action:    chain(ensureDaemon, ensureSession, orgsDelete),
+			},
+			{
+				Name:      "transfer",
+				Usage:     "Make a member of an organization one of its owners",
+				ArgsUsage: "<name> <username>",
+				Flags: []cli.Flag{
+					cli.BoolFlag{
+						Name:  "demote",
+						Usage: "Remove yourself from the owner team once the transfer is done",
+					},
+				},
+				Action: chain(ensureDaemon, ensureSession, orgsTransfer),
+			},
+			{
+				Name:  "audit",
+				Usage: "View the audit log of an organization",
+				Flags: []cli.Flag{
+					orgFlag("org to view the audit log of", true),
+					newPlaceholder("since", "TIME",
+						"Only show events at or after this time, as RFC 3339, YYYY-MM-DD or a duration ago such as 24h", "", "", false),
+					newPlaceholder("until", "TIME",
+						"Only show events before this time, as RFC 3339, YYYY-MM-DD or a duration ago such as 24h", "", "", false),
+					newPlaceholder("actor", "NAME", "Only show events by this user or machine", "", "", false),
+					formatFlag("table", "Output format (table or json)"),
+				},
+				Action: chain(
+					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+					checkRequiredFlags, orgsAuditCmd,
+				),
+			},
+			{
+				Name:  "members",
+				Usage: "View the members of an organization",
+				Subcommands: []cli.Command{
+					{
+						Name:  "list",
+						Usage: "List the users and machines in an org, and the teams they belong to",
+						Flags: []cli.Flag{
+							orgFlag("org to list members of", true),
+							teamFlag("Only list members of this team", false),
+						},
+						Action: chain(
+							ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+							checkRequiredFlags, orgsMembersListCmd,
+						),
+					},
+				},
+			},
 			{
 				Name:      "remove",
 				Usage:     "Remove a user from an org",
@@ -95,34 +166,174 @@ func createOrgByName(c context.Context, ctx *cli.Context, client *api.Client, na
 		return nil, errs.NewExitError(msg)
 	}
 
-	fmt.Println("Org " + org.Body.Name + " created.")
+	decorate("Org " + org.Body.Name + " created.")
 	return org, nil
 }
 
+// orgListing is an org as printed by orgs list --format json.
+type orgListing struct {
+	ID       *identity.ID `json:"id"`
+	Name     string       `json:"name"`
+	Role     string       `json:"role"`
+	Personal bool         `json:"personal"`
+}
+
 func orgsListCmd(ctx *cli.Context) error {
-	orgs, session, err := orgsList()
+	format := ctx.String("format")
+	if format != "table" && format != "json" {
+		return errs.NewUsageExitError("Unknown format: "+format, ctx)
+	}
+
+	cfg, err := config.LoadConfig()
 	if err != nil {
 		return err
 	}
 
-	withoutPersonal := orgs
+	client := api.NewClient(cfg)
+	c := context.Background()
 
-	if session.Type() == apitypes.UserSession {
-		for i, o := range orgs {
-			if o.Body.Name == session.Username() {
-				fmt.Printf("  %s [personal]\n", o.Body.Name)
-				withoutPersonal = append(orgs[:i], orgs[i+1:]...)
-			}
+	session, err := client.Session.Who(c)
+	if err != nil {
+		return errs.NewErrorExitError("Error fetching user details", err)
+	}
+
+	roles, err := client.Orgs.ListWithRoles(c, session)
+	if err != nil {
+		return errs.NewErrorExitError("Error fetching orgs list", err)
+	}
+
+	listings := make([]orgListing, len(roles))
+	for i, r := range roles {
+		listings[i] = orgListing{
+			ID:   r.Org.ID,
+			Name: r.Org.Body.Name,
+			Role: r.Role,
+			Personal: session.Type() == apitypes.UserSession &&
+				r.Org.Body.Name == session.Username(),
 		}
 	}
 
-	for _, o := range withoutPersonal {
-		fmt.Printf("  %s\n", o.Body.Name)
+	// The personal org always comes first.
+	sort.Stable(personalFirst(listings))
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(listings)
 	}
 
-	return nil
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
+	fmt.Fprintln(w, "ORG\tROLE\t")
+	for _, l := range listings {
+		name := l.Name
+		if l.Personal {
+			name += " [personal]"
+		}
+		fmt.Fprintf(w, "%s\t%s\t\n", name, l.Role)
+	}
+	return w.Flush()
 }
 
+// auditPageSize is the number of audit events requested per page.
+const auditPageSize = 100
+
+func orgsAuditCmd(ctx *cli.Context) error {
+	format := ctx.String("format")
+	if format != "table" && format != "json" {
+		return errs.NewUsageExitError("Unknown format: "+format, ctx)
+	}
+
+	now := time.Now()
+	opts := api.AuditLogOptions{Actor: ctx.String("actor"), Limit: auditPageSize}
+
+	var err error
+	if v := ctx.String("since"); v != "" {
+		opts.Since, err = parseTimeFlag(v, now)
+		if err != nil {
+			return errs.NewUsageExitError("Invalid --since: "+err.Error(), ctx)
+		}
+	}
+	if v := ctx.String("until"); v != "" {
+		opts.Until, err = parseTimeFlag(v, now)
+		if err != nil {
+			return errs.NewUsageExitError("Invalid --until: "+err.Error(), ctx)
+		}
+	}
+	if !opts.Since.IsZero() && !opts.Until.IsZero() && !opts.Since.Before(opts.Until) {
+		return errs.NewUsageExitError("--since must be before --until", ctx)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	org, err := getOrg(c, client, ctx.String("org"))
+	if err != nil {
+		return err
+	}
+
+	events := []apitypes.AuditEvent{}
+	for {
+		page, cursor, err := client.Orgs.AuditLog(c, org.ID, &opts)
+		if apiErr, ok := err.(*apitypes.Error); ok && apiErr.Type == apitypes.NotImplementedError {
+			return errs.NewExitError("The Torus registry you are connected to doesn't support audit logs.")
+		}
+		if err != nil {
+			return errs.NewErrorExitError("Could not retrieve the audit log.", err)
+		}
+
+		events = append(events, page...)
+		if cursor.Next <= opts.Offset || len(page) == 0 {
+			break
+		}
+		opts.Offset = cursor.Next
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(events)
+	}
+
+	if len(events) == 0 {
+		fmt.Println("No audit events found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
+	fmt.Fprintln(w, "TIME\tACTOR\tACTION\tTARGET\t")
+	for _, e := range events {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n", e.Created.Format(time.RFC3339), e.Actor, e.Action, e.Target)
+	}
+	return w.Flush()
+}
+
+// parseTimeFlag parses a time given as RFC 3339, as a YYYY-MM-DD date in the
+// local timezone, or as a duration before now, such as 24h.
+func parseTimeFlag(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+
+	return time.Time{}, fmt.Errorf("%q is not an RFC 3339 time, YYYY-MM-DD date or duration", value)
+}
+
+type personalFirst []orgListing
+
+func (p personalFirst) Len() int           { return len(p) }
+func (p personalFirst) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p personalFirst) Less(i, j int) bool { return p[i].Personal && !p[j].Personal }
+
 func orgsList() ([]api.OrgResult, *api.Session, error) {
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -156,6 +367,268 @@ func orgsList() ([]api.OrgResult, *api.Session, error) {
 	return orgs, session, nil
 }
 
+const orgRenameFailed = "Could not rename org."
+
+func orgsRename(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) < 2 || args[0] == "" || args[1] == "" {
+		return errs.NewUsageExitError("Missing old or new org name", ctx)
+	}
+	if len(args) > 2 {
+		return errs.NewUsageExitError("Too many arguments", ctx)
+	}
+	oldName, newName := args[0], args[1]
+
+	if err := validateSlug("Org")(newName); err != nil {
+		return errs.NewUsageExitError(err.Error(), ctx)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return errs.NewErrorExitError(orgRenameFailed, err)
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	org, err := getOrg(c, client, oldName)
+	if err != nil {
+		return err
+	}
+
+	existing, err := client.Orgs.GetByName(c, newName)
+	if err != nil {
+		return errs.NewErrorExitError(orgRenameFailed, err)
+	}
+	if existing != nil {
+		return errs.NewExitError("An org named " + newName + " already exists.")
+	}
+
+	updated, err := client.Orgs.Update(c, *org.ID, newName)
+	if err != nil {
+		return errs.NewErrorExitError(orgRenameFailed, err)
+	}
+
+	fmt.Printf("Org %s renamed to %s.\n", org.Body.Name, updated.Body.Name)
+	fmt.Println("\nLinked directories, preferences and integrations that refer to " +
+		org.Body.Name + " must be updated to use the new name.")
+
+	return nil
+}
+
+const orgDeleteFailed = "Could not delete org."
+
+func orgsDelete(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) < 1 || args[0] == "" {
+		return errs.NewUsageExitError("Missing org name", ctx)
+	}
+	if len(args) > 1 {
+		return errs.NewUsageExitError("Too many arguments", ctx)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return errs.NewErrorExitError(orgDeleteFailed, err)
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	org, err := getOrg(c, client, args[0])
+	if err != nil {
+		return err
+	}
+
+	session, err := client.Session.Who(c)
+	if err != nil {
+		return errs.NewErrorExitError(orgDeleteFailed, err)
+	}
+	err = preflightRole(c, client, session, org.ID, org.Body.Name, primitive.OwnerTeamName, "delete it")
+	if err != nil {
+		return err
+	}
+
+	if dryRun() {
+		printPlan([]planStep{{"delete org", org.Body.Name}})
+		return nil
+	}
+
+	warning := fmt.Sprintf("You are about to delete the %s org, including all of its projects, "+
+		"teams, machines and secrets. This cannot be undone.", org.Body.Name)
+	err = TypedConfirmPrompt(org.Body.Name, warning)
+	if err != nil {
+		return handleSelectError(err, orgDeleteFailed)
+	}
+
+	err = client.Orgs.Delete(c, *org.ID)
+	if err != nil {
+		if apiErr, ok := err.(*apitypes.Error); ok && apiErr.Type == apitypes.UnauthorizedError {
+			return errs.NewErrorExitError("Only owners of "+org.Body.Name+" can delete it.",
+				apitypes.FormatError(err))
+		}
+		return errs.NewErrorExitError(orgDeleteFailed, err)
+	}
+
+	fmt.Printf("Org %s deleted.\n", org.Body.Name)
+
+	dPrefs, err := dirprefs.Load(true)
+	if err != nil {
+		return errs.NewErrorExitError("Could not read linked directory.", err)
+	}
+	if dPrefs.Path != "" && dPrefs.Organization == org.Body.Name {
+		err = dPrefs.Remove()
+		if err != nil {
+			return errs.NewErrorExitError("Could not remove link", err)
+		}
+		fmt.Printf("Unlinked %s, which referred to the deleted org.\n", filepath.Dir(dPrefs.Path))
+	}
+
+	return nil
+}
+
+const orgTransferFailed = "Could not transfer org."
+
+func orgsTransfer(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) < 2 || args[0] == "" || args[1] == "" {
+		return errs.NewUsageExitError("Missing org name or username", ctx)
+	}
+	if len(args) > 2 {
+		return errs.NewUsageExitError("Too many arguments", ctx)
+	}
+	username := args[1]
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return errs.NewErrorExitError(orgTransferFailed, err)
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	session, err := client.Session.Who(c)
+	if err != nil {
+		return errs.NewErrorExitError(orgTransferFailed, err)
+	}
+	if session.Type() != apitypes.UserSession {
+		return errs.NewExitError("Machines can't transfer orgs.")
+	}
+	if username == session.Username() {
+		return errs.NewExitError("You can't transfer an org to yourself.")
+	}
+
+	org, err := getOrg(c, client, args[0])
+	if err != nil {
+		return err
+	}
+
+	teams, err := client.Teams.List(c, org.ID, primitive.OwnerTeamName, primitive.SystemTeam)
+	if err != nil {
+		return errs.NewErrorExitError(orgTransferFailed, err)
+	}
+	if len(teams) < 1 {
+		return errs.NewExitError("Could not find the owner team of " + org.Body.Name + ".")
+	}
+	owners := teams[0]
+
+	err = preflightRole(c, client, session, org.ID, org.Body.Name, primitive.OwnerTeamName, "transfer it")
+	if err != nil {
+		return err
+	}
+
+	profile, err := client.Profiles.ListByName(c, username)
+	if apitypes.IsNotFoundError(err) || (err == nil && profile == nil) {
+		return errs.NewExitError("User not found.")
+	}
+	if err != nil {
+		return errs.NewErrorExitError(orgTransferFailed, err)
+	}
+
+	memberships, err := client.Memberships.List(c, org.ID, profile.ID, nil)
+	if err != nil {
+		return errs.NewErrorExitError(orgTransferFailed, err)
+	}
+	if len(memberships) < 1 {
+		return errs.NewExitError(username + " is not a member of " + org.Body.Name +
+			"; invite them before transferring the org.")
+	}
+
+	isOwner := false
+	for _, m := range memberships {
+		if *m.Body.TeamID == *owners.ID {
+			isOwner = true
+			break
+		}
+	}
+	demote := ctx.Bool("demote")
+
+	var ownership []api.MembershipResult
+	if demote {
+		ownership, err = client.Memberships.List(c, org.ID, session.ID(), owners.ID)
+		if err != nil {
+			return errs.NewErrorExitError(orgTransferFailed, err)
+		}
+		if len(ownership) < 1 {
+			return errs.NewExitError("You are not an owner of " + org.Body.Name + ".")
+		}
+	}
+
+	if isOwner && !demote {
+		fmt.Printf("%s is already an owner of %s; nothing to do.\n", username, org.Body.Name)
+		return nil
+	}
+
+	if dryRun() {
+		var steps []planStep
+		if !isOwner {
+			steps = append(steps, planStep{"add to owner team", username})
+		}
+		if demote {
+			steps = append(steps, planStep{"remove from owner team", session.Username()})
+		}
+		printPlan(steps)
+		return nil
+	}
+
+	warning := fmt.Sprintf("You are about to make %s an owner of the %s org.", username, org.Body.Name)
+	if demote {
+		warning += " You will no longer be an owner, and can't undo this yourself."
+	}
+	err = TypedConfirmPrompt(org.Body.Name, warning)
+	if err != nil {
+		return handleSelectError(err, orgTransferFailed)
+	}
+
+	if !isOwner {
+		err = client.Memberships.Create(c, profile.ID, org.ID, owners.ID)
+		if err != nil {
+			return orgTransferError(err, org.Body.Name)
+		}
+		fmt.Printf("%s is now an owner of %s.\n", username, org.Body.Name)
+	}
+
+	if demote {
+		err = client.Memberships.Delete(c, ownership[0].ID)
+		if err != nil {
+			return orgTransferError(err, org.Body.Name)
+		}
+		fmt.Printf("You are no longer an owner of %s.\n", org.Body.Name)
+	}
+
+	return nil
+}
+
+// orgTransferError explains a failed membership change during an org
+// transfer, such as the registry refusing a change the role check allowed.
+func orgTransferError(err error, org string) error {
+	if apiErr, ok := err.(*apitypes.Error); ok && apiErr.Type == apitypes.UnauthorizedError {
+		return errs.NewErrorExitError("You don't have permission to change the owners of "+org+".",
+			apitypes.FormatError(err))
+	}
+	return errs.NewErrorExitError(orgTransferFailed, err)
+}
+
 func orgsRemove(ctx *cli.Context) error {
 	args := ctx.Args()
 	if len(args) < 1 || args[0] == "" {
@@ -206,6 +679,87 @@ func orgsRemove(ctx *cli.Context) error {
 	}
 
 	fmt.Println("User has been removed from the org.")
+
+	rotated, err := rotateKeyrings(client, org.ID, "The user was removed, but their keyrings "+
+		"were not all rotated. Run 'torus worklog resolve' to rotate the rest.")
+	if err != nil {
+		return err
+	}
+	if len(rotated) > 0 {
+		fmt.Println("\nThe user may have seen these secrets; consider changing their values.")
+	}
+
+	return nil
+}
+
+func orgsMembersListCmd(ctx *cli.Context) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	org, err := getOrg(c, client, ctx.String("org"))
+	if err != nil {
+		return err
+	}
+
+	members, err := client.Orgs.Members(c, *org.ID)
+	if err != nil {
+		return errs.NewErrorExitError("Could not list members.", err)
+	}
+
+	teamName := ctx.String("team")
+	if teamName != "" {
+		teams, err := client.Teams.GetByName(c, org.ID, teamName)
+		if err != nil {
+			return errs.NewErrorExitError("Could not list members.", err)
+		}
+		if len(teams) != 1 {
+			return errs.NewNotFoundExitError("Team not found.")
+		}
+
+		var inTeam []api.OrgMember
+		for _, m := range members {
+			for _, t := range m.Teams {
+				if *t.ID == *teams[0].ID {
+					inTeam = append(inTeam, m)
+					break
+				}
+			}
+		}
+		members = inTeam
+	}
+
+	if len(members) == 0 {
+		fmt.Println("No members found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tUSERNAME\tNAME\tEMAIL\tTEAMS")
+	for _, m := range members {
+		username, email := m.Username, m.Email
+		if m.Type == api.MemberTypeMachine {
+			username = "-"
+		}
+		if email == "" {
+			email = "-"
+		}
+
+		teamNames := make([]string, len(m.Teams))
+		for i, t := range m.Teams {
+			teamNames[i] = t.Body.Name
+		}
+		sort.Strings(teamNames)
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", m.Type, username, m.Name, email,
+			strings.Join(teamNames, ", "))
+	}
+	w.Flush()
+
 	return nil
 }
 
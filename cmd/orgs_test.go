@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeFlag(t *testing.T) {
+	now := time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tcs := []struct {
+		value    string
+		expected time.Time
+	}{
+		{"2017-02-01T08:30:00Z", time.Date(2017, 2, 1, 8, 30, 0, 0, time.UTC)},
+		{"2017-02-01", time.Date(2017, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"36h", time.Date(2017, 2, 28, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range tcs {
+		got, err := parseTimeFlag(tc.value, now)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tc.value, err)
+		} else if !got.Equal(tc.expected) {
+			t.Errorf("%s: expected %s, got %s", tc.value, tc.expected, got)
+		}
+	}
+
+	for _, value := range []string{"yesterday", "-24h", "2017-13-01"} {
+		if _, err := parseTimeFlag(value, now); err == nil {
+			t.Errorf("%s: expected an error", value)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/errs"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+// roleRanks orders the roles a user can hold in an org. Machine roles rank
+// below them all.
+var roleRanks = map[string]int{
+	primitive.MemberTeamName: 1,
+	primitive.AdminTeamName:  2,
+	primitive.OwnerTeamName:  3,
+}
+
+// hasRole returns whether role is required or a role above it.
+func hasRole(role, required string) bool {
+	return roleRanks[role] >= roleRanks[required] && roleRanks[role] > 0
+}
+
+// roleHolders names, in the plural, the roles at or above required, such as
+// "owners and admins".
+func roleHolders(required string) string {
+	var names []string
+	for _, name := range []string{primitive.OwnerTeamName, primitive.AdminTeamName, primitive.MemberTeamName} {
+		if hasRole(name, required) {
+			names = append(names, name+"s")
+		}
+	}
+
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+// preflightRole checks, before any work is done, whether the session's role
+// in the org allows it to perform action, returning an error saying who can
+// if it doesn't.
+//
+// The check is advisory; the registry has the final say. If the session's
+// role can't be determined, the action is allowed to go ahead.
+func preflightRole(c context.Context, client *api.Client, session *api.Session,
+	orgID *identity.ID, orgName, required, action string) error {
+
+	role, err := client.Orgs.Role(c, session, orgID)
+	if err != nil || hasRole(role, required) {
+		return nil
+	}
+
+	msg := fmt.Sprintf("Only %s of the %s org can %s.", roleHolders(required), orgName, action)
+	if role != "" {
+		msg += " Your role is " + role + "."
+	}
+	return errs.NewExitError(msg)
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+func TestHasRole(t *testing.T) {
+	tcs := []struct {
+		role     string
+		required string
+		expected bool
+	}{
+		{primitive.OwnerTeamName, primitive.OwnerTeamName, true},
+		{primitive.OwnerTeamName, primitive.AdminTeamName, true},
+		{primitive.AdminTeamName, primitive.OwnerTeamName, false},
+		{primitive.AdminTeamName, primitive.AdminTeamName, true},
+		{primitive.MemberTeamName, primitive.AdminTeamName, false},
+		{primitive.MemberTeamName, primitive.MemberTeamName, true},
+		{"deploy", primitive.MemberTeamName, false},
+		{primitive.MachineTeamName, primitive.AdminTeamName, false},
+	}
+
+	for _, tc := range tcs {
+		if got := hasRole(tc.role, tc.required); got != tc.expected {
+			t.Errorf("%s requiring %s: expected %t, got %t", tc.role, tc.required, tc.expected, got)
+		}
+	}
+}
+
+func TestRoleHolders(t *testing.T) {
+	tcs := map[string]string{
+		primitive.OwnerTeamName:  "owners",
+		primitive.AdminTeamName:  "owners and admins",
+		primitive.MemberTeamName: "owners, admins and members",
+	}
+
+	for required, expected := range tcs {
+		if got := roleHolders(required); got != expected {
+			t.Errorf("%s: expected %q, got %q", required, expected, got)
+		}
+	}
+}
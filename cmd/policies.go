@@ -12,6 +12,7 @@ import (
 	"github.com/urfave/cli"
 
 	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/config"
 	"github.com/manifoldco/torus-cli/errs"
 	"github.com/manifoldco/torus-cli/identity"
@@ -149,7 +150,7 @@ func detachPolicies(ctx *cli.Context) error {
 
 	err = client.Policies.Detach(c, attachments[0].ID)
 	if err != nil {
-		if strings.Contains(err.Error(), "system team") {
+		if apitypes.HasMessage(err, "system team") {
 			return errs.NewExitError("Cannot delete system team attachment")
 		}
 		return errs.NewErrorExitError(policyDetachFailed, err)
@@ -83,11 +83,6 @@ func listPref(ctx *cli.Context) error {
 }
 
 func setPref(ctx *cli.Context) error {
-	preferencess, err := prefs.NewPreferences(false)
-	if err != nil {
-		return errs.NewErrorExitError("Failed to load prefs.", err)
-	}
-
 	args := ctx.Args()
 	key := args.Get(0)
 	value := args.Get(1)
@@ -99,6 +94,27 @@ func setPref(ctx *cli.Context) error {
 		return errs.NewExitError("Key must be have at least two dot delimited segments.")
 	}
 
+	err := savePref(key, value)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Preferences updated.")
+	return nil
+}
+
+// savePref validates value for the preference key, such as core.timeout, and
+// saves it to the torusrc file. The core keys settable with 'torus config' are
+// validated the same way here.
+func savePref(key, value string) error {
+	if name := strings.TrimPrefix(key, "core."); name != key {
+		if ck, ok := configKeys[name]; ok {
+			if err := ck.validate(value); err != nil {
+				return errs.NewExitError(fmt.Sprintf("Invalid value for %s: %s", name, err))
+			}
+		}
+	}
+
 	// Validate public key file
 	if key == "core.public_key_file" {
 		err := prefs.ValidatePublicKey(value)
@@ -112,26 +128,22 @@ func setPref(ctx *cli.Context) error {
 		}
 	}
 
-	// Set value inside prefs struct
-	result, err := preferencess.SetValue(key, value)
+	preferences, err := prefs.NewPreferences(false)
 	if err != nil {
-		return err
+		return errs.NewErrorExitError("Failed to load prefs.", err)
 	}
 
-	// Reflect struct to ini format
-	cfg := ini.Empty()
-	err = ini.ReflectFrom(cfg, &result)
+	// Set value inside prefs struct
+	result, err := preferences.SetValue(key, value)
 	if err != nil {
-		return errs.NewErrorExitError("Failed to save preferences.", err)
+		return err
 	}
 
-	// Save updated ini to filePath
-	rcPath, _ := prefs.RcPath()
-	err = cfg.SaveTo(rcPath)
+	// Save updated ini to the torusrc file
+	err = prefs.Save(&result)
 	if err != nil {
 		return errs.NewErrorExitError("Failed to save preferences.", err)
 	}
 
-	fmt.Println("Preferences updated.")
 	return nil
 }
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/config"
+	"github.com/manifoldco/torus-cli/errs"
+)
+
+func init() {
+	profile := cli.Command{
+		Name:     "profile",
+		Usage:    "Manage your account profile",
+		Category: "ACCOUNT",
+		Subcommands: []cli.Command{
+			{
+				Name:  "update",
+				Usage: "Update your name or email address",
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "name",
+						Usage: "Change your name to this, without prompting",
+					},
+					cli.StringFlag{
+						Name:  "email",
+						Usage: "Change your email address to this, without prompting",
+					},
+					stdAutoAcceptFlag,
+				},
+				Action: chain(ensureDaemon, ensureSession, profileEdit),
+			},
+		},
+	}
+	Cmds = append(Cmds, profile)
+}
+
+const profileUpdateFailed = "Could not update profile."
+
+func profileEdit(ctx *cli.Context) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	session, err := client.Session.Who(c)
+	if err != nil {
+		return errs.NewErrorExitError(profileUpdateFailed, err)
+	}
+	if session.Type() == apitypes.MachineSession {
+		return errs.NewExitError("Machines do not have a profile to update.")
+	}
+
+	name := ctx.String("name")
+	email := ctx.String("email")
+
+	// Without either flag, ask for both values interactively.
+	if name == "" && email == "" {
+		name, err = FullNamePrompt()
+		if err != nil {
+			return handleSelectError(err, profileUpdateFailed)
+		}
+
+		email, err = EmailPrompt(session.Email())
+		if err != nil {
+			return handleSelectError(err, profileUpdateFailed)
+		}
+	} else {
+		if name != "" && !govalidator.StringMatches(name, namePattern) {
+			return errs.NewUsageExitError("Please enter a valid name", ctx)
+		}
+		if email != "" && !govalidator.IsEmail(email) {
+			return errs.NewUsageExitError("Please enter a valid email address", ctx)
+		}
+	}
+
+	delta := apitypes.ProfileUpdate{}
+	if name != session.Name() {
+		delta.Name = name
+	}
+	if email != session.Email() {
+		delta.Email = email
+	}
+
+	if delta.Name == "" && delta.Email == "" {
+		fmt.Println("No changes made.")
+		return nil
+	}
+
+	if delta.Email != "" {
+		preamble := "You will be required to re-verify your email address before taking any\n" +
+			"further actions within Torus."
+		abortErr := ConfirmDialogue(ctx, nil, &preamble)
+		if abortErr != nil {
+			return abortErr
+		}
+	}
+
+	_, err = client.Users.Update(c, delta)
+	if err != nil {
+		return errs.NewErrorExitError(profileUpdateFailed, err)
+	}
+
+	fmt.Println("Profile updated.")
+	if delta.Email != "" {
+		fmt.Printf("Check %s for a code, then run 'torus verify <code>'.\n", delta.Email)
+	}
+
+	return nil
+}
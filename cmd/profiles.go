@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/errs"
+	"github.com/manifoldco/torus-cli/prefs"
+)
+
+func init() {
+	profiles := cli.Command{
+		Name:     "profiles",
+		Usage:    "View and switch between registry profiles",
+		Category: "ACCOUNT",
+		Subcommands: []cli.Command{
+			{
+				Name:   "list",
+				Usage:  "List the profiles defined in your preferences",
+				Action: profilesListCmd,
+			},
+			{
+				Name:      "use",
+				Usage:     "Use this profile when --profile is not given",
+				ArgsUsage: "<name>",
+				Action:    profilesUseCmd,
+			},
+		},
+	}
+	Cmds = append(Cmds, profiles)
+}
+
+func profilesListCmd(ctx *cli.Context) error {
+	preferences, err := prefs.NewPreferences(true)
+	if err != nil {
+		return errs.NewErrorExitError("Failed to load prefs.", err)
+	}
+
+	profiles, err := prefs.LoadProfiles(preferences)
+	if err != nil {
+		return errs.NewErrorExitError("Failed to load profiles.", err)
+	}
+
+	active := preferences.ActiveProfile()
+
+	w := tabwriter.NewWriter(os.Stdout, 2, 0, 1, ' ', 0)
+	for _, p := range profiles {
+		marker := ""
+		if p.Name == active {
+			marker = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", marker, p.Name, p.RegistryURI)
+	}
+	w.Flush()
+
+	fmt.Println("\n  (*) active")
+	return nil
+}
+
+func profilesUseCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) < 1 || args[0] == "" {
+		return errs.NewUsageExitError("Missing profile name", ctx)
+	}
+	if len(args) > 1 {
+		return errs.NewUsageExitError("Too many arguments", ctx)
+	}
+	name := args[0]
+
+	preferences, err := prefs.NewPreferences(false)
+	if err != nil {
+		return errs.NewErrorExitError("Failed to load prefs.", err)
+	}
+
+	defaults, err := prefs.NewPreferences(true)
+	if err != nil {
+		return errs.NewErrorExitError("Failed to load prefs.", err)
+	}
+
+	_, err = prefs.LoadProfile(defaults, name)
+	if err != nil {
+		return err
+	}
+
+	preferences.Core.Profile = name
+	if name == prefs.DefaultProfile {
+		preferences.Core.Profile = ""
+	}
+
+	err = prefs.Save(preferences)
+	if err != nil {
+		return errs.NewErrorExitError("Failed to save preferences.", err)
+	}
+
+	fmt.Printf("Now using the %s profile.\n", name)
+	if os.Getenv("TORUS_PROFILE") != "" {
+		fmt.Println("TORUS_PROFILE or --profile is set, and takes precedence.")
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/chzyer/readline"
+)
+
+// progressBarWidth is the number of characters between the brackets of a
+// progress bar.
+const progressBarWidth = 20
+
+// progressCounter shows how many of a number of secrets have been processed,
+// as a bar on a line of stderr that is redrawn as work completes. It is only
+// shown when stderr is a terminal, so it never ends up in piped or redirected
+// output, and not with --quiet.
+type progressCounter struct {
+	w       io.Writer
+	label   string
+	total   int
+	done    int
+	visible bool
+	width   int // length of the line last drawn, so it can be cleared
+}
+
+// newProgressCounter returns a counter for total secrets. A total of 0 shows
+// only the label, for work whose progress can't be followed.
+func newProgressCounter(label string, total int) *progressCounter {
+	return &progressCounter{
+		w:       os.Stderr,
+		label:   label,
+		total:   total,
+		visible: !quiet() && readline.IsTerminal(int(os.Stderr.Fd())),
+	}
+}
+
+// hide keeps the counter from being shown, such as when output is meant to
+// be read by a program.
+func (p *progressCounter) hide() {
+	p.clear()
+	p.visible = false
+}
+
+// add records that n more secrets have been processed.
+func (p *progressCounter) add(n int) {
+	p.done += n
+	p.draw()
+}
+
+// draw shows the counter, replacing the line last drawn. Without a total,
+// only the label is shown.
+func (p *progressCounter) draw() {
+	if !p.visible {
+		return
+	}
+
+	line := p.label + "..."
+	if p.total > 0 {
+		filled := progressBarWidth * p.done / p.total
+		line = fmt.Sprintf("%s [%s%s] %d/%d", p.label, strings.Repeat("=", filled),
+			strings.Repeat(" ", progressBarWidth-filled), p.done, p.total)
+	}
+
+	// Pad to the width of the last line, in case this one is shorter.
+	fmt.Fprintf(p.w, "\r%-*s", p.width, line)
+	p.width = len(line)
+}
+
+// clear removes the counter, so any output that follows starts on a clean
+// line.
+func (p *progressCounter) clear() {
+	if !p.visible || p.width == 0 {
+		return
+	}
+
+	fmt.Fprintf(p.w, "\r%s\r", strings.Repeat(" ", p.width))
+	p.width = 0
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestProgressCounter(t *testing.T) {
+	t.Run("draws and clears", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		p := &progressCounter{w: buf, label: "Importing", total: 4, visible: true}
+
+		p.draw()
+		p.add(1)
+		p.clear()
+
+		last := "Importing [=====               ] 1/4"
+		expected := "\rImporting [                    ] 0/4" +
+			"\r" + last +
+			"\r" + strings.Repeat(" ", len(last)) + "\r"
+		if buf.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, buf.String())
+		}
+	})
+
+	t.Run("pads shorter lines", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		p := &progressCounter{w: buf, label: "Copying", total: 10, done: 10, width: 40, visible: true}
+
+		p.draw()
+		if got := buf.Len(); got != len("\r")+40 {
+			t.Errorf("Expected the line to be padded to 40 characters, got %q", buf.String())
+		}
+	})
+
+	t.Run("without a total", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		p := &progressCounter{w: buf, label: "Exporting secrets", visible: true}
+
+		p.draw()
+		if buf.String() != "\rExporting secrets..." {
+			t.Errorf("Expected only the label, got %q", buf.String())
+		}
+	})
+
+	t.Run("hidden", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		p := &progressCounter{w: buf, label: "Exporting", total: 2, visible: true}
+
+		p.hide()
+		p.add(2)
+		p.clear()
+		if buf.Len() != 0 {
+			t.Errorf("Expected nothing to be written, got %q", buf.String())
+		}
+	})
+}
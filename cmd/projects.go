@@ -10,6 +10,7 @@ import (
 	"github.com/urfave/cli"
 
 	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/config"
 	"github.com/manifoldco/torus-cli/errs"
 	"github.com/manifoldco/torus-cli/identity"
@@ -74,7 +75,7 @@ func listProjectsCmd(ctx *cli.Context) error {
 func listProjects(ctx *context.Context, client *api.Client, orgID *identity.ID, name *string) ([]api.ProjectResult, error) {
 	c, client, err := NewAPIClient(ctx, client)
 	if err != nil {
-		return nil, cli.NewExitError(projectListFailed, -1)
+		return nil, errs.NewErrorExitError(projectListFailed, err)
 	}
 
 	var orgIDs []*identity.ID
@@ -93,7 +94,7 @@ func listProjects(ctx *context.Context, client *api.Client, orgID *identity.ID,
 func listProjectsByOrgID(ctx *context.Context, client *api.Client, orgIDs []*identity.ID) ([]api.ProjectResult, error) {
 	c, client, err := NewAPIClient(ctx, client)
 	if err != nil {
-		return nil, cli.NewExitError(projectListFailed, -1)
+		return nil, errs.NewErrorExitError(projectListFailed, err)
 	}
 
 	return client.Projects.List(c, &orgIDs, nil)
@@ -102,24 +103,24 @@ func listProjectsByOrgID(ctx *context.Context, client *api.Client, orgIDs []*ide
 func listProjectsByOrgName(ctx *context.Context, client *api.Client, orgName string) ([]api.ProjectResult, error) {
 	c, client, err := NewAPIClient(ctx, client)
 	if err != nil {
-		return nil, cli.NewExitError(projectListFailed, -1)
+		return nil, errs.NewErrorExitError(projectListFailed, err)
 	}
 
 	// Look up the target org
 	var org *api.OrgResult
 	org, err = client.Orgs.GetByName(c, orgName)
 	if err != nil {
-		return nil, errs.NewExitError(projectListFailed)
+		return nil, errs.NewErrorExitError(projectListFailed, err)
 	}
 	if org == nil {
-		return nil, errs.NewExitError("Org not found.")
+		return nil, errs.NewNotFoundExitError("Org not found.")
 	}
 
 	// Pull all projects for the given orgID
 	orgIDs := []*identity.ID{org.ID}
 	projects, err := listProjectsByOrgID(&c, client, orgIDs)
 	if err != nil {
-		return nil, errs.NewExitError(projectListFailed)
+		return nil, errs.NewErrorExitError(projectListFailed, err)
 	}
 
 	return projects, nil
@@ -130,7 +131,7 @@ const projectCreateFailed = "Could not create project."
 func createProjectCmd(ctx *cli.Context) error {
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		return errs.NewExitError(projectCreateFailed)
+		return errs.NewErrorExitError(projectCreateFailed, err)
 	}
 
 	client := api.NewClient(cfg)
@@ -144,7 +145,7 @@ func createProjectCmd(ctx *cli.Context) error {
 	var orgID *identity.ID
 	if !newOrg {
 		if org == nil {
-			return errs.NewExitError("Org not found.")
+			return errs.NewNotFoundExitError("Org not found.")
 		}
 		orgID = org.ID
 	}
@@ -174,7 +175,7 @@ func createProjectCmd(ctx *cli.Context) error {
 			return err
 		}
 
-		fmt.Printf("Org %s created.\n\n", orgName)
+		decoratef("Org %s created.\n\n", orgName)
 	}
 
 	_, err = createProjectByName(c, client, orgID, name)
@@ -184,14 +185,14 @@ func createProjectCmd(ctx *cli.Context) error {
 func createProjectByName(c context.Context, client *api.Client, orgID *identity.ID, name string) (*api.ProjectResult, error) {
 	project, err := client.Projects.Create(c, orgID, name)
 	if orgID == nil {
-		return nil, errs.NewExitError("Org not found")
+		return nil, errs.NewNotFoundExitError("Org not found")
 	}
 	if err != nil {
-		if strings.Contains(err.Error(), "resource exists") {
+		if apitypes.HasMessage(err, "resource exists") {
 			return nil, errs.NewExitError("Project already exists")
 		}
 		return nil, errs.NewErrorExitError(projectCreateFailed, err)
 	}
-	fmt.Printf("Project %s created.\n", name)
+	decoratef("Project %s created.\n", name)
 	return project, nil
 }
@@ -3,9 +3,11 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/asaskevich/govalidator"
+	"github.com/chzyer/readline"
 	"github.com/urfave/cli"
 
 	"github.com/manifoldco/torus-cli/api"
@@ -69,6 +71,24 @@ func ConfirmDialogue(ctx *cli.Context, labelOverride, warningOverride *string) e
 	return err
 }
 
+// TypedConfirmPrompt asks the user to type expected to confirm an action
+// that is too destructive for a y/n confirmation.
+func TypedConfirmPrompt(expected string, warning string) error {
+	prompt := promptui.Prompt{
+		Label:    "Type " + expected + " to confirm",
+		Preamble: &warning,
+		Validate: func(input string) error {
+			if input == expected {
+				return nil
+			}
+			return promptui.NewValidationError("Please type " + expected + " to confirm")
+		},
+	}
+
+	_, err := prompt.Run()
+	return err
+}
+
 // NamePrompt prompts the user to input a person's name
 func NamePrompt(override *string, defaultValue string, autoAccept bool) (string, error) {
 	var prompt promptui.Prompt
@@ -83,7 +103,7 @@ func NamePrompt(override *string, defaultValue string, autoAccept bool) (string,
 		if err != nil {
 			fmt.Println(promptui.FailedValue(label, defaultValue))
 		} else {
-			fmt.Println(promptui.SuccessfulValue(label, defaultValue))
+			decorate(promptui.SuccessfulValue(label, defaultValue))
 		}
 		return defaultValue, err
 	}
@@ -221,7 +241,7 @@ func SelectCreateProject(c context.Context, client *api.Client, orgID *identity.
 			fmt.Println(promptui.FailedValue("Project name", name))
 			return nil, "", false, errs.NewExitError("Project not found.")
 		}
-		fmt.Println(promptui.SuccessfulValue("Project name", name))
+		decorate(promptui.SuccessfulValue("Project name", name))
 	}
 
 	if idx == promptui.SelectedAdd {
@@ -267,7 +287,7 @@ func SelectCreateOrg(c context.Context, client *api.Client, name string) (*api.O
 			fmt.Println(promptui.FailedValue("Org name", name))
 			return nil, "", false, errs.NewExitError("Org not found")
 		}
-		fmt.Println(promptui.SuccessfulValue("Org name", name))
+		decorate(promptui.SuccessfulValue("Org name", name))
 	}
 
 	if idx == promptui.SelectedAdd {
@@ -314,7 +334,7 @@ func SelectCreateRole(c context.Context, client *api.Client, orgID *identity.ID,
 			fmt.Println(promptui.FailedValue("Machine Role", name))
 			return nil, "", false, errs.NewExitError("Role not found")
 		}
-		fmt.Println(promptui.SuccessfulValue("Machine Role", name))
+		decorate(promptui.SuccessfulValue("Machine Role", name))
 	}
 
 	if idx == promptui.SelectedAdd {
@@ -324,6 +344,107 @@ func SelectCreateRole(c context.Context, client *api.Client, orgID *identity.ID,
 	return &teams[idx], name, false, nil
 }
 
+// canPrompt reports whether the user can be asked to pick a missing flag's
+// value. They can't unless both stdin and stdout are terminals, nor when
+// machine readable output was asked for with --format json, which a prompt
+// would garble.
+func canPrompt(ctx *cli.Context) bool {
+	if ctx.String("format") == "json" {
+		return false
+	}
+	return readline.IsTerminal(int(os.Stdin.Fd())) && readline.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// SelectFlagPrompt returns the value of flag, asking the user to pick one of
+// names if it wasn't given. When the user can't be prompted, the flag is
+// reported as missing, as checkRequiredFlags would.
+func SelectFlagPrompt(ctx *cli.Context, flag, label string, names []string) (string, error) {
+	if v := ctx.String(flag); v != "" {
+		return v, nil
+	}
+	if !canPrompt(ctx) {
+		return "", errs.NewUsageExitError("Missing flags: --"+flag, ctx)
+	}
+	if len(names) == 0 {
+		return "", errs.NewExitError("There is no " + strings.ToLower(label) + " to select from.")
+	}
+
+	prompt := promptui.Select{
+		Label: "Select " + label,
+		Items: names,
+	}
+
+	_, name, err := prompt.Run()
+	return name, err
+}
+
+// SelectOrg returns the org named by the --org flag, or if it wasn't given,
+// one the user picks from the orgs they belong to.
+func SelectOrg(c context.Context, ctx *cli.Context, client *api.Client) (*api.OrgResult, error) {
+	var orgs []api.OrgResult
+	if ctx.String("org") == "" && canPrompt(ctx) {
+		var err error
+		orgs, err = client.Orgs.List(c)
+		if err != nil {
+			return nil, errs.NewErrorExitError("Could not list orgs.", err)
+		}
+	}
+
+	names := make([]string, len(orgs))
+	for i, o := range orgs {
+		names[i] = o.Body.Name
+	}
+
+	name, err := SelectFlagPrompt(ctx, "org", "organization", names)
+	if err != nil {
+		return nil, handleSelectError(err, "Org selection failed.")
+	}
+
+	return getOrg(c, client, name)
+}
+
+// SelectProject returns the project in the given org named by the --project
+// flag, or if it wasn't given, one the user picks from the org's projects.
+func SelectProject(c context.Context, ctx *cli.Context, client *api.Client, orgID *identity.ID) (*api.ProjectResult, error) {
+	var projects []api.ProjectResult
+	if name := ctx.String("project"); name != "" {
+		var err error
+		projects, err = listProjects(&c, client, orgID, &name)
+		if err != nil {
+			return nil, err
+		}
+		if len(projects) != 1 {
+			return nil, errs.NewNotFoundExitError("Project not found.")
+		}
+		return &projects[0], nil
+	}
+
+	if canPrompt(ctx) {
+		var err error
+		projects, err = listProjects(&c, client, orgID, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	names := make([]string, len(projects))
+	for i, p := range projects {
+		names[i] = p.Body.Name
+	}
+
+	name, err := SelectFlagPrompt(ctx, "project", "project", names)
+	if err != nil {
+		return nil, handleSelectError(err, "Project selection failed.")
+	}
+
+	for i, p := range projects {
+		if p.Body.Name == name {
+			return &projects[i], nil
+		}
+	}
+	return nil, errs.NewNotFoundExitError("Project not found.")
+}
+
 // PasswordPrompt prompts the user to input a password value
 func PasswordPrompt(shouldConfirm bool) (string, error) {
 	prompt := promptui.Prompt{
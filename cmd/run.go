@@ -26,6 +26,9 @@ func init() {
 			machineFlag("Use this machine.", false),
 			serviceFlag("Use this service.", "default", true),
 			stdInstanceFlag,
+			envMapFlag,
+			expandFlag,
+			strictFlag,
 		},
 		Action: chain(
 			ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
@@ -45,7 +48,7 @@ func runCmd(ctx *cli.Context) error {
 		args = strings.Split(args[0], " ")
 	}
 
-	secrets, _, err := getSecrets(ctx)
+	secrets, err := getSecretVars(ctx)
 	if err != nil {
 		return err
 	}
@@ -59,10 +62,7 @@ func runCmd(ctx *cli.Context) error {
 
 	// Add the secrets into the env
 	for _, secret := range secrets {
-		value := (*secret.Body).GetValue()
-		key := strings.ToUpper((*secret.Body).GetName())
-
-		cmd.Env = append(cmd.Env, key+"="+value.String())
+		cmd.Env = append(cmd.Env, strings.ToUpper(secret.Name)+"="+secret.Value)
 	}
 
 	err = cmd.Start()
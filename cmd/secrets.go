@@ -0,0 +1,1086 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/config"
+	"github.com/manifoldco/torus-cli/errs"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/pathexp"
+)
+
+func init() {
+	secrets := cli.Command{
+		Name:     "secrets",
+		Usage:    "Set and view individual secrets",
+		Category: "SECRETS",
+		Subcommands: []cli.Command{
+			{
+				Name:      "set",
+				Usage:     "Set a secret for a service and environment, reading the value from stdin if it is -, or set several at once as NAME=VALUE pairs",
+				ArgsUsage: "<name|path> <value|-> | <NAME=VALUE>...",
+				Flags: append(setUnsetFlags, newPlaceholder("from-file", "PATH",
+					"Set the secret to the contents of this file, instead of a value argument", "", "", false),
+					newPlaceholder("description", "TEXT",
+						"Describe the secret. The description is not encrypted", "", "", false),
+					newSlicePlaceholder("tag", "TAG",
+						"Tag the secret, to find it with ls --tag. Tags are not encrypted", "", "", false),
+					setStrictFlag,
+				),
+				Action: chain(
+					checkSecretPairs, ensureDaemon, ensureSession, loadDirPrefs,
+					loadPrefDefaults, setSliceDefaults, secretsSetCmd,
+				),
+			},
+			{
+				Name:      "view",
+				Usage:     "View a single secret for the current service and environment",
+				ArgsUsage: "<name>",
+				Flags: []cli.Flag{
+					stdOrgFlag,
+					stdProjectFlag,
+					stdEnvFlag,
+					serviceFlag("Use this service.", "default", true),
+					userFlag("Use this user.", false),
+					machineFlag("Use this machine.", false),
+					stdInstanceFlag,
+					newPlaceholder("to-file", "PATH",
+						"Write the value to this file, readable only by you, instead of stdout", "", "", false),
+					cli.BoolFlag{
+						Name:  "force",
+						Usage: "Overwrite the --to-file file if it already exists",
+					},
+					cli.BoolFlag{
+						Name:  "base64",
+						Usage: "Decode a value that was base64 encoded when set with --from-file, such as a binary file",
+					},
+				},
+				Action: chain(
+					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+					setUserEnv, checkRequiredFlags, secretsViewCmd,
+				),
+			},
+			{
+				Name:      "diff",
+				Usage:     "Show the secrets added, removed or changed from one environment to another",
+				ArgsUsage: "<environment> <environment>",
+				Flags: []cli.Flag{
+					stdOrgFlag,
+					stdProjectFlag,
+					serviceFlag("Use this service.", "default", true),
+					userFlag("Use this user.", false),
+					machineFlag("Use this machine.", false),
+					stdInstanceFlag,
+					formatFlag("table", "Format used to display the differences (table, json)"),
+					cli.BoolFlag{
+						Name:  "reveal",
+						Usage: "Display the values of the secrets that differ",
+					},
+				},
+				Action: chain(
+					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+					setUserEnv, checkRequiredFlags, secretsDiffCmd,
+				),
+			},
+			{
+				Name:  "copy",
+				Usage: "Copy the secrets set at one path expression to another",
+				Flags: []cli.Flag{
+					newPlaceholder("from", "PATHEXP", "Copy the secrets set at this path expression", "", "", true),
+					newPlaceholder("to", "PATHEXP", "Copy the secrets to this path expression", "", "", true),
+					cli.BoolFlag{
+						Name:  "overwrite",
+						Usage: "Replace the values of secrets that are already set at the destination",
+					},
+				},
+				Action: chain(
+					ensureDaemon, ensureSession, checkRequiredFlags, secretsCopyCmd,
+				),
+			},
+			{
+				Name:      "unset",
+				Usage:     "Remove a secret from a service and environment",
+				ArgsUsage: "<name|path>",
+				Flags: append(setUnsetFlags, stdAutoAcceptFlag, setStrictFlag, cli.BoolFlag{
+					Name:  "all",
+					Usage: "Unset the secret at every path matching the path expression",
+				}),
+				Action: chain(
+					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+					setSliceDefaults, secretsUnsetCmd,
+				),
+			},
+			{
+				Name:      "history",
+				Usage:     "List every version of a secret and who set it",
+				ArgsUsage: "<name|path>",
+				Flags: append(setUnsetFlags, cli.BoolFlag{
+					Name:  "reveal",
+					Usage: "Decrypt and display the value of each version",
+				}),
+				Action: chain(
+					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+					setSliceDefaults, secretsHistoryCmd,
+				),
+			},
+			{
+				Name:      "access",
+				Usage:     "List the teams and members who can decrypt the secrets at a path expression",
+				ArgsUsage: "[pathexp]",
+				Flags: append(setUnsetFlags,
+					formatFlag("table", "Format used to display access (table, json)"),
+					newSlicePlaceholder("expect", "TEAM",
+						"Team expected to read the secrets, others are flagged (default: owner, admin)",
+						"", "", false),
+				),
+				Action: chain(
+					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+					setSliceDefaults, secretsAccessCmd,
+				),
+			},
+			{
+				Name:      "rollback",
+				Usage:     "Restore a secret to the value it had at a previous version",
+				ArgsUsage: "<name|path> <version>",
+				Flags: append(setUnsetFlags, stdAutoAcceptFlag, setStrictFlag, cli.BoolFlag{
+					Name:  "force",
+					Usage: "Allow rolling back to a version where the secret was unset",
+				}),
+				Action: chain(
+					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+					setSliceDefaults, secretsRollbackCmd,
+				),
+			},
+		},
+	}
+
+	Cmds = append(Cmds, secrets)
+}
+
+func secretsSetCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	fromFile := ctx.String("from-file")
+	if setsSecretPairs(ctx) {
+		return secretsSetPairsCmd(ctx)
+	}
+
+	if fromFile != "" {
+		if len(args) != 1 {
+			msg := "name is required."
+			if len(args) > 1 {
+				msg = "A value can't be given with --from-file."
+			}
+			return errs.NewUsageExitError(msg, ctx)
+		}
+	} else if len(args) != 2 {
+		msg := "name and value are required."
+		if len(args) > 2 {
+			msg = "Too many arguments provided."
+		}
+		return errs.NewUsageExitError(msg, ctx)
+	}
+
+	var value *apitypes.CredentialValue
+	if fromFile != "" {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return err
+		}
+
+		raw, err := readSecretFile(fromFile, cfg.MaxCredentialSize)
+		if err != nil {
+			return errs.NewErrorExitError("Could not read value from "+fromFile+".", err)
+		}
+
+		// File contents are always stored as a string, even if they look
+		// like a number.
+		value = apitypes.NewStringCredentialValue(raw)
+	} else {
+		raw := args[1]
+		if raw == "-" {
+			b, err := ioutil.ReadAll(os.Stdin)
+			if err != nil {
+				return errs.NewErrorExitError("Could not read value from stdin.", err)
+			}
+			raw = string(bytes.TrimSuffix(b, []byte("\n")))
+		}
+		value = parseCredentialValue(raw)
+	}
+
+	cred, err := setCredential(ctx, args[0], nil, func() *apitypes.CredentialValue {
+		return value
+	})
+	if err != nil {
+		return errs.NewErrorExitError("Could not set credential.", err)
+	}
+
+	name := (*cred.Body).GetName()
+	pe := (*cred.Body).GetPathExp()
+	fmt.Printf("\nCredential %s has been set at %s/%s\n", name, pe, name)
+
+	return nil
+}
+
+// secretPair is a secret given on the command line as NAME=VALUE.
+type secretPair struct {
+	Name  string
+	Value string
+}
+
+// parseSecretPairs parses NAME=VALUE arguments, lowercasing the names. Every
+// problem with the arguments is reported in the returned error.
+func parseSecretPairs(args []string) ([]secretPair, error) {
+	var pairs []secretPair
+	var malformed, problems []string
+	seen := make(map[string]bool)
+	for _, arg := range args {
+		idx := strings.Index(arg, "=")
+		if idx < 1 {
+			malformed = append(malformed, arg)
+			continue
+		}
+
+		name := strings.ToLower(arg[:idx])
+		if err := apitypes.ValidCredentialName(name); err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if seen[name] {
+			problems = append(problems, "Secret "+name+" is given more than once")
+			continue
+		}
+		seen[name] = true
+
+		pairs = append(pairs, secretPair{Name: name, Value: arg[idx+1:]})
+	}
+
+	if len(malformed) > 0 {
+		problems = append([]string{"Expected NAME=VALUE, got: " + strings.Join(malformed, ", ")}, problems...)
+	}
+	if len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, "\n"))
+	}
+
+	return pairs, nil
+}
+
+// setsSecretPairs reports whether secrets set was given NAME=VALUE pairs,
+// rather than a name and a value.
+func setsSecretPairs(ctx *cli.Context) bool {
+	args := ctx.Args()
+	return ctx.String("from-file") == "" && len(args) > 0 && strings.Contains(args[0], "=")
+}
+
+// checkSecretPairs validates the NAME=VALUE pairs given to secrets set, if
+// any, before the daemon or registry are contacted.
+func checkSecretPairs(ctx *cli.Context) error {
+	if !setsSecretPairs(ctx) {
+		return nil
+	}
+
+	_, err := parseSecretPairs(ctx.Args())
+	if err != nil {
+		return errs.NewUsageExitError(err.Error(), ctx)
+	}
+	return nil
+}
+
+// secretsSetPairsCmd sets every secret given as a NAME=VALUE argument at the
+// path from the flags, in a single batch.
+func secretsSetPairsCmd(ctx *cli.Context) error {
+	pairs, err := parseSecretPairs(ctx.Args())
+	if err != nil {
+		return errs.NewUsageExitError(err.Error(), ctx)
+	}
+
+	err = chain(setUserEnv, checkRequiredFlags)(ctx)
+	if err != nil {
+		return err
+	}
+
+	pe, err := flagPathExp(ctx)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	org, err := client.Orgs.GetByName(c, pe.Org())
+	if err != nil {
+		return errs.NewErrorExitError("Unable to lookup org.", err)
+	}
+	if org == nil {
+		return errs.NewNotFoundExitError("Org not found")
+	}
+
+	pName := pe.Project()
+	projects, err := listProjects(&c, client, org.ID, &pName)
+	if err != nil {
+		return errs.NewErrorExitError("Unable to lookup project.", err)
+	}
+	if len(projects) != 1 {
+		return errs.NewNotFoundExitError("Project not found")
+	}
+	project := projects[0]
+
+	creds := make([]apitypes.Credential, len(pairs))
+	for i, pair := range pairs {
+		creds[i] = &apitypes.CredentialV2{
+			BaseCredential: apitypes.BaseCredential{
+				OrgID:     org.ID,
+				ProjectID: project.ID,
+				Name:      pair.Name,
+				PathExp:   pe,
+				Value:     parseCredentialValue(pair.Value),
+			},
+			State:       "set",
+			Description: ctx.String("description"),
+			Tags:        ctx.StringSlice("tag"),
+		}
+	}
+
+	if dryRun() {
+		existing, err := secretsAtPathExp(c, client, pe)
+		if err != nil {
+			return errs.NewErrorExitError("Could not retrieve existing secrets", err)
+		}
+
+		steps := make([]planStep, len(pairs))
+		for i, pair := range pairs {
+			action := "create"
+			if _, ok := existing[pair.Name]; ok {
+				action = "overwrite"
+			}
+			steps[i] = planStep{action, pe.String() + "/" + pair.Name}
+		}
+		printPlan(steps)
+		return nil
+	}
+
+	_, err = client.Credentials.CreateBatch(c, creds, &progress)
+	if err != nil {
+		return errs.NewErrorExitError("Could not set credentials.", err)
+	}
+
+	fmt.Printf("\n%d secrets have been set at %s\n", len(pairs), pe)
+	return nil
+}
+
+// fileValuePrefix marks a value set from a file whose contents were base64
+// encoded, because they weren't valid UTF-8 text (e.g. a DER encoded key).
+// secrets view --base64 decodes them.
+const fileValuePrefix = "base64:"
+
+// readSecretFile returns the contents of the file at path as a secret value,
+// base64 encoded with fileValuePrefix if they aren't valid UTF-8 text, or if
+// they would otherwise be mistaken for an encoded value.
+//
+// An error is returned if the value is larger than max bytes. Where the size
+// of the file is known up front it is checked before the file is read, and no
+// more than max bytes are ever read.
+func readSecretFile(path string, max int) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if fi.IsDir() {
+		return "", fmt.Errorf("%s is a directory", path)
+	}
+	if max > 0 && fi.Mode().IsRegular() && fi.Size() > int64(max) {
+		return "", fmt.Errorf("%s is %d bytes, larger than the limit of %d bytes",
+			path, fi.Size(), max)
+	}
+
+	var r io.Reader = f
+	if max > 0 {
+		r = io.LimitReader(f, int64(max)+1)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	value := string(b)
+	if !utf8.Valid(b) || strings.HasPrefix(value, fileValuePrefix) {
+		value = fileValuePrefix + base64.StdEncoding.EncodeToString(b)
+	}
+
+	if max > 0 && len(value) > max {
+		if len(b) > max {
+			return "", fmt.Errorf("%s is larger than the limit of %d bytes", path, max)
+		}
+		return "", fmt.Errorf("%s is %d bytes once base64 encoded, larger than the limit of %d bytes",
+			path, len(value), max)
+	}
+
+	return value, nil
+}
+
+// decodeSecretFile reverses readSecretFile for a value it base64 encoded,
+// returning the original contents of the file. It is only used when asked for
+// with --base64, as a secret set some other way may begin with
+// fileValuePrefix too.
+func decodeSecretFile(value string) ([]byte, error) {
+	if !strings.HasPrefix(value, fileValuePrefix) {
+		return nil, errors.New("the value is not base64 encoded")
+	}
+
+	return base64.StdEncoding.DecodeString(strings.TrimPrefix(value, fileValuePrefix))
+}
+
+// secretContents returns the bytes of a secret value to write out, decoding
+// it with decodeSecretFile only if decode is set. Otherwise the value is
+// returned verbatim, even if it begins with fileValuePrefix.
+func secretContents(value string, decode bool) ([]byte, error) {
+	if !decode {
+		return []byte(value), nil
+	}
+	return decodeSecretFile(value)
+}
+
+func secretsViewCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) != 1 {
+		msg := "name is required."
+		if len(args) > 1 {
+			msg = "Too many arguments provided."
+		}
+		return errs.NewUsageExitError(msg, ctx)
+	}
+	name := strings.ToLower(args[0])
+
+	toFile := ctx.String("to-file")
+	if toFile != "" && !ctx.Bool("force") {
+		if _, err := os.Stat(toFile); err == nil {
+			return errs.NewExitError(toFile + " already exists. Use --force to overwrite it.")
+		}
+	}
+
+	secrets, path, err := getSecrets(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, secret := range secrets {
+		if (*secret.Body).GetName() != name {
+			continue
+		}
+
+		value := (*secret.Body).GetValue().String()
+		if toFile == "" && !ctx.Bool("base64") {
+			fmt.Println(value)
+			return nil
+		}
+
+		b, err := secretContents(value, ctx.Bool("base64"))
+		if err != nil {
+			return errs.NewErrorExitError("Could not decode "+name+".", err)
+		}
+
+		if toFile == "" {
+			_, err = os.Stdout.Write(b)
+			return err
+		}
+
+		err = writePrivateFile(toFile, b)
+		if err != nil {
+			return errs.NewErrorExitError("Could not write "+toFile+".", err)
+		}
+
+		if !quiet() {
+			fmt.Fprintf(os.Stderr, "Secret %s written to %s.\n", name, toFile)
+		}
+		return nil
+	}
+
+	return errs.NewExitError("Credential " + name + " not found at " + path + ".")
+}
+
+func secretsDiffCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) != 2 {
+		msg := "Two environments are required."
+		if len(args) > 2 {
+			msg = "Too many arguments provided."
+		}
+		return errs.NewUsageExitError(msg, ctx)
+	}
+
+	format := ctx.String("format")
+	if format != "table" && format != "json" {
+		return errs.NewUsageExitError("Unknown format: "+format, ctx)
+	}
+
+	from, _, err := getSecretsForEnv(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	to, _, err := getSecretsForEnv(ctx, args[1])
+	if err != nil {
+		return err
+	}
+
+	reveal := ctx.Bool("reveal")
+	diff := diffSecrets(from, to, reveal)
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(diff)
+	}
+
+	if len(diff.Added)+len(diff.Removed)+len(diff.Changed) == 0 {
+		fmt.Printf("No differences between %s and %s.\n", args[0], args[1])
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, s := range diff.Added {
+		printSecretChange(w, "+", s, reveal)
+	}
+	for _, s := range diff.Removed {
+		printSecretChange(w, "-", s, reveal)
+	}
+	for _, s := range diff.Changed {
+		printSecretChange(w, "~", s, reveal)
+	}
+	w.Flush()
+
+	return nil
+}
+
+// secretChange is a single secret that differs between two environments.
+// Values are only filled in when they are to be revealed.
+type secretChange struct {
+	Name string `json:"name"`
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// secretsDiff holds the differences from one set of secrets to another, each
+// sorted by name.
+type secretsDiff struct {
+	Added   []secretChange `json:"added"`
+	Removed []secretChange `json:"removed"`
+	Changed []secretChange `json:"changed"`
+}
+
+// diffSecrets compares two sets of resolved secrets, as returned by
+// getSecrets. A secret that has been unset is treated as absent, so one
+// unset in to but set in from is reported as removed.
+func diffSecrets(from, to []apitypes.CredentialEnvelope, reveal bool) secretsDiff {
+	fromValues := secretValues(from)
+	toValues := secretValues(to)
+
+	diff := secretsDiff{
+		Added:   []secretChange{},
+		Removed: []secretChange{},
+		Changed: []secretChange{},
+	}
+
+	for name, fromValue := range fromValues {
+		toValue, ok := toValues[name]
+		change := secretChange{Name: name}
+		switch {
+		case !ok:
+			if reveal {
+				change.From = fromValue
+			}
+			diff.Removed = append(diff.Removed, change)
+		case fromValue != toValue:
+			if reveal {
+				change.From = fromValue
+				change.To = toValue
+			}
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+
+	for name, toValue := range toValues {
+		if _, ok := fromValues[name]; ok {
+			continue
+		}
+
+		change := secretChange{Name: name}
+		if reveal {
+			change.To = toValue
+		}
+		diff.Added = append(diff.Added, change)
+	}
+
+	sort.Sort(secretChangesByName(diff.Added))
+	sort.Sort(secretChangesByName(diff.Removed))
+	sort.Sort(secretChangesByName(diff.Changed))
+
+	return diff
+}
+
+// secretValues maps the upper cased name of each set secret to its value.
+func secretValues(secrets []apitypes.CredentialEnvelope) map[string]string {
+	values := make(map[string]string, len(secrets))
+	for _, secret := range secrets {
+		value := (*secret.Body).GetValue()
+		if value == nil {
+			continue
+		}
+		values[strings.ToUpper((*secret.Body).GetName())] = value.String()
+	}
+
+	return values
+}
+
+func printSecretChange(w io.Writer, marker string, s secretChange, reveal bool) {
+	if !reveal {
+		fmt.Fprintf(w, "%s %s\n", marker, s.Name)
+		return
+	}
+
+	switch marker {
+	case "+":
+		fmt.Fprintf(w, "%s %s\t%s\n", marker, s.Name, s.To)
+	case "-":
+		fmt.Fprintf(w, "%s %s\t%s\n", marker, s.Name, s.From)
+	default:
+		fmt.Fprintf(w, "%s %s\t%s -> %s\n", marker, s.Name, s.From, s.To)
+	}
+}
+
+// secretChangesByName implements sort.Interface, for sorting secret changes
+// lexicographically by name.
+type secretChangesByName []secretChange
+
+func (s secretChangesByName) Len() int           { return len(s) }
+func (s secretChangesByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s secretChangesByName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+
+func secretsCopyCmd(ctx *cli.Context) error {
+	if len(ctx.Args()) > 0 {
+		return errs.NewUsageExitError("Too many arguments provided.", ctx)
+	}
+
+	from, err := pathexp.Parse(ctx.String("from"))
+	if err != nil {
+		return errs.NewErrorExitError("Invalid --from path expression", err)
+	}
+
+	to, err := pathexp.Parse(ctx.String("to"))
+	if err != nil {
+		return errs.NewErrorExitError("Invalid --to path expression", err)
+	}
+
+	if from.Equal(to) {
+		return errs.NewUsageExitError("Cannot copy secrets to the path they are copied from", ctx)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	org, err := client.Orgs.GetByName(c, to.Org())
+	if org == nil || err != nil {
+		return errs.NewNotFoundExitError("Org not found")
+	}
+
+	pName := to.Project()
+	projects, err := listProjects(&c, client, org.ID, &pName)
+	if len(projects) != 1 || err != nil {
+		return errs.NewNotFoundExitError("Project not found")
+	}
+	project := projects[0]
+
+	source, err := secretsAtPathExp(c, client, from)
+	if err != nil {
+		return errs.NewErrorExitError("Could not retrieve secrets to copy", err)
+	}
+
+	if len(source) == 0 {
+		fmt.Printf("No secrets are set at %s\n", from)
+		return nil
+	}
+
+	existing, err := secretsAtPathExp(c, client, to)
+	if err != nil {
+		return errs.NewErrorExitError("Could not retrieve existing secrets", err)
+	}
+
+	names := make([]string, 0, len(source))
+	for name := range source {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var creds []apitypes.Credential
+	var skipped []string
+	for _, name := range names {
+		if _, ok := existing[name]; ok && !ctx.Bool("overwrite") {
+			skipped = append(skipped, name)
+			continue
+		}
+
+		creds = append(creds, &apitypes.CredentialV2{
+			BaseCredential: apitypes.BaseCredential{
+				OrgID:     org.ID,
+				ProjectID: project.ID,
+				Name:      name,
+				PathExp:   to,
+				Value:     source[name],
+			},
+			State: "set",
+		})
+	}
+
+	if dryRun() {
+		var steps []planStep
+		for _, cred := range creds {
+			action := "create"
+			if _, ok := existing[cred.GetName()]; ok {
+				action = "overwrite"
+			}
+			steps = append(steps, planStep{action, to.String() + "/" + cred.GetName()})
+		}
+		for _, name := range skipped {
+			steps = append(steps, planStep{"skip (already set)", to.String() + "/" + name})
+		}
+		printPlan(steps)
+		return nil
+	}
+
+	// The daemon encrypts the values under the keyring for the destination,
+	// creating the keyring if there isn't one yet.
+	// They are created in a single request, so the counter shows no count;
+	// it stands in for the daemon's progress messages meanwhile.
+	if len(creds) > 0 {
+		counter := newProgressCounter("Copying secrets", 0)
+		output := &progress
+		if counter.visible {
+			output = nil
+		}
+		counter.draw()
+		_, err = client.Credentials.CreateBatch(c, creds, output)
+		counter.clear()
+		if err != nil {
+			return errs.NewErrorExitError("Could not copy secrets.", err)
+		}
+	}
+
+	fmt.Printf("\n%d secrets copied, %d skipped from %s to %s\n", len(creds), len(skipped), from, to)
+	if len(skipped) > 0 {
+		fmt.Printf("Skipped secrets that are already set (use --overwrite to replace them):\n\t%s\n",
+			strings.Join(skipped, "\n\t"))
+	}
+
+	return nil
+}
+
+// secretsAtPathExp returns the values of the secrets set at exactly the given
+// path expression, keyed by name. Secrets that only match it through a
+// wildcard or alternation are not included.
+func secretsAtPathExp(c context.Context, client *api.Client, pe *pathexp.PathExp) (map[string]*apitypes.CredentialValue, error) {
+	creds, err := client.Credentials.Search(c, pe.String())
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]*apitypes.CredentialValue)
+	for _, cred := range creds {
+		body := *cred.Body
+		if body.GetValue() == nil || !body.GetPathExp().Equal(pe) {
+			continue
+		}
+		values[body.GetName()] = body.GetValue()
+	}
+
+	return values, nil
+}
+
+func secretsUnsetCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) != 1 {
+		msg := "Name or path is required."
+		if len(args) > 1 {
+			msg = "Too many arguments provided."
+		}
+		return errs.NewUsageExitError(msg, ctx)
+	}
+
+	pe, cname, err := determineCredential(ctx, args[0])
+	if err != nil {
+		return errs.NewErrorExitError("Could not unset credential", err)
+	}
+	name := strings.ToLower(*cname)
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	creds, err := client.Credentials.Search(c, pe.String())
+	if err != nil {
+		return errs.NewErrorExitError("Could not unset credential", err)
+	}
+
+	// Find every path the credential is currently set at. Without --all, only
+	// the exact path expression given is considered.
+	var paths []string
+	for _, cred := range creds {
+		body := *cred.Body
+		if body.GetName() != name || body.GetValue() == nil {
+			continue
+		}
+		if !ctx.Bool("all") && !body.GetPathExp().Equal(pe) {
+			continue
+		}
+		paths = append(paths, body.GetPathExp().String()+"/"+name)
+	}
+	sort.Strings(paths)
+
+	if len(paths) == 0 {
+		return errs.NewExitError(fmt.Sprintf("Credential %s not found at %s.", name, pe))
+	}
+
+	preamble := fmt.Sprintf("You are about to unset \"%s\". This cannot be undone.", paths[0])
+	if len(paths) > 1 {
+		preamble = fmt.Sprintf("You are about to unset %d credentials. This cannot be undone.\n\n\t%s",
+			len(paths), strings.Join(paths, "\n\t"))
+	}
+
+	abortErr := ConfirmDialogue(ctx, nil, &preamble)
+	if abortErr != nil {
+		return abortErr
+	}
+
+	for _, path := range paths {
+		_, err := setCredential(ctx, path, nil, func() *apitypes.CredentialValue {
+			return apitypes.NewUnsetCredentialValue()
+		})
+		if err != nil {
+			return errs.NewErrorExitError("Could not unset credential "+path, err)
+		}
+
+		fmt.Printf("\nCredential %s has been unset at %s.\n", name, path)
+	}
+
+	return nil
+}
+
+func secretsHistoryCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) != 1 {
+		msg := "Name or path is required."
+		if len(args) > 1 {
+			msg = "Too many arguments provided."
+		}
+		return errs.NewUsageExitError(msg, ctx)
+	}
+
+	pe, cname, err := determineCredential(ctx, args[0])
+	if err != nil {
+		return errs.NewErrorExitError("Could not retrieve history", err)
+	}
+	name := strings.ToLower(*cname)
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	reveal := ctx.Bool("reveal")
+	versions, err := client.Credentials.History(c, pe.String(), name, reveal)
+	if err != nil {
+		if apitypes.IsNotFoundError(err) {
+			return errs.NewExitError(fmt.Sprintf("Credential %s not found at %s.", name, pe))
+		}
+		return errs.NewErrorExitError("Could not retrieve history", err)
+	}
+
+	authors, err := authorNames(c, client, versions)
+	if err != nil {
+		return errs.NewErrorExitError("Could not retrieve history", err)
+	}
+
+	fmt.Printf("\nHistory of %s/%s\n\n", pe, name)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', 0)
+	if reveal {
+		fmt.Fprintln(w, "VERSION\tSTATE\tSET BY\tVALUE")
+	} else {
+		fmt.Fprintln(w, "VERSION\tSTATE\tSET BY")
+	}
+	for _, v := range versions {
+		author := "-"
+		if v.AuthorID != nil {
+			author = authors[*v.AuthorID]
+		}
+
+		line := fmt.Sprintf("%d\t%s\t%s", v.Version, v.State, author)
+		if reveal {
+			value := "-"
+			if v.Value != nil && !v.Value.IsUnset() {
+				value = v.Value.String()
+			}
+			line += "\t" + value
+		}
+		fmt.Fprintln(w, line)
+	}
+	w.Flush()
+
+	return nil
+}
+
+func secretsRollbackCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) != 2 {
+		msg := "name and version are required."
+		if len(args) > 2 {
+			msg = "Too many arguments provided."
+		}
+		return errs.NewUsageExitError(msg, ctx)
+	}
+
+	target, err := strconv.Atoi(args[1])
+	if err != nil || target < 1 {
+		return errs.NewUsageExitError("version must be a positive number.", ctx)
+	}
+
+	pe, cname, err := determineCredential(ctx, args[0])
+	if err != nil {
+		return errs.NewErrorExitError("Could not roll back credential", err)
+	}
+	name := strings.ToLower(*cname)
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	// History only contains versions set at exactly this path expression, so
+	// finding the version here also confirms it belongs to it.
+	versions, err := client.Credentials.History(c, pe.String(), name, true)
+	if err != nil {
+		if apitypes.IsNotFoundError(err) {
+			return errs.NewExitError(fmt.Sprintf("Credential %s not found at %s.", name, pe))
+		}
+		return errs.NewErrorExitError("Could not roll back credential", err)
+	}
+
+	var version *apitypes.CredentialVersion
+	for i, v := range versions {
+		if v.Version == target {
+			version = &versions[i]
+			break
+		}
+	}
+	if version == nil {
+		return errs.NewExitError(fmt.Sprintf("Version %d of %s not found at %s.", target, name, pe))
+	}
+
+	current := versions[len(versions)-1].Version
+	if target == current {
+		return errs.NewExitError(fmt.Sprintf("Version %d is already the current version of %s.", target, name))
+	}
+
+	if version.Value == nil || version.Value.IsUnset() {
+		if !ctx.Bool("force") {
+			return errs.NewExitError(fmt.Sprintf(
+				"%s was unset at version %d. Use --force to roll back to it anyway.", name, target))
+		}
+		version.Value = apitypes.NewUnsetCredentialValue()
+	}
+
+	preamble := fmt.Sprintf("You are about to roll back %s/%s from version %d to version %d.",
+		pe, name, current, target)
+	abortErr := ConfirmDialogue(ctx, nil, &preamble)
+	if abortErr != nil {
+		return abortErr
+	}
+
+	// Only roll back if no one has set the credential since its history was
+	// read.
+	_, err = setCredential(ctx, pe.String()+"/"+name, &current, func() *apitypes.CredentialValue {
+		return version.Value
+	})
+	if err != nil {
+		return errs.NewErrorExitError("Could not roll back credential", err)
+	}
+
+	fmt.Printf("\nCredential %s has been rolled back from version %d to the value of version %d as version %d.\n",
+		name, current, target, current+1)
+
+	return nil
+}
+
+// authorNames maps the author of each version to a username, falling back to
+// the author's ID when no user profile exists (e.g. for machines).
+func authorNames(ctx context.Context, client *api.Client,
+	versions []apitypes.CredentialVersion) (map[identity.ID]string, error) {
+
+	names := make(map[identity.ID]string)
+	var ids []identity.ID
+	for _, v := range versions {
+		if v.AuthorID == nil {
+			continue
+		}
+		if _, ok := names[*v.AuthorID]; !ok {
+			names[*v.AuthorID] = v.AuthorID.String()
+			ids = append(ids, *v.AuthorID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return names, nil
+	}
+
+	profiles, err := client.Profiles.ListByID(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range *profiles {
+		names[*p.ID] = p.Body.Username
+	}
+
+	return names, nil
+}
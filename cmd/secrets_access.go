@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/config"
+	"github.com/manifoldco/torus-cli/errs"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/pathexp"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+// defaultExpectedTeams are the teams expected to read secrets when --expect
+// isn't given. Every user is in the member team, so access through it is only
+// accepted with --expect member.
+var defaultExpectedTeams = []string{primitive.OwnerTeamName, primitive.AdminTeamName}
+
+// accessTeam is a team with at least one member who can decrypt the secrets
+// in a keyring. It is unexpected if it grants access to a member who isn't in
+// any of the expected teams.
+type accessTeam struct {
+	Name       string `json:"name"`
+	Readers    int    `json:"readers"`
+	Members    int    `json:"members"`
+	Unexpected bool   `json:"unexpected"`
+}
+
+// accessMember is a user or machine who can decrypt the secrets in a keyring.
+// Members no longer in the org are listed by ID, with a type of "unknown".
+type accessMember struct {
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	Teams      []string `json:"teams"`
+	Unexpected bool     `json:"unexpected"`
+}
+
+// keyringAccessReport lists who can decrypt the secrets in the keyring for a
+// path expression. It is overexposed if any team or member that isn't
+// expected to can read them.
+type keyringAccessReport struct {
+	PathExp     string         `json:"pathexp"`
+	Teams       []accessTeam   `json:"teams"`
+	Members     []accessMember `json:"members"`
+	Overexposed bool           `json:"overexposed"`
+}
+
+func secretsAccessCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) > 1 {
+		return errs.NewUsageExitError("Too many arguments provided.", ctx)
+	}
+
+	format := ctx.String("format")
+	if format != "table" && format != "json" {
+		return errs.NewUsageExitError("Unknown format: "+format, ctx)
+	}
+
+	var pe *pathexp.PathExp
+	var err error
+	if len(args) == 1 {
+		pe, err = pathexp.Parse(args[0])
+		if err != nil {
+			return errs.NewExitError(err.Error())
+		}
+	} else {
+		err = chain(setUserEnv, checkRequiredFlags)(ctx)
+		if err != nil {
+			return err
+		}
+
+		pe, err = flagPathExp(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	expected := ctx.StringSlice("expect")
+	if len(expected) == 0 {
+		expected = defaultExpectedTeams
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	org, err := client.Orgs.GetByName(c, pe.Org())
+	if err != nil {
+		return errs.NewErrorExitError("Could not retrieve org.", err)
+	}
+	if org == nil {
+		return errs.NewExitError("Org not found.")
+	}
+
+	access, err := client.Keyrings.Members(c, pe.String())
+	if err != nil {
+		return errs.NewErrorExitError("Could not retrieve keyring members.", err)
+	}
+
+	members, err := client.Orgs.Members(c, *org.ID)
+	if err != nil {
+		return errs.NewErrorExitError("Could not retrieve org members.", err)
+	}
+
+	machines, err := client.Machines.List(c, org.ID, nil, nil, nil)
+	if err != nil {
+		return errs.NewErrorExitError("Could not retrieve machines.", err)
+	}
+
+	// Machines are keyring members by their tokens.
+	tokens := make(map[identity.ID]identity.ID)
+	for _, m := range machines {
+		for _, t := range m.Tokens {
+			tokens[*t.Token.ID] = *m.Machine.ID
+		}
+	}
+
+	reports := accessReport(access, members, tokens, expected)
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(reports)
+	}
+
+	if len(reports) == 0 {
+		fmt.Printf("No secrets found at %s.\n", pe)
+		return nil
+	}
+
+	for _, r := range reports {
+		printAccessReport(r)
+	}
+
+	for _, r := range reports {
+		if r.Overexposed {
+			warn(fmt.Sprintf("some secrets can be read by more than the %s teams.",
+				strings.Join(expected, ", ")))
+			break
+		}
+	}
+
+	return nil
+}
+
+func printAccessReport(r keyringAccessReport) {
+	fmt.Printf("\nAccess to %s\n\n", r.PathExp)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
+	fmt.Fprintln(w, "TEAM\tREADERS\t")
+	for _, t := range r.Teams {
+		fmt.Fprintf(w, "%s\t%d of %d\t%s\n", t.Name, t.Readers, t.Members, unexpectedMarker(t.Unexpected))
+	}
+	fmt.Fprintln(w, "\t\t")
+
+	fmt.Fprintln(w, "MEMBER\tTYPE\tTEAMS\t")
+	for _, m := range r.Members {
+		teams := strings.Join(m.Teams, ", ")
+		if teams == "" {
+			teams = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", m.Name, m.Type, teams, unexpectedMarker(m.Unexpected))
+	}
+	w.Flush()
+}
+
+func unexpectedMarker(unexpected bool) string {
+	if unexpected {
+		return "over-exposed"
+	}
+	return ""
+}
+
+// accessReport maps the members of each keyring back to the org's users,
+// machines and teams. tokens maps machine token IDs to their machine's ID.
+// A member is unexpected if it isn't in any of the expected teams, as is any
+// team it belongs to.
+func accessReport(access []apitypes.KeyringAccess, members []api.OrgMember,
+	tokens map[identity.ID]identity.ID, expected []string) []keyringAccessReport {
+
+	isExpected := make(map[string]bool, len(expected))
+	for _, name := range expected {
+		isExpected[strings.ToLower(name)] = true
+	}
+
+	teamSizes := make(map[string]int)
+	for _, m := range members {
+		for _, t := range m.Teams {
+			teamSizes[t.Body.Name]++
+		}
+	}
+
+	reports := make([]keyringAccessReport, 0, len(access))
+	for _, ka := range access {
+		readers := make(map[identity.ID]bool, len(ka.MemberIDs))
+		for _, id := range ka.MemberIDs {
+			if machineID, ok := tokens[*id]; ok {
+				readers[machineID] = true
+			} else {
+				readers[*id] = true
+			}
+		}
+
+		r := keyringAccessReport{
+			PathExp: ka.PathExp.String(),
+			Teams:   []accessTeam{},
+			Members: []accessMember{},
+		}
+
+		teamReaders := make(map[string]int)
+		teamExposed := make(map[string]bool)
+		for _, m := range members {
+			if !readers[*m.ID] {
+				continue
+			}
+			delete(readers, *m.ID)
+
+			name := m.Name
+			if m.Type == api.MemberTypeUser {
+				name = m.Username
+			}
+
+			am := accessMember{Name: name, Type: m.Type, Teams: []string{}, Unexpected: true}
+			for _, t := range m.Teams {
+				am.Teams = append(am.Teams, t.Body.Name)
+				teamReaders[t.Body.Name]++
+				if isExpected[strings.ToLower(t.Body.Name)] {
+					am.Unexpected = false
+				}
+			}
+			if am.Unexpected {
+				for _, t := range m.Teams {
+					teamExposed[t.Body.Name] = true
+				}
+			}
+
+			r.Members = append(r.Members, am)
+			r.Overexposed = r.Overexposed || am.Unexpected
+		}
+
+		// Whoever is left has access to the keyring without being in the org.
+		var unknown []string
+		for id := range readers {
+			unknown = append(unknown, id.String())
+		}
+		sort.Strings(unknown)
+		for _, id := range unknown {
+			r.Members = append(r.Members, accessMember{
+				Name: id, Type: "unknown", Teams: []string{}, Unexpected: true,
+			})
+			r.Overexposed = true
+		}
+
+		var teams []string
+		for name := range teamReaders {
+			teams = append(teams, name)
+		}
+		sort.Strings(teams)
+		for _, name := range teams {
+			r.Teams = append(r.Teams, accessTeam{
+				Name:       name,
+				Readers:    teamReaders[name],
+				Members:    teamSizes[name],
+				Unexpected: teamExposed[name],
+			})
+		}
+
+		reports = append(reports, r)
+	}
+
+	return reports
+}
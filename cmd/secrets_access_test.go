@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/pathexp"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+func TestAccessReport(t *testing.T) {
+	team := func(name string) *apitypes.Team {
+		body := &primitive.Team{Name: name}
+		return &apitypes.Team{ID: newID(t, body), Body: body}
+	}
+	owner, member, machine, eng := team("owner"), team("member"), team("machine"), team("eng")
+
+	members := []api.OrgMember{
+		{ID: newID(t, &primitive.User{Username: "alice"}), Type: api.MemberTypeUser,
+			Username: "alice", Teams: []*apitypes.Team{owner, member}},
+		{ID: newID(t, &primitive.User{Username: "bob"}), Type: api.MemberTypeUser,
+			Username: "bob", Teams: []*apitypes.Team{member, eng}},
+		{ID: newID(t, &primitive.User{Username: "carol"}), Type: api.MemberTypeUser,
+			Username: "carol", Teams: []*apitypes.Team{member}},
+		{ID: newID(t, &primitive.Machine{Name: "bot"}), Type: api.MemberTypeMachine,
+			Name: "bot", Teams: []*apitypes.Team{machine}},
+	}
+
+	tokenID := newID(t, &primitive.MachineToken{})
+	tokens := map[identity.ID]identity.ID{*tokenID: *members[3].ID}
+
+	pe, err := pathexp.Parse("/acme/api/prod/default/*/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	access := []apitypes.KeyringAccess{{
+		PathExp:   pe,
+		MemberIDs: []*identity.ID{members[0].ID, members[1].ID, tokenID},
+	}}
+
+	t.Run("default teams", func(t *testing.T) {
+		reports := accessReport(access, members, tokens, defaultExpectedTeams)
+		if len(reports) != 1 {
+			t.Fatalf("Expected 1 report, got %d", len(reports))
+		}
+
+		expected := keyringAccessReport{
+			PathExp: pe.String(),
+			Teams: []accessTeam{
+				{Name: "eng", Readers: 1, Members: 1, Unexpected: true},
+				{Name: "machine", Readers: 1, Members: 1, Unexpected: true},
+				{Name: "member", Readers: 2, Members: 3, Unexpected: true},
+				{Name: "owner", Readers: 1, Members: 1},
+			},
+			Members: []accessMember{
+				{Name: "alice", Type: "user", Teams: []string{"owner", "member"}},
+				{Name: "bob", Type: "user", Teams: []string{"member", "eng"}, Unexpected: true},
+				{Name: "bot", Type: "machine", Teams: []string{"machine"}, Unexpected: true},
+			},
+			Overexposed: true,
+		}
+		if !reflect.DeepEqual(reports[0], expected) {
+			t.Errorf("Expected %+v, got %+v", expected, reports[0])
+		}
+	})
+
+	t.Run("expected teams", func(t *testing.T) {
+		reports := accessReport(access, members, tokens, []string{"owner", "ENG", "machine"})
+		if reports[0].Overexposed {
+			t.Errorf("Expected no over-exposure, got %+v", reports[0])
+		}
+	})
+
+	t.Run("unknown member", func(t *testing.T) {
+		stranger := newID(t, &primitive.User{Username: "mallory"})
+		withStranger := []apitypes.KeyringAccess{{
+			PathExp:   pe,
+			MemberIDs: append(access[0].MemberIDs, stranger),
+		}}
+
+		reports := accessReport(withStranger, members, tokens, []string{"owner", "eng", "machine"})
+		m := reports[0].Members
+		last := m[len(m)-1]
+		if last.Name != stranger.String() || last.Type != "unknown" || !last.Unexpected {
+			t.Errorf("Expected unexpected unknown member %s, got %+v", stranger, last)
+		}
+		if !reports[0].Overexposed {
+			t.Error("Expected over-exposure")
+		}
+	})
+}
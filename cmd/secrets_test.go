@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/pathexp"
+)
+
+func TestDiffSecrets(t *testing.T) {
+	makeCred := func(env, name, state string, value interface{}) apitypes.CredentialEnvelope {
+		cv := map[string]interface{}{
+			"version": 2,
+			"body": map[string]interface{}{
+				"type":  "string",
+				"value": value,
+			},
+		}
+		if state == "unset" {
+			cv["state"] = "unset"
+			cv["body"] = map[string]interface{}{"type": "undefined", "value": ""}
+		}
+
+		v, err := interfaceToCredentialValue(t, cv)
+		if err != nil {
+			t.Fatal("Unable to decode credential value: " + err.Error())
+		}
+
+		path, _ := pathexp.Parse("/o/p/" + env + "/s/*/*")
+		var cBody apitypes.Credential = &apitypes.CredentialV2{
+			State: state,
+			BaseCredential: apitypes.BaseCredential{
+				Name:    name,
+				PathExp: path,
+				Value:   v,
+			},
+		}
+		return apitypes.CredentialEnvelope{Body: &cBody}
+	}
+
+	from := []apitypes.CredentialEnvelope{
+		makeCred("staging", "same", "set", "1"),
+		makeCred("staging", "changed", "set", "old"),
+		makeCred("staging", "gone", "set", "x"),
+		makeCred("staging", "tombstoned", "set", "y"),
+	}
+	to := []apitypes.CredentialEnvelope{
+		makeCred("production", "same", "set", "1"),
+		makeCred("production", "changed", "set", "new"),
+		makeCred("production", "tombstoned", "unset", nil),
+		makeCred("production", "new", "set", "z"),
+	}
+
+	t.Run("masked", func(t *testing.T) {
+		diff := diffSecrets(from, to, false)
+		expected := secretsDiff{
+			Added:   []secretChange{{Name: "NEW"}},
+			Removed: []secretChange{{Name: "GONE"}, {Name: "TOMBSTONED"}},
+			Changed: []secretChange{{Name: "CHANGED"}},
+		}
+		if !reflect.DeepEqual(diff, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, diff)
+		}
+	})
+
+	t.Run("revealed", func(t *testing.T) {
+		diff := diffSecrets(from, to, true)
+		expected := secretsDiff{
+			Added:   []secretChange{{Name: "NEW", To: "z"}},
+			Removed: []secretChange{{Name: "GONE", From: "x"}, {Name: "TOMBSTONED", From: "y"}},
+			Changed: []secretChange{{Name: "CHANGED", From: "old", To: "new"}},
+		}
+		if !reflect.DeepEqual(diff, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, diff)
+		}
+	})
+
+	t.Run("no differences", func(t *testing.T) {
+		diff := diffSecrets(from, from, true)
+		if len(diff.Added)+len(diff.Removed)+len(diff.Changed) != 0 {
+			t.Errorf("Expected no differences, got %+v", diff)
+		}
+	})
+}
+
+func TestReadSecretFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "torus-secret-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		name     string
+		contents []byte
+		encoded  bool
+	}{
+		{name: "text", contents: []byte("{\"type\": \"service_account\"}\n")},
+		{name: "binary", contents: []byte{0x30, 0x82, 0xff, 0x00}, encoded: true},
+		{name: "prefixed", contents: []byte("base64:not really"), encoded: true},
+		{name: "empty", contents: []byte{}},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(dir, test.name)
+			err := ioutil.WriteFile(path, test.contents, 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			value, err := readSecretFile(path, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if encoded := value != string(test.contents); encoded != test.encoded {
+				t.Errorf("Expected encoded to be %t, got value %q", test.encoded, value)
+			}
+
+			if !test.encoded {
+				return
+			}
+			b, err := decodeSecretFile(value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(b, test.contents) {
+				t.Errorf("Expected %q, got %q", test.contents, b)
+			}
+		})
+	}
+
+	t.Run("not encoded", func(t *testing.T) {
+		_, err := decodeSecretFile("plain text")
+		if err == nil {
+			t.Error("Expected an error decoding a value that isn't base64 encoded")
+		}
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		path := filepath.Join(dir, "large")
+		err := ioutil.WriteFile(path, bytes.Repeat([]byte("a"), 65), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = readSecretFile(path, 64)
+		if err == nil {
+			t.Error("Expected an error for a file over the limit")
+		}
+	})
+
+	t.Run("too large once encoded", func(t *testing.T) {
+		path := filepath.Join(dir, "large-binary")
+		err := ioutil.WriteFile(path, bytes.Repeat([]byte{0xff}, 60), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = readSecretFile(path, 64)
+		if err == nil {
+			t.Error("Expected an error for a file over the limit once encoded")
+		}
+	})
+}
+
+func TestSecretContents(t *testing.T) {
+	literal := "base64:aGVsbG8="
+
+	b, err := secretContents(literal, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != literal {
+		t.Errorf("Expected %q to be kept verbatim, got %q", literal, b)
+	}
+
+	b, err = secretContents(literal, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello" {
+		t.Errorf("Expected %q to be decoded, got %q", literal, b)
+	}
+}
+
+func TestParseSecretPairs(t *testing.T) {
+	pairs, err := parseSecretPairs([]string{"DB_HOST=localhost", "url=http://x?a=b", "empty="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []secretPair{
+		{Name: "db_host", Value: "localhost"},
+		{Name: "url", Value: "http://x?a=b"},
+		{Name: "empty", Value: ""},
+	}
+	if !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, pairs)
+	}
+
+	_, err = parseSecretPairs([]string{"a=1", "novalue", "=2", "9lives=3", "A=4"})
+	if err == nil {
+		t.Fatal("Expected an error for bad arguments")
+	}
+	for _, want := range []string{"novalue, =2", "9lives", "a is given more than once"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %q, got: %s", want, err)
+		}
+	}
+}
+
+func TestCheckSecretPairs(t *testing.T) {
+	flagset := flag.NewFlagSet("", flag.ContinueOnError)
+	flagset.String("from-file", "", "")
+	if err := flagset.Parse([]string{"a=1", "novalue"}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := cli.NewContext(cli.NewApp(), flagset, nil)
+	ctx.Command = cli.Command{Name: "set"}
+
+	reached := false
+	err := chain(checkSecretPairs, func(*cli.Context) error {
+		reached = true
+		return nil
+	})(ctx)
+	if err == nil {
+		t.Error("Expected an error for bad arguments")
+	}
+	if reached {
+		t.Error("Expected bad arguments to be refused before the rest of the chain")
+	}
+}
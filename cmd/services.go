@@ -3,16 +3,21 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"unicode/utf8"
 
 	"github.com/urfave/cli"
 
 	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/config"
 	"github.com/manifoldco/torus-cli/errs"
 	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/prefs"
 )
 
 func init() {
@@ -25,31 +30,64 @@ func init() {
 				Name:  "list",
 				Usage: "List services for an organization",
 				Flags: []cli.Flag{
-					orgFlag("org to show services for", true),
-					projectFlag("project to show services for", false),
+					orgFlag("org to show services for, chosen interactively if omitted", false),
+					projectFlag("project to show services for, chosen interactively if omitted", false),
 					cli.BoolFlag{
 						Name:  "all",
 						Usage: "Perform command on all projects",
 					},
+					newPlaceholder("filter", "TEXT",
+						"Only show services whose names contain this text, ignoring case", "", "", false),
+					newPlaceholder("regexp", "EXPR",
+						"Only show services whose names match this regular expression", "", "", false),
 				},
 				Action: chain(
-					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
-					setUserEnv, checkRequiredFlags, listServicesCmd,
+					checkServiceFilter, ensureDaemon, ensureSession, loadDirPrefs,
+					loadPrefDefaults, setUserEnv, checkRequiredFlags, listServicesCmd,
 				),
 			},
 			{
 				Name:      "create",
-				Usage:     "Create a service in an organization",
-				ArgsUsage: "[name]",
+				Usage:     "Create one or more services in an organization",
+				ArgsUsage: "[name...]",
 				Flags: []cli.Flag{
 					orgFlag("Create the project in this org", false),
 					projectFlag("project to create services for", false),
+					newPlaceholder("template", "NAME",
+						"Create the services listed in this template from your torusrc", "", "", false),
 				},
 				Action: chain(
 					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
 					createServiceCmd,
 				),
 			},
+			{
+				Name:      "rename",
+				Usage:     "Rename a service in a project",
+				ArgsUsage: "<old> <new>",
+				Flags: []cli.Flag{
+					orgFlag("org the service belongs to", true),
+					projectFlag("project the service belongs to", true),
+				},
+				Action: chain(
+					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+					checkRequiredFlags, renameServiceCmd,
+				),
+			},
+			{
+				Name:      "delete",
+				Usage:     "Delete a service from a project",
+				ArgsUsage: "<name>",
+				Flags: []cli.Flag{
+					orgFlag("org the service belongs to", true),
+					projectFlag("project the service belongs to", true),
+					stdAutoAcceptFlag,
+				},
+				Action: chain(
+					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+					checkRequiredFlags, deleteServiceCmd,
+				),
+			},
 		},
 	}
 	Cmds = append(Cmds, services)
@@ -58,14 +96,13 @@ func init() {
 const serviceListFailed = "Could not list services."
 
 func listServicesCmd(ctx *cli.Context) error {
-	if !ctx.Bool("all") {
-		if len(ctx.String("project")) < 1 {
-			return errs.NewUsageExitError("Missing flags: --project", ctx)
-		}
-	} else {
-		if len(ctx.String("project")) > 0 {
-			return errs.NewUsageExitError("Cannot use --project flag with --all", ctx)
-		}
+	if ctx.Bool("all") && len(ctx.String("project")) > 0 {
+		return errs.NewUsageExitError("Cannot use --project flag with --all", ctx)
+	}
+
+	match, err := serviceNameMatcher(ctx.String("filter"), ctx.String("regexp"))
+	if err != nil {
+		return errs.NewUsageExitError(err.Error(), ctx)
 	}
 
 	cfg, err := config.LoadConfig()
@@ -76,18 +113,13 @@ func listServicesCmd(ctx *cli.Context) error {
 	client := api.NewClient(cfg)
 	c := context.Background()
 
-	// Look up the target org
-	var org *api.OrgResult
-	org, err = client.Orgs.GetByName(c, ctx.String("org"))
+	// Look up the target org, asking for one if it wasn't given
+	org, err := SelectOrg(c, ctx, client)
 	if err != nil {
-		return errs.NewErrorExitError(serviceListFailed, err)
-	}
-	if org == nil {
-		return errs.NewExitError("Org not found")
+		return err
 	}
 
 	// Identify which projects to list services for
-	var projectID identity.ID
 	var projects []api.ProjectResult
 	if ctx.Bool("all") {
 		// Pull all projects for the given orgID
@@ -97,44 +129,31 @@ func listServicesCmd(ctx *cli.Context) error {
 		}
 
 	} else {
-		// Retrieve only a single project by name
-		projectName := ctx.String("project")
-		projects, err = listProjects(&c, client, org.ID, &projectName)
+		// Retrieve only a single project, asking for one if it wasn't given
+		project, err := SelectProject(c, ctx, client, org.ID)
 		if err != nil {
-			return errs.NewErrorExitError(serviceListFailed, err)
-		}
-		if len(projects) == 1 {
-			projectID = *projects[0].ID
-		} else {
-			return errs.NewExitError("Project not found")
+			return err
 		}
+		projects = []api.ProjectResult{*project}
 	}
 
-	// Retrieve services for targeted org and project
-	services, err := listServices(&c, client, org.ID, &projectID, nil)
+	// Retrieve services for each targeted project
+	sMap, err := listServicesForProjects(c, client, projects, serviceListConcurrency)
 	if err != nil {
 		return errs.NewErrorExitError(serviceListFailed, err)
 	}
 
-	// Build map of services to project
-	pMap := make(map[string]api.ProjectResult)
-	for _, project := range projects {
-		pMap[project.ID.String()] = project
-	}
-	sMap := make(map[string][]api.ServiceResult)
-	for _, service := range services {
-		ID := service.Body.ProjectID.String()
-		sMap[ID] = append(sMap[ID], service)
-	}
+	sort.Sort(projectsByName(projects))
 
 	// Build output of projects/services
 	fmt.Println("")
-	for projectID, project := range pMap {
-		count := strconv.Itoa(len(sMap[projectID]))
+	for _, project := range projects {
+		projectServices := filterServices(sMap[project.ID.String()], match)
+		count := strconv.Itoa(len(projectServices))
 		title := project.Body.Name + " (" + count + ")"
 		fmt.Println(title)
 		fmt.Println(strings.Repeat("-", utf8.RuneCountInString(title)))
-		for _, service := range sMap[projectID] {
+		for _, service := range projectServices {
 			fmt.Println(service.Body.Name)
 		}
 		fmt.Println("")
@@ -143,10 +162,89 @@ func listServicesCmd(ctx *cli.Context) error {
 	return nil
 }
 
+// checkServiceFilter validates the --regexp flag of services list before the
+// daemon or registry are contacted.
+func checkServiceFilter(ctx *cli.Context) error {
+	_, err := serviceNameMatcher(ctx.String("filter"), ctx.String("regexp"))
+	if err != nil {
+		return errs.NewUsageExitError(err.Error(), ctx)
+	}
+	return nil
+}
+
+// serviceNameMatcher returns a func reporting whether a service name contains
+// filter, ignoring case, and matches the regular expression expr. Either may
+// be empty to skip that check.
+func serviceNameMatcher(filter, expr string) (func(string) bool, error) {
+	var re *regexp.Regexp
+	if expr != "" {
+		var err error
+		re, err = regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid --regexp: %s", err)
+		}
+	}
+
+	filter = strings.ToLower(filter)
+	return func(name string) bool {
+		if !strings.Contains(strings.ToLower(name), filter) {
+			return false
+		}
+		return re == nil || re.MatchString(name)
+	}, nil
+}
+
+// filterServices returns the services whose names satisfy match.
+func filterServices(services []api.ServiceResult, match func(string) bool) []api.ServiceResult {
+	filtered := make([]api.ServiceResult, 0, len(services))
+	for _, service := range services {
+		if match(service.Body.Name) {
+			filtered = append(filtered, service)
+		}
+	}
+	return filtered
+}
+
+// serviceListConcurrency bounds the number of per-project service lookups
+// that listServicesCmd makes at once.
+const serviceListConcurrency = 8
+
+// listServicesForProjects fetches the services of each of the given projects,
+// with at most limit requests in flight. The services are returned keyed by
+// project id.
+func listServicesForProjects(c context.Context, client *api.Client, projects []api.ProjectResult, limit int) (map[string][]api.ServiceResult, error) {
+	results := make([][]api.ServiceResult, len(projects))
+	listErrs := make([]error, len(projects))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, limit)
+	for i, project := range projects {
+		wg.Add(1)
+		go func(i int, projectID *identity.ID) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i], listErrs[i] = client.Services.List(c, nil, &[]*identity.ID{projectID}, nil)
+		}(i, project.ID)
+	}
+	wg.Wait()
+
+	sMap := make(map[string][]api.ServiceResult, len(projects))
+	for i, project := range projects {
+		if listErrs[i] != nil {
+			return nil, listErrs[i]
+		}
+		sMap[project.ID.String()] = results[i]
+	}
+
+	return sMap, nil
+}
+
 func listServices(ctx *context.Context, client *api.Client, orgID, projID *identity.ID, name *string) ([]api.ServiceResult, error) {
 	c, client, err := NewAPIClient(ctx, client)
 	if err != nil {
-		return nil, cli.NewExitError(serviceListFailed, -1)
+		return nil, errs.NewErrorExitError(serviceListFailed, err)
 	}
 
 	var orgIDs []*identity.ID
@@ -170,7 +268,7 @@ func listServices(ctx *context.Context, client *api.Client, orgID, projID *ident
 func listServicesByProjectID(ctx *context.Context, client *api.Client, projectIDs []*identity.ID) ([]api.ServiceResult, error) {
 	c, client, err := NewAPIClient(ctx, client)
 	if err != nil {
-		return nil, cli.NewExitError(envListFailed, -1)
+		return nil, errs.NewErrorExitError(envListFailed, err)
 	}
 	return client.Services.List(c, nil, &projectIDs, nil)
 }
@@ -180,15 +278,43 @@ const serviceCreateFailed = "Could not create service."
 func createServiceCmd(ctx *cli.Context) error {
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		return errs.NewExitError(serviceCreateFailed)
+		return errs.NewErrorExitError(serviceCreateFailed, err)
+	}
+
+	args := []string(ctx.Args())
+	template := ctx.String("template")
+	if template != "" {
+		if len(args) > 0 {
+			return errs.NewUsageExitError("Service names can't be given with --template", ctx)
+		}
+
+		args, err = serviceTemplate(template)
+		if err != nil {
+			return err
+		}
 	}
 
-	args := ctx.Args()
 	serviceName := ""
 	if len(args) > 0 {
 		serviceName = args[0]
 	}
 
+	// With more than one name, or a template, there is nothing to prompt
+	// for, so check them all up front rather than failing part way through
+	// the batch.
+	if len(args) > 1 || template != "" {
+		var invalid []string
+		for _, name := range args {
+			if validateSlug("service")(name) != nil {
+				invalid = append(invalid, name)
+			}
+		}
+		if len(invalid) > 0 {
+			return errs.NewUsageExitError("Invalid service names: "+strings.Join(invalid, ", ")+
+				"\nService names can only use a-z, 0-9, hyphens and underscores", ctx)
+		}
+	}
+
 	client := api.NewClient(cfg)
 	c := context.Background()
 
@@ -198,11 +324,11 @@ func createServiceCmd(ctx *cli.Context) error {
 		return handleSelectError(err, "Org selection failed.")
 	}
 	if org == nil && !newOrg {
-		fmt.Println("")
-		return errs.NewExitError("Org not found")
+		decorate()
+		return errs.NewNotFoundExitError("Org not found")
 	}
 	if newOrg && oName == "" {
-		fmt.Println("")
+		decorate()
 		return errs.NewExitError("Invalid org name")
 	}
 
@@ -217,26 +343,30 @@ func createServiceCmd(ctx *cli.Context) error {
 		return handleSelectError(err, "Project selection failed.")
 	}
 	if project == nil && !newProject {
-		fmt.Println("")
-		return errs.NewExitError("Project not found")
+		decorate()
+		return errs.NewNotFoundExitError("Project not found")
 	}
 	if newProject && pName == "" {
-		fmt.Println("")
+		decorate()
 		return errs.NewExitError("Invalid project name")
 	}
 
-	label := "Service name"
-	autoAccept := serviceName != ""
-	serviceName, err = NamePrompt(&label, serviceName, autoAccept)
-	if err != nil {
-		return handleSelectError(err, serviceCreateFailed)
+	serviceNames := args
+	if len(args) <= 1 && template == "" {
+		label := "Service name"
+		autoAccept := serviceName != ""
+		serviceName, err = NamePrompt(&label, serviceName, autoAccept)
+		if err != nil {
+			return handleSelectError(err, serviceCreateFailed)
+		}
+		serviceNames = []string{serviceName}
 	}
 
 	// Create the org now if needed
 	if org == nil && newOrg {
 		org, err = createOrgByName(c, ctx, client, oName)
 		if err != nil {
-			fmt.Println("")
+			decorate()
 			return err
 		}
 		orgID = org.ID
@@ -246,21 +376,203 @@ func createServiceCmd(ctx *cli.Context) error {
 	if project == nil && newProject {
 		project, err = createProjectByName(c, client, orgID, pName)
 		if err != nil {
-			fmt.Println("")
+			decorate()
 			return err
 		}
 	}
 
-	// Create our new service
-	fmt.Println("")
-	err = client.Services.Create(c, orgID, project.ID, serviceName)
+	// Create our new services
+	decorate()
+	results, err := client.Services.CreateBatch(c, orgID, project.ID, serviceNames)
 	if err != nil {
-		if strings.Contains(err.Error(), "resource exists") {
-			return errs.NewExitError("Service already exists")
-		}
 		return errs.NewErrorExitError(serviceCreateFailed, err)
 	}
 
-	fmt.Printf("Service %s created.\n", serviceName)
+	if len(results) == 1 && results[0].Err != nil && template == "" {
+		return serviceCreateError(results[0].Err)
+	}
+
+	failed := 0
+	for _, result := range results {
+		// A template is applied to projects that may already have some of
+		// its services; those are skipped rather than treated as failures.
+		if result.Err != nil && template != "" && serviceExists(result.Err) {
+			fmt.Printf("Service %s already exists; skipped.\n", result.Name)
+			continue
+		}
+		if result.Err != nil {
+			failed++
+			fmt.Printf("Service %s not created: %s\n", result.Name, serviceCreateError(result.Err))
+			continue
+		}
+		decoratef("Service %s created.\n", result.Name)
+	}
+
+	if failed > 0 {
+		return errs.NewExitError(fmt.Sprintf("%d of %d services could not be created.", failed, len(results)))
+	}
 	return nil
 }
+
+func serviceCreateError(err error) error {
+	if serviceExists(err) {
+		return errs.NewExitError("Service already exists")
+	}
+	return errs.NewErrorExitError(serviceCreateFailed, err)
+}
+
+func serviceExists(err error) bool {
+	return apitypes.HasMessage(err, "resource exists")
+}
+
+// serviceTemplate returns the services listed in the named service template
+// from the torusrc file.
+func serviceTemplate(name string) ([]string, error) {
+	templates, err := prefs.LoadServiceTemplates()
+	if err != nil {
+		return nil, errs.NewErrorExitError("Could not read service templates.", err)
+	}
+
+	var names []string
+	for _, t := range templates {
+		if t.Name == name {
+			if len(t.Names) == 0 {
+				return nil, errs.NewExitError("Service template " + name + " has no services.")
+			}
+			return t.Names, nil
+		}
+		names = append(names, t.Name)
+	}
+
+	if len(names) == 0 {
+		return nil, errs.NewExitError("Service template " + name + " not found. Define templates " +
+			"in your torusrc as [services <name>] sections, listing the services as names = a, b, c.")
+	}
+	return nil, errs.NewExitError("Service template " + name + " not found. Available templates: " +
+		strings.Join(names, ", "))
+}
+
+const serviceRenameFailed = "Could not rename service."
+
+func renameServiceCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) < 2 || args[0] == "" || args[1] == "" {
+		return errs.NewUsageExitError("Missing old or new service name", ctx)
+	}
+	if len(args) > 2 {
+		return errs.NewUsageExitError("Too many arguments", ctx)
+	}
+	oldName, newName := args[0], args[1]
+
+	if err := validateSlug("Service")(newName); err != nil {
+		return errs.NewUsageExitError(err.Error(), ctx)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return errs.NewErrorExitError(serviceRenameFailed, err)
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	project, service, err := lookupService(c, client, ctx.String("org"), ctx.String("project"), oldName)
+	if err != nil {
+		return err
+	}
+
+	existing, err := client.Services.List(c, nil, &[]*identity.ID{project.ID}, &[]string{newName})
+	if err != nil {
+		return errs.NewErrorExitError(serviceRenameFailed, err)
+	}
+	if len(existing) > 0 {
+		return errs.NewExitError("A service named " + newName + " already exists in " +
+			project.Body.Name + ".")
+	}
+
+	updated, err := client.Services.Update(c, *service.ID, newName)
+	if err != nil {
+		return errs.NewErrorExitError(serviceRenameFailed, err)
+	}
+
+	fmt.Printf("Service %s renamed to %s.\n", service.Body.Name, updated.Body.Name)
+	fmt.Println("\nLinked directories, preferences and path expressions that refer to " +
+		service.Body.Name + " must be updated to use the new name.")
+
+	return nil
+}
+
+const serviceDeleteFailed = "Could not delete service."
+
+func deleteServiceCmd(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) < 1 || args[0] == "" {
+		return errs.NewUsageExitError("Missing service name", ctx)
+	}
+	if len(args) > 1 {
+		return errs.NewUsageExitError("Too many arguments", ctx)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return errs.NewErrorExitError(serviceDeleteFailed, err)
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	project, service, err := lookupService(c, client, ctx.String("org"), ctx.String("project"), args[0])
+	if err != nil {
+		return err
+	}
+
+	if dryRun() {
+		printPlan([]planStep{{"delete service", project.Body.Name + "/" + service.Body.Name}})
+		return nil
+	}
+
+	preamble := fmt.Sprintf("You are about to delete the %s service from %s. Secrets set "+
+		"for it will no longer be accessible. This cannot be undone.",
+		service.Body.Name, project.Body.Name)
+	abortErr := ConfirmDialogue(ctx, nil, &preamble)
+	if abortErr != nil {
+		return abortErr
+	}
+
+	err = client.Services.Delete(c, *service.ID)
+	if err != nil {
+		return errs.NewErrorExitError(serviceDeleteFailed, err)
+	}
+
+	fmt.Printf("Service %s deleted.\n", service.Body.Name)
+	return nil
+}
+
+// lookupService finds the named service within the given org and project.
+func lookupService(c context.Context, client *api.Client, orgName, projectName,
+	serviceName string) (*api.ProjectResult, *api.ServiceResult, error) {
+
+	org, err := getOrg(c, client, orgName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	projects, err := listProjects(&c, client, org.ID, &projectName)
+	if err != nil {
+		return nil, nil, errs.NewErrorExitError("Could not look up project.", err)
+	}
+	if len(projects) != 1 {
+		return nil, nil, errs.NewNotFoundExitError("Project not found")
+	}
+	project := projects[0]
+
+	services, err := client.Services.List(c, nil, &[]*identity.ID{project.ID}, &[]string{serviceName})
+	if err != nil {
+		return nil, nil, errs.NewErrorExitError("Could not look up service.", err)
+	}
+	if len(services) != 1 {
+		return nil, nil, errs.NewNotFoundExitError("Service not found")
+	}
+
+	return &project, &services[0], nil
+}
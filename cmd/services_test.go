@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/config"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+// benchmarkListServices lists the services of 50 projects per iteration,
+// against a daemon that takes 5ms to answer each services request.
+func benchmarkListServices(b *testing.B, limit int) {
+	dir, err := ioutil.TempDir("", "torus-services")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socketPath := filepath.Join(dir, "daemon.socket")
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(5 * time.Millisecond)
+
+			projectID, err := identity.DecodeFromString(r.URL.Query().Get("project_id"))
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			service := api.ServiceResult{
+				Body: &primitive.Service{Name: "default", ProjectID: &projectID},
+			}
+			json.NewEncoder(w).Encode([]api.ServiceResult{service})
+		}))
+	srv.Listener.Close()
+	srv.Listener = l
+	srv.Start()
+	defer srv.Close()
+
+	client := api.NewClient(&config.Config{SocketPath: socketPath})
+
+	projects := make([]api.ProjectResult, 50)
+	for i := range projects {
+		body := &primitive.Project{Name: "project"}
+		projects[i] = api.ProjectResult{ID: newID(b, body), Body: body}
+	}
+
+	c := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sMap, err := listServicesForProjects(c, client, projects, limit)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(sMap) != len(projects) {
+			b.Fatalf("Expected services for %d projects, got %d", len(projects), len(sMap))
+		}
+	}
+}
+
+func BenchmarkListServicesSerial(b *testing.B) {
+	benchmarkListServices(b, 1)
+}
+
+func BenchmarkListServicesConcurrent(b *testing.B) {
+	benchmarkListServices(b, serviceListConcurrency)
+}
+
+func TestFilterServices(t *testing.T) {
+	var services []api.ServiceResult
+	for _, name := range []string{"api", "api-worker", "billing", "web-API"} {
+		services = append(services, api.ServiceResult{Body: &primitive.Service{Name: name}})
+	}
+
+	names := func(services []api.ServiceResult) string {
+		out := []string{}
+		for _, s := range services {
+			out = append(out, s.Body.Name)
+		}
+		return strings.Join(out, ",")
+	}
+
+	tcs := []struct {
+		filter, expr, expected string
+	}{
+		{"", "", "api,api-worker,billing,web-API"},
+		{"API", "", "api,api-worker,web-API"},
+		{"", "^api", "api,api-worker"},
+		{"api", "worker$", "api-worker"},
+		{"nothing", "", ""},
+	}
+
+	for _, tc := range tcs {
+		match, err := serviceNameMatcher(tc.filter, tc.expr)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if out := names(filterServices(services, match)); out != tc.expected {
+			t.Errorf("filter %q, regexp %q: expected %q, got %q", tc.filter, tc.expr, tc.expected, out)
+		}
+	}
+
+	if _, err := serviceNameMatcher("", "api("); err == nil {
+		t.Error("Expected an error for an invalid regexp")
+	}
+}
+
+func TestCheckServiceFilter(t *testing.T) {
+	flagset := flag.NewFlagSet("", flag.ContinueOnError)
+	flagset.String("filter", "", "")
+	flagset.String("regexp", "api(", "")
+	ctx := cli.NewContext(cli.NewApp(), flagset, nil)
+	ctx.Command = cli.Command{Name: "list"}
+
+	reached := false
+	err := chain(checkServiceFilter, func(*cli.Context) error {
+		reached = true
+		return nil
+	})(ctx)
+	if err == nil {
+		t.Error("Expected an error for an invalid regexp")
+	}
+	if reached {
+		t.Error("Expected an invalid regexp to be refused before the rest of the chain")
+	}
+}
@@ -28,13 +28,20 @@ var setUnsetFlags = []cli.Flag{
 		"*", "TORUS_INSTANCE", true),
 }
 
+// setStrictFlag makes commands that set secrets fail if a secret is changed by
+// someone else while it is being set, rather than asking to overwrite it.
+var setStrictFlag = cli.BoolFlag{
+	Name:  "strict",
+	Usage: "Fail, rather than ask to overwrite it, if the secret is changed by someone else while being set",
+}
+
 func init() {
 	set := cli.Command{
 		Name:      "set",
 		Usage:     "Set a secret for a service and environment",
 		ArgsUsage: "<name|path> <value>",
 		Category:  "SECRETS",
-		Flags:     setUnsetFlags,
+		Flags:     append(setUnsetFlags, setStrictFlag),
 		Action: chain(
 			ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
 			setSliceDefaults, setCmd,
@@ -54,17 +61,8 @@ func setCmd(ctx *cli.Context) error {
 		return errs.NewUsageExitError(msg, ctx)
 	}
 
-	cred, err := setCredential(ctx, args[0], func() *apitypes.CredentialValue {
-		var v *apitypes.CredentialValue
-		if i, err := strconv.Atoi(args[1]); err == nil {
-			v = apitypes.NewIntCredentialValue(i)
-		} else if f, err := strconv.ParseFloat(args[1], 64); err == nil {
-			v = apitypes.NewFloatCredentialValue(f)
-		} else {
-			v = apitypes.NewStringCredentialValue(args[1])
-		}
-
-		return v
+	cred, err := setCredential(ctx, args[0], nil, func() *apitypes.CredentialValue {
+		return parseCredentialValue(args[1])
 	})
 
 	if err != nil {
@@ -78,6 +76,19 @@ func setCmd(ctx *cli.Context) error {
 	return nil
 }
 
+// parseCredentialValue creates a CredentialValue from raw, typing it as an int
+// or float if it parses as one.
+func parseCredentialValue(raw string) *apitypes.CredentialValue {
+	if i, err := strconv.Atoi(raw); err == nil {
+		return apitypes.NewIntCredentialValue(i)
+	}
+	if f, err := strconv.ParseFloat(raw, 64); err == nil {
+		return apitypes.NewFloatCredentialValue(f)
+	}
+
+	return apitypes.NewStringCredentialValue(raw)
+}
+
 func determineCredential(ctx *cli.Context, nameOrPath string) (*pathexp.PathExp, *string, error) {
 	// First try and use the cli args as a full path. it should override any
 	// options.
@@ -102,15 +113,7 @@ func determineCredential(ctx *cli.Context, nameOrPath string) (*pathexp.PathExp,
 			return nil, nil, err
 		}
 
-		identity, err := deriveIdentitySlice(ctx)
-		if err != nil {
-			return nil, nil, err
-		}
-
-		pe, err = pathexp.New(ctx.String("org"), ctx.String("project"),
-			ctx.StringSlice("environment"), ctx.StringSlice("service"),
-			identity, ctx.StringSlice("instance"),
-		)
+		pe, err = flagPathExp(ctx)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -119,7 +122,30 @@ func determineCredential(ctx *cli.Context, nameOrPath string) (*pathexp.PathExp,
 	return pe, &name, nil
 }
 
-func setCredential(ctx *cli.Context, nameOrPath string, valueMaker func() *apitypes.CredentialValue) (*apitypes.CredentialEnvelope, error) {
+// flagPathExp builds a PathExp from the org, project, environment, service,
+// user, machine and instance flags.
+func flagPathExp(ctx *cli.Context) (*pathexp.PathExp, error) {
+	identity, err := deriveIdentitySlice(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return pathexp.New(ctx.String("org"), ctx.String("project"),
+		ctx.StringSlice("environment"), ctx.StringSlice("service"),
+		identity, ctx.StringSlice("instance"),
+	)
+}
+
+// setCredential sets the credential at nameOrPath to the value made by
+// valueMaker, provided it is still at the expected version, or the version
+// it's at when setCredential is called if expected is nil.
+//
+// If someone else sets the credential first, the user is asked whether to
+// overwrite their change, or with --strict, an error is returned. The version
+// is checked by the daemon just before writing, so this is best-effort.
+func setCredential(ctx *cli.Context, nameOrPath string, expected *int,
+	valueMaker func() *apitypes.CredentialValue) (*apitypes.CredentialEnvelope, error) {
+
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		return nil, err
@@ -137,37 +163,63 @@ func setCredential(ctx *cli.Context, nameOrPath string, valueMaker func() *apity
 		name = *credName
 	}
 
-	org, err := client.Orgs.GetByName(c, pe.Org())
-	if org == nil || err != nil {
-		return nil, errs.NewExitError("Org not found")
+	if expected == nil {
+		current, err := currentCredentialVersion(c, client, pe, name)
+		if err != nil {
+			return nil, err
+		}
+		expected = &current
 	}
 
-	pName := pe.Project()
-	projects, err := listProjects(&c, client, org.ID, &pName)
-	if len(projects) != 1 || err != nil {
-		return nil, errs.NewExitError("Project not found")
-	}
-	project := projects[0]
-	value := valueMaker()
+	for {
+		cred, err := client.Credentials.Set(c, pe, name, valueMaker(),
+			ctx.String("description"), ctx.StringSlice("tag"), expected, &progress)
+		switch {
+		case err == nil:
+			return cred, nil
+		case err == api.ErrOrgNotFound:
+			return nil, errs.NewExitError("Org not found")
+		case err == api.ErrProjectNotFound:
+			return nil, errs.NewExitError("Project not found")
+		case !apitypes.IsVersionConflictError(err):
+			return nil, err
+		}
+
+		msg := fmt.Sprintf("%s/%s was changed by someone else while it was being set.", pe, name)
+		if ctx.Bool("strict") {
+			return nil, errs.NewExitError(msg)
+		}
 
-	state := "set"
-	if value.IsUnset() {
-		state = "unset"
-		value = nil
+		current, err := currentCredentialVersion(c, client, pe, name)
+		if err != nil {
+			return nil, err
+		}
+
+		preamble := fmt.Sprintf("%s It is now at version %d.", msg, current)
+		label := "Overwrite their change"
+		abortErr := ConfirmDialogue(ctx, &label, &preamble)
+		if abortErr != nil {
+			return nil, abortErr
+		}
+		expected = &current
 	}
+}
 
-	var cred apitypes.Credential
-	cBodyV2 := apitypes.CredentialV2{
-		BaseCredential: apitypes.BaseCredential{
-			OrgID:     org.ID,
-			ProjectID: project.ID,
-			Name:      strings.ToLower(name),
-			PathExp:   pe,
-			Value:     value,
-		},
-		State: state,
+// currentCredentialVersion returns the latest version of the named credential
+// at pe, or 0 if it has never been set there.
+func currentCredentialVersion(ctx context.Context, client *api.Client,
+	pe *pathexp.PathExp, name string) (int, error) {
+
+	versions, err := client.Credentials.History(ctx, pe.String(), name, false)
+	if apitypes.IsNotFoundError(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(versions) == 0 {
+		return 0, nil
 	}
-	cred = &cBodyV2
 
-	return client.Credentials.Create(c, &cred, &progress)
+	return versions[len(versions)-1].Version, nil
 }
@@ -3,7 +3,6 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	"github.com/manifoldco/torus-cli/api"
 	"github.com/manifoldco/torus-cli/apitypes"
@@ -103,14 +102,14 @@ func signup(ctx *cli.Context, subCommand bool) error {
 	fmt.Println("")
 	user, err := client.Users.Signup(c, &signup, &progress)
 	if err != nil {
-		if strings.Contains(err.Error(), "resource exists") {
+		if apitypes.HasMessage(err, "resource exists") {
 			return errs.NewExitError("Username or email address in use.")
 		}
 		return errs.NewExitError("Signup failed, please try again.")
 	}
 
 	// Log the user in
-	err = performLogin(c, client, user.Body.Email, password)
+	err = performLogin(c, client, user.Body.Email, []byte(password))
 	if err != nil {
 		return err
 	}
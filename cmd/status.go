@@ -10,6 +10,7 @@ import (
 	"github.com/manifoldco/torus-cli/api"
 	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/config"
+	"github.com/manifoldco/torus-cli/dirprefs"
 	"github.com/manifoldco/torus-cli/errs"
 	"github.com/manifoldco/torus-cli/prefs"
 
@@ -42,10 +43,9 @@ func init() {
 				Required:   true,
 			},
 		},
-		Action: chain(
-			ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
-			setUserEnv, statusCmd,
-		),
+		// Prefs are loaded by statusCmd, so it can tell where each
+		// value came from.
+		Action: chain(ensureDaemon, ensureSession, statusCmd),
 	}
 
 	Cmds = append(Cmds, status)
@@ -75,6 +75,19 @@ func statusCmd(ctx *cli.Context) error {
 		return errs.NewErrorExitError("Error fetching user details", err)
 	}
 
+	contextFlags := []string{"org", "project", "environment", "service", "instance"}
+	explicit := make(map[string]string)
+	for _, name := range contextFlags {
+		explicit[name] = explicitSource(ctx, name)
+	}
+
+	for _, load := range []func(*cli.Context) error{loadDirPrefs, loadPrefDefaults, setUserEnv} {
+		err = load(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
 	w := tabwriter.NewWriter(os.Stdout, 2, 0, 1, ' ', 0)
 	if session.Type() == apitypes.MachineSession {
 		fmt.Fprintf(w, "Machine ID:\t%s\n", session.ID())
@@ -100,13 +113,49 @@ func statusCmd(ctx *cli.Context) error {
 	service := ctx.String("service")
 	instance := ctx.String("instance")
 
-	fmt.Fprintf(w, "Org:\t%s\n", org)
-	fmt.Fprintf(w, "Project:\t%s\n", project)
-	fmt.Fprintf(w, "Environment:\t%s\n", env)
-	fmt.Fprintf(w, "Service:\t%s\n", service)
-	fmt.Fprintf(w, "Instance:\t%s\n", instance)
+	dirPrefs, err := dirprefs.Load(true)
+	if err != nil {
+		return err
+	}
+
+	dirSource := ".torus.json"
+	if dirPrefs.Path != "" {
+		dirSource = dirPrefs.Path
+	}
+	defaults := preferences.Defaults
+
+	fmt.Fprintf(w, "Org:\t%s\t(%s)\n", org, valueSource(ctx, "org", explicit["org"],
+		dirSource, dirPrefs.Organization, defaults.Organization, ""))
+	fmt.Fprintf(w, "Project:\t%s\t(%s)\n", project, valueSource(ctx, "project", explicit["project"],
+		dirSource, dirPrefs.Project, defaults.Project, ""))
+	fmt.Fprintf(w, "Environment:\t%s\t(%s)\n", env, valueSource(ctx, "environment", explicit["environment"],
+		dirSource, dirPrefs.Environment, defaults.Environment, "dev-"+session.Username()))
+	fmt.Fprintf(w, "Service:\t%s\t(%s)\n", service, valueSource(ctx, "service", explicit["service"],
+		dirSource, dirPrefs.Service, defaults.Service, "default"))
+	fmt.Fprintf(w, "Instance:\t%s\t(%s)\n", instance, valueSource(ctx, "instance", explicit["instance"],
+		dirSource, "", "", "1"))
 	w.Flush()
 
+	orgResult, err := client.Orgs.GetByName(c, org)
+	if err != nil {
+		return errs.NewErrorExitError("Could not retrieve org information.", err)
+	}
+
+	if orgResult == nil {
+		fmt.Println()
+		warn(fmt.Sprintf("the org %s does not exist, or you are not a member of it.", org))
+	} else {
+		projects, err := listProjects(&c, client, orgResult.ID, &project)
+		if err != nil {
+			return errs.NewErrorExitError("Could not retrieve project information.", err)
+		}
+
+		if len(projects) == 0 {
+			fmt.Println()
+			warn(fmt.Sprintf("the project %s does not exist in the %s org.", project, org))
+		}
+	}
+
 	identity, err := deriveIdentity(ctx, session)
 	if err != nil {
 		return err
@@ -118,3 +167,48 @@ func statusCmd(ctx *cli.Context) error {
 
 	return nil
 }
+
+// explicitSource returns how the named flag was set on the command line or
+// through its environment variable, or "" if it wasn't. It must be called
+// before any prefs are loaded into the flags.
+func explicitSource(ctx *cli.Context, name string) string {
+	for _, f := range ctx.Command.Flags {
+		psf, ok := f.(placeHolderStringFlag)
+		if !ok || strings.SplitN(psf.Name, ",", 2)[0] != name {
+			continue
+		}
+
+		value := ctx.String(name)
+		switch {
+		case !isSet(ctx, name) || value == psf.Value:
+			return ""
+		case psf.EnvVar != "" && os.Getenv(psf.EnvVar) == value:
+			return "environment variable"
+		default:
+			return "flag"
+		}
+	}
+
+	return ""
+}
+
+// valueSource describes where the value of the named flag came from, given
+// how it was set explicitly, the values the directory prefs and preference
+// defaults would supply, and the value used when nothing else is set.
+func valueSource(ctx *cli.Context, name, explicit, dirSource, dirValue,
+	prefValue, fallback string) string {
+
+	value := ctx.String(name)
+	switch {
+	case explicit != "":
+		return explicit
+	case dirValue != "" && value == dirValue:
+		return dirSource
+	case prefValue != "" && value == prefValue:
+		return "defaults"
+	case value == fallback:
+		return "default"
+	}
+
+	return "unknown"
+}
@@ -37,6 +37,32 @@ func init() {
 					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
 					setUserEnv, checkRequiredFlags, teamMembersListCmd,
 				),
+				Subcommands: []cli.Command{
+					{
+						Name:      "add",
+						Usage:     "Add user to a team in an organization you administer",
+						ArgsUsage: "<team> <username>",
+						Flags: []cli.Flag{
+							stdOrgFlag,
+						},
+						Action: chain(
+							ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+							setUserEnv, checkRequiredFlags, teamMembersAddCmd,
+						),
+					},
+					{
+						Name:      "remove",
+						Usage:     "Remove user from a team in an organization you administer",
+						ArgsUsage: "<team> <username>",
+						Flags: []cli.Flag{
+							stdOrgFlag,
+						},
+						Action: chain(
+							ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+							setUserEnv, checkRequiredFlags, teamMembersRemoveCmd,
+						),
+					},
+				},
 			},
 			{
 				Name:  "list",
@@ -61,6 +87,23 @@ func init() {
 					createTeamCmd,
 				),
 			},
+			{
+				Name:      "delete",
+				Usage:     "Delete a team from an organization you administer",
+				ArgsUsage: "<team>",
+				Flags: []cli.Flag{
+					stdOrgFlag,
+					cli.BoolFlag{
+						Name:  "force",
+						Usage: "Remove the team's members, and delete it, even if it isn't empty",
+					},
+					stdAutoAcceptFlag,
+				},
+				Action: chain(
+					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
+					setUserEnv, checkRequiredFlags, deleteTeamCmd,
+				),
+			},
 			{
 				Name:      "remove",
 				Usage:     "Remove user from a specified team in an organization you administer",
@@ -320,11 +363,11 @@ func createTeamCmd(ctx *cli.Context) error {
 		return handleSelectError(err, "Org selection failed")
 	}
 	if org == nil && !newOrg {
-		fmt.Println("")
+		decorate()
 		return errs.NewExitError("Org not found.")
 	}
 	if newOrg && oName == "" {
-		fmt.Println("")
+		decorate()
 		return errs.NewExitError("Invalid org name")
 	}
 
@@ -340,120 +383,197 @@ func createTeamCmd(ctx *cli.Context) error {
 		return handleSelectError(err, teamCreateFailed)
 	}
 
+	// The system teams exist in every org; don't bother the registry with a
+	// request that can only fail.
+	if isSystemTeamName(teamName) {
+		decorate()
+		return errs.NewExitError(teamName + " is reserved for a system team.")
+	}
+
 	// Create the org now if needed
 	if org == nil && newOrg {
 		org, err = createOrgByName(c, ctx, client, oName)
 		if err != nil {
-			fmt.Println("")
+			decorate()
 			return err
 		}
 		orgID = org.ID
 	}
 
 	// Create our new team
-	fmt.Println("")
+	decorate()
 	_, err = client.Teams.Create(c, orgID, teamName, "")
 	if err != nil {
-		if strings.Contains(err.Error(), "resource exists") {
+		if apitypes.HasMessage(err, "resource exists") {
 			return errs.NewExitError("Team already exists")
 		}
 		return errs.NewErrorExitError(teamCreateFailed, err)
 	}
 
-	fmt.Printf("Team %s created.\n", teamName)
+	decoratef("Team %s created.\n", teamName)
 	return nil
 }
 
-const teamRemoveFailed = "Failed to remove team member."
+const teamDeleteFailed = "Could not delete team."
 
-func teamsRemoveCmd(ctx *cli.Context) error {
+func deleteTeamCmd(ctx *cli.Context) error {
 	args := ctx.Args()
-	if len(args) > 2 {
+	if len(args) < 1 || args[0] == "" {
+		return errs.NewUsageExitError("Missing team name", ctx)
+	}
+	if len(args) > 1 {
 		return errs.NewUsageExitError("Too many arguments", ctx)
 	}
-	if len(args) < 2 {
-		return errs.NewUsageExitError("Too few arguments", ctx)
+	teamName := args[0]
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
 	}
 
-	username := args[0]
-	teamName := args[1]
+	client := api.NewClient(cfg)
+	c := context.Background()
 
-	if username == "" {
-		return errs.NewUsageExitError("Invalid username", ctx)
-	}
-	if teamName == "" {
-		return errs.NewUsageExitError("Invalid team naem", ctx)
+	org, err := getOrg(c, client, ctx.String("org"))
+	if err != nil {
+		return err
 	}
 
-	cfg, err := config.LoadConfig()
+	session, err := client.Session.Who(c)
+	if err != nil {
+		return errs.NewErrorExitError(teamDeleteFailed, err)
+	}
+	err = preflightRole(c, client, session, org.ID, org.Body.Name, primitive.AdminTeamName, "delete its teams")
 	if err != nil {
 		return err
 	}
 
-	client := api.NewClient(cfg)
-	c := context.Background()
+	teams, err := client.Teams.GetByName(c, org.ID, teamName)
+	if err != nil {
+		return errs.NewErrorExitError(teamDeleteFailed, err)
+	}
+	if len(teams) != 1 {
+		return errs.NewExitError("Team not found.")
+	}
+	team := teams[0]
 
-	var wait sync.WaitGroup
-	wait.Add(2)
+	if team.Body.TeamType == primitive.SystemTeam {
+		return errs.NewExitError("The " + team.Body.Name + " team is created with every org, " +
+			"and can't be deleted.")
+	}
 
-	var uErr, oErr, tErr error
-	var org *api.OrgResult
-	var team api.TeamResult
-	var user *apitypes.Profile
+	memberships, err := client.Memberships.List(c, org.ID, nil, team.ID)
+	if err != nil {
+		return errs.NewErrorExitError(teamDeleteFailed, err)
+	}
+	if len(memberships) > 0 && !ctx.Bool("force") {
+		return errs.NewExitError(fmt.Sprintf("The %s team still has %d member(s). "+
+			"Use --force to remove them and delete the team.", team.Body.Name, len(memberships)))
+	}
 
-	go func() {
-		// Identify the org supplied
-		result, err := client.Orgs.GetByName(c, ctx.String("org"))
-		if result == nil || err != nil {
-			oErr = errs.NewExitError("Org not found.")
-			wait.Done()
-			return
+	if dryRun() {
+		var steps []planStep
+		if len(memberships) > 0 {
+			steps = append(steps, planStep{"remove members",
+				fmt.Sprintf("%d from team %s", len(memberships), team.Body.Name)})
 		}
-		org = result
+		steps = append(steps, planStep{"delete team", team.Body.Name})
+		printPlan(steps)
+		return nil
+	}
 
-		// Retrieve the team by name supplied
-		results, err := client.Teams.GetByName(c, org.ID, teamName)
-		if len(results) != 1 || err != nil {
-			tErr = errs.NewExitError("Team not found.")
-		} else {
-			team = results[0]
-		}
-		wait.Done()
-	}()
+	preamble := fmt.Sprintf("You are about to delete the %s team from %s.", team.Body.Name, org.Body.Name)
+	if len(memberships) > 0 {
+		preamble += fmt.Sprintf(" Its %d member(s) will be removed from it.", len(memberships))
+	}
+	abortErr := ConfirmDialogue(ctx, nil, &preamble)
+	if abortErr != nil {
+		return abortErr
+	}
 
-	go func() {
-		// Retrieve the user by name supplied
-		result, err := client.Profiles.ListByName(c, username)
-		if result == nil || err != nil {
-			uErr = errs.NewExitError("User not found.")
-		} else {
-			user = result
+	removed := 0
+	for _, m := range memberships {
+		err = client.Memberships.Delete(c, m.ID)
+		if err != nil {
+			return teamDeleteError(fmt.Sprintf("Could not remove the team's members; %d of %d removed.",
+				removed, len(memberships)), err)
 		}
-		wait.Done()
-	}()
+		removed++
+	}
 
-	wait.Wait()
-	if uErr != nil || oErr != nil || tErr != nil {
-		return cli.NewMultiError(
-			oErr,
-			uErr,
-			tErr,
-		)
+	err = client.Teams.Delete(c, team.ID)
+	if err != nil {
+		return teamDeleteError(teamDeleteFailed, err)
+	}
+
+	fmt.Printf("Team %s deleted; %d member(s) removed.\n", team.Body.Name, removed)
+	return nil
+}
+
+// teamDeleteError wraps a failure deleting a team or its memberships, naming
+// the admin team if the registry refused the session.
+func teamDeleteError(msg string, err error) error {
+	if apiErr, ok := err.(*apitypes.Error); ok && apiErr.Type == apitypes.UnauthorizedError {
+		return errs.NewErrorExitError("Must be a member of the admin team to delete teams.",
+			apitypes.FormatError(err))
+	}
+	return errs.NewErrorExitError(msg, err)
+}
+
+const teamRemoveFailed = "Failed to remove team member."
+
+func teamsRemoveCmd(ctx *cli.Context) error {
+	username, teamName, err := teamMemberArgs(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	return removeTeamMember(ctx, username, teamName, false)
+}
+
+func teamMembersRemoveCmd(ctx *cli.Context) error {
+	username, teamName, err := teamMemberArgs(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	return removeTeamMember(ctx, username, teamName, true)
+}
+
+// removeTeamMember removes username from teamName. If idempotent is true,
+// removing a user who isn't in the team succeeds without changing anything,
+// rather than being an error.
+func removeTeamMember(ctx *cli.Context, username, teamName string, idempotent bool) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	org, team, user, err := lookupTeamMember(c, client, ctx.String("org"), username, teamName)
+	if err != nil {
+		return err
 	}
 
 	// Lookup their membership row
-	memberships, mErr := client.Memberships.List(c, org.ID, user.ID, team.ID)
-	if mErr != nil || len(memberships) < 1 {
+	memberships, err := client.Memberships.List(c, org.ID, user.ID, team.ID)
+	if idempotent && err == nil && len(memberships) < 1 {
+		fmt.Println(username + " is not a member of the " + teamName + " team.")
+		return nil
+	}
+	if err != nil || len(memberships) < 1 {
 		return errs.NewExitError("Memberships not found.")
 	}
 
 	err = client.Memberships.Delete(c, memberships[0].ID)
 	if err != nil {
 		msg := teamRemoveFailed
-		if strings.Contains(err.Error(), "member of the") {
+		if apitypes.HasMessage(err, "member of the") {
 			msg = "Must be a member of the admin team to remove members"
 		}
-		if strings.Contains(err.Error(), "cannot remove") {
+		if apitypes.HasMessage(err, "cannot remove") {
 			msg = "Cannot remove members from the member team"
 		}
 		return errs.NewExitError(msg)
@@ -466,31 +586,96 @@ func teamsRemoveCmd(ctx *cli.Context) error {
 const teamAddFailed = "Failed to add team member, please try again"
 
 func teamsAddCmd(ctx *cli.Context) error {
+	username, teamName, err := teamMemberArgs(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	return addTeamMember(ctx, username, teamName, false)
+}
+
+func teamMembersAddCmd(ctx *cli.Context) error {
+	username, teamName, err := teamMemberArgs(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	return addTeamMember(ctx, username, teamName, true)
+}
+
+// addTeamMember adds username to teamName. If idempotent is true, adding an
+// existing member succeeds without changing anything, rather than being an
+// error.
+func addTeamMember(ctx *cli.Context, username, teamName string, idempotent bool) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	org, team, user, err := lookupTeamMember(c, client, ctx.String("org"), username, teamName)
+	if err != nil {
+		return err
+	}
+
+	err = client.Memberships.Create(c, user.ID, org.ID, team.ID)
+	if err != nil {
+		exists := apitypes.IsConflictError(err)
+		if exists && idempotent {
+			fmt.Println(username + " is already a member of the " + teamName + " team.")
+			return nil
+		}
+
+		msg := teamAddFailed
+		if apitypes.HasMessage(err, "member of the") {
+			msg = "Must be a member of the admin team to add members."
+		}
+		if exists {
+			msg = username + " is already a member of the " + teamName + " team."
+		}
+		if apitypes.HasMessage(err, "to the members team") {
+			msg = username + " cannot be added to the " + teamName + " team."
+		}
+		return errs.NewExitError(msg)
+	}
+
+	fmt.Println(username + " has been added to the " + teamName + " team.")
+	return nil
+}
+
+// teamMemberArgs returns the username and team name arguments. teamFirst
+// selects between the <team> <username> order used by the members
+// subcommands and the <username> <team> order of teams add and remove.
+func teamMemberArgs(ctx *cli.Context, teamFirst bool) (string, string, error) {
 	args := ctx.Args()
 	if len(args) > 2 {
-		return errs.NewUsageExitError("Too many arguments", ctx)
+		return "", "", errs.NewUsageExitError("Too many arguments", ctx)
 	}
 	if len(args) < 2 {
-		return errs.NewUsageExitError("Too few arguments", ctx)
+		return "", "", errs.NewUsageExitError("Too few arguments", ctx)
 	}
 
-	username := args[0]
-	teamName := args[1]
+	username, teamName := args[0], args[1]
+	if teamFirst {
+		username, teamName = args[1], args[0]
+	}
 
 	if username == "" {
-		return errs.NewUsageExitError("Invalid username", ctx)
+		return "", "", errs.NewUsageExitError("Invalid username", ctx)
 	}
 	if teamName == "" {
-		return errs.NewUsageExitError("Invalid team name", ctx)
+		return "", "", errs.NewUsageExitError("Invalid team name", ctx)
 	}
 
-	cfg, err := config.LoadConfig()
-	if err != nil {
-		return err
-	}
+	return username, teamName, nil
+}
 
-	client := api.NewClient(cfg)
-	c := context.Background()
+// lookupTeamMember concurrently resolves the org and team, and the profile of
+// the user whose membership is being changed.
+func lookupTeamMember(c context.Context, client *api.Client, orgName, username,
+	teamName string) (*api.OrgResult, *api.TeamResult, *apitypes.Profile, error) {
 
 	var wait sync.WaitGroup
 	wait.Add(2)
@@ -502,7 +687,7 @@ func teamsAddCmd(ctx *cli.Context) error {
 
 	go func() {
 		// Identify the org supplied
-		result, err := client.Orgs.GetByName(c, ctx.String("org"))
+		result, err := client.Orgs.GetByName(c, orgName)
 		if result == nil || err != nil {
 			oErr = errs.NewExitError("Org not found.")
 			wait.Done()
@@ -514,10 +699,9 @@ func teamsAddCmd(ctx *cli.Context) error {
 		results, err := client.Teams.GetByName(c, org.ID, teamName)
 		if len(results) != 1 || err != nil {
 			tErr = errs.NewExitError("Team not found.")
-			wait.Done()
-			return
+		} else {
+			team = results[0]
 		}
-		team = results[0]
 		wait.Done()
 	}()
 
@@ -534,30 +718,14 @@ func teamsAddCmd(ctx *cli.Context) error {
 
 	wait.Wait()
 	if uErr != nil || oErr != nil || tErr != nil {
-		return cli.NewMultiError(
+		return nil, nil, nil, cli.NewMultiError(
 			oErr,
 			uErr,
 			tErr,
 		)
 	}
 
-	err = client.Memberships.Create(c, user.ID, org.ID, team.ID)
-	if err != nil {
-		msg := teamAddFailed
-		if strings.Contains(err.Error(), "member of the") {
-			msg = "Must be a member of the admin team to add members."
-		}
-		if strings.Contains(err.Error(), "resource exists") {
-			msg = username + " is already a member of the " + teamName + " team."
-		}
-		if strings.Contains(err.Error(), "to the members team") {
-			msg = username + " cannot be added to the " + teamName + " team."
-		}
-		return errs.NewExitError(msg)
-	}
-
-	fmt.Println(username + " has been added to the " + teamName + " team.")
-	return nil
+	return org, &team, user, nil
 }
 
 // isMachineTeam returns whether or not the given team represents a machine
@@ -565,3 +733,14 @@ func teamsAddCmd(ctx *cli.Context) error {
 func isMachineTeam(team *primitive.Team) bool {
 	return team.TeamType == primitive.MachineTeam || (team.TeamType == primitive.SystemTeam && team.Name == primitive.MachineTeamName)
 }
+
+// isSystemTeamName returns whether or not the given name is reserved for one
+// of the system teams created with every org.
+func isSystemTeamName(name string) bool {
+	switch strings.ToLower(name) {
+	case primitive.OwnerTeamName, primitive.AdminTeamName,
+		primitive.MemberTeamName, primitive.MachineTeamName:
+		return true
+	}
+	return false
+}
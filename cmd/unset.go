@@ -15,7 +15,7 @@ func init() {
 		Usage:     "Remove a secret from a service and environment",
 		ArgsUsage: "<name|path>",
 		Category:  "SECRETS",
-		Flags:     append(setUnsetFlags, stdAutoAcceptFlag),
+		Flags:     append(setUnsetFlags, stdAutoAcceptFlag, setStrictFlag),
 		Action: chain(
 			ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
 			setSliceDefaults, unsetCmd,
@@ -48,7 +48,7 @@ func unsetCmd(ctx *cli.Context) error {
 	}
 
 	var cred *apitypes.CredentialEnvelope
-	cred, err = setCredential(ctx, args[0], func() *apitypes.CredentialValue {
+	cred, err = setCredential(ctx, args[0], nil, func() *apitypes.CredentialValue {
 		return apitypes.NewUnsetCredentialValue()
 	})
 
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/manifoldco/torus-cli/config"
+)
+
+// updateCheckTTL is how long the result of checking for a newer release is
+// reused before the releases endpoint is queried again.
+const updateCheckTTL = 24 * time.Hour
+
+// updateCheckTimeout bounds the request to the releases endpoint, so an
+// unreachable endpoint doesn't hold up 'torus version'.
+const updateCheckTimeout = 5 * time.Second
+
+// updateCheck is the cached result of the last check for a newer release.
+type updateCheck struct {
+	URL       string    `json:"url"`
+	CheckedAt time.Time `json:"checked_at"`
+	Latest    string    `json:"latest"`
+}
+
+// latestRelease returns the latest released version of Torus, reusing the
+// last result if it was fetched from the same endpoint within updateCheckTTL.
+func latestRelease(cfg *config.Config, now time.Time) (string, error) {
+	url := cfg.UpdateURL.String()
+
+	cached := loadUpdateCheck(cfg.UpdateCheckPath)
+	if cached != nil && cached.URL == url && now.Sub(cached.CheckedAt) < updateCheckTTL {
+		return cached.Latest, nil
+	}
+
+	latest, err := fetchLatestRelease(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	// The cache only saves a request, so failing to write it isn't an error.
+	saveUpdateCheck(cfg.UpdateCheckPath, &updateCheck{
+		URL:       url,
+		CheckedAt: now,
+		Latest:    latest,
+	})
+
+	return latest, nil
+}
+
+// fetchLatestRelease queries the releases endpoint, which responds with a JSON
+// object such as {"version": "0.22.0"}.
+func fetchLatestRelease(cfg *config.Config) (string, error) {
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	if cfg.Proxy != nil {
+		transport.Proxy = http.ProxyURL(cfg.Proxy)
+	}
+	client := &http.Client{Transport: transport, Timeout: updateCheckTimeout}
+
+	resp, err := client.Get(cfg.UpdateURL.String())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s responded with %s", cfg.UpdateURL, resp.Status)
+	}
+
+	release := struct {
+		Version string `json:"version"`
+	}{}
+	err = json.NewDecoder(resp.Body).Decode(&release)
+	if err != nil {
+		return "", err
+	}
+	if release.Version == "" {
+		return "", fmt.Errorf("%s did not include a version", cfg.UpdateURL)
+	}
+
+	return release.Version, nil
+}
+
+// loadUpdateCheck returns the cached update check at path, or nil if there
+// isn't a readable one.
+func loadUpdateCheck(path string) *updateCheck {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	check := &updateCheck{}
+	if json.Unmarshal(b, check) != nil || check.Latest == "" {
+		return nil
+	}
+
+	return check
+}
+
+func saveUpdateCheck(path string, check *updateCheck) error {
+	b, err := json.Marshal(check)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+// newerVersion reports whether version a is newer than version b. Versions
+// that aren't dotted numbers, such as development builds, are never newer or
+// older than any other.
+func newerVersion(a, b string) bool {
+	ap, ok := parseVersion(a)
+	if !ok {
+		return false
+	}
+	bp, ok := parseVersion(b)
+	if !ok {
+		return false
+	}
+
+	for i := 0; i < len(ap) || i < len(bp); i++ {
+		var x, y int
+		if i < len(ap) {
+			x = ap[i]
+		}
+		if i < len(bp) {
+			y = bp[i]
+		}
+		if x != y {
+			return x > y
+		}
+	}
+
+	return false
+}
+
+func parseVersion(v string) ([]int, bool) {
+	parts := strings.Split(strings.TrimPrefix(v, "v"), ".")
+	nums := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		nums[i] = n
+	}
+
+	return nums, true
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/manifoldco/torus-cli/config"
+)
+
+func TestNewerVersion(t *testing.T) {
+	tcs := []struct {
+		a, b  string
+		newer bool
+	}{
+		{"0.22.0", "0.21.1", true},
+		{"v0.22.0", "0.21.1", true},
+		{"0.21.10", "0.21.9", true},
+		{"1.0", "0.99.99", true},
+		{"0.21.1", "0.21.1", false},
+		{"0.21.0", "0.21", false},
+		{"0.21.0", "0.22.0", false},
+		{"0.22.0", "alpha", false},
+		{"alpha", "0.22.0", false},
+	}
+
+	for _, tc := range tcs {
+		if newer := newerVersion(tc.a, tc.b); newer != tc.newer {
+			t.Errorf("newerVersion(%q, %q) = %t, expected %t", tc.a, tc.b, newer, tc.newer)
+		}
+	}
+}
+
+func TestLatestRelease(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"version": "0.22.0"}`)
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "torus-update-check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		UpdateURL:       u,
+		UpdateCheckPath: filepath.Join(dir, "update-check.json"),
+	}
+
+	now := time.Now()
+	for i, at := range []time.Time{now, now.Add(time.Hour), now.Add(25 * time.Hour)} {
+		latest, err := latestRelease(cfg, at)
+		if err != nil {
+			t.Fatalf("Unexpected error on check %d: %s", i, err)
+		}
+		if latest != "0.22.0" {
+			t.Errorf("Expected latest version 0.22.0, got %s", latest)
+		}
+	}
+
+	if requests != 2 {
+		t.Errorf("Expected the cached result to be reused once, got %d requests", requests)
+	}
+
+	srv.Close()
+	_, err = latestRelease(cfg, now.Add(50*time.Hour))
+	if err == nil {
+		t.Error("Expected an error when the endpoint is unreachable")
+	}
+}
@@ -3,11 +3,11 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	"github.com/urfave/cli"
 
 	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/config"
 	"github.com/manifoldco/torus-cli/errs"
 )
@@ -57,7 +57,7 @@ func verifyEmail(ctx *cli.Context, code *string, subCommand bool) error {
 
 	err = client.Users.VerifyEmail(c, verifyCode)
 	if err != nil {
-		if strings.Contains(err.Error(), "wrong user state: active") {
+		if apitypes.HasMessage(err, "wrong user state: active") {
 			return errs.NewExitError("Email already verified :)")
 		}
 		return errs.NewExitError("Email verification failed, please try again.")
@@ -6,6 +6,7 @@ import (
 	"os"
 	"sync"
 	"text/tabwriter"
+	"time"
 
 	"github.com/urfave/cli"
 
@@ -19,7 +20,13 @@ func init() {
 		Name:     "version",
 		Usage:    "Display versions of utility components",
 		Category: "SYSTEM",
-		Action:   VersionLookup,
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "check",
+				Usage: "Check whether a newer version of Torus has been released",
+			},
+		},
+		Action: VersionLookup,
 	}
 	Cmds = append(Cmds, version)
 }
@@ -39,17 +46,41 @@ func listVersionsCmd(ctx *cli.Context) error {
 
 	client := api.NewClient(cfg)
 	c := context.Background()
-	daemonVersion, registryVersion, err := retrieveVersions(c, client)
+	daemonVersion, err := client.Version.Get(c)
 	if err != nil {
 		return err
 	}
 
+	// The registry may be unreachable, for example when offline, which
+	// shouldn't stop the local versions from being shown.
+	registryVersion := "unavailable"
+	if v, err := client.Version.GetRegistry(c); err == nil {
+		registryVersion = v.Version
+	}
+
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
 	fmt.Fprintf(w, "%s\t%s\n", "CLI", cfg.Version)
 	fmt.Fprintf(w, "%s\t%s\n", "Daemon", daemonVersion.Version)
-	fmt.Fprintf(w, "%s\t%s\n", "Registry", registryVersion.Version)
+	fmt.Fprintf(w, "%s\t%s\n", "Registry", registryVersion)
 	w.Flush()
 
+	if !ctx.Bool("check") {
+		return nil
+	}
+
+	fmt.Println("")
+	latest, err := latestRelease(cfg, time.Now())
+	switch {
+	case err != nil:
+		fmt.Printf("Could not check for a newer version: %s\n", err)
+	case newerVersion(latest, cfg.Version):
+		fmt.Printf("Version %s of Torus is available; you have %s.\n", latest, cfg.Version)
+	case newerVersion(cfg.Version, latest) || cfg.Version == latest:
+		fmt.Printf("You have the latest version of Torus (%s).\n", latest)
+	default:
+		fmt.Printf("The latest version of Torus is %s.\n", latest)
+	}
+
 	return nil
 }
 
@@ -30,7 +30,7 @@ func init() {
 			stdInstanceFlag,
 			cli.BoolFlag{
 				Name:  "verbose, v",
-				Usage: "list the sources of the values",
+				Usage: "list the sources, descriptions and tags of the values",
 			},
 		},
 		Action: chain(
@@ -59,7 +59,8 @@ func viewCmd(ctx *cli.Context) error {
 		key := strings.ToUpper(name)
 		if verbose {
 			spath := (*secret.Body).GetPathExp().String() + "/" + name
-			fmt.Fprintf(w, "%s=%s\t%s\n", key, value.String(), spath)
+			meta := credentialMetadata(*secret.Body)
+			fmt.Fprintf(w, "%s=%s\t%s\t%s\n", key, value.String(), spath, meta)
 		} else {
 			fmt.Fprintf(w, "%s=%s\n", key, value.String())
 
@@ -69,7 +70,20 @@ func viewCmd(ctx *cli.Context) error {
 	return nil
 }
 
+// strictFlag makes commands fail when any secret can't be decrypted, rather
+// than warning and continuing with the rest.
+var strictFlag = cli.BoolFlag{
+	Name:  "strict",
+	Usage: "Fail if any secret can't be decrypted, instead of skipping it",
+}
+
 func getSecrets(ctx *cli.Context) ([]apitypes.CredentialEnvelope, string, error) {
+	return getSecretsForEnv(ctx, ctx.String("environment"))
+}
+
+// getSecretsForEnv is like getSecrets, for the given environment rather than
+// the one from the --environment flag.
+func getSecretsForEnv(ctx *cli.Context, env string) ([]apitypes.CredentialEnvelope, string, error) {
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		return nil, "", err
@@ -89,20 +103,26 @@ func getSecrets(ctx *cli.Context) ([]apitypes.CredentialEnvelope, string, error)
 	}
 
 	parts := []string{
-		"", ctx.String("org"), ctx.String("project"), ctx.String("environment"),
+		"", ctx.String("org"), ctx.String("project"), env,
 		ctx.String("service"), identity, ctx.String("instance"),
 	}
 
 	path := strings.Join(parts, "/")
 
 	secrets, err := client.Credentials.Get(c, path)
+	if failures, ok := err.(*apitypes.DecryptionFailuresError); ok && !ctx.Bool("strict") {
+		warn(failures.Error())
+		err = nil
+	}
 	if err != nil {
 		return nil, "", errs.NewErrorExitError("Error fetching secrets", err)
 	}
 
 	cset := credentialSet{}
 	for _, c := range secrets {
-		cset.Add(c)
+		if err := cset.Add(c); err != nil {
+			return nil, "", errs.NewExitError(err.Error())
+		}
 	}
 
 	return cset.ToSlice(), path, nil
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/urfave/cli"
+
+	"github.com/manifoldco/torus-cli/api"
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/config"
+	"github.com/manifoldco/torus-cli/errs"
+)
+
+func init() {
+	whoami := cli.Command{
+		Name:     "whoami",
+		Usage:    "Display the identity of the current session",
+		Category: "ACCOUNT",
+		Flags: []cli.Flag{
+			formatFlag("table", "Format used to display data (table, json)"),
+		},
+		Action: chain(ensureDaemon, ensureSession, whoamiCmd),
+	}
+	Cmds = append(Cmds, whoami)
+}
+
+func whoamiCmd(ctx *cli.Context) error {
+	format := ctx.String("format")
+	if format != "table" && format != "json" {
+		return errs.NewUsageExitError("Unknown format: "+format, ctx)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client := api.NewClient(cfg)
+	c := context.Background()
+
+	self, err := client.Session.Self(c)
+	if err != nil {
+		return errs.NewErrorExitError("Could not retrieve session.", err)
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(self)
+	}
+
+	session, err := api.NewSession(self)
+	if err != nil {
+		return errs.NewErrorExitError("Could not retrieve session.", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
+	if session.Type() == apitypes.MachineSession {
+		// Machines have no username or email; identify them by the
+		// machine and the token they logged in with.
+		fmt.Fprintf(w, "Machine ID\t%s\n", session.ID())
+		fmt.Fprintf(w, "Name\t%s\n", session.Name())
+		fmt.Fprintf(w, "Token ID\t%s\n", session.AuthID())
+	} else {
+		fmt.Fprintf(w, "ID\t%s\n", session.ID())
+		fmt.Fprintf(w, "Name\t%s\n", session.Name())
+		fmt.Fprintf(w, "Username\t%s\n", session.Username())
+		fmt.Fprintf(w, "Email\t%s\n", session.Email())
+	}
+	fmt.Fprintf(w, "Session\t%s\n", session.Type())
+	w.Flush()
+
+	return nil
+}
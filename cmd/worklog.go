@@ -48,7 +48,6 @@ func init() {
 					ensureDaemon, ensureSession, loadDirPrefs, loadPrefDefaults,
 					checkRequiredFlags, worklogResolve,
 				),
-				Hidden: true, // XXX nothing can be resolved automatically yet
 			},
 		},
 	}
@@ -74,6 +73,37 @@ func worklogList(ctx *cli.Context) error {
 		return err
 	}
 
+	if len(items) == 0 {
+		fmt.Println("No worklog items.")
+		return nil
+	}
+
+	var automatic, manual []apitypes.WorklogItem
+	for _, item := range items {
+		if item.Type().Automatic() {
+			automatic = append(automatic, item)
+		} else {
+			manual = append(manual, item)
+		}
+	}
+
+	if len(automatic) > 0 {
+		fmt.Println("Automatic, run `torus worklog resolve` to perform:")
+		printWorklogItems(automatic)
+	}
+
+	if len(manual) > 0 {
+		if len(automatic) > 0 {
+			fmt.Println("")
+		}
+		fmt.Println("Manual, see `torus worklog view` for what to do:")
+		printWorklogItems(manual)
+	}
+
+	return nil
+}
+
+func printWorklogItems(items []apitypes.WorklogItem) {
 	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
 	fmt.Fprintln(w, "IDENTITY\tTYPE\tSUBJECT")
 	for _, item := range items {
@@ -81,7 +111,6 @@ func worklogList(ctx *cli.Context) error {
 	}
 
 	w.Flush()
-	return nil
 }
 
 func worklogView(ctx *cli.Context) error {
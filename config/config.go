@@ -9,6 +9,8 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"strconv"
+	"time"
 
 	"github.com/manifoldco/torus-cli/data"
 	"github.com/manifoldco/torus-cli/errs"
@@ -21,20 +23,121 @@ var apiVersion = "0.1.0"
 
 const requiredPermissions = 0700
 
+// defaultGracePeriod is how long the daemon waits for in-flight requests to
+// complete on shutdown, unless overridden in the user's preferences.
+const defaultGracePeriod = 10 * time.Second
+
+// Defaults for the pool of keep-alive connections to the registry.
+const (
+	defaultMaxIdleConnsPerHost = 16
+	defaultIdleConnTimeout     = 90 * time.Second
+)
+
+// Defaults for retrying idempotent registry requests that fail transiently.
+const (
+	defaultRetryAttempts  = 3
+	defaultRetryBaseDelay = 250 * time.Millisecond
+)
+
+// defaultSocketMode is the file mode of the daemon's socket, unless
+// overridden in the user's preferences.
+const defaultSocketMode os.FileMode = 0600
+
+// Defaults for the limits on secrets stored by the daemon.
+const (
+	defaultMaxCredentialSize     = 64 * 1024
+	defaultMaxKeyringCredentials = 1000
+)
+
+// defaultMaxResponseSize is the largest registry response body the daemon
+// reads, unless overridden in the user's preferences. It is generous enough
+// for large credential graphs, while keeping a misbehaving registry from
+// exhausting the daemon's memory.
+const defaultMaxResponseSize = 32 * 1024 * 1024
+
+// defaultDecryptWorkers is the number of secrets the daemon decrypts at once,
+// unless overridden in the user's preferences.
+const defaultDecryptWorkers = 8
+
+// defaultUpdateURL is the releases endpoint describing the latest version of
+// Torus, unless overridden in the user's preferences.
+const defaultUpdateURL = "https://get.torus.sh/latest.json"
+
 // Config represents the static and user defined configuration data
 // for Torus.
 type Config struct {
 	APIVersion string
 	Version    string
 
-	TorusRoot  string
-	SocketPath string
-	PidPath    string
-	DBPath     string
+	// Profile is the name of the profile in use, which selects the registry
+	// and the TorusRoot.
+	Profile string
+
+	TorusRoot       string
+	SocketPath      string
+	PidPath         string
+	DBPath          string
+	UpdateCheckPath string
 
 	RegistryURI *url.URL
 	CABundle    *x509.CertPool
 	PublicKey   *prefs.PublicKey
+
+	GracePeriod time.Duration
+
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+
+	RetryAttempts  int
+	RetryBaseDelay time.Duration
+
+	// MaxCredentialSize is the largest secret value, in bytes, and
+	// MaxKeyringCredentials the most secrets per keyring, the daemon will
+	// store.
+	MaxCredentialSize     int
+	MaxKeyringCredentials int
+
+	// MaxResponseSize is the largest response body, in bytes, the daemon
+	// reads from the registry.
+	MaxResponseSize int64
+
+	// DecryptWorkers is the size of the pool of goroutines used to decrypt
+	// secrets.
+	DecryptWorkers int
+
+	// Metrics enables the daemon's /v1/metrics endpoint, which is only
+	// served on its socket.
+	Metrics bool
+
+	// SocketMode is the file mode of the daemon's socket. If SocketUID is
+	// set, only connections from processes running as that user id are
+	// accepted.
+	SocketMode os.FileMode
+	SocketUID  *int
+
+	// Proxy, if set, is used for registry requests instead of any proxy
+	// from the environment.
+	Proxy *url.URL
+
+	// Verbose enables logging of each request's timing to stderr. It is set
+	// through the TORUS_VERBOSE environment variable.
+	Verbose bool
+
+	// LogFormat is the format of the daemon's log, "text" or "json". It can
+	// be overridden with the TORUS_LOG_FORMAT environment variable.
+	LogFormat string
+
+	// UpdateURL is queried for the latest released version of Torus.
+	UpdateURL *url.URL
+
+	// Timeout, if set, bounds how long a command waits on the daemon and
+	// registry. It is set through the timeout preference, or the
+	// TORUS_TIMEOUT environment variable.
+	Timeout time.Duration
+
+	// DryRun forbids requests that would change anything. It is set through
+	// the TORUS_DRY_RUN environment variable.
+	DryRun bool
 }
 
 // NewConfig returns a new Config, with loaded user preferences.
@@ -49,68 +152,259 @@ func NewConfig(torusRoot string) (*Config, error) {
 		return nil, fmt.Errorf("Failed to load public key.")
 	}
 
-	caBundle, err := loadCABundle(preferences.Core.CABundleFile)
+	profile, err := prefs.LoadProfile(preferences, preferences.ActiveProfile())
 	if err != nil {
 		return nil, err
 	}
 
-	registryURI, err := url.Parse(preferences.Core.RegistryURI)
+	caBundle, err := loadCABundle(profile.CABundleFile)
+	if err != nil {
+		return nil, err
+	}
+
+	extraCAs := preferences.Core.ExtraCABundleFile
+	if f := os.Getenv("TORUS_CA_BUNDLE"); f != "" {
+		extraCAs = f
+	}
+	if extraCAs != "" {
+		err = addCABundle(caBundle, extraCAs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	registryURI, err := url.Parse(profile.RegistryURI)
 	if err != nil {
 		return nil, fmt.Errorf("Invalid registry_uri.")
 	}
 
+	var proxy *url.URL
+	if preferences.Core.Proxy != "" {
+		proxy, err = url.Parse(preferences.Core.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid proxy.")
+		}
+	}
+
+	gracePeriod := defaultGracePeriod
+	if preferences.Core.ShutdownGracePeriod > 0 {
+		gracePeriod = time.Duration(preferences.Core.ShutdownGracePeriod) * time.Second
+	}
+
+	maxIdleConnsPerHost := defaultMaxIdleConnsPerHost
+	if preferences.Core.MaxIdleConnsPerHost > 0 {
+		maxIdleConnsPerHost = preferences.Core.MaxIdleConnsPerHost
+	}
+
+	idleConnTimeout := defaultIdleConnTimeout
+	if preferences.Core.IdleConnTimeout > 0 {
+		idleConnTimeout = time.Duration(preferences.Core.IdleConnTimeout) * time.Second
+	}
+
+	retryAttempts := defaultRetryAttempts
+	if preferences.Core.RetryAttempts > 0 {
+		retryAttempts = preferences.Core.RetryAttempts
+	}
+
+	retryBaseDelay := defaultRetryBaseDelay
+	if preferences.Core.RetryBaseDelay > 0 {
+		retryBaseDelay = time.Duration(preferences.Core.RetryBaseDelay) * time.Millisecond
+	}
+
+	maxCredentialSize := defaultMaxCredentialSize
+	if preferences.Core.MaxCredentialSize > 0 {
+		maxCredentialSize = preferences.Core.MaxCredentialSize
+	}
+
+	maxKeyringCredentials := defaultMaxKeyringCredentials
+	if preferences.Core.MaxKeyringCredentials > 0 {
+		maxKeyringCredentials = preferences.Core.MaxKeyringCredentials
+	}
+
+	maxResponseSize := int64(defaultMaxResponseSize)
+	if preferences.Core.MaxResponseSize > 0 {
+		maxResponseSize = preferences.Core.MaxResponseSize
+	}
+
+	decryptWorkers := defaultDecryptWorkers
+	if preferences.Core.DecryptWorkers > 0 {
+		decryptWorkers = preferences.Core.DecryptWorkers
+	}
+
+	socketMode := defaultSocketMode
+	if m := preferences.Core.SocketMode; m != "" {
+		mode, err := strconv.ParseUint(m, 8, 32)
+		if err != nil || mode > 0777 {
+			return nil, fmt.Errorf("Invalid socket_mode: %s", m)
+		}
+		socketMode = os.FileMode(mode)
+	}
+
+	var socketUID *int
+	if u := preferences.Core.SocketUID; u != "" {
+		uid, err := strconv.Atoi(u)
+		if err != nil || uid < 0 {
+			return nil, fmt.Errorf("Invalid socket_uid: %s", u)
+		}
+		socketUID = &uid
+	}
+
+	logFormat := preferences.Core.LogFormat
+	if f := os.Getenv("TORUS_LOG_FORMAT"); f != "" {
+		logFormat = f
+	}
+	switch logFormat {
+	case "":
+		logFormat = "text"
+	case "text", "json":
+	default:
+		return nil, fmt.Errorf("Invalid log_format: %s", logFormat)
+	}
+
+	updateURI := defaultUpdateURL
+	if preferences.Core.UpdateURL != "" {
+		updateURI = preferences.Core.UpdateURL
+	}
+	updateURL, err := url.Parse(updateURI)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid update_url.")
+	}
+
+	var timeout time.Duration
+	t := preferences.Core.Timeout
+	if envTimeout := os.Getenv("TORUS_TIMEOUT"); envTimeout != "" {
+		t = envTimeout
+	}
+	if t != "" {
+		timeout, err = time.ParseDuration(t)
+		if err != nil || timeout < 0 {
+			return nil, fmt.Errorf("Invalid timeout: %s", t)
+		}
+	}
+
 	cfg := &Config{
 		APIVersion: apiVersion,
 		Version:    Version,
 
-		TorusRoot:  torusRoot,
-		SocketPath: path.Join(torusRoot, "daemon.socket"),
-		PidPath:    path.Join(torusRoot, "daemon.pid"),
-		DBPath:     path.Join(torusRoot, "daemon.db"),
+		Profile: profile.Name,
+
+		TorusRoot:       torusRoot,
+		SocketPath:      path.Join(torusRoot, "daemon.socket"),
+		PidPath:         path.Join(torusRoot, "daemon.pid"),
+		DBPath:          path.Join(torusRoot, "daemon.db"),
+		UpdateCheckPath: path.Join(torusRoot, "update-check.json"),
 
 		RegistryURI: registryURI,
 		CABundle:    caBundle,
 		PublicKey:   publicKey,
+
+		GracePeriod: gracePeriod,
+
+		MaxIdleConnsPerHost: maxIdleConnsPerHost,
+		IdleConnTimeout:     idleConnTimeout,
+
+		RetryAttempts:  retryAttempts,
+		RetryBaseDelay: retryBaseDelay,
+
+		MaxCredentialSize:     maxCredentialSize,
+		MaxKeyringCredentials: maxKeyringCredentials,
+
+		MaxResponseSize: maxResponseSize,
+
+		DecryptWorkers: decryptWorkers,
+		Metrics:        preferences.Core.Metrics,
+
+		SocketMode: socketMode,
+		SocketUID:  socketUID,
+
+		Proxy: proxy,
+
+		LogFormat: logFormat,
+
+		UpdateURL: updateURL,
+
+		Verbose: os.Getenv("TORUS_VERBOSE") != "",
+		Timeout: timeout,
+		DryRun:  DryRun(),
 	}
 
 	return cfg, nil
 }
 
-// CreateTorusRoot creates the root directory for the Torus daemon.
+// CreateTorusRoot creates the root directory for the Torus daemon. Each
+// profile other than the default has its own root, under profiles/ in the
+// default root, so that its daemon and session are kept apart.
 func CreateTorusRoot() (string, error) {
 	torusRoot := os.Getenv("TORUS_ROOT")
 	if len(torusRoot) == 0 {
 		torusRoot = path.Join(os.Getenv("HOME"), ".torus")
 	}
 
-	src, err := os.Stat(torusRoot)
-	if err != nil && !os.IsNotExist(err) {
+	err := createDir(torusRoot)
+	if err != nil {
+		return "", err
+	}
+
+	preferences, err := prefs.NewPreferences(true)
+	if err != nil {
 		return "", err
 	}
 
+	profile := preferences.ActiveProfile()
+	if profile == prefs.DefaultProfile {
+		return torusRoot, nil
+	}
+
+	if _, err := prefs.LoadProfile(preferences, profile); err != nil {
+		return "", err
+	}
+
+	profilesRoot := path.Join(torusRoot, "profiles")
+	err = createDir(profilesRoot)
+	if err != nil {
+		return "", err
+	}
+
+	torusRoot = path.Join(profilesRoot, profile)
+	err = createDir(torusRoot)
+	if err != nil {
+		return "", err
+	}
+
+	return torusRoot, nil
+}
+
+// createDir creates dir with the required permissions if it does not exist,
+// or checks the permissions of an existing dir.
+func createDir(dir string) error {
+	src, err := os.Stat(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
 	if err == nil && !src.IsDir() {
-		return "", fmt.Errorf("%s exists but is not a dir", torusRoot)
+		return fmt.Errorf("%s exists but is not a dir", dir)
 	}
 
 	if os.IsNotExist(err) {
-		err = os.Mkdir(torusRoot, requiredPermissions)
+		err = os.Mkdir(dir, requiredPermissions)
 		if err != nil {
-			return "", err
+			return err
 		}
 
-		src, err = os.Stat(torusRoot)
+		src, err = os.Stat(dir)
 		if err != nil {
-			return "", err
+			return err
 		}
 	}
 
 	fMode := src.Mode()
 	if fMode.Perm() != requiredPermissions {
-		return "", fmt.Errorf("%s has permissions %d requires %d",
-			torusRoot, fMode.Perm(), requiredPermissions)
+		return fmt.Errorf("%s has permissions %d requires %d",
+			dir, fMode.Perm(), requiredPermissions)
 	}
 
-	return torusRoot, nil
+	return nil
 }
 
 // Load CABundle creates a new CertPool from the given filename
@@ -137,6 +431,34 @@ func loadCABundle(cafile string) (*x509.CertPool, error) {
 	return c, nil
 }
 
+// addCABundle adds the certificates in the PEM file cafile to pool, so they
+// are trusted along with the bundled CAs.
+func addCABundle(pool *x509.CertPool, cafile string) error {
+	pem, err := ioutil.ReadFile(cafile)
+	if err != nil {
+		return fmt.Errorf("Unable to read CA bundle %s: %s", cafile, err)
+	}
+
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("Unable to load CA bundle from %s: no PEM encoded certificates found.", cafile)
+	}
+
+	return nil
+}
+
+// DryRun reports whether the TORUS_DRY_RUN environment variable asks for a
+// dry run. It is parsed as a boolean; a value that isn't one is taken as true,
+// so that a mistyped value can't turn a dry run into a real one.
+func DryRun() bool {
+	v := os.Getenv("TORUS_DRY_RUN")
+	if v == "" {
+		return false
+	}
+
+	dryRun, err := strconv.ParseBool(v)
+	return dryRun || err != nil
+}
+
 // LoadConfig loads the config, standardizing cli errors on failure.
 func LoadConfig() (*Config, error) {
 	torusRoot, err := CreateTorusRoot()
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// selfSignedCert returns a certificate for 127.0.0.1 that is its own CA,
+// along with its PEM encoding.
+func selfSignedCert(t *testing.T) (tls.Certificate, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"Torus Test CA"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	return cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestAddCABundle(t *testing.T) {
+	cert, certPEM := selfSignedCert(t)
+
+	dir, err := ioutil.TempDir("", "torus-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cafile := filepath.Join(dir, "ca.pem")
+	err = ioutil.WriteFile(cafile, certPEM, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	srv.StartTLS()
+	defer srv.Close()
+
+	get := func(pool *x509.CertPool) error {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		}}
+		resp, err := client.Get(srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	pool := x509.NewCertPool()
+	if err := get(pool); err == nil {
+		t.Fatal("Expected the self-signed cert not to be trusted by default")
+	}
+
+	err = addCABundle(pool, cafile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := get(pool); err != nil {
+		t.Errorf("Expected the self-signed cert to be trusted, got %s", err)
+	}
+}
+
+func TestAddCABundleInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "torus-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cafile := filepath.Join(dir, "ca.pem")
+	err = ioutil.WriteFile(cafile, []byte("not a certificate"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := addCABundle(x509.NewCertPool(), cafile); err == nil {
+		t.Error("Expected an error for a file without certificates")
+	}
+	if err := addCABundle(x509.NewCertPool(), filepath.Join(dir, "missing.pem")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	defer os.Setenv("TORUS_DRY_RUN", os.Getenv("TORUS_DRY_RUN"))
+
+	tcs := []struct {
+		value  string
+		dryRun bool
+	}{
+		{"", false},
+		{"false", false},
+		{"0", false},
+		{"true", true},
+		{"1", true},
+		{"yes", true},
+	}
+
+	for _, tc := range tcs {
+		os.Setenv("TORUS_DRY_RUN", tc.value)
+		if got := DryRun(); got != tc.dryRun {
+			t.Errorf("TORUS_DRY_RUN=%q: expected %t, got %t", tc.value, tc.dryRun, got)
+		}
+	}
+}
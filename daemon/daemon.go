@@ -16,6 +16,7 @@ import (
 	"github.com/manifoldco/torus-cli/daemon/crypto"
 	"github.com/manifoldco/torus-cli/daemon/db"
 	"github.com/manifoldco/torus-cli/daemon/logic"
+	"github.com/manifoldco/torus-cli/daemon/metrics"
 	"github.com/manifoldco/torus-cli/daemon/registry"
 	"github.com/manifoldco/torus-cli/daemon/session"
 	"github.com/manifoldco/torus-cli/daemon/socket"
@@ -25,6 +26,7 @@ import (
 // cryptographic operations, and communication with the registry.
 type Daemon struct {
 	proxy       *socket.AuthProxy
+	client      *registry.Client
 	lock        lockfile.Lockfile // actually a string
 	session     session.Session
 	config      *config.Config
@@ -61,18 +63,31 @@ func New(cfg *config.Config) (*Daemon, error) {
 
 	session := session.NewSession()
 	cryptoEngine := crypto.NewEngine(session)
-	transport := socket.CreateHTTPTransport(cfg)
+	transport := socket.NewTransport(cfg)
+	retry := registry.RetryPolicy{
+		MaxAttempts: cfg.RetryAttempts,
+		BaseDelay:   cfg.RetryBaseDelay,
+	}
+
+	// Metrics are only kept when asked for, and are never served anywhere
+	// but the daemon's socket.
+	var m *metrics.Metrics
+	if cfg.Metrics {
+		m = metrics.New()
+	}
+
 	client := registry.NewClient(cfg.RegistryURI.String(), cfg.APIVersion,
-		cfg.Version, session, transport)
-	logic := logic.NewEngine(cfg, session, db, cryptoEngine, client)
+		cfg.Version, session, transport, retry, cfg.MaxResponseSize, m)
+	logic := logic.NewEngine(cfg, session, db, cryptoEngine, client, m)
 
-	proxy, err := socket.NewAuthProxy(cfg, session, db, transport, client, logic)
+	proxy, err := socket.NewAuthProxy(cfg, session, db, transport, client, logic, m)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to create auth proxy: %s", err)
 	}
 
 	daemon := &Daemon{
 		proxy:       proxy,
+		client:      client,
 		lock:        lock,
 		session:     session,
 		config:      cfg,
@@ -139,6 +154,37 @@ func (d *Daemon) Run() error {
 	return d.proxy.Listen()
 }
 
+// Reload re-reads the daemon's config, applying the registry proxy, idle
+// connection timeout, retry policy and shutdown grace period from it. The new
+// config is returned, for the caller to apply the log format.
+//
+// The registry URI is not reloaded, as the session belongs to the registry
+// it was started with; the daemon must be restarted to use a new one.
+func (d *Daemon) Reload() (*config.Config, error) {
+	cfg, err := config.NewConfig(d.config.TorusRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	d.proxy.Reload(cfg)
+	d.client.SetRetryPolicy(registry.RetryPolicy{
+		MaxAttempts: cfg.RetryAttempts,
+		BaseDelay:   cfg.RetryBaseDelay,
+	})
+
+	if cfg.RegistryURI.String() != d.config.RegistryURI.String() {
+		log.Printf("registry_uri changed to %s; restart the daemon to use it", cfg.RegistryURI)
+	}
+
+	return cfg, nil
+}
+
+// StopRequested returns a channel that is closed when a client asks the
+// daemon to stop, over the /v1/stop endpoint.
+func (d *Daemon) StopRequested() <-chan struct{} {
+	return d.proxy.StopRequested()
+}
+
 // Shutdown gracefully shuts down the daemon.
 func (d *Daemon) Shutdown() error {
 	if d.hasShutdown {
@@ -146,14 +192,17 @@ func (d *Daemon) Shutdown() error {
 	}
 
 	d.hasShutdown = true
-	if err := d.lock.Unlock(); err != nil {
-		return fmt.Errorf("Could not unlock: %s", err)
-	}
 
+	// Close the proxy first, so the lock is held until in-flight requests
+	// have drained.
 	if err := d.proxy.Close(); err != nil {
 		return fmt.Errorf("Could not stop http proxy: %s", err)
 	}
 
+	if err := d.lock.Unlock(); err != nil {
+		return fmt.Errorf("Could not unlock: %s", err)
+	}
+
 	if err := d.db.Close(); err != nil {
 		return fmt.Errorf("Could not close db: %s", err)
 	}
@@ -141,3 +141,49 @@ func (db *DB) Get(id *identity.ID, env envelope.Envelope) error {
 		return json.Unmarshal(b, env)
 	})
 }
+
+// SetValue stores the serialized value of v into the named bucket, under key.
+// It is used for the daemon's own records, which aren't envelopes from the
+// registry.
+func (db *DB) SetValue(bucket, key string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return db.db.Update(func(tx *bolt.Tx) error {
+		bkt, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+		return bkt.Put([]byte(key), b)
+	})
+}
+
+// GetValue reads the value stored under key in the named bucket into v. It
+// returns false if there is none.
+func (db *DB) GetValue(bucket, key string, v interface{}) (bool, error) {
+	var b []byte
+	err := db.db.View(func(tx *bolt.Tx) error {
+		if bkt := tx.Bucket([]byte(bucket)); bkt != nil {
+			b = bkt.Get([]byte(key))
+		}
+		if b == nil {
+			return nil
+		}
+		return json.Unmarshal(b, v)
+	})
+
+	return b != nil, err
+}
+
+// DeleteValue removes the value stored under key in the named bucket, if any.
+func (db *DB) DeleteValue(bucket, key string) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte(bucket))
+		if bkt == nil {
+			return nil
+		}
+		return bkt.Delete([]byte(key))
+	})
+}
@@ -0,0 +1,108 @@
+// Package logging sets up the daemon's log output, either as plain text or
+// as one JSON object per line for consumption by log shippers.
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+)
+
+// The log formats the daemon can write.
+const (
+	TextFormat = "text"
+	JSONFormat = "json"
+)
+
+// Log levels. Messages written through the standard logger are at the info
+// level; Warnf and Errorf write at the warn and error levels.
+const (
+	InfoLevel  = "info"
+	WarnLevel  = "warn"
+	ErrorLevel = "error"
+)
+
+// errorPrefix and warnPrefix mark error and warn level lines as they pass
+// through the standard logger. In the text format they are left in place.
+const (
+	errorPrefix = "error: "
+	warnPrefix  = "warning: "
+)
+
+// Setup directs the standard logger's output to w, in the given format.
+func Setup(w io.Writer, format string) {
+	if format == JSONFormat {
+		log.SetFlags(log.Lshortfile)
+		log.SetOutput(&jsonWriter{w: w, now: time.Now})
+		return
+	}
+
+	log.SetFlags(log.LstdFlags)
+	log.SetOutput(w)
+}
+
+// Errorf logs a message at the error level, formatted as with log.Printf.
+func Errorf(format string, v ...interface{}) {
+	log.Output(2, errorPrefix+fmt.Sprintf(format, v...))
+}
+
+// Warnf logs a message at the warn level, formatted as with log.Printf.
+func Warnf(format string, v ...interface{}) {
+	log.Output(2, warnPrefix+fmt.Sprintf(format, v...))
+}
+
+// entry is a single line of the JSON log format.
+type entry struct {
+	TS     string            `json:"ts"`
+	Level  string            `json:"level"`
+	Msg    string            `json:"msg"`
+	Fields map[string]string `json:"fields"`
+}
+
+// jsonWriter converts each line written by the standard logger into an
+// entry. The logger must be set to log.Lshortfile only, so that each line
+// starts with the source location of the call.
+type jsonWriter struct {
+	w   io.Writer
+	now func() time.Time
+}
+
+func (j *jsonWriter) Write(p []byte) (int, error) {
+	line := strings.TrimSuffix(string(p), "\n")
+
+	e := entry{
+		TS:     j.now().UTC().Format(time.RFC3339Nano),
+		Level:  InfoLevel,
+		Fields: map[string]string{},
+	}
+
+	if i := strings.Index(line, ": "); i >= 0 {
+		e.Fields["source"] = line[:i]
+		line = line[i+2:]
+	}
+
+	switch {
+	case strings.HasPrefix(line, errorPrefix):
+		e.Level = ErrorLevel
+		line = strings.TrimPrefix(line, errorPrefix)
+	case strings.HasPrefix(line, warnPrefix):
+		e.Level = WarnLevel
+		line = strings.TrimPrefix(line, warnPrefix)
+	}
+	e.Msg = line
+
+	b, err := json.Marshal(&e)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = j.w.Write(append(b, '\n'))
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
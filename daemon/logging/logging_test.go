@@ -0,0 +1,89 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONWriter(t *testing.T) {
+	now := time.Date(2017, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	tcs := []struct {
+		name   string
+		line   string
+		level  string
+		msg    string
+		source string
+	}{
+		{name: "info", line: "proxy.go:164: GET /v1/self\n",
+			level: InfoLevel, msg: "GET /v1/self", source: "proxy.go:164"},
+		{name: "error", line: "orgs.go:35: error: Error performing api request: boom\n",
+			level: ErrorLevel, msg: "Error performing api request: boom", source: "orgs.go:35"},
+		{name: "warn", line: "listener.go:60: warning: Rejected connection\n",
+			level: WarnLevel, msg: "Rejected connection", source: "listener.go:60"},
+		{name: "multiline", line: "daemon.go:10: Did not shutdown cleanly.\nboom\n",
+			level: InfoLevel, msg: "Did not shutdown cleanly.\nboom", source: "daemon.go:10"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			w := &jsonWriter{w: buf, now: func() time.Time { return now }}
+
+			n, err := w.Write([]byte(tc.line))
+			if err != nil {
+				t.Fatal("Error writing line:", err)
+			}
+			if n != len(tc.line) {
+				t.Errorf("Expected %d bytes written, got %d", len(tc.line), n)
+			}
+
+			e := entry{}
+			err = json.Unmarshal(buf.Bytes(), &e)
+			if err != nil {
+				t.Fatal("Error decoding entry:", err)
+			}
+
+			if e.TS != "2017-03-01T12:30:00Z" {
+				t.Error("Wrong ts:", e.TS)
+			}
+			if e.Level != tc.level {
+				t.Errorf("Expected level %s, got %s", tc.level, e.Level)
+			}
+			if e.Msg != tc.msg {
+				t.Errorf("Expected msg %q, got %q", tc.msg, e.Msg)
+			}
+			if e.Fields["source"] != tc.source {
+				t.Errorf("Expected source %s, got %s", tc.source, e.Fields["source"])
+			}
+		})
+	}
+}
+
+func TestErrorf(t *testing.T) {
+	buf := &bytes.Buffer{}
+	Setup(buf, JSONFormat)
+	defer func() {
+		log.SetFlags(log.LstdFlags)
+		Setup(&bytes.Buffer{}, TextFormat)
+	}()
+
+	Errorf("Failed: %s", "boom")
+
+	e := entry{}
+	err := json.Unmarshal(buf.Bytes(), &e)
+	if err != nil {
+		t.Fatal("Error decoding entry:", err)
+	}
+
+	if e.Level != ErrorLevel || e.Msg != "Failed: boom" {
+		t.Errorf("Unexpected entry: %+v", e)
+	}
+	if !strings.HasPrefix(e.Fields["source"], "logging_test.go:") {
+		t.Error("Wrong source:", e.Fields["source"])
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"sort"
 
+	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/envelope"
 	"github.com/manifoldco/torus-cli/identity"
 	"github.com/manifoldco/torus-cli/pathexp"
@@ -30,16 +31,9 @@ func newCredentialGraphSet() *credentialGraphSet {
 
 func (cgs *credentialGraphSet) Add(graphs ...registry.CredentialGraph) error {
 	for _, c := range graphs {
-		var pe *pathexp.PathExp
-		env := c.GetKeyring()
-		switch env.Version {
-		case 1:
-			pe = env.Body.(*primitive.KeyringV1).PathExp
-		case 2:
-			pe = env.Body.(*primitive.Keyring).PathExp
-
-		default:
-			return errors.New("Unknown keyring version")
+		pe, err := keyringPathExp(c)
+		if err != nil {
+			return err
 		}
 
 		cgs.graphs[pe.String()] = append(cgs.graphs[pe.String()], c)
@@ -48,6 +42,19 @@ func (cgs *credentialGraphSet) Add(graphs ...registry.CredentialGraph) error {
 	return nil
 }
 
+// keyringPathExp returns the PathExp of the graph's keyring.
+func keyringPathExp(graph registry.CredentialGraph) (*pathexp.PathExp, error) {
+	env := graph.GetKeyring()
+	switch env.Version {
+	case 1:
+		return env.Body.(*primitive.KeyringV1).PathExp, nil
+	case 2:
+		return env.Body.(*primitive.Keyring).PathExp, nil
+	default:
+		return nil, errors.New("Unknown keyring version")
+	}
+}
+
 func (credentialGraphSet) activeCreds(parents []identity.ID,
 	graph registry.CredentialGraph) ([]envelope.Signed, []identity.ID, error) {
 
@@ -166,6 +173,98 @@ func (cgs *credentialGraphSet) NeedRotation() ([]envelope.Signed, error) {
 	return needRotation, nil
 }
 
+// RevokedHeads returns the most recent version of each CredentialGraph that
+// contains a revocation of a member's share, meaning a new version of the
+// keyring is needed to keep secrets from that member.
+func (cgs *credentialGraphSet) RevokedHeads() []registry.CredentialGraph {
+	var heads []registry.CredentialGraph
+	for _, graphs := range cgs.graphs {
+		sort.Sort(graphSorter(graphs))
+		if graphs[0].HasRevocations() {
+			heads = append(heads, graphs[0])
+		}
+	}
+
+	return heads
+}
+
+// ActiveCredentials returns the still reachable credentials held in each
+// version of the CredentialGraph for the given (keyring) PathExp, keyed by
+// the version holding them.
+func (cgs *credentialGraphSet) ActiveCredentials(pe *pathexp.PathExp) (map[registry.CredentialGraph][]envelope.Signed, error) {
+	active := make(map[registry.CredentialGraph][]envelope.Signed)
+	graphs := cgs.graphs[pe.String()]
+
+	var parents []identity.ID
+	sort.Sort(graphSorter(graphs))
+	for _, graph := range graphs {
+		var activeCreds []envelope.Signed
+		var err error
+		activeCreds, parents, err = cgs.activeCreds(parents, graph)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(activeCreds) > 0 {
+			active[graph] = activeCreds
+		}
+	}
+
+	return active, nil
+}
+
+// ActiveKeys returns the names, PathExps and metadata of the still reachable
+// credentials in the set, sorted by name and then PathExp.
+func (cgs *credentialGraphSet) ActiveKeys() ([]apitypes.CredentialKey, error) {
+	keys := []apitypes.CredentialKey{}
+	for _, graphs := range cgs.graphs {
+		var parents []identity.ID
+		sort.Sort(graphSorter(graphs))
+		for _, graph := range graphs {
+			var activeCreds []envelope.Signed
+			var err error
+			activeCreds, parents, err = cgs.activeCreds(parents, graph)
+			if err != nil {
+				return nil, err
+			}
+
+			for i := range activeCreds {
+				base, err := baseCredential(&activeCreds[i])
+				if err != nil {
+					return nil, err
+				}
+
+				key := apitypes.CredentialKey{
+					Name:    base.Name,
+					PathExp: base.PathExp,
+					Version: base.CredentialVersion,
+				}
+				if c, ok := activeCreds[i].Body.(*primitive.Credential); ok {
+					key.Description = c.Description
+					key.Tags = c.Tags
+				}
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Sort(credentialKeys(keys))
+
+	return keys, nil
+}
+
+// credentialKeys implements sort.Interface, ordering keys by name and then
+// PathExp.
+type credentialKeys []apitypes.CredentialKey
+
+func (k credentialKeys) Len() int      { return len(k) }
+func (k credentialKeys) Swap(i, j int) { k[i], k[j] = k[j], k[i] }
+func (k credentialKeys) Less(i, j int) bool {
+	if k[i].Name != k[j].Name {
+		return k[i].Name < k[j].Name
+	}
+	return k[i].PathExp.String() < k[j].PathExp.String()
+}
+
 // Head returns the most recent version of a CredentialGraph that would contain
 // the given PathExp.
 func (cgs *credentialGraphSet) Head(pe *pathexp.PathExp) (registry.CredentialGraph, error) {
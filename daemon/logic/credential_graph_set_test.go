@@ -20,11 +20,12 @@ var (
 )
 
 type cred struct {
-	id    *identity.ID
-	prev  *identity.ID
-	state *string
-	pe    *string
-	name  *string
+	id      *identity.ID
+	prev    *identity.ID
+	state   *string
+	pe      *string
+	name    *string
+	version int
 }
 
 func mustID(raw string) *identity.ID {
@@ -63,7 +64,8 @@ func buildGraph(rawPathExp string, version int, secrets ...cred) registry.Creden
 
 	for _, secret := range secrets {
 		base := primitive.BaseCredential{
-			Previous: secret.prev,
+			Previous:          secret.prev,
+			CredentialVersion: secret.version,
 		}
 
 		if secret.pe != nil {
@@ -390,6 +392,39 @@ func TestCredentialGraphSetHeadCredential(t *testing.T) {
 	})
 }
 
+func TestCredentialGraphSetActiveKeys(t *testing.T) {
+	cgs := newCredentialGraphSet()
+
+	pe := "/o/p/e/s/u/i"
+	other := "/o/p/f/s/u/i"
+	a, b, c := "a", "b", "c"
+
+	cgs.Add(buildGraph("/o/p/e/s/u/*", 2, cred{id: id3, prev: id2, pe: &pe, name: &b, state: &unset}))
+	cgs.Add(buildGraph("/o/p/e/s/u/*", 1, cred{id: id2, pe: &pe, name: &b}, cred{id: id1, pe: &pe, name: &a, version: 3}))
+	cgs.Add(buildGraph("/o/p/f/s/u/*", 1, cred{id: id1, pe: &other, name: &c}, cred{id: id2, pe: &other, name: &a}))
+
+	keys, err := cgs.ActiveKeys()
+	if err != nil {
+		t.Fatal("error seen:", err)
+	}
+
+	want := []struct {
+		name    string
+		pe      string
+		version int
+	}{{a, pe, 3}, {a, other, 0}, {c, other, 0}}
+	if len(keys) != len(want) {
+		t.Fatal("Wrong number of keys found. wanted:", len(want), "got:", keys)
+	}
+	for i, w := range want {
+		k := keys[i]
+		if k.Name != w.name || k.PathExp.String() != w.pe || k.Version != w.version {
+			t.Errorf("Wrong key %d. wanted: %s %s %d got: %s %s %d", i,
+				w.name, w.pe, w.version, k.Name, k.PathExp, k.Version)
+		}
+	}
+}
+
 func TestCredentialGraphSetNeedRotation(t *testing.T) {
 	t.Run("no credentials need rotation", func(t *testing.T) {
 		cgs := newCredentialGraphSet()
@@ -469,3 +504,71 @@ func TestCredentialGraphSetNeedRotation(t *testing.T) {
 		}
 	})
 }
+
+func TestCredentialGraphSetRevokedHeads(t *testing.T) {
+	t.Run("no revocations", func(t *testing.T) {
+		cgs := newCredentialGraphSet()
+		cgs.Add(buildGraph("/o/p/e/s/u/*", 1, cred{id: id1}))
+
+		if heads := cgs.RevokedHeads(); len(heads) != 0 {
+			t.Errorf("Expected no revoked heads, got %d", len(heads))
+		}
+	})
+
+	t.Run("revocation in head", func(t *testing.T) {
+		cgs := newCredentialGraphSet()
+		cgs.Add(buildGraph("/o/p/e1/s/u/*", 1, cred{id: id1}))
+		cgs.Add(buildGraphWithRevocation("/o/p/e1/s/u/*", 2, cred{id: id2}))
+		cgs.Add(buildGraph("/o/p/e2/s/u/*", 1, cred{id: id3}))
+
+		heads := cgs.RevokedHeads()
+		if len(heads) != 1 {
+			t.Fatalf("Expected one revoked head, got %d", len(heads))
+		}
+		if heads[0].KeyringVersion() != 2 {
+			t.Errorf("Expected version 2 to be returned, got %d", heads[0].KeyringVersion())
+		}
+	})
+
+	t.Run("revocation in old version only", func(t *testing.T) {
+		cgs := newCredentialGraphSet()
+		cgs.Add(buildGraphWithRevocation("/o/p/e/s/u/*", 1, cred{id: id1}))
+		cgs.Add(buildGraph("/o/p/e/s/u/*", 2, cred{id: id2}))
+
+		if heads := cgs.RevokedHeads(); len(heads) != 0 {
+			t.Errorf("Expected an already rotated keyring to be skipped, got %d heads", len(heads))
+		}
+	})
+}
+
+func TestCredentialGraphSetActiveCredentials(t *testing.T) {
+	pe := "/o/p/e/s/u/i"
+	name := "cred"
+	othername := "othercred"
+
+	cgs := newCredentialGraphSet()
+	old := buildGraph("/o/p/e/s/u/*", 1,
+		cred{id: id1, pe: &pe, name: &name},
+		cred{id: id2, pe: &pe, name: &othername})
+	head := buildGraphWithRevocation("/o/p/e/s/u/*", 2,
+		cred{id: id3, prev: id1, pe: &pe, name: &name})
+	cgs.Add(old, head)
+
+	// Other keyrings aren't included.
+	cgs.Add(buildGraph("/o/p/e2/s/u/*", 1, cred{id: mustID("04100000000000000000000001000")}))
+
+	active, err := cgs.ActiveCredentials(mustPathExp("/o/p/e/s/u/*"))
+	if err != nil {
+		t.Fatal("error seen:", err)
+	}
+
+	if len(active) != 2 {
+		t.Fatalf("Expected credentials from both versions, got %d", len(active))
+	}
+	if creds := active[head]; len(creds) != 1 || *creds[0].ID != *id3 {
+		t.Errorf("Expected the new version of cred from the head, got %v", creds)
+	}
+	if creds := active[old]; len(creds) != 1 || *creds[0].ID != *id2 {
+		t.Errorf("Expected only othercred from the old version, got %v", creds)
+	}
+}
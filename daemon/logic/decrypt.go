@@ -0,0 +1,106 @@
+package logic
+
+import (
+	"context"
+	"sync"
+
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/primitive"
+
+	"github.com/manifoldco/torus-cli/daemon/crypto"
+)
+
+// decryptResult is the outcome of decrypting a single credential. Exactly one
+// of cred and err is set.
+type decryptResult struct {
+	cred *PlaintextCredentialEnvelope
+	err  error
+}
+
+// decryptCredentials decrypts creds with u, using a pool of at most workers
+// goroutines. The results are in the same order as creds. A credential that
+// can't be decrypted doesn't stop the others; its result holds the error.
+func decryptCredentials(ctx context.Context, u crypto.Unboxer, creds []envelope.Signed,
+	workers int) []decryptResult {
+
+	results := make([]decryptResult, len(creds))
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(creds) {
+		workers = len(creds)
+	}
+
+	work := make(chan int)
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				results[i].cred, results[i].err = decryptCredential(ctx, u, &creds[i])
+			}
+		}()
+	}
+
+	for i := range creds {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	return results
+}
+
+// decryptCredential opens the value of a single credential with u.
+func decryptCredential(ctx context.Context, u crypto.Unboxer,
+	cred *envelope.Signed) (*PlaintextCredentialEnvelope, error) {
+
+	base, err := baseCredential(cred)
+	if err != nil {
+		return nil, err
+	}
+
+	var state *string
+	var description string
+	var tags []string
+	if c, ok := cred.Body.(*primitive.Credential); ok {
+		state = c.State
+		description = c.Description
+		tags = c.Tags
+	}
+
+	pt, err := u.Unbox(ctx, *base.Credential.Value, *base.Nonce, *base.Credential.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PlaintextCredentialEnvelope{
+		ID:      cred.ID,
+		Version: cred.Version,
+		Body: &PlaintextCredential{
+			Name:        base.Name,
+			PathExp:     base.PathExp,
+			ProjectID:   base.ProjectID,
+			OrgID:       base.OrgID,
+			Value:       string(pt),
+			State:       state,
+			Description: description,
+			Tags:        tags,
+		},
+	}, nil
+}
+
+// plaintextCredentials implements sort.Interface, ordering credentials by
+// path expression and then name.
+type plaintextCredentials []PlaintextCredentialEnvelope
+
+func (p plaintextCredentials) Len() int      { return len(p) }
+func (p plaintextCredentials) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p plaintextCredentials) Less(i, j int) bool {
+	pi, pj := p[i].Body.PathExp.String(), p[j].Body.PathExp.String()
+	if pi != pj {
+		return pi < pj
+	}
+	return p[i].Body.Name < p[j].Body.Name
+}
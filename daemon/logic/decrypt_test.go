@@ -0,0 +1,119 @@
+package logic
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"testing"
+
+	"golang.org/x/crypto/nacl/secretbox"
+
+	"github.com/manifoldco/torus-cli/base64"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+// secretboxUnboxer opens credential values sealed with a single key, standing
+// in for the key derived from a keyring's master encryption key.
+type secretboxUnboxer struct {
+	key [32]byte
+}
+
+func (s *secretboxUnboxer) seal(t testing.TB, value string) envelope.Signed {
+	nonce := [24]byte{}
+	if _, err := rand.Read(nonce[:]); err != nil {
+		t.Fatal(err)
+	}
+
+	ct := secretbox.Seal(nil, []byte(value), &nonce, &s.key)
+	return envelope.Signed{
+		Version: 2,
+		Body: &primitive.Credential{
+			BaseCredential: primitive.BaseCredential{
+				Name:    value,
+				PathExp: mustPathExp("/o/p/e/s/u/i"),
+				Nonce:   base64.NewValue(make([]byte, 24)),
+				Credential: &primitive.CredentialValue{
+					Nonce: base64.NewValue(nonce[:]),
+					Value: base64.NewValue(ct),
+				},
+			},
+		},
+	}
+}
+
+func (s *secretboxUnboxer) Unbox(ctx context.Context, ct, cekNonce, ctNonce []byte) ([]byte, error) {
+	nonce := [24]byte{}
+	copy(nonce[:], ctNonce)
+
+	pt, ok := secretbox.Open(nil, ct, &nonce, &s.key)
+	if !ok {
+		return nil, errors.New("Failed to decrypt ciphertext")
+	}
+	return pt, nil
+}
+
+func newSecretboxUnboxer(t testing.TB) *secretboxUnboxer {
+	u := &secretboxUnboxer{}
+	if _, err := rand.Read(u.key[:]); err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestDecryptCredentials(t *testing.T) {
+	u := newSecretboxUnboxer(t)
+
+	var creds []envelope.Signed
+	for i := 0; i < 20; i++ {
+		creds = append(creds, u.seal(t, fmt.Sprintf("secret%02d", i)))
+	}
+
+	// Corrupt a couple of credentials; they should fail on their own.
+	(*creds[3].Body.(*primitive.Credential).Credential.Value)[0] ^= 0xff
+	creds[7].Version = 9
+
+	for _, workers := range []int{0, 1, 4, 50} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			results := decryptCredentials(context.Background(), u, creds, workers)
+			if len(results) != len(creds) {
+				t.Fatalf("Expected %d results, got %d", len(creds), len(results))
+			}
+
+			for i, r := range results {
+				if i == 3 || i == 7 {
+					if r.err == nil || r.cred != nil {
+						t.Errorf("Expected credential %d to fail, got %+v", i, r)
+					}
+					continue
+				}
+
+				if r.err != nil {
+					t.Errorf("Unexpected error for credential %d: %s", i, r.err)
+					continue
+				}
+				if expected := fmt.Sprintf("secret%02d", i); r.cred.Body.Value != expected {
+					t.Errorf("Expected %s at %d, got %s", expected, i, r.cred.Body.Value)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecryptCredentials(b *testing.B) {
+	u := newSecretboxUnboxer(b)
+
+	creds := make([]envelope.Signed, 500)
+	for i := range creds {
+		creds[i] = u.seal(b, fmt.Sprintf("secret%03d", i))
+	}
+
+	for _, workers := range []int{1, 8} {
+		b.Run(fmt.Sprintf("%d workers", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				decryptCredentials(context.Background(), u, creds, workers)
+			}
+		})
+	}
+}
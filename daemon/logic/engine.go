@@ -5,17 +5,23 @@ package logic
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"sort"
+	"strings"
 
 	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/base64"
 	"github.com/manifoldco/torus-cli/config"
 	"github.com/manifoldco/torus-cli/envelope"
 	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/pathexp"
 	"github.com/manifoldco/torus-cli/primitive"
 
 	"github.com/manifoldco/torus-cli/daemon/crypto"
+	"github.com/manifoldco/torus-cli/daemon/ctxutil"
 	"github.com/manifoldco/torus-cli/daemon/db"
+	"github.com/manifoldco/torus-cli/daemon/metrics"
 	"github.com/manifoldco/torus-cli/daemon/observer"
 	"github.com/manifoldco/torus-cli/daemon/registry"
 	"github.com/manifoldco/torus-cli/daemon/session"
@@ -32,21 +38,30 @@ type Engine struct {
 	db      *db.DB
 	crypto  *crypto.Engine
 	client  *registry.Client
+	metrics *metrics.Metrics
+
+	// plaintexts holds credentials already decrypted, for when their
+	// keyrings are retrieved again unchanged.
+	plaintexts *plaintextCache
 
 	Worklog Worklog
 	Machine Machine
 	Session Session
 }
 
-// NewEngine returns a new Engine
+// NewEngine returns a new Engine. Credential decryptions are counted in m,
+// if it isn't nil.
 func NewEngine(c *config.Config, s session.Session, db *db.DB, e *crypto.Engine,
-	client *registry.Client) *Engine {
+	client *registry.Client, m *metrics.Metrics) *Engine {
 	engine := &Engine{
 		config:  c,
 		session: s,
 		db:      db,
 		crypto:  e,
 		client:  client,
+		metrics: m,
+
+		plaintexts: newPlaintextCache(plaintextCacheSize),
 	}
 	engine.Worklog = Worklog{engine: engine}
 	engine.Machine = Machine{engine: engine}
@@ -54,15 +69,62 @@ func NewEngine(c *config.Config, s session.Session, db *db.DB, e *crypto.Engine,
 	return engine
 }
 
+// FlushCache drops the cached credential graphs, and the credentials
+// decrypted from them.
+func (e *Engine) FlushCache() {
+	e.client.CredentialGraph.Flush()
+	e.plaintexts.flush()
+}
+
 // AppendCredential attempts to append a plain-text Credential object to the
 // Credential Graph.
 func (e *Engine) AppendCredential(ctx context.Context, notifier *observer.Notifier,
 	cred *PlaintextCredentialEnvelope) (*PlaintextCredentialEnvelope, error) {
 
+	creds, err := e.AppendCredentials(ctx, notifier, []*PlaintextCredentialEnvelope{cred})
+	if err != nil {
+		return nil, err
+	}
+
+	return creds[0], nil
+}
+
+// AppendCredentials attempts to append a set of plain-text Credential objects
+// sharing the same PathExp to the Credential Graph.
+//
+// The keyring is looked up (or created) once for the whole set. When a new
+// keyring is required, the keyring and all credentials are uploaded in a
+// single request. Otherwise each credential is uploaded in turn, stopping if
+// ctx is cancelled; the returned error then lists those already saved.
+func (e *Engine) AppendCredentials(ctx context.Context, notifier *observer.Notifier,
+	creds []*PlaintextCredentialEnvelope) ([]*PlaintextCredentialEnvelope, error) {
+
+	if len(creds) == 0 {
+		return creds, nil
+	}
+
+	pe := creds[0].Body.PathExp
+	orgID := creds[0].Body.OrgID
+	for _, cred := range creds[1:] {
+		if !cred.Body.PathExp.Equal(pe) {
+			return nil, &apitypes.Error{
+				StatusCode: 400,
+				Type:       apitypes.BadRequestError,
+				Err:        []string{"All credentials must share the same pathexp"},
+			}
+		}
+	}
+
+	// Check the values before doing any of the work of encrypting them.
+	err := checkCredentialSizes(creds, e.config.MaxCredentialSize)
+	if err != nil {
+		return nil, err
+	}
+
 	n := notifier.Notifier(4)
 
 	// Ensure we have an existing keyring for this credential's pathexp
-	graphs, err := e.client.CredentialGraph.List(ctx, "", cred.Body.PathExp,
+	graphs, err := e.client.CredentialGraph.ListAll(ctx, "", pe,
 		e.session.AuthID())
 	if err != nil {
 		log.Printf("Error retrieving credential graphs: %s", err)
@@ -71,7 +133,7 @@ func (e *Engine) AppendCredential(ctx context.Context, notifier *observer.Notifi
 
 	n.Notify(observer.Progress, "Credentials retrieved", true)
 
-	sigID, encID, kp, err := fetchKeyPairs(ctx, e.client, cred.Body.OrgID)
+	sigID, encID, kp, err := fetchKeyPairs(ctx, e.client, orgID)
 	if err != nil {
 		log.Printf("Error fetching keypairs: %s", err)
 		return nil, err
@@ -86,15 +148,31 @@ func (e *Engine) AppendCredential(ctx context.Context, notifier *observer.Notifi
 	}
 
 	// Find the credentialgraph/keyring that we should store our credential in
-	graph, err := cgs.Head(cred.Body.PathExp)
+	graph, err := cgs.Head(pe)
 	if err != nil {
 		return nil, err
 	}
 
-	// Find the  most recent version of this credential to act as our previous.
-	previousCred, err := cgs.HeadCredential(cred.Body.PathExp, cred.Body.Name)
+	// Find the  most recent version of each credential to act as its previous.
+	previousCreds := make([]*envelope.Signed, len(creds))
+	added := 0
+	for i, cred := range creds {
+		previousCreds[i], err = cgs.HeadCredential(pe, cred.Body.Name)
+		if err != nil {
+			log.Printf("error finding credentials to match: %s", err)
+			return nil, err
+		}
+		err = checkExpectedVersion(cred.Body, previousCreds[i])
+		if err != nil {
+			return nil, err
+		}
+		if addsCredential(cred.Body, previousCreds[i]) {
+			added++
+		}
+	}
+
+	err = checkKeyringCount(cgs, pe, added, e.config.MaxKeyringCredentials)
 	if err != nil {
-		log.Printf("error finding credentials to match: %s", err)
 		return nil, err
 	}
 
@@ -102,7 +180,7 @@ func (e *Engine) AppendCredential(ctx context.Context, notifier *observer.Notifi
 	// No matching CredentialGraph/KeyRing for this credential.
 	// We'll make a new one now.
 	if graph == nil || graph.HasRevocations() {
-		newGraph, err = createCredentialGraph(ctx, cred.Body, graph, sigID,
+		newGraph, err = createCredentialGraph(ctx, creds[0].Body, graph, sigID,
 			encID, kp, e.client, e.crypto)
 		if err != nil {
 			log.Printf("error creating credential graph: %s", err)
@@ -112,91 +190,259 @@ func (e *Engine) AppendCredential(ctx context.Context, notifier *observer.Notifi
 		graph = newGraph
 	}
 
-	// Construct an encrypted and signed version of the credential
-	credBody := primitive.Credential{
-		State: cred.Body.State,
-		BaseCredential: primitive.BaseCredential{
-			Name:      cred.Body.Name,
-			PathExp:   cred.Body.PathExp,
-			KeyringID: graph.GetKeyring().ID,
-			ProjectID: cred.Body.ProjectID,
-			OrgID:     cred.Body.OrgID,
-			Credential: &primitive.CredentialValue{
-				Algorithm: crypto.SecretBox,
-			},
-		},
-	}
-
-	if previousCred == nil {
-		log.Printf("no previous")
-		credBody.Previous = nil
-		credBody.CredentialVersion = 1
-	} else {
-		base, err := baseCredential(previousCred)
+	krm, mekshare, err := graph.FindMember(e.session.AuthID())
+	if err != nil {
+		log.Printf("Error finding keyring membership: %s", err)
+		return nil, err
+	}
+
+	encryptingKey, err := findEncryptingKey(ctx, e.client, orgID,
+		krm.EncryptingKeyID)
+	if err != nil {
+		log.Printf("Error finding encrypting key: %s", err)
+		return nil, err
+	}
+
+	n.Notify(observer.Progress, "Encrypting key retrieved", true)
+
+	signedCreds := make([]envelope.Signed, len(creds))
+	for i, cred := range creds {
+		signed, err := encryptCredential(ctx, e.crypto, cred.Body,
+			graph.GetKeyring().ID, previousCreds[i], mekshare, encryptingKey,
+			sigID, kp)
 		if err != nil {
 			return nil, err
 		}
 
-		credBody.Previous = previousCred.ID
-		credBody.CredentialVersion = base.CredentialVersion + 1
+		signedCreds[i] = *signed
 	}
 
-	krm, mekshare, err := graph.FindMember(e.session.AuthID())
+	n.Notify(observer.Progress, "Credentials encrypted", true)
+
+	if newGraph != nil {
+		newGraph.Credentials = signedCreds
+		_, err = e.client.CredentialGraph.Post(ctx, &graph)
+		if err != nil {
+			log.Printf("error creating credential: %s", err)
+			return nil, err
+		}
+	} else {
+		for i := range signedCreds {
+			err = ctxutil.ErrIfDone(ctx)
+			if err == nil {
+				_, err = e.client.Credentials.Create(ctx, &signedCreds[i])
+			}
+			if err != nil {
+				log.Printf("error creating credential: %s", err)
+				if i == 0 {
+					return nil, err
+				}
+
+				names := make([]string, i)
+				for j, cred := range creds[:i] {
+					names[j] = cred.Body.Name
+				}
+				return nil, partialWriteError(err, fmt.Sprintf("saving %d of %d secrets at %s (%s)",
+					i, len(creds), pe, strings.Join(names, ", ")))
+			}
+		}
+	}
+
+	return creds, nil
+}
+
+// RotateKeyrings creates a new version of every keyring in the org that has
+// had a member's share revoked, re-encrypting the keyring's active credentials
+// under a new master key shared only with the remaining members.
+//
+// Each keyring is uploaded along with its credentials in a single request, so
+// a keyring is either fully rotated or left untouched. Keyrings without
+// revocations are skipped. The PathExps of the rotated keyrings are returned.
+// If ctx is cancelled or a keyring can't be rotated, no further keyrings are
+// rotated, and the returned error lists those that were and those that weren't.
+func (e *Engine) RotateKeyrings(ctx context.Context, notifier *observer.Notifier,
+	orgID *identity.ID) ([]*pathexp.PathExp, error) {
+
+	graphs, err := orgCredentialGraphs(ctx, e.client, orgID, e.session.AuthID())
 	if err != nil {
-		log.Printf("Error finding keyring membership: %s", err)
 		return nil, err
 	}
 
-	encryptingKey, err := findEncryptingKey(ctx, e.client, credBody.OrgID,
-		krm.EncryptingKeyID)
+	cgs := newCredentialGraphSet()
+	err = cgs.Add(graphs...)
 	if err != nil {
-		log.Printf("Error finding encrypting key: %s", err)
 		return nil, err
 	}
 
-	n.Notify(observer.Progress, "Encrypting key retrieved", true)
+	heads := cgs.RevokedHeads()
+	n := notifier.Notifier(uint(len(heads)) + 1)
+	n.Notify(observer.Progress, "Keyrings retrieved", true)
+
+	if len(heads) == 0 {
+		return nil, nil
+	}
 
-	// Derive a key for the credential using the keyring master key
-	// and use the derived key to encrypt the credential
-	cekNonce, ctNonce, ct, err := e.crypto.BoxCredential(
-		ctx, []byte(cred.Body.Value), *mekshare.Key.Value, *mekshare.Key.Nonce,
-		&kp.Encryption, *encryptingKey.Key.Value)
+	sigID, encID, kp, err := fetchKeyPairs(ctx, e.client, orgID)
 	if err != nil {
-		log.Printf("Error encrypting credential: %s", err)
+		log.Printf("Error fetching keypairs: %s", err)
 		return nil, err
 	}
 
-	credBody.Nonce = base64.NewValue(cekNonce)
+	var rotated []*pathexp.PathExp
+	for i, head := range heads {
+		// Stop between keyrings if the request has been cancelled, rather
+		// than carrying on with the rest.
+		var pe *pathexp.PathExp
+		err := ctxutil.ErrIfDone(ctx)
+		if err == nil {
+			pe, err = e.rotateKeyring(ctx, cgs, head, orgID, sigID, encID, kp)
+		}
+		if err != nil {
+			log.Printf("Stopped rotating keyrings after %d of %d: %s", len(rotated), len(heads), err)
+			return nil, rotateKeyringsError(err, rotated, heads[i:])
+		}
+
+		rotated = append(rotated, pe)
+		n.Notify(observer.Progress, "Keyring rotated: "+pe.String(), true)
+	}
+
+	return rotated, nil
+}
+
+// rotateKeyringsError wraps err, which stopped RotateKeyrings, naming the
+// keyrings that were rotated before it and those left to rotate.
+func rotateKeyringsError(err error, rotated []*pathexp.PathExp, pending []registry.CredentialGraph) error {
+	var left []*pathexp.PathExp
+	for _, head := range pending {
+		if keyring, kerr := baseKeyring(head); kerr == nil {
+			left = append(left, keyring.PathExp)
+		}
+	}
+
+	saved := fmt.Sprintf("rotating %d of %d keyrings", len(rotated), len(rotated)+len(pending))
+	if len(rotated) > 0 {
+		saved += " (" + joinPathExps(rotated) + ")"
+	}
+	return partialWriteError(err, saved+". Not rotated: "+joinPathExps(left))
+}
 
-	credBody.Credential.Nonce = base64.NewValue(ctNonce)
-	credBody.Credential.Value = base64.NewValue(ct)
+// rotateKeyring uploads a new version of head's keyring, holding its active
+// credentials re-encrypted under a new master key, and returns its PathExp.
+func (e *Engine) rotateKeyring(ctx context.Context, cgs *credentialGraphSet,
+	head registry.CredentialGraph, orgID, sigID, encID *identity.ID, kp *crypto.KeyPairs) (*pathexp.PathExp, error) {
 
-	signed, err := e.crypto.SignedEnvelope(ctx, &credBody, sigID, &kp.Signature)
+	keyring, err := baseKeyring(head)
 	if err != nil {
-		log.Printf("Error signing credential body: %s", err)
 		return nil, err
 	}
 
-	n.Notify(observer.Progress, "Credential encrypted", true)
+	active, err := cgs.ActiveCredentials(keyring.PathExp)
+	if err != nil {
+		return nil, err
+	}
 
-	if newGraph != nil {
-		newGraph.Credentials = []envelope.Signed{*signed}
-		_, err = e.client.CredentialGraph.Post(ctx, &graph)
-	} else {
-		_, err = e.client.Credentials.Create(ctx, signed)
+	newGraph, err := createCredentialGraph(ctx, &PlaintextCredential{
+		OrgID:     keyring.OrgID,
+		ProjectID: keyring.ProjectID,
+		PathExp:   keyring.PathExp,
+	}, head, sigID, encID, kp, e.client, e.crypto)
+	if err != nil {
+		log.Printf("error creating credential graph: %s", err)
+		return nil, err
+	}
+
+	newKrm, mekshare, err := newGraph.FindMember(e.session.AuthID())
+	if err != nil {
+		log.Printf("Error finding keyring membership: %s", err)
+		return nil, err
+	}
+
+	newEncryptingKey, err := findEncryptingKey(ctx, e.client, orgID,
+		newKrm.EncryptingKeyID)
+	if err != nil {
+		log.Printf("Error finding encrypting key for user: %s", err)
+		return nil, err
+	}
+
+	for graph, creds := range active {
+		krm, oldMekshare, err := graph.FindMember(e.session.AuthID())
+		if err != nil {
+			log.Printf("Error finding keyring membership: %s", err)
+			return nil, err
+		}
+
+		encryptingKey, err := findEncryptingKey(ctx, e.client, orgID,
+			krm.EncryptingKeyID)
+		if err != nil {
+			log.Printf("Error finding encrypting key for user: %s", err)
+			return nil, err
+		}
+
+		var plaintexts []*PlaintextCredential
+		err = e.crypto.WithUnboxer(ctx, *oldMekshare.Key.Value, *oldMekshare.Key.Nonce, &kp.Encryption, *encryptingKey.Key.Value, func(u crypto.Unboxer) error {
+			for _, cred := range creds {
+				base, err := baseCredential(&cred)
+				if err != nil {
+					return err
+				}
+
+				pt, err := u.Unbox(ctx, *base.Credential.Value, *base.Nonce, *base.Credential.Nonce)
+				e.metrics.Decrypt(err)
+				if err != nil {
+					log.Printf("Error decrypting credential: %s", err)
+					return err
+				}
+
+				state := "set"
+				plaintext := &PlaintextCredential{
+					Name:      base.Name,
+					OrgID:     base.OrgID,
+					PathExp:   base.PathExp,
+					ProjectID: base.ProjectID,
+					Value:     string(pt),
+					State:     &state,
+				}
+				if c, ok := cred.Body.(*primitive.Credential); ok {
+					plaintext.Description = c.Description
+					plaintext.Tags = c.Tags
+				}
+				plaintexts = append(plaintexts, plaintext)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for i, pt := range plaintexts {
+			signed, err := encryptCredential(ctx, e.crypto, pt,
+				newGraph.GetKeyring().ID, &creds[i], mekshare,
+				newEncryptingKey, sigID, kp)
+			if err != nil {
+				return nil, err
+			}
+
+			newGraph.Credentials = append(newGraph.Credentials, *signed)
+		}
 	}
 
+	var graph registry.CredentialGraph = newGraph
+	_, err = e.client.CredentialGraph.Post(ctx, &graph)
 	if err != nil {
-		log.Printf("error creating credential: %s", err)
+		log.Printf("error rotating keyring: %s", err)
 		return nil, err
 	}
 
-	return cred, nil
+	return keyring.PathExp, nil
 }
 
-// RetrieveCredentials returns all credentials for the given CPath string
-func (e *Engine) RetrieveCredentials(ctx context.Context,
-	notifier *observer.Notifier, cpath, cpathexp *string) ([]PlaintextCredentialEnvelope, error) {
+// RetrieveCredentials returns all credentials for the given CPath string.
+//
+// A credential that can't be decrypted, for example because its envelope is
+// corrupt or the user's keyring membership can't be read, doesn't stop the
+// others from being returned. It is reported in the returned failures instead.
+func (e *Engine) RetrieveCredentials(ctx context.Context, notifier *observer.Notifier,
+	cpath, cpathexp *string) ([]PlaintextCredentialEnvelope, []apitypes.CredentialFailure, error) {
 	if cpath != nil && cpathexp != nil {
 		panic("cannot use both cpath and cpathexp")
 	}
@@ -207,24 +453,24 @@ func (e *Engine) RetrieveCredentials(ctx context.Context,
 	var err error
 	var graphs []registry.CredentialGraph
 	if cpath != nil {
-		graphs, err = e.client.CredentialGraph.List(ctx, *cpath, nil, e.session.AuthID())
+		graphs, err = e.client.CredentialGraph.ListAll(ctx, *cpath, nil, e.session.AuthID())
 	} else if cpathexp != nil {
 		graphs, err = e.client.CredentialGraph.Search(ctx, *cpathexp, e.session.AuthID())
 	}
 	if err != nil {
 		log.Printf("error retrieving credential graphs: %s", err)
-		return nil, err
+		return nil, nil, err
 	}
 
 	cgs := newCredentialGraphSet()
 	err = cgs.Add(graphs...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	activeGraphs, err := cgs.Active()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var steps uint = 1
@@ -237,12 +483,29 @@ func (e *Engine) RetrieveCredentials(ctx context.Context,
 
 	keypairs := make(map[identity.ID]*crypto.KeyPairs)
 	encryptingKeys := make(map[identity.ID]*primitive.PublicKey)
+	claimTrees := make(map[identity.ID][]registry.ClaimTree)
+
+	// failGraph records every credential in graph as a failure, for when the
+	// keyring itself can't be decrypted.
+	failures := []apitypes.CredentialFailure{}
+	failGraph := func(graph registry.CredentialGraph, reason error) {
+		for _, cred := range graph.GetCredentials() {
+			failures = append(failures, newCredentialFailure(graph, &cred, reason))
+			n.Notify(observer.Progress, "Credential could not be decrypted", true)
+		}
+	}
 
 	// Loop over the trees and unpack the credentials; later on we will
 	// actually do real work and decrypt each of these credentials but for
 	// now we just need ot return a list of them!
 	creds := []PlaintextCredentialEnvelope{}
 	for _, graph := range activeGraphs {
+		// Don't mistake a cancelled request for credentials that can't be
+		// decrypted.
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
 		var orgID *identity.ID
 		switch b := graph.GetKeyring().Body.(type) {
 		case *primitive.Keyring:
@@ -250,7 +513,7 @@ func (e *Engine) RetrieveCredentials(ctx context.Context,
 		case *primitive.KeyringV1:
 			orgID = b.OrgID
 		default:
-			return nil, &apitypes.Error{
+			return nil, nil, &apitypes.Error{
 				Type: apitypes.InternalServerError,
 				Err:  []string{"Malformed keyring body"},
 			}
@@ -260,7 +523,7 @@ func (e *Engine) RetrieveCredentials(ctx context.Context,
 			_, _, kp, err = fetchKeyPairs(ctx, e.client, orgID)
 			if err != nil {
 				log.Printf("Error fetching keypairs: %s", err)
-				return nil, err
+				return nil, nil, err
 			}
 			keypairs[*orgID] = kp
 		}
@@ -268,7 +531,25 @@ func (e *Engine) RetrieveCredentials(ctx context.Context,
 		krm, mekshare, err := graph.FindMember(e.session.AuthID())
 		if err != nil {
 			log.Printf("Error finding keyring membership: %s", err)
-			return nil, err
+			failGraph(graph, err)
+			continue
+		}
+
+		trees, ok := claimTrees[*orgID]
+		if !ok {
+			trees, err = e.client.ClaimTree.List(ctx, orgID, nil)
+			if err != nil {
+				log.Printf("Error retrieving claim tree: %s", err)
+				return nil, nil, err
+			}
+			claimTrees[*orgID] = trees
+		}
+
+		err = verifyMembership(graph, e.session.AuthID(), trees)
+		if err != nil {
+			log.Printf("Error verifying keyring membership: %s", err)
+			failGraph(graph, err)
+			continue
 		}
 
 		encryptingKey, ok := encryptingKeys[*krm.EncryptingKeyID]
@@ -277,136 +558,630 @@ func (e *Engine) RetrieveCredentials(ctx context.Context,
 				krm.EncryptingKeyID)
 			if err != nil {
 				log.Printf("Error finding encrypting key for user: %s", err)
-				return nil, err
+				failGraph(graph, err)
+				continue
 			}
 			encryptingKeys[*krm.EncryptingKeyID] = encryptingKey
 		}
 
-		err = e.crypto.WithUnboxer(ctx, *mekshare.Key.Value, *mekshare.Key.Nonce, &kp.Encryption, *encryptingKey.Key.Value, func(u crypto.Unboxer) error {
-			for _, cred := range graph.GetCredentials() {
-				var state *string
-
-				base, err := baseCredential(&cred)
-				if err != nil {
-					return err
-				}
-
-				if c, ok := cred.Body.(*primitive.Credential); ok {
-					state = c.State
-				}
-
-				pt, err := u.Unbox(ctx, *base.Credential.Value, *base.Nonce, *base.Credential.Nonce)
-				if err != nil {
-					log.Printf("Error decrypting credential: %s", err)
-					return err
-				}
+		// Membership has been checked against the latest graph, so the
+		// credentials can be served without decrypting them again.
+		if cached, ok := e.plaintexts.getAll(graph.GetCredentials()); ok {
+			for _, cred := range cached {
+				creds = append(creds, cred)
+				n.Notify(observer.Progress, "Credential decrypted", true)
+			}
+			continue
+		}
 
-				plainCred := PlaintextCredentialEnvelope{
-					ID:      cred.ID,
-					Version: cred.Version,
-					Body: &PlaintextCredential{
-						Name:      base.Name,
-						PathExp:   base.PathExp,
-						ProjectID: base.ProjectID,
-						OrgID:     base.OrgID,
-						Value:     string(pt),
-						State:     state,
-					},
+		err = e.crypto.WithUnboxer(ctx, *mekshare.Key.Value, *mekshare.Key.Nonce, &kp.Encryption, *encryptingKey.Key.Value, func(u crypto.Unboxer) error {
+			graphCreds := graph.GetCredentials()
+			results := decryptCredentials(ctx, u, graphCreds, e.config.DecryptWorkers)
+			for i, r := range results {
+				e.metrics.Decrypt(r.err)
+				if r.err != nil {
+					log.Printf("Error decrypting credential: %s", r.err)
+					failures = append(failures, newCredentialFailure(graph, &graphCreds[i], r.err))
+					n.Notify(observer.Progress, "Credential could not be decrypted", true)
+					continue
 				}
-				creds = append(creds, plainCred)
 
+				creds = append(creds, *r.cred)
+				e.plaintexts.put(*r.cred)
 				n.Notify(observer.Progress, "Credential decrypted", true)
 			}
 			return nil
 		})
 		if err != nil {
-			return nil, err
+			log.Printf("Error decrypting keyring membership: %s", err)
+			failGraph(graph, err)
 		}
 	}
 
-	return creds, nil
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	// Credentials are decrypted concurrently, so give them a stable order.
+	sort.Sort(plaintextCredentials(creds))
+
+	return creds, failures, nil
 }
 
-// ApproveInvite approves an invitation of a user into an organzation by
-// encoding them into a Keyring.
-func (e *Engine) ApproveInvite(ctx context.Context, notifier *observer.Notifier,
-	InviteID *identity.ID) (*envelope.Unsigned, error) {
+// CredentialKeys returns the names and metadata of the secrets matching the
+// given path expression. Both are stored in the clear, so nothing is
+// decrypted, and the credential graphs are served from the registry client's
+// ETag cache when unchanged; this is cheap enough for listing and shell
+// completion.
+func (e *Engine) CredentialKeys(ctx context.Context, pe string) ([]apitypes.CredentialKey, error) {
+	graphs, err := e.client.CredentialGraph.Search(ctx, pe, e.session.AuthID())
+	if err != nil {
+		log.Printf("error retrieving credential graphs: %s", err)
+		return nil, err
+	}
 
-	n := notifier.Notifier(3)
+	cgs := newCredentialGraphSet()
+	err = cgs.Add(graphs...)
+	if err != nil {
+		return nil, err
+	}
 
-	invite, err := e.client.OrgInvite.Get(ctx, InviteID)
+	return cgs.ActiveKeys()
+}
+
+// CredentialHistory returns every version of the named credential stored at
+// exactly the given PathExp, oldest first. Values are only decrypted when
+// reveal is true.
+func (e *Engine) CredentialHistory(ctx context.Context, notifier *observer.Notifier,
+	pe *pathexp.PathExp, name string, reveal bool) ([]CredentialVersion, error) {
+
+	graphs, err := e.client.CredentialGraph.ListAll(ctx, "", pe, e.session.AuthID())
 	if err != nil {
-		log.Printf("could not fetch org invitation: %s", err)
+		log.Printf("error retrieving credential graphs: %s", err)
 		return nil, err
 	}
 
-	inviteBody := invite.Body.(*primitive.OrgInvite)
+	// Collect the matching credentials, keeping track of the graph they
+	// belong to so they can be decrypted with the right keyring.
+	var orgID *identity.ID
+	matches := make(map[registry.CredentialGraph][]envelope.Signed)
+	count := 0
+	for _, graph := range graphs {
+		for _, cred := range graph.GetCredentials() {
+			base, err := baseCredential(&cred)
+			if err != nil {
+				return nil, err
+			}
 
-	if inviteBody.State != primitive.OrgInviteAcceptedState {
-		log.Printf("invitation not in accepted state: %s", inviteBody.State)
-		return nil, &apitypes.Error{
-			Type: apitypes.BadRequestError,
-			Err:  []string{"Invite must be accepted before it can be approved"},
+			if base.Name != name || !base.PathExp.Equal(pe) {
+				continue
+			}
+
+			orgID = base.OrgID
+			matches[graph] = append(matches[graph], cred)
+			count++
 		}
 	}
 
-	n.Notify(observer.Progress, "Invite retrieved", true)
+	if count == 0 {
+		return nil, &apitypes.Error{
+			Type: apitypes.NotFoundError,
+			Err:  []string{"Credential not found"},
+		}
+	}
 
-	v1members, v2members, err := createKeyringMemberships(ctx, e.crypto,
-		e.client, e.session, inviteBody.OrgID, inviteBody.InviteeID)
-	if err != nil {
-		return nil, err
+	var steps uint = 2
+	if reveal {
+		steps += uint(count)
 	}
 
-	n.Notify(observer.Progress, "Keyring memberships created", true)
+	n := notifier.Notifier(steps)
+	n.Notify(observer.Progress, "Credentials retrieved", true)
 
-	invite, err = e.client.OrgInvite.Approve(ctx, InviteID)
+	// Credentials are signed by their author; look up who owns each
+	// signing key.
+	claimTrees, err := e.client.ClaimTree.List(ctx, orgID, nil)
 	if err != nil {
-		log.Printf("could not approve org invite: %s", err)
+		log.Printf("error retrieving claim tree: %s", err)
 		return nil, err
 	}
 
-	n.Notify(observer.Progress, "Invite approved", true)
-
-	if len(v1members) != 0 {
-		_, err = e.client.KeyringMember.Post(ctx, v1members)
-		if err != nil {
-			log.Printf("error uploading memberships: %s", err)
-			return nil, err
+	owners := make(map[identity.ID]*identity.ID)
+	for _, tree := range claimTrees {
+		for _, segment := range tree.PublicKeys {
+			// Anything but a public key can't have signed a version; its
+			// author is shown as unknown.
+			pubKey, ok := segment.Key.Body.(*primitive.PublicKey)
+			if !ok {
+				continue
+			}
+			owners[*segment.Key.ID] = pubKey.OwnerID
 		}
 	}
 
-	for _, member := range v2members {
-		err = e.client.Keyring.Members.Post(ctx, member)
+	n.Notify(observer.Progress, "Authors retrieved", true)
+
+	var kp *crypto.KeyPairs
+	if reveal {
+		_, _, kp, err = fetchKeyPairs(ctx, e.client, orgID)
 		if err != nil {
-			log.Printf("error uploading memberships: %s", err)
+			log.Printf("Error fetching keypairs: %s", err)
 			return nil, err
 		}
 	}
 
-	return invite, nil
-}
-
-// GenerateKeypair creates a signing and encrypting keypair for the current
-// user for the given organization.
-func (e *Engine) GenerateKeypair(ctx context.Context, notifier *observer.Notifier,
-	OrgID *identity.ID) error {
+	versions := make([]CredentialVersion, 0, count)
+	for graph, creds := range matches {
+		values := make(map[identity.ID]string, len(creds))
 
-	n := notifier.Notifier(4)
+		if reveal {
+			krm, mekshare, err := graph.FindMember(e.session.AuthID())
+			if err != nil {
+				log.Printf("Error finding keyring membership: %s", err)
+				return nil, err
+			}
 
-	kp, err := e.crypto.GenerateKeyPairs(ctx)
-	if err != nil {
-		log.Printf("Error generating keypairs: %s", err)
+			encryptingKey, err := findEncryptingKey(ctx, e.client, orgID,
+				krm.EncryptingKeyID)
+			if err != nil {
+				log.Printf("Error finding encrypting key for user: %s", err)
+				return nil, err
+			}
+
+			err = e.crypto.WithUnboxer(ctx, *mekshare.Key.Value, *mekshare.Key.Nonce, &kp.Encryption, *encryptingKey.Key.Value, func(u crypto.Unboxer) error {
+				for _, cred := range creds {
+					base, err := baseCredential(&cred)
+					if err != nil {
+						return err
+					}
+
+					pt, err := u.Unbox(ctx, *base.Credential.Value, *base.Nonce, *base.Credential.Nonce)
+					e.metrics.Decrypt(err)
+					if err != nil {
+						log.Printf("Error decrypting credential: %s", err)
+						return err
+					}
+
+					values[*cred.ID] = string(pt)
+					n.Notify(observer.Progress, "Credential decrypted", true)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, cred := range creds {
+			base, err := baseCredential(&cred)
+			if err != nil {
+				return nil, err
+			}
+
+			state := "set"
+			if c, ok := cred.Body.(*primitive.Credential); ok && c.State != nil {
+				state = *c.State
+			}
+
+			versions = append(versions, CredentialVersion{
+				ID:       cred.ID,
+				Version:  base.CredentialVersion,
+				State:    state,
+				AuthorID: owners[*cred.Signature.PublicKeyID],
+				Value:    values[*cred.ID],
+			})
+		}
+	}
+
+	sort.Sort(credentialVersionSorter(versions))
+	return versions, nil
+}
+
+// credentialVersionSorter implements sort.Interface, for sorting
+// CredentialVersions by version in increasing order
+type credentialVersionSorter []CredentialVersion
+
+func (c credentialVersionSorter) Len() int           { return len(c) }
+func (c credentialVersionSorter) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c credentialVersionSorter) Less(i, j int) bool { return c[i].Version < c[j].Version }
+
+// KeyringAccess returns who can decrypt the credentials in each keyring
+// contained within the given loose PathExp. Every version of a keyring that
+// still holds active credentials is considered, so a member of an older
+// version is listed until the credentials it holds are replaced.
+func (e *Engine) KeyringAccess(ctx context.Context, notifier *observer.Notifier,
+	pe string) ([]apitypes.KeyringAccess, error) {
+
+	n := notifier.Notifier(2)
+
+	graphs, err := e.client.CredentialGraph.Search(ctx, pe, e.session.AuthID())
+	if err != nil {
+		log.Printf("error retrieving credential graphs: %s", err)
+		return nil, err
+	}
+
+	n.Notify(observer.Progress, "Keyrings retrieved", true)
+
+	cgs := newCredentialGraphSet()
+	err = cgs.Add(graphs...)
+	if err != nil {
+		return nil, err
+	}
+
+	activeGraphs, err := cgs.Active()
+	if err != nil {
+		return nil, err
+	}
+
+	byPathExp := make(map[string]*apitypes.KeyringAccess)
+	seen := make(map[string]map[identity.ID]bool)
+	for _, graph := range activeGraphs {
+		kpe, err := keyringPathExp(graph)
+		if err != nil {
+			return nil, err
+		}
+
+		ka, ok := byPathExp[kpe.String()]
+		if !ok {
+			ka = &apitypes.KeyringAccess{PathExp: kpe, MemberIDs: []*identity.ID{}}
+			byPathExp[kpe.String()] = ka
+			seen[kpe.String()] = make(map[identity.ID]bool)
+		}
+
+		for _, id := range graph.MemberIDs() {
+			if !seen[kpe.String()][*id] {
+				seen[kpe.String()][*id] = true
+				ka.MemberIDs = append(ka.MemberIDs, id)
+			}
+		}
+	}
+
+	n.Notify(observer.Progress, "Keyring members found", true)
+
+	keys := make([]string, 0, len(byPathExp))
+	for k := range byPathExp {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	access := make([]apitypes.KeyringAccess, len(keys))
+	for i, k := range keys {
+		access[i] = *byPathExp[k]
+	}
+
+	return access, nil
+}
+
+// RepairKeyrings adds the keyring memberships that are missing for members of
+// the org, for each keyring in the project that holds active credentials.
+// Every user in the org's member team, and every active token of an active
+// machine, should be a member of these keyrings.
+//
+// Only members of the org's owner and admin teams may repair keyrings. The
+// master key of each keyring is encrypted to the missing member's public key,
+// so the current user must also be a member of the keyrings they repair.
+// Memberships that already exist are left alone, so repairing is idempotent.
+func (e *Engine) RepairKeyrings(ctx context.Context, notifier *observer.Notifier,
+	orgID, projectID *identity.ID) ([]apitypes.KeyringRepair, error) {
+
+	n := notifier.Notifier(3)
+
+	err := requireOrgRole(ctx, e.client, orgID, e.session.AuthID(), "repair keyrings",
+		primitive.OwnerTeamName, primitive.AdminTeamName)
+	if err != nil {
+		return nil, err
+	}
+
+	owners, err := e.keyringOwners(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	n.Notify(observer.Progress, "Org members retrieved", true)
+
+	claimTrees, err := e.client.ClaimTree.List(ctx, orgID, nil)
+	if err != nil {
+		log.Printf("Error retrieving claim trees: %s", err)
+		return nil, err
+	}
+
+	graphs, err := orgCredentialGraphs(ctx, e.client, orgID, e.session.AuthID())
+	if err != nil {
+		return nil, err
+	}
+
+	cgs := newCredentialGraphSet()
+	err = cgs.Add(graphs...)
+	if err != nil {
+		return nil, err
+	}
+
+	activeGraphs, err := cgs.Active()
+	if err != nil {
+		return nil, err
+	}
+
+	n.Notify(observer.Progress, "Keyrings retrieved", true)
+
+	sigID, encID, kp, err := fetchKeyPairs(ctx, e.client, orgID)
+	if err != nil {
+		log.Printf("Error fetching keypairs: %s", err)
+		return nil, err
+	}
+
+	authID := e.session.AuthID()
+	repairs := []apitypes.KeyringRepair{}
+	repaired := 0
+	for _, graph := range activeGraphs {
+		keyring, err := baseKeyring(graph)
+		if err != nil {
+			return nil, err
+		}
+		if *keyring.ProjectID != *projectID {
+			continue
+		}
+
+		existing := make(map[identity.ID]bool)
+		for _, id := range graph.MemberIDs() {
+			existing[*id] = true
+		}
+		isMember := existing[*authID]
+
+		for _, owner := range owners {
+			if existing[*owner] {
+				continue
+			}
+			existing[*owner] = true
+
+			repair := apitypes.KeyringRepair{PathExp: keyring.PathExp, OwnerID: owner}
+			targetPubKey, err := findEncryptionPublicKey(claimTrees, orgID, owner)
+			switch {
+			case !isMember:
+				repair.Reason = "You are not a member of this keyring."
+			case err != nil:
+				repair.Reason = "No encryption key was found for this member."
+			default:
+				err = e.addKeyringMember(ctx, claimTrees, graph, owner, targetPubKey, sigID, encID, kp)
+				if err != nil {
+					if repaired == 0 {
+						return nil, err
+					}
+					return nil, partialWriteError(err, fmt.Sprintf("adding %d keyring memberships", repaired))
+				}
+
+				repair.Repaired = true
+				repaired++
+			}
+
+			repairs = append(repairs, repair)
+		}
+	}
+
+	n.Notify(observer.Progress, "Keyrings repaired", true)
+
+	sort.Sort(keyringRepairSorter(repairs))
+	return repairs, nil
+}
+
+// VerifyKeyrings checks the integrity of the credential tree of the org: the
+// signatures on its keyrings, keyring memberships and credentials, that
+// credentials were written by members of their keyring and refer to a keyring
+// that exists, and that every member of the org belongs to the keyrings
+// holding active credentials. The problems found are returned, most severe
+// first.
+//
+// Only the keyrings the current user is a member of can be retrieved, and so
+// verified.
+func (e *Engine) VerifyKeyrings(ctx context.Context, notifier *observer.Notifier,
+	orgID *identity.ID) ([]apitypes.KeyringFinding, error) {
+
+	n := notifier.Notifier(3)
+
+	owners, err := e.keyringOwners(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	claimTrees, err := e.client.ClaimTree.List(ctx, orgID, nil)
+	if err != nil {
+		log.Printf("Error retrieving claim trees: %s", err)
+		return nil, err
+	}
+
+	n.Notify(observer.Progress, "Org members retrieved", true)
+
+	graphs, err := orgCredentialGraphs(ctx, e.client, orgID, e.session.AuthID())
+	if err != nil {
+		return nil, err
+	}
+
+	cgs := newCredentialGraphSet()
+	err = cgs.Add(graphs...)
+	if err != nil {
+		return nil, err
+	}
+
+	activeGraphs, err := cgs.Active()
+	if err != nil {
+		return nil, err
+	}
+
+	n.Notify(observer.Progress, "Keyrings retrieved", true)
+
+	findings, err := verifyKeyrings(graphs, activeGraphs, claimTrees, owners)
+	if err != nil {
+		return nil, err
+	}
+
+	n.Notify(observer.Progress, "Keyrings verified", true)
+
+	return findings, nil
+}
+
+// keyringOwners returns the IDs of every user in the org's member team, and
+// every active token of an active machine in the org; the owners of the
+// keyring memberships each keyring in the org should have.
+func (e *Engine) keyringOwners(ctx context.Context, orgID *identity.ID) ([]*identity.ID, error) {
+	teams, err := e.client.Teams.List(ctx, orgID)
+	if err != nil {
+		log.Printf("Error retrieving teams: %s", err)
+		return nil, err
+	}
+
+	memberTeam, _, err := findSystemTeams(teams)
+	if err != nil {
+		return nil, err
+	}
+
+	memberships, err := e.client.Memberships.List(ctx, orgID, memberTeam.ID, nil)
+	if err != nil {
+		log.Printf("Error retrieving memberships: %s", err)
+		return nil, err
+	}
+
+	machines, err := e.client.Machines.List(ctx, orgID,
+		&registry.MachineListOptions{State: primitive.MachineActiveState})
+	if err != nil {
+		log.Printf("Error retrieving machines: %s", err)
+		return nil, err
+	}
+
+	var owners []*identity.ID
+	for _, m := range memberships {
+		owners = append(owners, m.Body.(*primitive.Membership).OwnerID)
+	}
+	for _, machine := range machines {
+		for _, token := range machine.Tokens {
+			if token.Token.Body.State == primitive.MachineTokenActiveState {
+				owners = append(owners, token.Token.ID)
+			}
+		}
+	}
+
+	return owners, nil
+}
+
+// addKeyringMember adds ownerID, whose encryption key is targetPubKey, as a
+// member of graph's keyring.
+func (e *Engine) addKeyringMember(ctx context.Context, claimTrees []registry.ClaimTree,
+	graph registry.CredentialGraph, ownerID *identity.ID, targetPubKey *envelope.Signed,
+	sigID, encID *identity.ID, kp *crypto.KeyPairs) error {
+
+	v1member, v2member, err := cloneKeyringMember(ctx, e.crypto, claimTrees, graph,
+		e.session.AuthID(), ownerID, targetPubKey, sigID, encID, kp)
+	if err != nil {
 		return err
 	}
 
+	if v1member != nil {
+		_, err = e.client.KeyringMember.Post(ctx, []envelope.Signed{*v1member})
+	} else {
+		err = e.client.Keyring.Members.Post(ctx, *v2member)
+	}
+	if err != nil {
+		log.Printf("error uploading membership: %s", err)
+	}
+
+	return err
+}
+
+// keyringRepairSorter implements sort.Interface, ordering repairs by PathExp.
+type keyringRepairSorter []apitypes.KeyringRepair
+
+func (k keyringRepairSorter) Len() int      { return len(k) }
+func (k keyringRepairSorter) Swap(i, j int) { k[i], k[j] = k[j], k[i] }
+func (k keyringRepairSorter) Less(i, j int) bool {
+	return k[i].PathExp.String() < k[j].PathExp.String()
+}
+
+// ApproveInvite approves an invitation of a user into an organzation by
+// encoding them into a Keyring.
+func (e *Engine) ApproveInvite(ctx context.Context, notifier *observer.Notifier,
+	InviteID *identity.ID) (*envelope.Unsigned, error) {
+
+	n := notifier.Notifier(3)
+
+	invite, err := e.client.OrgInvite.Get(ctx, InviteID)
+	if err != nil {
+		log.Printf("could not fetch org invitation: %s", err)
+		return nil, err
+	}
+
+	inviteBody := invite.Body.(*primitive.OrgInvite)
+
+	if inviteBody.State != primitive.OrgInviteAcceptedState {
+		log.Printf("invitation not in accepted state: %s", inviteBody.State)
+		return nil, &apitypes.Error{
+			Type: apitypes.BadRequestError,
+			Err:  []string{"Invite must be accepted before it can be approved"},
+		}
+	}
+
+	n.Notify(observer.Progress, "Invite retrieved", true)
+
+	v1members, v2members, err := createKeyringMemberships(ctx, e.crypto,
+		e.client, e.session, inviteBody.OrgID, inviteBody.InviteeID)
+	if err != nil {
+		return nil, err
+	}
+
+	n.Notify(observer.Progress, "Keyring memberships created", true)
+
+	invite, err = e.client.OrgInvite.Approve(ctx, InviteID)
+	if err != nil {
+		log.Printf("could not approve org invite: %s", err)
+		return nil, err
+	}
+
+	n.Notify(observer.Progress, "Invite approved", true)
+
+	if len(v1members) != 0 {
+		_, err = e.client.KeyringMember.Post(ctx, v1members)
+		if err != nil {
+			log.Printf("error uploading memberships: %s", err)
+			return nil, err
+		}
+	}
+
+	for _, member := range v2members {
+		err = e.client.Keyring.Members.Post(ctx, member)
+		if err != nil {
+			log.Printf("error uploading memberships: %s", err)
+			return nil, err
+		}
+	}
+
+	return invite, nil
+}
+
+// GenerateKeypair creates a signing and encrypting keypair for the current
+// user for the given organization.
+func (e *Engine) GenerateKeypair(ctx context.Context, notifier *observer.Notifier,
+	OrgID *identity.ID) error {
+
+	_, _, _, err := e.generateKeypair(ctx, notifier.Notifier(4), OrgID)
+	return err
+}
+
+// generateKeypair creates and uploads a signing and encrypting keypair for
+// the current user for the given organization, returning the ids of the new
+// public keys along with the keypairs.
+func (e *Engine) generateKeypair(ctx context.Context, n *observer.Notifier,
+	OrgID *identity.ID) (*identity.ID, *identity.ID, *crypto.KeyPairs, error) {
+
+	kp, err := e.crypto.GenerateKeyPairs(ctx)
+	if err != nil {
+		log.Printf("Error generating keypairs: %s", err)
+		return nil, nil, nil, err
+	}
+
 	n.Notify(observer.Progress, "Keypairs generated", true)
 
 	pubsig, privsig, err := packageSigningKeypair(ctx, e.crypto, e.session.AuthID(),
 		OrgID, kp)
 	if err != nil {
 		log.Printf("Error packaging signing keypair: %s", err)
-		return err
+		return nil, nil, nil, err
 	}
 
 	sigclaim, err := e.crypto.SignedEnvelope(
@@ -415,7 +1190,7 @@ func (e *Engine) GenerateKeypair(ctx context.Context, notifier *observer.Notifie
 		pubsig.ID, &kp.Signature)
 	if err != nil {
 		log.Printf("Error creating signature claim: %s", err)
-		return err
+		return nil, nil, nil, err
 	}
 
 	n.Notify(observer.Progress, "Signing keys signed", true)
@@ -424,7 +1199,7 @@ func (e *Engine) GenerateKeypair(ctx context.Context, notifier *observer.Notifie
 		privsig, sigclaim)
 	if err != nil {
 		log.Printf("Error uploading signature keypair: %s", err)
-		return err
+		return nil, nil, nil, err
 	}
 
 	objs := make([]envelope.Envelope, len(claims)+2)
@@ -436,7 +1211,7 @@ func (e *Engine) GenerateKeypair(ctx context.Context, notifier *observer.Notifie
 	err = e.db.Set(objs...)
 	if err != nil {
 		log.Printf("Error storing signing keys in local db: %s", err)
-		return err
+		return nil, nil, nil, err
 	}
 
 	n.Notify(observer.Progress, "Signing keys uploaded", true)
@@ -445,6 +1220,7 @@ func (e *Engine) GenerateKeypair(ctx context.Context, notifier *observer.Notifie
 		OrgID, kp, pubsig)
 	if err != nil {
 		log.Printf("Error packaging encryption keypair: %s", err)
+		return nil, nil, nil, err
 	}
 
 	encclaim, err := e.crypto.SignedEnvelope(
@@ -453,7 +1229,7 @@ func (e *Engine) GenerateKeypair(ctx context.Context, notifier *observer.Notifie
 		pubsig.ID, &kp.Signature)
 	if err != nil {
 		log.Printf("Error creating signature claim for encryption key: %s", err)
-		return err
+		return nil, nil, nil, err
 	}
 
 	n.Notify(observer.Progress, "Encryption keys signed", true)
@@ -462,7 +1238,7 @@ func (e *Engine) GenerateKeypair(ctx context.Context, notifier *observer.Notifie
 		privenc, encclaim)
 	if err != nil {
 		log.Printf("Error uploading encryption keypair: %s", err)
-		return err
+		return nil, nil, nil, err
 	}
 
 	objs = make([]envelope.Envelope, len(claims)+2)
@@ -474,8 +1250,235 @@ func (e *Engine) GenerateKeypair(ctx context.Context, notifier *observer.Notifie
 	err = e.db.Set(objs...)
 	if err != nil {
 		log.Printf("Error storing encryption keys in local db: %s", err)
+		return nil, nil, nil, err
+	}
+
+	return pubsig.ID, pubenc.ID, kp, nil
+}
+
+// RegenerateKeypair replaces the current user's signing and encryption
+// keypairs for the given organization, revoking the old ones.
+//
+// The user's share of every version of each keyring they belong to is
+// re-encrypted for the new encryption key, so access to existing credentials
+// and their history is kept. Every share is decrypted before anything is
+// changed, so a share that can't be read aborts regeneration. The old keys are
+// only revoked once every share has been re-encrypted. If regeneration stops
+// part way, running it again from the same daemon reuses the new keys, which
+// it recorded in its db, and finishes the remaining shares; elsewhere, fresh
+// keys are made and every other keypair revoked.
+func (e *Engine) RegenerateKeypair(ctx context.Context, notifier *observer.Notifier,
+	orgID *identity.ID) error {
+
+	n := notifier.Notifier(7)
+	authID := e.session.AuthID()
+
+	oldPairs, err := e.client.KeyPairs.List(ctx, orgID)
+	if err != nil {
+		log.Printf("Error fetching keypairs: %s", err)
 		return err
 	}
 
+	// Shares may be encrypted for any of the user's encryption keys, including
+	// ones revoked by an earlier regeneration, so all of them are kept.
+	encPairs := make(map[identity.ID]crypto.EncryptionKeyPair)
+	for i, pair := range oldPairs {
+		if pair.PublicKey.Body.(*primitive.PublicKey).KeyType == encryptionKeyType {
+			encPairs[*pair.PublicKey.ID] = claimedEncryptionKeyPair(&oldPairs[i])
+		}
+	}
+
+	var inProgress regeneration
+	found, err := e.db.GetValue(regenerationBucket, orgID.String(), &inProgress)
+	if err != nil {
+		log.Printf("Error reading regeneration record: %s", err)
+		return err
+	}
+	var sigID, encID *identity.ID
+	var kp *crypto.KeyPairs
+	if found {
+		sigID, encID, kp = interruptedKeyPairs(oldPairs, &inProgress)
+	}
+
+	claimTrees, err := e.client.ClaimTree.List(ctx, orgID, nil)
+	if err != nil {
+		log.Printf("Error retrieving claim tree: %s", err)
+		return err
+	}
+
+	graphs, err := orgCredentialGraphs(ctx, e.client, orgID, authID)
+	if err != nil {
+		return err
+	}
+
+	type share struct {
+		graph registry.CredentialGraph
+		krm   *primitive.KeyringMember
+		mek   []byte
+	}
+
+	var shares []share
+	for _, graph := range graphs {
+		krm, mekshare, err := graph.FindMember(authID)
+		if err == registry.ErrMemberNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		// Already re-encrypted by the run that was interrupted.
+		if encID != nil && *krm.PublicKeyID == *encID {
+			continue
+		}
+
+		encPair, ok := encPairs[*krm.PublicKeyID]
+		if !ok {
+			log.Printf("no keypair found for keyring membership of %s", krm.KeyringID)
+			continue
+		}
+
+		encPubKey, err := findEncryptionPublicKeyByID(claimTrees, orgID, krm.EncryptingKeyID)
+		if err != nil {
+			log.Printf("could not find encypting public key for membership: %s", err)
+			return err
+		}
+
+		encPKBody := encPubKey.Body.(*primitive.PublicKey)
+		mek, err := e.crypto.Unbox(ctx, *mekshare.Key.Value, *mekshare.Key.Nonce,
+			&encPair, *encPKBody.Key.Value)
+		if err != nil {
+			log.Printf("could not decrypt keyring membership: %s", err)
+			return err
+		}
+
+		shares = append(shares, share{graph: graph, krm: krm, mek: mek})
+	}
+
+	n.Notify(observer.Progress, "Keyring memberships decrypted", true)
+
+	// Nothing has been written yet, so stop here if the request was cancelled
+	// while the memberships were being decrypted.
+	err = ctxutil.ErrIfDone(ctx)
+	if err != nil {
+		return err
+	}
+
+	if kp == nil {
+		sigID, encID, kp, err = e.generateKeypair(ctx, n, orgID)
+		if err != nil {
+			return err
+		}
+
+		err = e.db.SetValue(regenerationBucket, orgID.String(), &regeneration{SigID: sigID, EncID: encID})
+		if err != nil {
+			log.Printf("Error recording regeneration: %s", err)
+			return err
+		}
+	} else {
+		n.Notify(observer.Progress, "Resuming with keypairs from an earlier regeneration", true)
+	}
+
+	v1members := []envelope.Signed{}
+	v2members := []registry.KeyringMember{}
+	for _, s := range shares {
+		encMek, nonce, err := e.crypto.Box(ctx, s.mek, &kp.Encryption, kp.Encryption.Public[:])
+		if err != nil {
+			log.Printf("could not encrypt keyring membership: %s", err)
+			return err
+		}
+
+		key := &primitive.KeyringMemberKey{
+			Algorithm: crypto.EasyBox,
+			Nonce:     base64.NewValue(nonce),
+			Value:     base64.NewValue(encMek),
+		}
+
+		switch s.graph.GetKeyring().Version {
+		case 1:
+			projectID := s.graph.GetKeyring().Body.(*primitive.KeyringV1).ProjectID
+			member, err := newV1KeyringMember(ctx, e.crypto, s.krm.OrgID, projectID,
+				s.krm.KeyringID, authID, encID, encID, sigID, key, kp)
+			if err != nil {
+				return err
+			}
+			v1members = append(v1members, *member)
+		case 2:
+			member, err := newV2KeyringMember(ctx, e.crypto, s.krm.OrgID, s.krm.KeyringID,
+				authID, encID, encID, sigID, key, kp)
+			if err != nil {
+				return err
+			}
+			v2members = append(v2members, *member)
+		default:
+			return &apitypes.Error{
+				Type: apitypes.InternalServerError,
+				Err:  []string{"Unknown keyring schema version"},
+			}
+		}
+	}
+
+	if len(v1members) != 0 {
+		_, err = e.client.KeyringMember.Post(ctx, v1members)
+		if err != nil {
+			log.Printf("error uploading memberships: %s", err)
+			return partialWriteError(err, "generating new keypairs. "+
+				"Regenerate again to finish; the old keypairs have not been revoked")
+		}
+	}
+
+	for i, member := range v2members {
+		err = ctxutil.ErrIfDone(ctx)
+		if err == nil {
+			err = e.client.Keyring.Members.Post(ctx, member)
+		}
+		if err != nil {
+			log.Printf("error uploading memberships: %s", err)
+			return partialWriteError(err, fmt.Sprintf(
+				"generating new keypairs and re-encrypting %d of %d keyring memberships. "+
+					"Regenerate again to finish; the old keypairs have not been revoked",
+				len(v1members)+i, len(v1members)+len(v2members)))
+		}
+	}
+
+	n.Notify(observer.Progress, "Keyring memberships re-encrypted", true)
+
+	revoked := 0
+	for _, pair := range oldPairs {
+		if isRevoked(pair.Claims) || *pair.PublicKey.ID == *sigID || *pair.PublicKey.ID == *encID {
+			continue
+		}
+
+		err = ctxutil.ErrIfDone(ctx)
+		if err != nil {
+			return partialWriteError(err, fmt.Sprintf(
+				"re-encrypting keyring memberships and revoking %d old keypairs", revoked))
+		}
+
+		claim, err := e.crypto.SignedEnvelope(
+			ctx, primitive.NewClaim(orgID, authID, latestClaim(pair.Claims),
+				pair.PublicKey.ID, primitive.RevocationClaimType),
+			sigID, &kp.Signature)
+		if err != nil {
+			log.Printf("Error creating revocation claim: %s", err)
+			return err
+		}
+
+		_, err = e.client.Claims.Post(ctx, claim)
+		if err != nil {
+			log.Printf("Error revoking keypair: %s", err)
+			return partialWriteError(err, fmt.Sprintf(
+				"re-encrypting keyring memberships and revoking %d old keypairs", revoked))
+		}
+		revoked++
+	}
+
+	n.Notify(observer.Progress, "Old keypairs revoked", true)
+
+	err = e.db.DeleteValue(regenerationBucket, orgID.String())
+	if err != nil {
+		log.Printf("Error clearing regeneration record: %s", err)
+	}
+
 	return nil
 }
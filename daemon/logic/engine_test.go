@@ -0,0 +1,840 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/base64"
+	"github.com/manifoldco/torus-cli/config"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+
+	"github.com/manifoldco/torus-cli/daemon/crypto"
+	"github.com/manifoldco/torus-cli/daemon/db"
+	"github.com/manifoldco/torus-cli/daemon/observer"
+	"github.com/manifoldco/torus-cli/daemon/registry"
+	"github.com/manifoldco/torus-cli/daemon/session"
+)
+
+// fakeRegistry serves the parts of the registry API the engine uses to
+// create, read and rotate keyrings and machine tokens, for a single org and
+// project, from memory. Callers are told apart by their session token.
+type fakeRegistry struct {
+	t        *testing.T
+	srv      *httptest.Server
+	dir      string
+	observer *observer.Observer
+
+	mu          sync.Mutex
+	org         *envelope.Unsigned
+	project     *envelope.Unsigned
+	teams       []envelope.Unsigned
+	memberships []envelope.Unsigned
+	users       map[string]*identity.ID
+	keypairs    map[identity.ID][]registry.ClaimedKeyPair
+	graphs      []*registry.CredentialGraphV2
+	machine     *envelope.Unsigned
+	tokens      []*envelope.Unsigned
+
+	// requests lists the method and path of each request served, in order.
+	requests []string
+
+	// fail, if set, is called before each write is saved. If it returns an
+	// error, the write is refused with that error instead.
+	fail func(r *http.Request) *apitypes.Error
+}
+
+func newFakeRegistry(t *testing.T) *fakeRegistry {
+	dir, err := os.MkdirTemp("", "torus-registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &fakeRegistry{
+		t:        t,
+		dir:      dir,
+		observer: observer.New(),
+		users:    make(map[string]*identity.ID),
+		keypairs: make(map[identity.ID][]registry.ClaimedKeyPair),
+	}
+
+	r.org = r.unsigned(&primitive.Org{Name: "acme"})
+	r.project = r.unsigned(&primitive.Project{Name: "api", OrgID: r.org.ID})
+	for _, name := range []string{primitive.MemberTeamName, primitive.MachineTeamName,
+		primitive.OwnerTeamName, primitive.AdminTeamName} {
+		r.teams = append(r.teams, *r.unsigned(&primitive.Team{
+			Name:     name,
+			OrgID:    r.org.ID,
+			TeamType: primitive.SystemTeam,
+		}))
+	}
+
+	r.srv = httptest.NewServer(r)
+	go r.observer.Start()
+	return r
+}
+
+func (r *fakeRegistry) close() {
+	r.srv.Close()
+	r.observer.Stop()
+	os.RemoveAll(r.dir)
+}
+
+func (r *fakeRegistry) unsigned(body identity.Mutable) *envelope.Unsigned {
+	id, err := identity.NewMutable(body)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return &envelope.Unsigned{ID: &id, Version: 1, Body: body}
+}
+
+// notifier returns a notifier for a single request to the engine.
+func (r *fakeRegistry) notifier() *observer.Notifier {
+	ctx := context.WithValue(context.Background(), observer.CtxRequestID, "test")
+	n, err := r.observer.Notifier(ctx, 1)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return n
+}
+
+// addUser creates a user in the org's member team, with their own keypairs,
+// and returns an engine logged in as them.
+func (r *fakeRegistry) addUser(username string) (*Engine, *identity.ID) {
+	ctx := context.Background()
+	passphrase := []byte("passphrase for " + username)
+
+	master, err := crypto.CreateMasterKeyObject(ctx, passphrase)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+
+	user := r.unsigned(&primitive.User{Username: username, State: "active", Master: master})
+	sess := session.NewSession()
+	err = sess.Set(apitypes.UserSession, user, user, passphrase, username)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+
+	store, err := db.NewDB(filepath.Join(r.dir, username+".db"))
+	if err != nil {
+		r.t.Fatal(err)
+	}
+
+	client := registry.NewClient(r.srv.URL, "0.1.0", "test", sess, &http.Transport{},
+		registry.RetryPolicy{}, 0, nil)
+	e := NewEngine(&config.Config{}, sess, store, crypto.NewEngine(sess), client, nil)
+
+	r.mu.Lock()
+	r.users[username] = user.ID
+	r.mu.Unlock()
+	r.join(user.ID, primitive.MemberTeamName)
+
+	err = e.GenerateKeypair(ctx, r.notifier(), r.org.ID)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+
+	return e, user.ID
+}
+
+// join adds ownerID to the org's system team with the given name.
+func (r *fakeRegistry) join(ownerID *identity.ID, teamName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, t := range r.teams {
+		if t.Body.(*primitive.Team).Name == teamName {
+			r.memberships = append(r.memberships, *r.unsigned(&primitive.Membership{
+				OrgID:   r.org.ID,
+				OwnerID: ownerID,
+				TeamID:  t.ID,
+			}))
+			return
+		}
+	}
+	r.t.Fatalf("No %s team", teamName)
+}
+
+// removeUser removes ownerID from the org, revoking their keyring
+// memberships as the registry does.
+func (r *fakeRegistry) removeUser(ownerID *identity.ID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var kept []envelope.Unsigned
+	for _, m := range r.memberships {
+		if *m.Body.(*primitive.Membership).OwnerID != *ownerID {
+			kept = append(kept, m)
+		}
+	}
+	r.memberships = kept
+
+	for _, graph := range r.graphs {
+		for _, m := range graph.Members {
+			body := m.Member.Body.(*primitive.KeyringMember)
+			if *body.OwnerID != *ownerID {
+				continue
+			}
+
+			claim := &primitive.KeyringMemberClaim{
+				OrgID:           body.OrgID,
+				KeyringID:       body.KeyringID,
+				KeyringMemberID: m.Member.ID,
+				OwnerID:         ownerID,
+				ClaimType:       primitive.RevocationClaimType,
+				Created:         time.Now().UTC(),
+			}
+			sig := primitive.Signature{Algorithm: crypto.EdDSA, Value: base64.NewValue([]byte("system"))}
+			id, err := identity.NewImmutable(claim, &sig)
+			if err != nil {
+				r.t.Fatal(err)
+			}
+			graph.Claims = append(graph.Claims, envelope.Signed{ID: &id, Version: 1, Body: claim, Signature: sig})
+		}
+	}
+}
+
+// addMachine creates a machine in the org, with a single active token, and
+// returns the IDs of the machine and its token.
+func (r *fakeRegistry) addMachine(name string) (*identity.ID, *identity.ID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.machine = r.unsigned(&primitive.Machine{
+		Name:    name,
+		OrgID:   r.org.ID,
+		State:   primitive.MachineActiveState,
+		Created: time.Now().UTC(),
+	})
+	token := r.unsigned(&primitive.MachineToken{
+		OrgID:     r.org.ID,
+		MachineID: r.machine.ID,
+		State:     primitive.MachineTokenActiveState,
+		Created:   time.Now().UTC(),
+	})
+	r.tokens = []*envelope.Unsigned{token}
+
+	return r.machine.ID, token.ID
+}
+
+// tokenState returns the state of the machine token with the given ID.
+func (r *fakeRegistry) tokenState(tokenID *identity.ID) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if *token.ID == *tokenID {
+			return token.Body.(*primitive.MachineToken).State
+		}
+	}
+	return ""
+}
+
+// keyrings returns the versions of the keyring for pe held by the registry,
+// oldest first.
+func (r *fakeRegistry) keyrings(pe string) []*registry.CredentialGraphV2 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var graphs []*registry.CredentialGraphV2
+	for _, graph := range r.graphs {
+		if graph.Keyring.Body.(*primitive.Keyring).PathExp.String() == pe {
+			graphs = append(graphs, graph)
+		}
+	}
+	return graphs
+}
+
+func (r *fakeRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	caller := r.users[strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")]
+	query := req.URL.Query()
+	path := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	r.requests = append(r.requests, req.Method+" "+req.URL.Path)
+
+	if req.Method == "POST" && r.fail != nil {
+		if apiErr := r.fail(req); apiErr != nil {
+			w.WriteHeader(apiErr.StatusCode)
+			json.NewEncoder(w).Encode(apiErr)
+			return
+		}
+	}
+
+	var resp interface{}
+	switch req.Method + " " + path[0] {
+	case "GET orgs":
+		resp = r.org
+	case "GET projects":
+		resp = []envelope.Unsigned{*r.project}
+	case "GET teams":
+		resp = r.teams
+	case "GET memberships":
+		memberships := []envelope.Unsigned{}
+		for _, m := range r.memberships {
+			body := m.Body.(*primitive.Membership)
+			if teamID := query.Get("team_id"); teamID != "" && body.TeamID.String() != teamID {
+				continue
+			}
+			if ownerID := query.Get("owner_id"); ownerID != "" && body.OwnerID.String() != ownerID {
+				continue
+			}
+			memberships = append(memberships, m)
+		}
+		resp = memberships
+	case "GET machines":
+		if len(path) == 1 {
+			resp = []apitypes.MachineSegment{}
+			break
+		}
+		tokens := []interface{}{}
+		for _, token := range r.tokens {
+			tokens = append(tokens, map[string]interface{}{"token": token})
+		}
+		resp = map[string]interface{}{"machine": r.machine, "tokens": tokens}
+	case "POST machines":
+		segment := registry.MachineTokenCreationSegment{}
+		r.decode(req, &segment)
+		for _, pair := range segment.Keypairs {
+			r.keypairs[*segment.Token.ID] = append(r.keypairs[*segment.Token.ID], *pair)
+		}
+		r.tokens = append(r.tokens, segment.Token)
+		resp = map[string]interface{}{"token": segment.Token}
+	case "DELETE machines":
+		for _, token := range r.tokens {
+			if token.ID.String() == path[3] {
+				token.Body.(*primitive.MachineToken).State = primitive.MachineTokenDestroyedState
+			}
+		}
+	case "GET keypairs":
+		resp = append([]registry.ClaimedKeyPair{}, r.keypairs[*caller]...)
+	case "POST keypairs":
+		pair := registry.ClaimedKeyPair{}
+		r.decode(req, &pair)
+		r.keypairs[*caller] = append(r.keypairs[*caller], pair)
+		resp = pair
+	case "POST claims":
+		claim := envelope.Signed{}
+		r.decode(req, &claim)
+		pairs := r.keypairs[*caller]
+		for i, pair := range pairs {
+			if *pair.PublicKey.ID == *claim.Body.(*primitive.Claim).PublicKeyID {
+				pairs[i].Claims = append(pairs[i].Claims, claim)
+			}
+		}
+		resp = claim
+	case "GET claimtree":
+		segments := []apitypes.PublicKeySegment{}
+		for _, pairs := range r.keypairs {
+			for _, pair := range pairs {
+				segments = append(segments, apitypes.PublicKeySegment{Key: pair.PublicKey, Claims: pair.Claims})
+			}
+		}
+		resp = []registry.ClaimTree{{Org: r.org, PublicKeys: segments}}
+	case "GET credentialgraph":
+		// Like the registry, only keyrings the caller belongs to are listed.
+		graphs := []*registry.CredentialGraphV2{}
+		for _, graph := range r.graphs {
+			if _, _, err := graph.FindMember(caller); err == nil {
+				graphs = append(graphs, graph)
+			}
+		}
+		resp = graphs
+	case "POST credentialgraph":
+		graph := &registry.CredentialGraphV2{}
+		r.decode(req, graph)
+		r.graphs = append(r.graphs, graph)
+		resp = graph
+	case "POST credentials":
+		cred := envelope.Signed{}
+		r.decode(req, &cred)
+		keyringID := cred.Body.(*primitive.Credential).KeyringID
+		for _, graph := range r.graphs {
+			if *graph.Keyring.ID == *keyringID {
+				graph.Credentials = append(graph.Credentials, cred)
+			}
+		}
+		resp = cred
+	case "POST keyrings":
+		var members []registry.KeyringMember
+		r.decode(req, &members)
+		for _, graph := range r.graphs {
+			if graph.Keyring.ID.String() == path[1] {
+				graph.Members = append(graph.Members, members...)
+			}
+		}
+		resp = members
+	default:
+		r.t.Errorf("Unexpected registry request: %s %s", req.Method, req.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		r.t.Error(err)
+	}
+}
+
+func (r *fakeRegistry) decode(req *http.Request, v interface{}) {
+	err := json.NewDecoder(req.Body).Decode(v)
+	if err != nil {
+		r.t.Errorf("Could not decode %s %s: %s", req.Method, req.URL.Path, err)
+	}
+}
+
+// setCredentials stores plaintext values for names in the keyring for pe.
+func setCredentials(t *testing.T, r *fakeRegistry, e *Engine, pe string, values map[string]string) {
+	state := "set"
+	var creds []*PlaintextCredentialEnvelope
+	for name, value := range values {
+		creds = append(creds, &PlaintextCredentialEnvelope{
+			Version: 2,
+			Body: &PlaintextCredential{
+				Name:      name,
+				OrgID:     r.org.ID,
+				ProjectID: r.project.ID,
+				PathExp:   mustPathExp(pe),
+				Value:     value,
+				State:     &state,
+			},
+		})
+	}
+
+	_, err := e.AppendCredentials(context.Background(), r.notifier(), creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// retrieveValues returns the values of the credentials e can decrypt, by
+// name.
+func retrieveValues(t *testing.T, r *fakeRegistry, e *Engine) map[string]string {
+	pe := "/acme/api/*/*/*/*"
+	creds, failures, err := e.RetrieveCredentials(context.Background(), r.notifier(), nil, &pe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) > 0 {
+		t.Fatalf("Unexpected failures: %+v", failures)
+	}
+
+	values := make(map[string]string)
+	for _, cred := range creds {
+		values[cred.Body.Name] = cred.Body.Value
+	}
+	return values
+}
+
+// hasMember returns whether ownerID can decrypt graph's keyring.
+func hasMember(graph registry.CredentialGraph, ownerID *identity.ID) bool {
+	for _, id := range graph.MemberIDs() {
+		if *id == *ownerID {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	devPathExp  = "/acme/api/dev/default/*/*"
+	prodPathExp = "/acme/api/prod/default/*/*"
+)
+
+func TestRotateKeyrings(t *testing.T) {
+	r := newFakeRegistry(t)
+	defer r.close()
+
+	alice, _ := r.addUser("alice")
+	_, bobID := r.addUser("bob")
+
+	setCredentials(t, r, alice, devPathExp, map[string]string{"db_url": "dev-db"})
+	setCredentials(t, r, alice, prodPathExp, map[string]string{"db_url": "prod-db", "token": "prod-token"})
+	r.removeUser(bobID)
+
+	rotated, err := alice.RotateKeyrings(context.Background(), r.notifier(), r.org.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotated) != 2 {
+		t.Errorf("Expected both keyrings to be rotated, got %v", rotated)
+	}
+
+	for _, pe := range []string{devPathExp, prodPathExp} {
+		versions := r.keyrings(pe)
+		if len(versions) != 2 {
+			t.Fatalf("Expected a new version of %s, got %d versions", pe, len(versions))
+		}
+
+		head := versions[1]
+		if head.KeyringVersion() != versions[0].KeyringVersion()+1 {
+			t.Errorf("Expected %s to be version %d, got %d", pe, versions[0].KeyringVersion()+1, head.KeyringVersion())
+		}
+		if hasMember(head, bobID) {
+			t.Errorf("Expected bob not to be a member of the new version of %s", pe)
+		}
+		if len(head.Credentials) != len(versions[0].Credentials) {
+			t.Errorf("Expected %d credentials in the new version of %s, got %d",
+				len(versions[0].Credentials), pe, len(head.Credentials))
+		}
+	}
+
+	values := retrieveValues(t, r, alice)
+	if len(values) != 2 || values["db_url"] == "" || values["token"] != "prod-token" {
+		t.Errorf("Expected the secrets to be readable after rotation, got %v", values)
+	}
+
+	// Nothing is left to rotate.
+	rotated, err = alice.RotateKeyrings(context.Background(), r.notifier(), r.org.ID)
+	if err != nil || len(rotated) != 0 {
+		t.Errorf("Expected nothing to rotate, got %v, %v", rotated, err)
+	}
+}
+
+func TestRotateKeyringsFailure(t *testing.T) {
+	r := newFakeRegistry(t)
+	defer r.close()
+
+	alice, _ := r.addUser("alice")
+	_, bobID := r.addUser("bob")
+
+	setCredentials(t, r, alice, devPathExp, map[string]string{"db_url": "dev-db"})
+	setCredentials(t, r, alice, prodPathExp, map[string]string{"db_url": "prod-db"})
+	r.removeUser(bobID)
+
+	// Refuse the second keyring to be rotated.
+	posts := 0
+	r.fail = func(req *http.Request) *apitypes.Error {
+		if req.URL.Path != "/credentialgraph" {
+			return nil
+		}
+		if posts++; posts < 2 {
+			return nil
+		}
+		return &apitypes.Error{StatusCode: 409, Type: apitypes.ConflictError, Err: []string{"keyring exists"}}
+	}
+
+	_, err := alice.RotateKeyrings(context.Background(), r.notifier(), r.org.ID)
+	if err == nil {
+		t.Fatal("Expected rotation to fail")
+	}
+
+	var rotatedPE, failedPE string
+	for _, pe := range []string{devPathExp, prodPathExp} {
+		versions := r.keyrings(pe)
+		switch len(versions) {
+		case 1:
+			failedPE = pe
+			if hasMember(versions[0], bobID) {
+				t.Errorf("Expected bob's membership of %s to stay revoked", pe)
+			}
+		case 2:
+			rotatedPE = pe
+			if hasMember(versions[1], bobID) {
+				t.Errorf("Expected bob not to be a member of the new version of %s", pe)
+			}
+		default:
+			t.Errorf("Expected one or two versions of %s, got %d", pe, len(versions))
+		}
+	}
+	if rotatedPE == "" || failedPE == "" {
+		t.Fatalf("Expected one keyring to rotate fully and the other not at all")
+	}
+
+	msg := err.Error()
+	if !strings.Contains(msg, "Not rotated: "+failedPE) || !strings.Contains(msg, rotatedPE) {
+		t.Errorf("Expected the error to name the keyrings rotated and not, got %q", msg)
+	}
+
+	// Running it again rotates what was left.
+	r.fail = nil
+	rotated, err := alice.RotateKeyrings(context.Background(), r.notifier(), r.org.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotated) != 1 || rotated[0].String() != failedPE {
+		t.Errorf("Expected %s to be rotated, got %v", failedPE, rotated)
+	}
+
+	values := retrieveValues(t, r, alice)
+	if len(values) != 1 || values["db_url"] == "" {
+		t.Errorf("Expected the secrets to be readable after rotation, got %v", values)
+	}
+}
+
+func TestRepairKeyrings(t *testing.T) {
+	r := newFakeRegistry(t)
+	defer r.close()
+
+	alice, aliceID := r.addUser("alice")
+	r.join(aliceID, primitive.AdminTeamName)
+	setCredentials(t, r, alice, devPathExp, map[string]string{"db_url": "dev-db"})
+
+	// Bob joins after the keyring was made, so isn't a member of it.
+	bob, bobID := r.addUser("bob")
+	if values := retrieveValues(t, r, bob); len(values) != 0 {
+		t.Fatalf("Expected bob not to read any secrets before the repair, got %v", values)
+	}
+
+	t.Run("members can't repair", func(t *testing.T) {
+		_, err := bob.RepairKeyrings(context.Background(), r.notifier(), r.org.ID, r.project.ID)
+		apiErr, ok := err.(*apitypes.Error)
+		if !ok || apiErr.Type != apitypes.UnauthorizedError {
+			t.Errorf("Expected an unauthorized error, got %v", err)
+		}
+	})
+
+	repairs, err := alice.RepairKeyrings(context.Background(), r.notifier(), r.org.ID, r.project.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repairs) != 1 || !repairs[0].Repaired || *repairs[0].OwnerID != *bobID ||
+		repairs[0].PathExp.String() != devPathExp {
+		t.Errorf("Expected bob to be added to %s, got %+v", devPathExp, repairs)
+	}
+
+	if values := retrieveValues(t, r, bob); values["db_url"] != "dev-db" {
+		t.Errorf("Expected bob to read the secrets after the repair, got %v", values)
+	}
+
+	// Repairing again finds nothing missing, and adds no one.
+	members := len(r.keyrings(devPathExp)[0].Members)
+	repairs, err = alice.RepairKeyrings(context.Background(), r.notifier(), r.org.ID, r.project.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repairs) != 0 {
+		t.Errorf("Expected nothing to repair, got %+v", repairs)
+	}
+	if again := len(r.keyrings(devPathExp)[0].Members); again != members {
+		t.Errorf("Expected %d keyring members, got %d", members, again)
+	}
+}
+
+func TestRetrieveCredentialsFailures(t *testing.T) {
+	r := newFakeRegistry(t)
+	defer r.close()
+
+	alice, aliceID := r.addUser("alice")
+	setCredentials(t, r, alice, devPathExp, map[string]string{"db_url": "dev-db"})
+	setCredentials(t, r, alice, prodPathExp, map[string]string{"db_url": "prod-db", "token": "prod-token"})
+
+	// Corrupt alice's share of the prod keyring, so it can't be decrypted.
+	graph := r.keyrings(prodPathExp)[0]
+	for _, m := range graph.Members {
+		if *m.Member.Body.(*primitive.KeyringMember).OwnerID == *aliceID {
+			key := m.MEKShare.Body.(*primitive.MEKShare).Key
+			corrupt := base64.NewValue(append([]byte{}, *key.Value...))
+			(*corrupt)[0] ^= 0xff
+			key.Value = corrupt
+		}
+	}
+	alice.FlushCache()
+
+	pe := "/acme/api/*/*/*/*"
+	creds, failures, err := alice.RetrieveCredentials(context.Background(), r.notifier(), nil, &pe)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(creds) != 1 || creds[0].Body.Value != "dev-db" || creds[0].Body.PathExp.String() != devPathExp {
+		t.Errorf("Expected the dev secret to be decrypted, got %+v", creds)
+	}
+
+	if len(failures) != 2 {
+		t.Fatalf("Expected both prod secrets to fail, got %+v", failures)
+	}
+	for _, f := range failures {
+		if f.PathExp == nil || f.PathExp.String() != prodPathExp || f.Reason == "" {
+			t.Errorf("Expected a reason for the failure at %s, got %+v", prodPathExp, f)
+		}
+		if f.Name != "db_url" && f.Name != "token" {
+			t.Errorf("Expected the failure to name the secret, got %q", f.Name)
+		}
+	}
+}
+
+func TestRotateMachineToken(t *testing.T) {
+	r := newFakeRegistry(t)
+	defer r.close()
+
+	alice, _ := r.addUser("alice")
+	setCredentials(t, r, alice, devPathExp, map[string]string{"db_url": "dev-db"})
+	machineID, oldTokenID := r.addMachine("ci")
+
+	secret := base64.NewValue([]byte("new machine token secret"))
+	token, err := alice.Machine.RotateToken(context.Background(), r.notifier(), machineID, oldTokenID, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if state := r.tokenState(oldTokenID); state != primitive.MachineTokenDestroyedState {
+		t.Errorf("Expected the old token to be destroyed, got %q", state)
+	}
+	if state := r.tokenState(token.Token.ID); state != primitive.MachineTokenActiveState {
+		t.Errorf("Expected the new token to be active, got %q", state)
+	}
+	if !hasMember(r.keyrings(devPathExp)[0], token.Token.ID) {
+		t.Error("Expected the new token to be a member of the keyring")
+	}
+
+	// The old token is only destroyed once the new one is encoded.
+	encoded, destroyed := -1, -1
+	for i, req := range r.requests {
+		switch {
+		case strings.HasPrefix(req, "POST /keyrings/"):
+			encoded = i
+		case req == "DELETE /machines/"+machineID.String()+"/tokens/"+oldTokenID.String():
+			destroyed = i
+		}
+	}
+	if encoded < 0 || destroyed < encoded {
+		t.Errorf("Expected the old token to be destroyed after the new one was encoded, got %v", r.requests)
+	}
+}
+
+func TestRotateMachineTokenFailure(t *testing.T) {
+	r := newFakeRegistry(t)
+	defer r.close()
+
+	alice, _ := r.addUser("alice")
+	setCredentials(t, r, alice, devPathExp, map[string]string{"db_url": "dev-db"})
+	machineID, oldTokenID := r.addMachine("ci")
+
+	// Refuse to encode the new token into the keyring.
+	r.fail = func(req *http.Request) *apitypes.Error {
+		if !strings.HasPrefix(req.URL.Path, "/keyrings/") {
+			return nil
+		}
+		return &apitypes.Error{StatusCode: 500, Type: apitypes.InternalServerError, Err: []string{"down"}}
+	}
+
+	secret := base64.NewValue([]byte("new machine token secret"))
+	_, err := alice.Machine.RotateToken(context.Background(), r.notifier(), machineID, oldTokenID, secret)
+	if err == nil {
+		t.Fatal("Expected rotation to fail")
+	}
+
+	if state := r.tokenState(oldTokenID); state != primitive.MachineTokenActiveState {
+		t.Errorf("Expected the old token to stay active, got %q", state)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.tokens) != 2 {
+		t.Fatalf("Expected a new token to have been created, got %d tokens", len(r.tokens))
+	}
+	if state := r.tokens[1].Body.(*primitive.MachineToken).State; state != primitive.MachineTokenDestroyedState {
+		t.Errorf("Expected the new token to be destroyed, got %q", state)
+	}
+}
+
+// activeKeyPairs returns the IDs of ownerID's unrevoked public keys, and how
+// many keypairs they have in all.
+func activeKeyPairs(r *fakeRegistry, ownerID *identity.ID) (map[identity.ID]bool, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	active := make(map[identity.ID]bool)
+	for _, pair := range r.keypairs[*ownerID] {
+		if !isRevoked(pair.Claims) {
+			active[*pair.PublicKey.ID] = true
+		}
+	}
+	return active, len(r.keypairs[*ownerID])
+}
+
+func TestRegenerateKeypair(t *testing.T) {
+	r := newFakeRegistry(t)
+	defer r.close()
+
+	alice, aliceID := r.addUser("alice")
+	setCredentials(t, r, alice, devPathExp, map[string]string{"db_url": "dev-db"})
+	setCredentials(t, r, alice, prodPathExp, map[string]string{"db_url": "prod-db"})
+
+	// A second set of keypairs, not made by a regeneration, mustn't be
+	// mistaken for one that was interrupted.
+	err := alice.GenerateKeypair(context.Background(), r.notifier(), r.org.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, _ := activeKeyPairs(r, aliceID)
+
+	err = alice.RegenerateKeypair(context.Background(), r.notifier(), r.org.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	active, total := activeKeyPairs(r, aliceID)
+	if total != 6 || len(active) != 2 {
+		t.Fatalf("Expected a new signing and encryption keypair, with the 4 before revoked; "+
+			"got %d keypairs, %d active", total, len(active))
+	}
+	for id := range active {
+		if before[id] {
+			t.Errorf("Expected keypair %s to have been replaced", id)
+		}
+	}
+
+	values := retrieveValues(t, r, alice)
+	if len(values) != 1 || values["db_url"] == "" {
+		t.Errorf("Expected the secrets to be readable after regeneration, got %v", values)
+	}
+}
+
+func TestRegenerateKeypairResume(t *testing.T) {
+	r := newFakeRegistry(t)
+	defer r.close()
+
+	alice, aliceID := r.addUser("alice")
+	setCredentials(t, r, alice, devPathExp, map[string]string{"db_url": "dev-db"})
+	setCredentials(t, r, alice, prodPathExp, map[string]string{"db_url": "prod-db"})
+
+	// Refuse the second re-encrypted membership.
+	posts := 0
+	r.fail = func(req *http.Request) *apitypes.Error {
+		if !strings.HasPrefix(req.URL.Path, "/keyrings/") {
+			return nil
+		}
+		if posts++; posts < 2 {
+			return nil
+		}
+		return &apitypes.Error{StatusCode: 500, Type: apitypes.InternalServerError, Err: []string{"down"}}
+	}
+
+	err := alice.RegenerateKeypair(context.Background(), r.notifier(), r.org.ID)
+	if err == nil {
+		t.Fatal("Expected regeneration to fail")
+	}
+	if active, total := activeKeyPairs(r, aliceID); total != 4 || len(active) != 4 {
+		t.Fatalf("Expected new keypairs with none revoked, got %d keypairs, %d active", total, len(active))
+	}
+
+	r.fail = nil
+	err = alice.RegenerateKeypair(context.Background(), r.notifier(), r.org.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if active, total := activeKeyPairs(r, aliceID); total != 4 || len(active) != 2 {
+		t.Errorf("Expected the new keypairs to be reused and the old revoked, "+
+			"got %d keypairs, %d active", total, len(active))
+	}
+
+	values := retrieveValues(t, r, alice)
+	if len(values) != 1 || values["db_url"] == "" {
+		t.Errorf("Expected the secrets to be readable after regeneration, got %v", values)
+	}
+}
@@ -0,0 +1,88 @@
+package logic
+
+import (
+	"fmt"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/pathexp"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+// checkCredentialSizes returns an error naming each credential whose value
+// is larger than max bytes. A max of zero, as in a Config not built by
+// config.LoadConfig, skips the check; LoadConfig uses the default instead.
+func checkCredentialSizes(creds []*PlaintextCredentialEnvelope, max int) error {
+	if max <= 0 {
+		return nil
+	}
+
+	var msgs []string
+	for _, cred := range creds {
+		if size := len(cred.Body.Value); size > max {
+			msgs = append(msgs, fmt.Sprintf(
+				"The value of %s is %d bytes, larger than the limit of %d bytes.",
+				cred.Body.Name, size, max))
+		}
+	}
+
+	if len(msgs) > 0 {
+		return &apitypes.Error{
+			StatusCode: 400,
+			Type:       apitypes.BadRequestError,
+			Err:        msgs,
+		}
+	}
+
+	return nil
+}
+
+// addsCredential returns whether setting cred, whose most recent version is
+// previous, adds to the number of credentials held in its keyring.
+func addsCredential(cred *PlaintextCredential, previous *envelope.Signed) bool {
+	if cred.State != nil && *cred.State == "unset" {
+		return false
+	}
+	if previous == nil {
+		return true
+	}
+
+	body, ok := previous.Body.(*primitive.Credential)
+	return ok && body.State != nil && *body.State == "unset"
+}
+
+// checkKeyringCount returns an error if adding added new credentials to the
+// keyring for pe would leave it holding more than max credentials. As with
+// checkCredentialSizes, a max of zero skips the check.
+func checkKeyringCount(cgs *credentialGraphSet, pe *pathexp.PathExp, added, max int) error {
+	if max <= 0 || added == 0 {
+		return nil
+	}
+
+	gpe, err := pe.WithInstance("*")
+	if err != nil {
+		return err
+	}
+
+	active, err := cgs.ActiveCredentials(gpe)
+	if err != nil {
+		return err
+	}
+
+	existing := 0
+	for _, creds := range active {
+		existing += len(creds)
+	}
+
+	if existing+added > max {
+		return &apitypes.Error{
+			StatusCode: 400,
+			Type:       apitypes.BadRequestError,
+			Err: []string{fmt.Sprintf(
+				"Adding %d secrets to %s would leave %d in its keyring, more than the limit of %d.",
+				added, pe, existing+added, max)},
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package logic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+func TestCheckCredentialSizes(t *testing.T) {
+	creds := []*PlaintextCredentialEnvelope{
+		{Body: &PlaintextCredential{Name: "small", Value: "1234"}},
+		{Body: &PlaintextCredential{Name: "large", Value: "123456789"}},
+	}
+
+	err := checkCredentialSizes(creds, 8)
+	if err == nil {
+		t.Fatal("Expected an error for the large value")
+	}
+	if !strings.Contains(err.Error(), "large is 9 bytes, larger than the limit of 8 bytes") {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if strings.Contains(err.Error(), "small") {
+		t.Errorf("Expected only the large value to be named, got: %s", err)
+	}
+
+	if err := checkCredentialSizes(creds, 9); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if err := checkCredentialSizes(creds, 0); err != nil {
+		t.Errorf("Expected a zero limit to disable the check, got: %s", err)
+	}
+}
+
+func TestCheckKeyringCount(t *testing.T) {
+	cgs := newCredentialGraphSet()
+	cgs.Add(buildGraph("/o/p/e/s/u/*", 1, cred{id: id1}, cred{id: id2}))
+	pe := mustPathExp("/o/p/e/s/u/i")
+
+	if err := checkKeyringCount(cgs, pe, 1, 3); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	err := checkKeyringCount(cgs, pe, 2, 3)
+	if err == nil {
+		t.Fatal("Expected an error when exceeding the limit")
+	}
+	if !strings.Contains(err.Error(), "would leave 4 in its keyring, more than the limit of 3") {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	if err := checkKeyringCount(cgs, pe, 0, 1); err != nil {
+		t.Errorf("Expected replacing credentials to be allowed, got: %s", err)
+	}
+}
+
+func TestAddsCredential(t *testing.T) {
+	set := &PlaintextCredential{Name: "a"}
+	removed := &PlaintextCredential{Name: "a", State: &unset}
+	previous := &envelope.Signed{Body: &primitive.Credential{}}
+	tombstone := &envelope.Signed{Body: &primitive.Credential{State: &unset}}
+
+	if !addsCredential(set, nil) {
+		t.Error("Expected a new credential to be added")
+	}
+	if addsCredential(set, previous) {
+		t.Error("Expected replacing a credential not to add one")
+	}
+	if !addsCredential(set, tombstone) {
+		t.Error("Expected setting an unset credential to add one")
+	}
+	if addsCredential(removed, nil) {
+		t.Error("Expected unsetting a credential not to add one")
+	}
+}
@@ -153,6 +153,72 @@ func (m *Machine) EncodeToken(ctx context.Context, notifier *observer.Notifier,
 	return nil
 }
 
+// RotateToken replaces the given machine token with a new one derived from
+// the provided secret. The new token is encoded into all of the keyrings the
+// machine has access to before the old token is destroyed. If encoding fails,
+// the new token is destroyed instead, so the machine keeps its working token.
+func (m *Machine) RotateToken(ctx context.Context, notifier *observer.Notifier,
+	machineID, tokenID *identity.ID, secret *base64.Value) (*apitypes.MachineTokenSegment, error) {
+
+	n := notifier.Notifier(3)
+
+	n.Notify(observer.Progress, "Retrieving machine", true)
+	segment, err := m.engine.client.Machines.Get(ctx, machineID)
+	if err != nil {
+		log.Printf("Error retrieving machine: %s", err)
+		return nil, err
+	}
+
+	found := false
+	for _, t := range segment.Tokens {
+		if *t.Token.ID == *tokenID {
+			found = t.Token.Body.State == primitive.MachineTokenActiveState
+			break
+		}
+	}
+	if !found {
+		return nil, &apitypes.Error{
+			StatusCode: 404,
+			Type:       apitypes.NotFoundError,
+			Err:        []string{"Active machine token not found"},
+		}
+	}
+
+	machine := &envelope.Unsigned{
+		ID:      segment.Machine.ID,
+		Version: 1,
+		Body:    segment.Machine.Body,
+	}
+
+	token, err := m.CreateToken(ctx, n, machine, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	n.Notify(observer.Progress, "Uploading token keypairs", true)
+	tokenSegment, err := m.engine.client.Machines.CreateToken(ctx, machineID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.EncodeToken(ctx, n, token.Token)
+	if err != nil {
+		log.Printf("Error encoding new machine token, destroying it: %s", err)
+		if derr := m.engine.client.Machines.DestroyToken(ctx, machineID, token.Token.ID); derr != nil {
+			log.Printf("Error destroying new machine token: %s", derr)
+		}
+		return nil, err
+	}
+
+	n.Notify(observer.Progress, "Destroying old token", true)
+	err = m.engine.client.Machines.DestroyToken(ctx, machineID, tokenID)
+	if err != nil {
+		return nil, partialWriteError(err, "creating the new token")
+	}
+
+	return tokenSegment, nil
+}
+
 func generateKeypairs(ctx context.Context, c *crypto.Engine, orgID, authID *identity.ID,
 	kp *crypto.KeyPairs) ([]*registry.ClaimedKeyPair, error) {
 
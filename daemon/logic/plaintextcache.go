@@ -0,0 +1,83 @@
+package logic
+
+import (
+	"sync"
+
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/identity"
+)
+
+// plaintextCacheSize is the number of decrypted credentials kept, so that
+// unchanged credentials aren't decrypted again each time they're retrieved.
+const plaintextCacheSize = 1024
+
+// plaintextCache holds decrypted credentials by ID. A credential's ID is
+// derived from its contents, so a cached value is never stale; the oldest
+// entries are dropped once the cache is full.
+//
+// The cache is flushed whenever the session changes, so one session never
+// sees credentials decrypted for another.
+type plaintextCache struct {
+	mu      sync.Mutex
+	size    int
+	order   []identity.ID
+	entries map[identity.ID]PlaintextCredentialEnvelope
+}
+
+func newPlaintextCache(size int) *plaintextCache {
+	return &plaintextCache{
+		size:    size,
+		entries: make(map[identity.ID]PlaintextCredentialEnvelope),
+	}
+}
+
+// getAll returns the decrypted versions of creds, in the same order. ok is
+// false unless every credential is cached.
+func (c *plaintextCache) getAll(creds []envelope.Signed) ([]PlaintextCredentialEnvelope, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := make([]PlaintextCredentialEnvelope, len(creds))
+	for i, cred := range creds {
+		if cred.ID == nil {
+			return nil, false
+		}
+		pt, ok := c.entries[*cred.ID]
+		if !ok {
+			return nil, false
+		}
+		out[i] = pt
+	}
+
+	return out, true
+}
+
+// put caches a decrypted credential.
+func (c *plaintextCache) put(cred PlaintextCredentialEnvelope) {
+	if cred.ID == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[*cred.ID]; ok {
+		return
+	}
+
+	c.entries[*cred.ID] = cred
+	c.order = append(c.order, *cred.ID)
+	for len(c.order) > c.size {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+// flush drops every cached credential.
+func (c *plaintextCache) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order = nil
+	c.entries = make(map[identity.ID]PlaintextCredentialEnvelope)
+}
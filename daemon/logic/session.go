@@ -27,6 +27,9 @@ func (s *Session) Login(ctx context.Context, creds apitypes.LoginCredential) err
 		}
 	}
 
+	// Nothing cached for a previous session may be served to this one.
+	s.engine.FlushCache()
+
 	var authToken string
 	var err error
 	switch creds.Type() {
@@ -59,11 +62,14 @@ func (s *Session) Logout(ctx context.Context) error {
 
 	if tok == "" {
 		return &apitypes.Error{
-			Type: apitypes.UnauthorizedError,
-			Err:  []string{"You must be logged in, to logout!"},
+			StatusCode: 401,
+			Type:       apitypes.UnauthorizedError,
+			Err:        []string{"You must be logged in, to logout!"},
 		}
 	}
 
+	s.engine.FlushCache()
+
 	err := s.engine.client.Tokens.Delete(ctx, tok)
 	switch err := err.(type) {
 	case *apitypes.Error:
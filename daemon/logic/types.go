@@ -20,4 +20,20 @@ type PlaintextCredential struct {
 	ProjectID *identity.ID     `json:"project_id"`
 	Value     string           `json:"value"`
 	State     *string          `json:"state"`
+
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+
+	ExpectedVersion *int `json:"expected_version,omitempty"`
+}
+
+// CredentialVersion is a single version of a Credential, as returned when
+// retrieving its history. Value is only populated when the history is
+// requested with values revealed.
+type CredentialVersion struct {
+	ID       *identity.ID `json:"id"`
+	Version  int          `json:"version"`
+	State    string       `json:"state"`
+	AuthorID *identity.ID `json:"author_id"`
+	Value    string       `json:"value,omitempty"`
 }
@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/ed25519"
@@ -14,6 +16,7 @@ import (
 	"github.com/manifoldco/torus-cli/base64"
 	"github.com/manifoldco/torus-cli/envelope"
 	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/pathexp"
 	"github.com/manifoldco/torus-cli/primitive"
 
 	"github.com/manifoldco/torus-cli/daemon/crypto"
@@ -270,30 +273,11 @@ func createKeyringMemberships(ctx context.Context, c *crypto.Engine, client *reg
 	// Get all the keyrings and memberships for the current user. This way we
 	// can decrypt the MEK for each and then create a new KeyringMember for
 	// our wonderful new org member!
-	org, err := client.Orgs.Get(ctx, orgID)
+	graphs, err := orgCredentialGraphs(ctx, client, orgID, s.AuthID())
 	if err != nil {
 		return nil, nil, err
 	}
 
-	projects, err := client.Projects.List(ctx, org.ID)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	var graphs []registry.CredentialGraph
-	orgName := org.Body.(*primitive.Org).Name
-	for _, project := range projects {
-		projName := project.Body.(*primitive.Project).Name
-		projGraphs, err := client.CredentialGraph.Search(ctx,
-			"/"+orgName+"/"+projName+"/*/*/*/*", s.AuthID())
-		if err != nil {
-			log.Printf("Error retrieving credential graphs: %s", err)
-			return nil, nil, err
-		}
-
-		graphs = append(graphs, projGraphs...)
-	}
-
 	// Find encryption keys for user
 	targetPubKey, err := findEncryptionPublicKey(claimTrees, orgID, ownerID)
 	if err != nil {
@@ -315,62 +299,77 @@ func createKeyringMemberships(ctx context.Context, c *crypto.Engine, client *reg
 	v1members := []envelope.Signed{}
 	v2members := []registry.KeyringMember{}
 	for _, graph := range activeGraphs {
-		krm, mekshare, err := graph.FindMember(s.AuthID())
+		v1member, v2member, err := cloneKeyringMember(ctx, c, claimTrees, graph,
+			s.AuthID(), ownerID, targetPubKey, sigID, encID, kp)
 		if err != nil {
-			log.Printf("could not find keyring membership: %s", err)
-			return nil, nil, &apitypes.Error{
-				Type: apitypes.NotFoundError,
-				Err:  []string{"Keyring membership not found."},
-			}
+			return nil, nil, err
 		}
 
-		encPubKey, err := findEncryptionPublicKeyByID(claimTrees, orgID, krm.EncryptingKeyID)
-		if err != nil {
-			log.Printf("could not find encypting public key for membership: %s", err)
-			return nil, nil, err
+		if v1member != nil {
+			v1members = append(v1members, *v1member)
+		} else {
+			v2members = append(v2members, *v2member)
 		}
+	}
 
-		encPKBody := encPubKey.Body.(*primitive.PublicKey)
-		targetPKBody := targetPubKey.Body.(*primitive.PublicKey)
+	return v1members, v2members, nil
+}
 
-		encMek, nonce, err := c.CloneMembership(ctx, *mekshare.Key.Value,
-			*mekshare.Key.Nonce, &kp.Encryption, *encPKBody.Key.Value, *targetPKBody.Key.Value)
-		if err != nil {
-			log.Printf("could not clone keyring membership: %s", err)
-			return nil, nil, err
-		}
+// cloneKeyringMember creates a membership of graph's keyring for ownerID, by
+// encrypting the keyring's master key, which authID can decrypt, to ownerID's
+// encryption key targetPubKey. Depending on the keyring's schema version,
+// either a v1 membership or a v2 membership and mekshare is returned.
+func cloneKeyringMember(ctx context.Context, c *crypto.Engine, claimTrees []registry.ClaimTree,
+	graph registry.CredentialGraph, authID, ownerID *identity.ID, targetPubKey *envelope.Signed,
+	sigID, encID *identity.ID, kp *crypto.KeyPairs) (*envelope.Signed, *registry.KeyringMember, error) {
 
-		key := &primitive.KeyringMemberKey{
-			Algorithm: crypto.EasyBox,
-			Nonce:     base64.NewValue(nonce),
-			Value:     base64.NewValue(encMek),
+	krm, mekshare, err := graph.FindMember(authID)
+	if err != nil {
+		log.Printf("could not find keyring membership: %s", err)
+		return nil, nil, &apitypes.Error{
+			Type: apitypes.NotFoundError,
+			Err:  []string{"Keyring membership not found."},
 		}
+	}
 
-		switch graph.GetKeyring().Version {
-		case 1:
-			projectID := graph.GetKeyring().Body.(*primitive.KeyringV1).ProjectID
-			member, err := newV1KeyringMember(ctx, c, krm.OrgID, projectID,
-				krm.KeyringID, ownerID, targetPubKey.ID, encID, sigID, key, kp)
-			if err != nil {
-				return nil, nil, err
-			}
-			v1members = append(v1members, *member)
-		case 2:
-			member, err := newV2KeyringMember(ctx, c, krm.OrgID, krm.KeyringID,
-				ownerID, targetPubKey.ID, encID, sigID, key, kp)
-			if err != nil {
-				return nil, nil, err
-			}
-			v2members = append(v2members, *member)
-		default:
-			return nil, nil, &apitypes.Error{
-				Type: apitypes.InternalServerError,
-				Err:  []string{"Unknown keyring schema version"},
-			}
-		}
+	encPubKey, err := findEncryptionPublicKeyByID(claimTrees, krm.OrgID, krm.EncryptingKeyID)
+	if err != nil {
+		log.Printf("could not find encypting public key for membership: %s", err)
+		return nil, nil, err
 	}
 
-	return v1members, v2members, nil
+	encPKBody := encPubKey.Body.(*primitive.PublicKey)
+	targetPKBody := targetPubKey.Body.(*primitive.PublicKey)
+
+	encMek, nonce, err := c.CloneMembership(ctx, *mekshare.Key.Value,
+		*mekshare.Key.Nonce, &kp.Encryption, *encPKBody.Key.Value, *targetPKBody.Key.Value)
+	if err != nil {
+		log.Printf("could not clone keyring membership: %s", err)
+		return nil, nil, err
+	}
+
+	key := &primitive.KeyringMemberKey{
+		Algorithm: crypto.EasyBox,
+		Nonce:     base64.NewValue(nonce),
+		Value:     base64.NewValue(encMek),
+	}
+
+	switch graph.GetKeyring().Version {
+	case 1:
+		projectID := graph.GetKeyring().Body.(*primitive.KeyringV1).ProjectID
+		member, err := newV1KeyringMember(ctx, c, krm.OrgID, projectID,
+			krm.KeyringID, ownerID, targetPubKey.ID, encID, sigID, key, kp)
+		return member, nil, err
+	case 2:
+		member, err := newV2KeyringMember(ctx, c, krm.OrgID, krm.KeyringID,
+			ownerID, targetPubKey.ID, encID, sigID, key, kp)
+		return nil, member, err
+	default:
+		return nil, nil, &apitypes.Error{
+			Type: apitypes.InternalServerError,
+			Err:  []string{"Unknown keyring schema version"},
+		}
+	}
 }
 
 // fetchKeyPairs fetches the user's signing and encryption keypairs from the
@@ -386,6 +385,10 @@ func fetchKeyPairs(ctx context.Context, client *registry.Client,
 	var sigClaimed registry.ClaimedKeyPair
 	var encClaimed registry.ClaimedKeyPair
 	for _, keyPair := range keyPairs {
+		if isRevoked(keyPair.Claims) {
+			continue
+		}
+
 		pubKey := keyPair.PublicKey.Body.(*primitive.PublicKey)
 		switch pubKey.KeyType {
 		case signingKeyType:
@@ -407,28 +410,112 @@ func fetchKeyPairs(ctx context.Context, client *registry.Client,
 		}
 	}
 
-	sigPub := sigClaimed.PublicKey.Body.(*primitive.PublicKey).Key.Value
-	sigKP := crypto.SignatureKeyPair{
-		Public:  ed25519.PublicKey(*sigPub),
-		Private: *sigClaimed.PrivateKey.Body.(*primitive.PrivateKey).Key.Value,
-		PNonce:  *sigClaimed.PrivateKey.Body.(*primitive.PrivateKey).PNonce,
+	kp := crypto.KeyPairs{
+		Signature:  claimedSignatureKeyPair(&sigClaimed),
+		Encryption: claimedEncryptionKeyPair(&encClaimed),
 	}
 
-	encPub := *encClaimed.PublicKey.Body.(*primitive.PublicKey).Key.Value
-	encPubB := [32]byte{}
-	copy(encPubB[:], encPub)
-	encKP := crypto.EncryptionKeyPair{
-		Public:  encPubB,
-		Private: *encClaimed.PrivateKey.Body.(*primitive.PrivateKey).Key.Value,
-		PNonce:  *encClaimed.PrivateKey.Body.(*primitive.PrivateKey).PNonce,
+	return sigClaimed.PublicKey.ID, encClaimed.PublicKey.ID, &kp, nil
+}
+
+// claimedSignatureKeyPair returns the signing keypair held in pair. Its
+// private key is still encrypted with the user's master key.
+func claimedSignatureKeyPair(pair *registry.ClaimedKeyPair) crypto.SignatureKeyPair {
+	priv := pair.PrivateKey.Body.(*primitive.PrivateKey)
+	return crypto.SignatureKeyPair{
+		Public:  ed25519.PublicKey(*pair.PublicKey.Body.(*primitive.PublicKey).Key.Value),
+		Private: *priv.Key.Value,
+		PNonce:  *priv.PNonce,
 	}
+}
 
-	kp := crypto.KeyPairs{
-		Signature:  sigKP,
-		Encryption: encKP,
+// claimedEncryptionKeyPair returns the encryption keypair held in pair. Its
+// private key is still encrypted with the user's master key.
+func claimedEncryptionKeyPair(pair *registry.ClaimedKeyPair) crypto.EncryptionKeyPair {
+	priv := pair.PrivateKey.Body.(*primitive.PrivateKey)
+	pub := [32]byte{}
+	copy(pub[:], *pair.PublicKey.Body.(*primitive.PublicKey).Key.Value)
+	return crypto.EncryptionKeyPair{
+		Public:  pub,
+		Private: *priv.Key.Value,
+		PNonce:  *priv.PNonce,
 	}
+}
 
-	return sigClaimed.PublicKey.ID, encClaimed.PublicKey.ID, &kp, nil
+// regenerationBucket holds a regeneration record for each org whose keypairs
+// are being regenerated, keyed by org ID.
+const regenerationBucket = "regeneration"
+
+// regeneration records the keypairs made by a regeneration that hasn't yet
+// revoked the keypairs they replace, so that running it again can finish the
+// job with them rather than making more.
+type regeneration struct {
+	SigID *identity.ID `json:"sig_id"`
+	EncID *identity.ID `json:"enc_id"`
+}
+
+// interruptedKeyPairs returns the signing and encryption keypairs in pairs
+// named by r, a regeneration that stopped part way, if both are still
+// unrevoked. Otherwise, including when r is nil, it returns nil. Other
+// unrevoked keypairs are never reused, however new they are, as a user
+// regenerating may be replacing any of them.
+func interruptedKeyPairs(pairs []registry.ClaimedKeyPair, r *regeneration) (*identity.ID, *identity.ID, *crypto.KeyPairs) {
+	if r == nil || r.SigID == nil || r.EncID == nil {
+		return nil, nil, nil
+	}
+
+	var sig, enc *registry.ClaimedKeyPair
+	for i, pair := range pairs {
+		if isRevoked(pair.Claims) {
+			continue
+		}
+
+		switch *pair.PublicKey.ID {
+		case *r.SigID:
+			sig = &pairs[i]
+		case *r.EncID:
+			enc = &pairs[i]
+		}
+	}
+
+	if sig == nil || enc == nil {
+		return nil, nil, nil
+	}
+
+	return sig.PublicKey.ID, enc.PublicKey.ID, &crypto.KeyPairs{
+		Signature:  claimedSignatureKeyPair(sig),
+		Encryption: claimedEncryptionKeyPair(enc),
+	}
+}
+
+// isRevoked returns whether the given claims against a public key include a
+// revocation.
+func isRevoked(claims []envelope.Signed) bool {
+	for _, claim := range claims {
+		if body, ok := claim.Body.(*primitive.Claim); ok && body.KeyType == primitive.RevocationClaimType {
+			return true
+		}
+	}
+	return false
+}
+
+// latestClaim returns the id of the most recent of the given claims against a
+// public key, which a new claim must name as its previous.
+func latestClaim(claims []envelope.Signed) *identity.ID {
+	var latest *envelope.Signed
+	var created time.Time
+	for i, claim := range claims {
+		body, ok := claim.Body.(*primitive.Claim)
+		if ok && (latest == nil || !body.Created.Before(created)) {
+			latest = &claims[i]
+			created = body.Created
+		}
+	}
+
+	if latest == nil {
+		return nil
+	}
+	return latest.ID
 }
 
 // findEncryptingKey queries the registry for public keys in the given org, to
@@ -509,6 +596,49 @@ func findSystemTeams(teams []envelope.Unsigned) (*envelope.Unsigned, *envelope.U
 	return members, machines, nil
 }
 
+// requireOrgRole returns an unauthorized error unless ownerID is a member of
+// one of the org's system teams named in roles. action describes what the
+// role is needed for, and completes the error's message.
+func requireOrgRole(ctx context.Context, client *registry.Client, orgID, ownerID *identity.ID,
+	action string, roles ...string) error {
+
+	teams, err := client.Teams.List(ctx, orgID)
+	if err != nil {
+		log.Printf("Error retrieving teams: %s", err)
+		return err
+	}
+
+	memberships, err := client.Memberships.List(ctx, orgID, nil, ownerID)
+	if err != nil {
+		log.Printf("Error retrieving memberships: %s", err)
+		return err
+	}
+
+	held := make(map[identity.ID]bool, len(memberships))
+	for _, m := range memberships {
+		held[*m.Body.(*primitive.Membership).TeamID] = true
+	}
+
+	for _, t := range teams {
+		body := t.Body.(*primitive.Team)
+		if body.TeamType != primitive.SystemTeam || !held[*t.ID] {
+			continue
+		}
+		for _, role := range roles {
+			if body.Name == role {
+				return nil
+			}
+		}
+	}
+
+	return &apitypes.Error{
+		StatusCode: http.StatusForbidden,
+		Type:       apitypes.UnauthorizedError,
+		Err: []string{fmt.Sprintf("You must be in the %s team to %s.",
+			strings.Join(roles, " or "), action)},
+	}
+}
+
 func findEncryptionPublicKey(trees []registry.ClaimTree, orgID *identity.ID,
 	userID *identity.ID) (*envelope.Signed, error) {
 
@@ -522,7 +652,7 @@ func findEncryptionPublicKey(trees []registry.ClaimTree, orgID *identity.ID,
 		for _, segment := range tree.PublicKeys {
 			key := segment.Key
 			keyBody := key.Body.(*primitive.PublicKey)
-			if *keyBody.OwnerID != *userID {
+			if *keyBody.OwnerID != *userID || isRevoked(segment.Claims) {
 				continue
 			}
 
@@ -579,6 +709,48 @@ func findEncryptionPublicKeyByID(trees []registry.ClaimTree, orgID *identity.ID,
 	return encKey, nil
 }
 
+// verifyMembership checks the signatures on ownerID's membership of the
+// keyring in graph, using the signing keys in the org's claim trees, so that a
+// tampered membership is reported as such rather than as a failure to decrypt.
+func verifyMembership(graph registry.CredentialGraph, ownerID *identity.ID,
+	trees []registry.ClaimTree) error {
+
+	signed, err := graph.FindSignedMember(ownerID)
+	if err != nil {
+		return err
+	}
+
+	for _, env := range signed {
+		err = env.Verify(findSigningKey(trees, env))
+		if err != nil {
+			return fmt.Errorf("Keyring membership failed verification (%s). It may "+
+				"have been tampered with; ask an admin of the org to check the "+
+				"keyring's members.", err)
+		}
+	}
+
+	return nil
+}
+
+// findSigningKey returns the public key in the org's claim trees that env
+// claims to be signed by, or nil if there is none.
+func findSigningKey(trees []registry.ClaimTree, env *envelope.Signed) *envelope.Signed {
+	if env.Signature.PublicKeyID == nil {
+		return nil
+	}
+
+	var signer *envelope.Signed
+	for _, tree := range trees {
+		for _, segment := range tree.PublicKeys {
+			if *segment.Key.ID == *env.Signature.PublicKeyID {
+				signer = segment.Key
+			}
+		}
+	}
+
+	return signer
+}
+
 func packagePublicKey(ctx context.Context, engine *crypto.Engine, ownerID,
 	orgID *identity.ID, keyType string, public []byte, sigID *identity.ID,
 	sigKP *crypto.SignatureKeyPair) (*envelope.Signed, error) {
@@ -626,6 +798,145 @@ func packagePrivateKey(ctx context.Context, engine *crypto.Engine, ownerID,
 	return engine.SignedEnvelope(ctx, &body, sigID, sigKP)
 }
 
+// checkExpectedVersion returns a version conflict error if cred expects to
+// replace a different version of itself than previous, its most recent
+// version, so that a change made since cred's author last read it isn't
+// silently overwritten.
+//
+// The check is best-effort. The registry doesn't enforce it, so a credential
+// written through another daemon between the check and the write can still be
+// replaced.
+func checkExpectedVersion(cred *PlaintextCredential, previous *envelope.Signed) error {
+	if cred.ExpectedVersion == nil {
+		return nil
+	}
+
+	current := 0
+	if previous != nil {
+		base, err := baseCredential(previous)
+		if err != nil {
+			return err
+		}
+		current = base.CredentialVersion
+	}
+
+	if current == *cred.ExpectedVersion {
+		return nil
+	}
+
+	msg := fmt.Sprintf("%s was set by someone else, and is now at version %d.", cred.Name, current)
+	if current == 0 {
+		msg = fmt.Sprintf("%s no longer exists.", cred.Name)
+	}
+
+	return &apitypes.Error{
+		StatusCode: 409,
+		Type:       apitypes.VersionConflictError,
+		Err:        []string{msg},
+	}
+}
+
+// encryptCredential constructs an encrypted and signed version of the given
+// credential for storage in the keyring with the given ID. previous is the
+// credential it replaces, if any.
+func encryptCredential(ctx context.Context, engine *crypto.Engine,
+	cred *PlaintextCredential, keyringID *identity.ID, previous *envelope.Signed,
+	mekshare *primitive.MEKShare, encryptingKey *primitive.PublicKey,
+	sigID *identity.ID, kp *crypto.KeyPairs) (*envelope.Signed, error) {
+
+	credBody := primitive.Credential{
+		State:       cred.State,
+		Description: cred.Description,
+		Tags:        cred.Tags,
+		BaseCredential: primitive.BaseCredential{
+			Name:      cred.Name,
+			PathExp:   cred.PathExp,
+			KeyringID: keyringID,
+			ProjectID: cred.ProjectID,
+			OrgID:     cred.OrgID,
+			Credential: &primitive.CredentialValue{
+				Algorithm: crypto.SecretBox,
+			},
+		},
+	}
+
+	if previous == nil {
+		credBody.Previous = nil
+		credBody.CredentialVersion = 1
+	} else {
+		base, err := baseCredential(previous)
+		if err != nil {
+			return nil, err
+		}
+
+		credBody.Previous = previous.ID
+		credBody.CredentialVersion = base.CredentialVersion + 1
+
+		// Keep the previous version's description and tags unless new ones
+		// were given, so changing a value doesn't lose its metadata.
+		prev, ok := previous.Body.(*primitive.Credential)
+		if ok && cred.Description == "" && len(cred.Tags) == 0 {
+			credBody.Description = prev.Description
+			credBody.Tags = prev.Tags
+		}
+	}
+
+	// Derive a key for the credential using the keyring master key
+	// and use the derived key to encrypt the credential
+	cekNonce, ctNonce, ct, err := engine.BoxCredential(
+		ctx, []byte(cred.Value), *mekshare.Key.Value, *mekshare.Key.Nonce,
+		&kp.Encryption, *encryptingKey.Key.Value)
+	if err != nil {
+		log.Printf("Error encrypting credential: %s", err)
+		return nil, err
+	}
+
+	credBody.Nonce = base64.NewValue(cekNonce)
+
+	credBody.Credential.Nonce = base64.NewValue(ctNonce)
+	credBody.Credential.Value = base64.NewValue(ct)
+
+	signed, err := engine.SignedEnvelope(ctx, &credBody, sigID, &kp.Signature)
+	if err != nil {
+		log.Printf("Error signing credential body: %s", err)
+		return nil, err
+	}
+
+	return signed, nil
+}
+
+// orgCredentialGraphs returns every version of every CredentialGraph in the
+// org that authID has access to, across all of the org's projects.
+func orgCredentialGraphs(ctx context.Context, client *registry.Client,
+	orgID, authID *identity.ID) ([]registry.CredentialGraph, error) {
+
+	org, err := client.Orgs.Get(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	projects, err := client.Projects.List(ctx, org.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var graphs []registry.CredentialGraph
+	orgName := org.Body.(*primitive.Org).Name
+	for _, project := range projects {
+		projName := project.Body.(*primitive.Project).Name
+		projGraphs, err := client.CredentialGraph.Search(ctx,
+			"/"+orgName+"/"+projName+"/*/*/*/*", authID)
+		if err != nil {
+			log.Printf("Error retrieving credential graphs: %s", err)
+			return nil, err
+		}
+
+		graphs = append(graphs, projGraphs...)
+	}
+
+	return graphs, nil
+}
+
 var errCredVersionMistmach = errors.New("Mismatched credential version and body")
 
 func baseCredential(cred *envelope.Signed) (*primitive.BaseCredential, error) {
@@ -644,3 +955,66 @@ func baseCredential(cred *envelope.Signed) (*primitive.BaseCredential, error) {
 		return nil, fmt.Errorf("Unknown credential version %d", v)
 	}
 }
+
+// newCredentialFailure describes why cred, from the given graph, could not be
+// decrypted.
+func newCredentialFailure(graph registry.CredentialGraph, cred *envelope.Signed,
+	reason error) apitypes.CredentialFailure {
+
+	failure := apitypes.CredentialFailure{ID: cred.ID, Reason: reason.Error()}
+	if cred.ID != nil {
+		failure.Name = cred.ID.String()
+	}
+
+	if base, err := baseCredential(cred); err == nil {
+		failure.Name = base.Name
+		failure.PathExp = base.PathExp
+	} else if keyring, err := baseKeyring(graph); err == nil {
+		failure.PathExp = keyring.PathExp
+	}
+
+	return failure
+}
+
+func baseKeyring(graph registry.CredentialGraph) (*primitive.BaseKeyring, error) {
+	switch b := graph.GetKeyring().Body.(type) {
+	case *primitive.Keyring:
+		return &b.BaseKeyring, nil
+	case *primitive.KeyringV1:
+		return &b.BaseKeyring, nil
+	default:
+		return nil, &apitypes.Error{
+			Type: apitypes.InternalServerError,
+			Err:  []string{"Malformed keyring body"},
+		}
+	}
+}
+
+// partialWriteError wraps err, which stopped an operation after some of its
+// changes had been saved, with a description of what was saved, so the user
+// knows the state the operation was left in. The status and type of err are
+// kept, so it is still reported as the failure it was.
+func partialWriteError(err error, saved string) error {
+	msg := "Stopped after " + saved + "."
+	if apiErr, ok := err.(*apitypes.Error); ok {
+		return &apitypes.Error{
+			StatusCode: apiErr.StatusCode,
+			Type:       apiErr.Type,
+			Err:        append([]string{msg}, apiErr.Err...),
+		}
+	}
+
+	return &apitypes.Error{
+		StatusCode: http.StatusInternalServerError,
+		Type:       apitypes.InternalServerError,
+		Err:        []string{msg, err.Error()},
+	}
+}
+
+func joinPathExps(pes []*pathexp.PathExp) string {
+	strs := make([]string, len(pes))
+	for i, pe := range pes {
+		strs[i] = pe.String()
+	}
+	return strings.Join(strs, ", ")
+}
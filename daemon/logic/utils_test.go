@@ -0,0 +1,202 @@
+package logic
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/base64"
+	"github.com/manifoldco/torus-cli/daemon/registry"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+func TestKeypairClaims(t *testing.T) {
+	now := time.Now().UTC()
+	claim := func(id *identity.ID, keyType string, created time.Time) envelope.Signed {
+		return envelope.Signed{
+			ID:   id,
+			Body: &primitive.Claim{KeyType: keyType, Created: created},
+		}
+	}
+
+	active := []envelope.Signed{
+		claim(id1, primitive.SignatureClaimType, now.Add(-time.Hour)),
+		claim(id2, primitive.SignatureClaimType, now),
+	}
+	revoked := append(active, claim(id3, primitive.RevocationClaimType, now.Add(time.Hour)))
+
+	if isRevoked(active) {
+		t.Error("Expected keypair without a revocation claim to be active")
+	}
+	if !isRevoked(revoked) {
+		t.Error("Expected keypair with a revocation claim to be revoked")
+	}
+
+	if id := latestClaim(active); id != id2 {
+		t.Errorf("Expected latest claim %s, got %s", id2, id)
+	}
+	if id := latestClaim(nil); id != nil {
+		t.Errorf("Expected no latest claim, got %s", id)
+	}
+}
+
+func TestInterruptedKeyPairs(t *testing.T) {
+	now := time.Now().UTC()
+	pair := func(id *identity.ID, keyType string, created time.Time, claims ...envelope.Signed) registry.ClaimedKeyPair {
+		return registry.ClaimedKeyPair{
+			PublicKey: &envelope.Signed{ID: id, Body: &primitive.PublicKey{
+				KeyType: keyType,
+				Created: created,
+				Key:     primitive.PublicKeyValue{Value: base64.NewValue([]byte("public"))},
+			}},
+			PrivateKey: &envelope.Signed{Body: &primitive.PrivateKey{
+				Key:    primitive.PrivateKeyValue{Value: base64.NewValue([]byte("private"))},
+				PNonce: base64.NewValue([]byte("nonce")),
+			}},
+			Claims: claims,
+		}
+	}
+	revocation := envelope.Signed{Body: &primitive.Claim{KeyType: primitive.RevocationClaimType}}
+
+	oldSig := mustID("04100000000000000000000001000")
+	oldEnc := mustID("04100000000000000000000010000")
+	newSig := mustID("04100000000000000000000100000")
+	newEnc := mustID("04100000000000000000001000000")
+
+	pairs := []registry.ClaimedKeyPair{
+		pair(id1, signingKeyType, now.Add(-time.Hour), revocation),
+		pair(id2, encryptionKeyType, now.Add(-time.Hour), revocation),
+		pair(newSig, signingKeyType, now.Add(time.Minute)),
+		pair(oldSig, signingKeyType, now),
+		pair(oldEnc, encryptionKeyType, now),
+		pair(newEnc, encryptionKeyType, now.Add(time.Minute)),
+	}
+
+	t.Run("no regeneration recorded", func(t *testing.T) {
+		sigID, encID, kp := interruptedKeyPairs(pairs, nil)
+		if sigID != nil || encID != nil || kp != nil {
+			t.Errorf("Expected no interrupted keypairs, got %s and %s", sigID, encID)
+		}
+	})
+
+	t.Run("recorded regeneration", func(t *testing.T) {
+		sigID, encID, kp := interruptedKeyPairs(pairs, &regeneration{SigID: oldSig, EncID: oldEnc})
+		if sigID != oldSig || encID != oldEnc {
+			t.Errorf("Expected the recorded keypairs, not the newest, got %s and %s", sigID, encID)
+		}
+		if kp == nil {
+			t.Error("Expected the recorded keypairs to be returned")
+		}
+	})
+
+	t.Run("recorded keypairs revoked", func(t *testing.T) {
+		sigID, encID, kp := interruptedKeyPairs(pairs, &regeneration{SigID: id1, EncID: id2})
+		if sigID != nil || encID != nil || kp != nil {
+			t.Errorf("Expected revoked keypairs not to be reused, got %s and %s", sigID, encID)
+		}
+	})
+}
+
+func TestCheckExpectedVersion(t *testing.T) {
+	rawPE := "/o/p/e/s/u/i"
+	name := "secret"
+	pe := mustPathExp(rawPE)
+	v1 := cred{id: id1, pe: &rawPE, name: &name, version: 1}
+	v2 := cred{id: id2, prev: id1, pe: &rawPE, name: &name, version: 2}
+
+	head := func(secrets ...cred) *envelope.Signed {
+		cgs := newCredentialGraphSet()
+		cgs.Add(buildGraph("/o/p/e/s/u/*", 1, secrets...))
+
+		previous, err := cgs.HeadCredential(pe, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return previous
+	}
+
+	expecting := func(version int) *PlaintextCredential {
+		return &PlaintextCredential{Name: name, PathExp: pe, ExpectedVersion: &version}
+	}
+
+	t.Run("unchecked without an expected version", func(t *testing.T) {
+		err := checkExpectedVersion(&PlaintextCredential{Name: name, PathExp: pe}, head(v1, v2))
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+	})
+
+	t.Run("matching version", func(t *testing.T) {
+		if err := checkExpectedVersion(expecting(1), head(v1)); err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		if err := checkExpectedVersion(expecting(0), nil); err != nil {
+			t.Errorf("Unexpected error for a new credential: %s", err)
+		}
+	})
+
+	t.Run("concurrent version bump", func(t *testing.T) {
+		// Read at version 1, but someone else set version 2 before the
+		// write was made.
+		err := checkExpectedVersion(expecting(1), head(v1, v2))
+		if !apitypes.IsVersionConflictError(err) {
+			t.Errorf("Expected a conflict error, got %v", err)
+		}
+	})
+
+	t.Run("concurrent create", func(t *testing.T) {
+		err := checkExpectedVersion(expecting(0), head(v1))
+		if !apitypes.IsVersionConflictError(err) {
+			t.Errorf("Expected a conflict error, got %v", err)
+		}
+	})
+}
+
+func TestPartialWriteError(t *testing.T) {
+	t.Run("api error", func(t *testing.T) {
+		cause := &apitypes.Error{
+			StatusCode: http.StatusForbidden,
+			Type:       apitypes.UnauthorizedError,
+			Err:        []string{"not allowed"},
+		}
+
+		err := partialWriteError(cause, "saving 1 of 2 secrets")
+		apiErr, ok := err.(*apitypes.Error)
+		if !ok {
+			t.Fatalf("Expected an api error, got %T", err)
+		}
+		if apiErr.StatusCode != cause.StatusCode || apiErr.Type != cause.Type {
+			t.Errorf("Expected %d %s to be kept, got %d %s",
+				cause.StatusCode, cause.Type, apiErr.StatusCode, apiErr.Type)
+		}
+
+		want := []string{"Stopped after saving 1 of 2 secrets.", "not allowed"}
+		if !reflect.DeepEqual(apiErr.Err, want) {
+			t.Errorf("Expected %q, got %q", want, apiErr.Err)
+		}
+		if len(cause.Err) != 1 {
+			t.Errorf("Expected the wrapped error to be unchanged, got %q", cause.Err)
+		}
+	})
+
+	t.Run("other error", func(t *testing.T) {
+		err := partialWriteError(errors.New("connection reset"), "saving 1 of 2 secrets")
+		apiErr, ok := err.(*apitypes.Error)
+		if !ok {
+			t.Fatalf("Expected an api error, got %T", err)
+		}
+		if apiErr.StatusCode != http.StatusInternalServerError || apiErr.Type != apitypes.InternalServerError {
+			t.Errorf("Expected an internal server error, got %d %s", apiErr.StatusCode, apiErr.Type)
+		}
+
+		want := []string{"Stopped after saving 1 of 2 secrets.", "connection reset"}
+		if !reflect.DeepEqual(apiErr.Err, want) {
+			t.Errorf("Expected %q, got %q", want, apiErr.Err)
+		}
+	})
+}
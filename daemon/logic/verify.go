@@ -0,0 +1,140 @@
+package logic
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/daemon/registry"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+// verifyKeyrings checks the integrity of the credential tree made up of
+// graphs, every version of the keyrings of an org:
+//
+//   - keyrings, memberships and credentials must be signed by a key in the
+//     org's claim trees, and their bodies must match their signatures;
+//   - credentials must be signed by a member of the keyring holding them;
+//   - credentials must refer to the keyring holding them;
+//   - each of owners, the users and machine tokens expected to be able to
+//     read the org's secrets, must be a member of every keyring in active.
+//
+// The problems found are returned, most severe first.
+func verifyKeyrings(graphs, active []registry.CredentialGraph, trees []registry.ClaimTree,
+	owners []*identity.ID) ([]apitypes.KeyringFinding, error) {
+
+	findings := []apitypes.KeyringFinding{}
+	critical := func(f apitypes.KeyringFinding, format string, a ...interface{}) {
+		f.Severity = apitypes.FindingCritical
+		f.Detail = fmt.Sprintf(format, a...)
+		findings = append(findings, f)
+	}
+
+	for _, graph := range graphs {
+		kpe, err := keyringPathExp(graph)
+		if err != nil {
+			return nil, err
+		}
+
+		keyring := graph.GetKeyring()
+		if err := keyring.Verify(findSigningKey(trees, keyring)); err != nil {
+			critical(apitypes.KeyringFinding{PathExp: kpe},
+				"Keyring version %d failed verification: %s", graph.KeyringVersion(), err)
+		}
+
+		members := graph.SignedMembers()
+		ownerIDs := make([]string, 0, len(members))
+		byOwner := make(map[string]identity.ID, len(members))
+		for ownerID := range members {
+			ownerIDs = append(ownerIDs, ownerID.String())
+			byOwner[ownerID.String()] = ownerID
+		}
+		sort.Strings(ownerIDs)
+
+		for _, raw := range ownerIDs {
+			ownerID := byOwner[raw]
+			for _, env := range members[ownerID] {
+				if err := env.Verify(findSigningKey(trees, env)); err != nil {
+					critical(apitypes.KeyringFinding{PathExp: kpe, OwnerID: &ownerID},
+						"Membership of keyring version %d failed verification: %s",
+						graph.KeyringVersion(), err)
+				}
+			}
+		}
+
+		for i := range graph.GetCredentials() {
+			cred := &graph.GetCredentials()[i]
+			base, err := baseCredential(cred)
+			if err != nil {
+				return nil, err
+			}
+
+			f := apitypes.KeyringFinding{PathExp: kpe, CredentialName: base.Name}
+			if base.KeyringID == nil || *base.KeyringID != *keyring.ID {
+				critical(f, "Version %d refers to keyring %s, not keyring version %d holding it",
+					base.CredentialVersion, base.KeyringID, graph.KeyringVersion())
+			}
+
+			signer := findSigningKey(trees, cred)
+			if err := cred.Verify(signer); err != nil {
+				critical(f, "Version %d failed verification: %s", base.CredentialVersion, err)
+				continue
+			}
+
+			ownerID := signer.Body.(*primitive.PublicKey).OwnerID
+			if _, ok := members[*ownerID]; !ok {
+				f.OwnerID = ownerID
+				critical(f, "Version %d was signed by someone who is not a member of keyring version %d",
+					base.CredentialVersion, graph.KeyringVersion())
+			}
+		}
+	}
+
+	missing := make(map[string]map[identity.ID]bool)
+	for _, graph := range active {
+		kpe, err := keyringPathExp(graph)
+		if err != nil {
+			return nil, err
+		}
+		if missing[kpe.String()] == nil {
+			missing[kpe.String()] = make(map[identity.ID]bool)
+		}
+
+		existing := make(map[identity.ID]bool)
+		for _, id := range graph.MemberIDs() {
+			existing[*id] = true
+		}
+
+		for _, owner := range owners {
+			if existing[*owner] || missing[kpe.String()][*owner] {
+				continue
+			}
+			missing[kpe.String()][*owner] = true
+
+			findings = append(findings, apitypes.KeyringFinding{
+				Severity: apitypes.FindingWarning,
+				PathExp:  kpe,
+				OwnerID:  owner,
+				Detail: fmt.Sprintf("Not a member of keyring version %d, so can't read the secrets it holds",
+					graph.KeyringVersion()),
+			})
+		}
+	}
+
+	sort.Stable(keyringFindingSorter(findings))
+	return findings, nil
+}
+
+// keyringFindingSorter implements sort.Interface, ordering findings by
+// severity and then PathExp.
+type keyringFindingSorter []apitypes.KeyringFinding
+
+func (k keyringFindingSorter) Len() int      { return len(k) }
+func (k keyringFindingSorter) Swap(i, j int) { k[i], k[j] = k[j], k[i] }
+func (k keyringFindingSorter) Less(i, j int) bool {
+	if k[i].Severity != k[j].Severity {
+		return k[i].Severity == apitypes.FindingCritical
+	}
+	return k[i].PathExp.String() < k[j].PathExp.String()
+}
@@ -0,0 +1,199 @@
+package logic
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/base64"
+	"github.com/manifoldco/torus-cli/daemon/registry"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+// signer holds a signing keypair registered in a claim tree for ownerID.
+type signer struct {
+	ownerID *identity.ID
+	key     *envelope.Signed
+	priv    ed25519.PrivateKey
+}
+
+func newSigner(t *testing.T, keyID, ownerID *identity.ID) *signer {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &signer{
+		ownerID: ownerID,
+		key: &envelope.Signed{
+			ID:      keyID,
+			Version: 1,
+			Body: &primitive.PublicKey{
+				OwnerID:   ownerID,
+				Algorithm: "eddsa",
+				Key:       primitive.PublicKeyValue{Value: base64.NewValue(pub)},
+				KeyType:   "signing",
+			},
+		},
+		priv: priv,
+	}
+}
+
+func (s *signer) sign(t *testing.T, id *identity.ID, body identity.Immutable) *envelope.Signed {
+	b, err := json.Marshal(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := ed25519.Sign(s.priv, append([]byte(strconv.Itoa(body.Version())), b...))
+
+	return &envelope.Signed{
+		ID:      id,
+		Version: uint8(body.Version()),
+		Body:    body,
+		Signature: primitive.Signature{
+			Algorithm:   "eddsa",
+			PublicKeyID: s.key.ID,
+			Value:       base64.NewValue(sig),
+		},
+	}
+}
+
+func TestVerifyKeyrings(t *testing.T) {
+	aliceID := mustID("04200000000000000000000000001")
+	bobID := mustID("04200000000000000000000000010")
+	malloryID := mustID("04200000000000000000000000100")
+	keyringID := mustID("04300000000000000000000000001")
+	otherKeyringID := mustID("04300000000000000000000000010")
+
+	alice := newSigner(t, mustID("04100000000000000000000000001"), aliceID)
+	mallory := newSigner(t, mustID("04100000000000000000000000010"), malloryID)
+	trees := []registry.ClaimTree{{
+		PublicKeys: []apitypes.PublicKeySegment{{Key: alice.key}, {Key: mallory.key}},
+	}}
+
+	pe := mustPathExp("/o/p/e/s/u/*")
+	newGraphFor := func(t *testing.T, keyringID *identity.ID, creds ...*envelope.Signed) registry.CredentialGraph {
+		cg := &registry.CredentialGraphV2{
+			KeyringSectionV2: registry.KeyringSectionV2{
+				Keyring: alice.sign(t, keyringID, &primitive.Keyring{
+					BaseKeyring: primitive.BaseKeyring{PathExp: pe, KeyringVersion: 1},
+				}),
+				Members: []registry.KeyringMember{{
+					Member: alice.sign(t, mustID("04400000000000000000000000001"), &primitive.KeyringMember{
+						KeyringID: keyringID,
+						OwnerID:   aliceID,
+					}),
+					MEKShare: alice.sign(t, mustID("04400000000000000000000000010"), &primitive.MEKShare{
+						KeyringID: keyringID,
+						OwnerID:   aliceID,
+					}),
+				}},
+			},
+		}
+		for _, c := range creds {
+			cg.Credentials = append(cg.Credentials, *c)
+		}
+		return cg
+	}
+	newGraph := func(t *testing.T, creds ...*envelope.Signed) registry.CredentialGraph {
+		return newGraphFor(t, keyringID, creds...)
+	}
+
+	credential := func(s *signer, name string, keyring *identity.ID) *envelope.Signed {
+		return s.sign(t, mustID("04500000000000000000000000001"), &primitive.Credential{
+			BaseCredential: primitive.BaseCredential{
+				Name:              name,
+				PathExp:           mustPathExp("/o/p/e/s/u/i"),
+				KeyringID:         keyring,
+				CredentialVersion: 1,
+			},
+		})
+	}
+
+	verify := func(t *testing.T, graph registry.CredentialGraph, owners ...*identity.ID) []apitypes.KeyringFinding {
+		graphs := []registry.CredentialGraph{graph}
+		findings, err := verifyKeyrings(graphs, graphs, trees, owners)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		return findings
+	}
+
+	t.Run("intact", func(t *testing.T) {
+		findings := verify(t, newGraph(t, credential(alice, "password", keyringID)), aliceID)
+		if len(findings) != 0 {
+			t.Errorf("Expected no findings, got %+v", findings)
+		}
+	})
+
+	t.Run("tampered credential", func(t *testing.T) {
+		cred := credential(alice, "password", keyringID)
+		cred.Body.(*primitive.Credential).State = &unset
+
+		findings := verify(t, newGraph(t, cred), aliceID)
+		if len(findings) != 1 || findings[0].Severity != apitypes.FindingCritical ||
+			findings[0].CredentialName != "password" {
+			t.Errorf("Expected a critical finding for password, got %+v", findings)
+		}
+	})
+
+	t.Run("credential signed by a non-member", func(t *testing.T) {
+		findings := verify(t, newGraph(t, credential(mallory, "password", keyringID)), aliceID)
+		if len(findings) != 1 || findings[0].Severity != apitypes.FindingCritical ||
+			findings[0].OwnerID == nil || *findings[0].OwnerID != *malloryID {
+			t.Errorf("Expected a critical finding for mallory, got %+v", findings)
+		}
+	})
+
+	t.Run("credential in a missing keyring", func(t *testing.T) {
+		findings := verify(t, newGraph(t, credential(alice, "password", otherKeyringID)), aliceID)
+		if len(findings) != 1 || findings[0].Severity != apitypes.FindingCritical {
+			t.Errorf("Expected a critical finding, got %+v", findings)
+		}
+	})
+
+	t.Run("credential in another keyring", func(t *testing.T) {
+		graphs := []registry.CredentialGraph{
+			newGraph(t, credential(alice, "password", otherKeyringID)),
+			newGraphFor(t, otherKeyringID),
+		}
+		findings, err := verifyKeyrings(graphs, graphs, trees, []*identity.ID{aliceID})
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if len(findings) != 1 || findings[0].Severity != apitypes.FindingCritical ||
+			findings[0].CredentialName != "password" {
+			t.Errorf("Expected a critical finding for password, got %+v", findings)
+		}
+	})
+
+	t.Run("tampered keyring", func(t *testing.T) {
+		graph := newGraph(t)
+		graph.GetKeyring().Body.(*primitive.Keyring).KeyringVersion = 2
+
+		findings := verify(t, graph, aliceID)
+		if len(findings) != 1 || findings[0].Severity != apitypes.FindingCritical ||
+			findings[0].CredentialName != "" || findings[0].OwnerID != nil {
+			t.Errorf("Expected a critical finding for the keyring, got %+v", findings)
+		}
+	})
+
+	t.Run("missing member", func(t *testing.T) {
+		findings := verify(t, newGraph(t, credential(mallory, "password", keyringID)), aliceID, bobID)
+		if len(findings) != 2 {
+			t.Fatalf("Expected 2 findings, got %+v", findings)
+		}
+		if findings[0].Severity != apitypes.FindingCritical {
+			t.Errorf("Expected critical findings first, got %+v", findings)
+		}
+		if f := findings[1]; f.Severity != apitypes.FindingWarning || f.OwnerID == nil || *f.OwnerID != *bobID {
+			t.Errorf("Expected a warning for bob, got %+v", f)
+		}
+	})
+}
@@ -2,12 +2,22 @@ package logic
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/identity"
 	"github.com/manifoldco/torus-cli/primitive"
+
+	"github.com/manifoldco/torus-cli/daemon/observer"
+	"github.com/manifoldco/torus-cli/daemon/registry"
 )
 
+// staleMachineTokenAge is the age after which a machine token should be
+// rotated.
+const staleMachineTokenAge = 90 * 24 * time.Hour
+
 // Worklog holds the logic for discovering and acting on worklog items.
 // A Worklog item is some action the user should take, either for
 // maintenance (this user should be in this keyring, this invite can be
@@ -24,32 +34,18 @@ type Worklog struct {
 func (w *Worklog) List(ctx context.Context, orgID *identity.ID) ([]apitypes.WorklogItem, error) {
 	var items []apitypes.WorklogItem
 
-	org, err := w.engine.client.Orgs.Get(ctx, orgID)
+	graphs, err := orgCredentialGraphs(ctx, w.engine.client, orgID,
+		w.engine.session.AuthID())
 	if err != nil {
 		return nil, err
 	}
 
-	projects, err := w.engine.client.Projects.List(ctx, orgID)
+	cgs := newCredentialGraphSet()
+	err = cgs.Add(graphs...)
 	if err != nil {
 		return nil, err
 	}
 
-	cgs := newCredentialGraphSet()
-	orgName := org.Body.(*primitive.Org).Name
-	for _, project := range projects {
-		projName := project.Body.(*primitive.Project).Name
-		graphs, err := w.engine.client.CredentialGraph.Search(ctx,
-			"/"+orgName+"/"+projName+"/*/*/*/*", w.engine.session.AuthID())
-		if err != nil {
-			return nil, err
-		}
-
-		err = cgs.Add(graphs...)
-		if err != nil {
-			return nil, err
-		}
-	}
-
 	needRotation, err := cgs.NeedRotation()
 	if err != nil {
 		return nil, err
@@ -70,6 +66,105 @@ func (w *Worklog) List(ctx context.Context, orgID *identity.ID) ([]apitypes.Work
 		items = append(items, item)
 	}
 
+	for _, head := range cgs.RevokedHeads() {
+		keyring, err := baseKeyring(head)
+		if err != nil {
+			return nil, err
+		}
+
+		item := apitypes.WorklogItem{
+			Subject: keyring.PathExp.String(),
+			Summary: "A member's access was revoked. This keyring should be rotated to keep new secrets from them.",
+		}
+		item.CreateID(apitypes.KeyringRotateWorklogType)
+
+		items = append(items, item)
+	}
+
+	inviteItems, err := w.inviteItems(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	items = append(items, inviteItems...)
+
+	machineItems, err := w.machineItems(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	items = append(items, machineItems...)
+
+	return items, nil
+}
+
+// inviteItems returns an item for each outstanding invite to the org.
+// Accepted invites can be approved automatically; the rest are waiting on
+// the invitee.
+func (w *Worklog) inviteItems(ctx context.Context, orgID *identity.ID) ([]apitypes.WorklogItem, error) {
+	invites, err := w.engine.client.OrgInvite.List(ctx, orgID, []string{
+		primitive.OrgInvitePendingState,
+		primitive.OrgInviteAssociatedState,
+		primitive.OrgInviteAcceptedState,
+	})
+	if err != nil {
+		// Only admins may see the invites for an org.
+		if isUnauthorized(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var items []apitypes.WorklogItem
+	for _, invite := range invites {
+		body := invite.Body.(*primitive.OrgInvite)
+
+		item := apitypes.WorklogItem{Subject: body.Email}
+		if body.State == primitive.OrgInviteAcceptedState {
+			item.Summary = "This invite has been accepted, and can be approved."
+			item.CreateID(apitypes.InviteApproveWorklogType)
+		} else {
+			item.Summary = "This invite is waiting to be accepted by " + body.Email + "."
+			item.CreateID(apitypes.InvitePendingWorklogType)
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// machineItems returns an item for each active machine token older than
+// staleMachineTokenAge.
+func (w *Worklog) machineItems(ctx context.Context, orgID *identity.ID) ([]apitypes.WorklogItem, error) {
+	machines, err := w.engine.client.Machines.List(ctx, orgID,
+		&registry.MachineListOptions{State: primitive.MachineActiveState})
+	if err != nil {
+		if isUnauthorized(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var items []apitypes.WorklogItem
+	for _, machine := range machines {
+		name := machine.Machine.Body.Name
+		for _, token := range machine.Tokens {
+			body := token.Token.Body
+			if body.State != primitive.MachineTokenActiveState ||
+				time.Since(body.Created) < staleMachineTokenAge {
+				continue
+			}
+
+			item := apitypes.WorklogItem{
+				Subject: name + " token " + token.Token.ID.String(),
+				Summary: fmt.Sprintf("This token was created on %s. Rotate it with `torus machines roles rotate %s --token %s`.",
+					body.Created.Format("2006-01-02"), name, token.Token.ID),
+			}
+			item.CreateID(apitypes.MachineTokenWorklogType)
+
+			items = append(items, item)
+		}
+	}
+
 	return items, nil
 }
 
@@ -93,7 +188,7 @@ func (w *Worklog) Get(ctx context.Context, orgID *identity.ID,
 
 // Resolve attempts to resolve the worklog item in the given org with the given
 // ident.
-func (w *Worklog) Resolve(ctx context.Context, orgID *identity.ID,
+func (w *Worklog) Resolve(ctx context.Context, n *observer.Notifier, orgID *identity.ID,
 	ident *apitypes.WorklogID) (*apitypes.WorklogResult, error) {
 
 	item, err := w.Get(ctx, orgID, ident)
@@ -112,8 +207,116 @@ func (w *Worklog) Resolve(ctx context.Context, orgID *identity.ID,
 			State:   apitypes.ManualWorklogResult,
 			Message: "Please set a new value for the secret at " + item.Subject,
 		}, nil
+	case apitypes.InviteApproveWorklogType:
+		return w.approveInvite(ctx, n, orgID, item)
+	case apitypes.InvitePendingWorklogType:
+		return &apitypes.WorklogResult{
+			ID:      item.ID,
+			State:   apitypes.ManualWorklogResult,
+			Message: item.Subject + " must accept their invite before it can be approved",
+		}, nil
+	case apitypes.MachineTokenWorklogType:
+		return &apitypes.WorklogResult{
+			ID:      item.ID,
+			State:   apitypes.ManualWorklogResult,
+			Message: "Please rotate the " + item.Subject,
+		}, nil
+	case apitypes.KeyringRotateWorklogType:
+		return w.rotateKeyring(ctx, orgID, item)
+	}
+
+	return nil, nil
+}
+
+// approveInvite approves the accepted invite for the email address in the
+// item's subject.
+func (w *Worklog) approveInvite(ctx context.Context, n *observer.Notifier,
+	orgID *identity.ID, item *apitypes.WorklogItem) (*apitypes.WorklogResult, error) {
+
+	invites, err := w.engine.client.OrgInvite.List(ctx, orgID,
+		[]string{primitive.OrgInviteAcceptedState})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, invite := range invites {
+		if invite.Body.(*primitive.OrgInvite).Email != item.Subject {
+			continue
+		}
+
+		_, err = w.engine.ApproveInvite(ctx, n, invite.ID)
+		if err != nil {
+			return &apitypes.WorklogResult{
+				ID:      item.ID,
+				State:   apitypes.FailureWorklogResult,
+				Message: "Could not approve the invite for " + item.Subject + ": " + err.Error(),
+			}, nil
+		}
+
+		return &apitypes.WorklogResult{
+			ID:      item.ID,
+			State:   apitypes.SuccessWorklogResult,
+			Message: "Approved the invite for " + item.Subject,
+		}, nil
+	}
+
+	return nil, nil
+}
 
+// rotateKeyring rotates the keyring whose PathExp is the item's subject, if it
+// still has a member's share revoked.
+func (w *Worklog) rotateKeyring(ctx context.Context, orgID *identity.ID,
+	item *apitypes.WorklogItem) (*apitypes.WorklogResult, error) {
+
+	graphs, err := orgCredentialGraphs(ctx, w.engine.client, orgID,
+		w.engine.session.AuthID())
+	if err != nil {
+		return nil, err
+	}
+
+	cgs := newCredentialGraphSet()
+	err = cgs.Add(graphs...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, head := range cgs.RevokedHeads() {
+		keyring, err := baseKeyring(head)
+		if err != nil {
+			return nil, err
+		}
+		if keyring.PathExp.String() != item.Subject {
+			continue
+		}
+
+		sigID, encID, kp, err := fetchKeyPairs(ctx, w.engine.client, orgID)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = w.engine.rotateKeyring(ctx, cgs, head, orgID, sigID, encID, kp)
+		if err != nil {
+			return &apitypes.WorklogResult{
+				ID:      item.ID,
+				State:   apitypes.FailureWorklogResult,
+				Message: "Could not rotate the keyring for " + item.Subject + ": " + err.Error(),
+			}, nil
+		}
+
+		return &apitypes.WorklogResult{
+			ID:      item.ID,
+			State:   apitypes.SuccessWorklogResult,
+			Message: "Rotated the keyring for " + item.Subject,
+		}, nil
 	}
 
 	return nil, nil
 }
+
+// isUnauthorized returns whether err is an error from the registry denying
+// access to a resource.
+func isUnauthorized(err error) bool {
+	apiErr, ok := err.(*apitypes.Error)
+	return ok && (apiErr.Type == apitypes.UnauthorizedError ||
+		apiErr.StatusCode == http.StatusForbidden)
+}
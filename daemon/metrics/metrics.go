@@ -0,0 +1,149 @@
+// Package metrics counts the daemon's registry requests and decryptions, and
+// exposes them in the Prometheus text format.
+//
+// A nil *Metrics is valid and records nothing, so callers don't need to
+// check whether metrics are enabled.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the registry request
+// latency histogram.
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// Metrics holds the daemon's counters. Only counts and timings are kept,
+// never paths, names or values.
+type Metrics struct {
+	mu sync.Mutex
+
+	requests     map[string]uint64 // by status code, or "error"
+	latency      []uint64          // count per bucket, not cumulative
+	latencySum   float64
+	latencyCount uint64
+
+	decrypts map[string]uint64 // by result, "ok" or "error"
+}
+
+// New returns an empty Metrics.
+func New() *Metrics {
+	return &Metrics{
+		requests: make(map[string]uint64),
+		latency:  make([]uint64, len(latencyBuckets)),
+		decrypts: make(map[string]uint64),
+	}
+}
+
+// Request records a registry request that completed with the given status
+// code after elapsed. A status of 0 means no response was received.
+func (m *Metrics) Request(status int, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+
+	label := "error"
+	if status != 0 {
+		label = strconv.Itoa(status)
+	}
+
+	secs := elapsed.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[label]++
+	for i, le := range latencyBuckets {
+		if secs <= le {
+			m.latency[i]++
+			break
+		}
+	}
+	m.latencySum += secs
+	m.latencyCount++
+}
+
+// Decrypt records an attempt to decrypt a credential.
+func (m *Metrics) Decrypt(err error) {
+	if m == nil {
+		return
+	}
+
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+
+	m.mu.Lock()
+	m.decrypts[result]++
+	m.mu.Unlock()
+}
+
+// Expose writes the metrics to w in the Prometheus text format, along with
+// the number of active sessions, which is read when the metrics are scraped
+// rather than counted.
+func (m *Metrics) Expose(w io.Writer, sessions int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ew := &errWriter{w: w}
+
+	ew.printf("# HELP torus_registry_requests_total Registry requests, by response status.\n")
+	ew.printf("# TYPE torus_registry_requests_total counter\n")
+	for _, status := range sortedKeys(m.requests) {
+		ew.printf("torus_registry_requests_total{status=%q} %d\n", status, m.requests[status])
+	}
+
+	ew.printf("# HELP torus_registry_request_duration_seconds Registry request latency.\n")
+	ew.printf("# TYPE torus_registry_request_duration_seconds histogram\n")
+	var cumulative uint64
+	for i, le := range latencyBuckets {
+		cumulative += m.latency[i]
+		ew.printf("torus_registry_request_duration_seconds_bucket{le=\"%s\"} %d\n",
+			strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+	}
+	ew.printf("torus_registry_request_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.latencyCount)
+	ew.printf("torus_registry_request_duration_seconds_sum %s\n",
+		strconv.FormatFloat(m.latencySum, 'g', -1, 64))
+	ew.printf("torus_registry_request_duration_seconds_count %d\n", m.latencyCount)
+
+	ew.printf("# HELP torus_active_sessions Sessions currently logged in to the daemon.\n")
+	ew.printf("# TYPE torus_active_sessions gauge\n")
+	ew.printf("torus_active_sessions %d\n", sessions)
+
+	ew.printf("# HELP torus_credential_decrypts_total Credential decryptions, by result.\n")
+	ew.printf("# TYPE torus_credential_decrypts_total counter\n")
+	for _, result := range sortedKeys(m.decrypts) {
+		ew.printf("torus_credential_decrypts_total{result=%q} %d\n", result, m.decrypts[result])
+	}
+
+	return ew.err
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// errWriter holds on to the first error writing to w, skipping any writes
+// after it.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) printf(format string, a ...interface{}) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = fmt.Fprintf(e.w, format, a...)
+}
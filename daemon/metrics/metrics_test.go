@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNilMetrics(t *testing.T) {
+	var m *Metrics
+	m.Request(200, time.Second)
+	m.Decrypt(nil)
+}
+
+func TestExpose(t *testing.T) {
+	m := New()
+	m.Request(200, 30*time.Millisecond)
+	m.Request(200, 300*time.Millisecond)
+	m.Request(404, 2*time.Second)
+	m.Request(0, 10*time.Second)
+	m.Decrypt(nil)
+	m.Decrypt(nil)
+	m.Decrypt(errors.New("bad"))
+
+	buf := &bytes.Buffer{}
+	if err := m.Expose(buf, 1); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	expected := []string{
+		`torus_registry_requests_total{status="200"} 2`,
+		`torus_registry_requests_total{status="404"} 1`,
+		`torus_registry_requests_total{status="error"} 1`,
+		`torus_registry_request_duration_seconds_bucket{le="0.05"} 1`,
+		`torus_registry_request_duration_seconds_bucket{le="0.5"} 2`,
+		`torus_registry_request_duration_seconds_bucket{le="2.5"} 3`,
+		`torus_registry_request_duration_seconds_bucket{le="5"} 3`,
+		`torus_registry_request_duration_seconds_bucket{le="+Inf"} 4`,
+		`torus_registry_request_duration_seconds_sum 12.33`,
+		`torus_registry_request_duration_seconds_count 4`,
+		`torus_active_sessions 1`,
+		`torus_credential_decrypts_total{result="error"} 1`,
+		`torus_credential_decrypts_total{result="ok"} 2`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("Expected output to contain %q, got:\n%s", line, out)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package registry
+
+import (
+	"context"
+
+	"github.com/manifoldco/torus-cli/daemon/logging"
+	"github.com/manifoldco/torus-cli/envelope"
+)
+
+// ClaimsClient represents the `/claims` registry endpoint, used for making
+// signature or revocation claims against public keys.
+type ClaimsClient struct {
+	client *Client
+}
+
+// Post creates a new claim on the registry.
+func (c *ClaimsClient) Post(ctx context.Context, claim *envelope.Signed) (*envelope.Signed, error) {
+	req, err := c.client.NewRequest("POST", "/claims", nil, claim)
+	if err != nil {
+		logging.Errorf("Error building http request: %s", err)
+		return nil, err
+	}
+
+	resp := envelope.Signed{}
+	_, err = c.client.Do(ctx, req, &resp)
+	if err != nil {
+		logging.Errorf("Failed to create claim: %s", err)
+		return nil, err
+	}
+
+	return &resp, nil
+}
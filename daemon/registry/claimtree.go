@@ -2,10 +2,10 @@ package registry
 
 import (
 	"context"
-	"log"
 	"net/url"
 
 	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/daemon/logging"
 	"github.com/manifoldco/torus-cli/envelope"
 	"github.com/manifoldco/torus-cli/identity"
 )
@@ -43,7 +43,7 @@ func (c *ClaimTreeClient) List(ctx context.Context, orgID *identity.ID,
 
 	req, err := c.client.NewRequest("GET", "/claimtree", query, nil)
 	if err != nil {
-		log.Printf("Error building http request: %s", err)
+		logging.Errorf("Error building http request: %s", err)
 		return nil, err
 	}
 
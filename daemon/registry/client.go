@@ -6,15 +6,27 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
+	"github.com/satori/go.uuid"
+
 	"github.com/manifoldco/torus-cli/apitypes"
 
+	"github.com/manifoldco/torus-cli/daemon/metrics"
 	"github.com/manifoldco/torus-cli/daemon/session"
 )
 
+// DefaultTimeout bounds a request to the registry when the caller has not
+// set a deadline of its own.
+const DefaultTimeout = 6 * time.Second
+
 // Client exposes the registry REST API.
 type Client struct {
 	client     *http.Client
@@ -22,6 +34,12 @@ type Client struct {
 	apiVersion string
 	version    string
 	sess       session.Session
+	limit      rateLimit
+	maxBody    int64
+	metrics    *metrics.Metrics
+
+	retryMu sync.RWMutex
+	retry   RetryPolicy
 
 	KeyPairs        *KeyPairs
 	Tokens          *Tokens
@@ -35,19 +53,27 @@ type Client struct {
 	Keyring         *KeyringClient
 	KeyringMember   *KeyringMemberClientV1
 	ClaimTree       *ClaimTreeClient
+	Claims          *ClaimsClient
 	CredentialGraph *CredentialGraphClient
 	Machines        *MachinesClient
 	Self            *SelfClient
 }
 
-// NewClient returns a new Client.
-func NewClient(prefix string, apiVersion string, version string, sess session.Session, t *http.Transport) *Client {
+// NewClient returns a new Client. Response bodies larger than maxBody bytes
+// are rejected, unless maxBody is 0. Requests are counted in m, if it isn't
+// nil.
+func NewClient(prefix string, apiVersion string, version string, sess session.Session,
+	t http.RoundTripper, retry RetryPolicy, maxBody int64, m *metrics.Metrics) *Client {
+
 	c := &Client{
 		client:     &http.Client{Transport: t},
 		prefix:     prefix,
 		apiVersion: apiVersion,
 		version:    version,
 		sess:       sess,
+		retry:      retry,
+		maxBody:    maxBody,
+		metrics:    m,
 	}
 
 	c.KeyPairs = &KeyPairs{client: c}
@@ -60,16 +86,33 @@ func NewClient(prefix string, apiVersion string, version string, sess session.Se
 	c.OrgInvite = &OrgInviteClient{client: c}
 	c.Projects = &ProjectsClient{client: c}
 	c.ClaimTree = &ClaimTreeClient{client: c}
+	c.Claims = &ClaimsClient{client: c}
 	c.Keyring = &KeyringClient{client: c}
 	c.Keyring.Members = &KeyringMembersClient{client: c}
 	c.KeyringMember = &KeyringMemberClientV1{client: c}
-	c.CredentialGraph = &CredentialGraphClient{client: c}
+	c.CredentialGraph = &CredentialGraphClient{client: c, cache: newGraphCache(graphCacheSize)}
 	c.Machines = &MachinesClient{client: c}
 	c.Self = &SelfClient{client: c}
 
 	return c
 }
 
+// SetRetryPolicy replaces the client's RetryPolicy, for requests made from
+// now on.
+func (c *Client) SetRetryPolicy(retry RetryPolicy) {
+	c.retryMu.Lock()
+	defer c.retryMu.Unlock()
+
+	c.retry = retry
+}
+
+func (c *Client) retryPolicy() RetryPolicy {
+	c.retryMu.RLock()
+	defer c.retryMu.RUnlock()
+
+	return c.retry
+}
+
 // NewRequest constructs a new http.Request, with a body containing the json
 // representation of body, if provided.
 func (c *Client) NewRequest(method, path string, query *url.Values,
@@ -77,6 +120,24 @@ func (c *Client) NewRequest(method, path string, query *url.Values,
 	return c.NewTokenRequest(c.sess.Token(), method, path, query, body)
 }
 
+// NewIdempotentRequest constructs a new http.Request like NewRequest, tagged
+// with a newly generated Idempotency-Key.
+//
+// Each call represents a new logical operation. The key is carried by the
+// request, so it is reused when Do retries the request, allowing the
+// registry to recognize and deduplicate the retry.
+func (c *Client) NewIdempotentRequest(method, path string, query *url.Values,
+	body interface{}) (*http.Request, error) {
+
+	req, err := c.NewRequest(method, path, query, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Idempotency-Key", uuid.NewV4().String())
+	return req, nil
+}
+
 // NewTokenRequest constructs a new http.Request, with a body containing the
 // json representation of body, if provided.
 //
@@ -124,12 +185,93 @@ func (c *Client) NewTokenRequest(token, method, path string, query *url.Values,
 //
 // If the request errors with a JSON formatted response body, it will be
 // unmarshaled into the returned error.
+//
+// Idempotent requests that fail with a transient error are retried according
+// to the client's RetryPolicy. The error from the final attempt is returned.
 func (c *Client) Do(ctx context.Context, r *http.Request, v interface{}) (*http.Response, error) {
-	ctx, cancelFunc := context.WithTimeout(ctx, 6*time.Second)
+	if !isIdempotent(r) {
+		return c.do(ctx, r, v)
+	}
+
+	// The body has to be replayed for each attempt.
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	retry := c.retryPolicy()
+	for attempt := 1; ; attempt++ {
+		if body != nil {
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
+		resp, err := c.do(ctx, r, v)
+		if attempt >= retry.MaxAttempts || !shouldRetry(ctx, resp, err) {
+			return resp, err
+		}
+
+		delay := retry.backoff(attempt, resp)
+		log.Printf("Retrying %s %s in %s after error: %s", r.Method, r.URL.Path,
+			delay, err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return resp, err
+		}
+	}
+}
+
+// observe records the outcome and timing of a request in the trace carried
+// by ctx, if any, and in the client's metrics.
+func (c *Client) observe(ctx context.Context, r *http.Request, resp *http.Response,
+	err error, elapsed time.Duration) {
+
+	if t := traceFrom(ctx); t != nil {
+		t.record(r, resp, err, elapsed)
+	}
+
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	c.metrics.Request(status, elapsed)
+}
+
+func (c *Client) do(ctx context.Context, r *http.Request, v interface{}) (*http.Response, error) {
+	// Rather than send a request the registry will reject, wait for the
+	// rate limit to reset once it has been used up.
+	if d := c.limit.wait(time.Now()); d > 0 {
+		log.Printf("Registry rate limit reached; waiting %s for it to reset", d)
+		if t := traceFrom(ctx); t != nil {
+			t.note(fmt.Sprintf("waited %s for the rate limit to reset", d))
+		}
+
+		select {
+		case <-time.After(d):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// A request made on behalf of a client with a timeout is already bounded
+	// by it; anything else gets the default.
+	if _, ok := ctx.Deadline(); !ok {
+		var cancelFunc context.CancelFunc
+		ctx, cancelFunc = context.WithTimeout(ctx, DefaultTimeout)
+		defer cancelFunc()
+	}
 	r = r.WithContext(ctx)
-	defer cancelFunc()
 
+	start := time.Now()
 	resp, err := c.client.Do(r)
+	c.limit.update(resp, time.Now())
+	c.observe(ctx, r, resp, err, time.Since(start))
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
 			err = &apitypes.Error{
@@ -142,7 +284,15 @@ func (c *Client) Do(ctx context.Context, r *http.Request, v interface{}) (*http.
 		return nil, err
 	}
 
-	defer resp.Body.Close()
+	if c.maxBody > 0 {
+		resp.Body = newLimitedBody(resp.Body, c.maxBody)
+	}
+
+	// Drain anything left unread so the connection can be reused.
+	defer func() {
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}()
 
 	err = checkResponseCode(resp)
 	if err != nil {
@@ -160,6 +310,44 @@ func (c *Client) Do(ctx context.Context, r *http.Request, v interface{}) (*http.
 	return resp, nil
 }
 
+// limitedBody fails reads once more than max bytes of a response body have
+// been read, rather than letting a decoder buffer an unbounded response.
+type limitedBody struct {
+	io.Reader
+	io.Closer
+	max  int64
+	read int64
+}
+
+func newLimitedBody(body io.ReadCloser, max int64) *limitedBody {
+	return &limitedBody{
+		Reader: io.LimitReader(body, max+1),
+		Closer: body,
+		max:    max,
+	}
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	b.read += int64(n)
+	if b.read > b.max {
+		return n - int(b.read-b.max), &apitypes.Error{
+			StatusCode: http.StatusBadGateway,
+			Type:       apitypes.InternalServerError,
+			Err: []string{fmt.Sprintf("Response from the registry is larger than %d bytes; "+
+				"raise max_response_size in your preferences to allow it", b.max)},
+		}
+	}
+	return n, err
+}
+
+// RateLimit returns the number of requests the registry last reported as
+// remaining before it starts rejecting them, and when that limit resets. ok
+// is false if the registry has not reported a limit.
+func (c *Client) RateLimit() (remaining int, reset time.Time, ok bool) {
+	return c.limit.state()
+}
+
 func checkResponseCode(r *http.Response) error {
 	if r.StatusCode >= 200 && r.StatusCode < 300 {
 		return nil
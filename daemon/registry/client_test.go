@@ -0,0 +1,133 @@
+package registry
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/envelope"
+
+	"github.com/manifoldco/torus-cli/daemon/session"
+)
+
+// benchmarkCreateCredentials posts 100 credentials per iteration, reporting
+// how many TLS connections the registry had to accept to serve them.
+func benchmarkCreateCredentials(b *testing.B, t *http.Transport) {
+	var conns int64
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("{}\n"))
+		}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&conns, 1)
+		}
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	t.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	defer t.CloseIdleConnections()
+
+	c := NewClient(srv.URL, "0.1.0", "test", session.NewSession(), t, RetryPolicy{}, 0, nil)
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 100; j++ {
+			_, err := c.Credentials.Create(ctx, &envelope.Signed{})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.StopTimer()
+
+	b.Logf("%d connections for %d credentials", atomic.LoadInt64(&conns), 100*b.N)
+}
+
+func BenchmarkCreateCredentialsKeepAlive(b *testing.B) {
+	benchmarkCreateCredentials(b, &http.Transport{MaxIdleConnsPerHost: 16})
+}
+
+func BenchmarkCreateCredentialsNoKeepAlive(b *testing.B) {
+	benchmarkCreateCredentials(b, &http.Transport{DisableKeepAlives: true})
+}
+
+func TestIdempotentRequestRetry(t *testing.T) {
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			keys = append(keys, r.Header.Get("Idempotency-Key"))
+
+			// Fail the first attempt of every operation
+			if len(keys)%2 == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("{}\n"))
+		}))
+	defer srv.Close()
+
+	retry := RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}
+	c := NewClient(srv.URL, "0.1.0", "test", session.NewSession(), &http.Transport{}, retry, 0, nil)
+
+	for i := 0; i < 2; i++ {
+		req, err := c.NewIdempotentRequest("POST", "/credentialgraph", nil, struct{}{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = c.Do(context.Background(), req, nil)
+		if err != nil {
+			t.Fatalf("Expected request to succeed on retry, got: %s", err)
+		}
+	}
+
+	if len(keys) != 4 {
+		t.Fatalf("Expected 4 attempts, got %d", len(keys))
+	}
+
+	if keys[0] == "" || keys[0] != keys[1] || keys[2] != keys[3] {
+		t.Errorf("Expected retries to reuse the idempotency key: %v", keys)
+	}
+
+	if keys[0] == keys[2] {
+		t.Errorf("Expected a new idempotency key for each operation: %v", keys)
+	}
+}
+
+func TestLimitedBody(t *testing.T) {
+	t.Run("at the limit", func(t *testing.T) {
+		body := newLimitedBody(ioutil.NopCloser(strings.NewReader("0123456789")), 10)
+		b, err := ioutil.ReadAll(body)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if string(b) != "0123456789" {
+			t.Errorf("Expected the whole body, got %q", b)
+		}
+	})
+
+	t.Run("over the limit", func(t *testing.T) {
+		body := newLimitedBody(ioutil.NopCloser(strings.NewReader("0123456789a")), 10)
+		b, err := ioutil.ReadAll(body)
+		if _, ok := err.(*apitypes.Error); !ok {
+			t.Fatalf("Expected an apitypes.Error, got %#v", err)
+		}
+		if len(b) != 10 {
+			t.Errorf("Expected no more than 10 bytes, got %d", len(b))
+		}
+	})
+}
@@ -4,11 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"log"
+	"net/http"
 	"net/url"
 
+	"github.com/manifoldco/torus-cli/daemon/ctxutil"
+	"github.com/manifoldco/torus-cli/daemon/logging"
 	"github.com/manifoldco/torus-cli/envelope"
 	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/pagination"
 	"github.com/manifoldco/torus-cli/pathexp"
 	"github.com/manifoldco/torus-cli/primitive"
 )
@@ -16,8 +19,13 @@ import (
 // CredentialGraphClient represents the `/credentialgraph` registry endpoint,
 // user for retrieving keyrings, keyring members, and credentials associated
 // with claims.
+//
+// Responses are cached along with their ETag. Repeated queries are sent with
+// If-None-Match, and answered from the cache when the registry reports the
+// graphs haven't changed.
 type CredentialGraphClient struct {
 	client *Client
+	cache  *graphCache
 }
 
 // CredentialGraph is the shared interface between different credential graph
@@ -65,33 +73,70 @@ func (c *CredentialGraphV2) KeyringVersion() int {
 // Post creates a new CredentialGraph on the registry.
 //
 // The CredentialGraph includes the keyring, it's members, and credentials.
+// The request carries an idempotency key, so it is safely retried if the
+// registry fails to respond.
 func (c *CredentialGraphClient) Post(ctx context.Context, t *CredentialGraph) (*CredentialGraphV2, error) {
-	req, err := c.client.NewRequest("POST", "/credentialgraph", nil, t)
+	req, err := c.client.NewIdempotentRequest("POST", "/credentialgraph", nil, t)
 	if err != nil {
-		log.Printf("Error building http request: %s", err)
+		logging.Errorf("Error building http request: %s", err)
 		return nil, err
 	}
 
 	resp := CredentialGraphV2{}
 	_, err = c.client.Do(ctx, req, &resp)
 	if err != nil {
-		log.Printf("Failed to create credential graph: %s", err)
+		logging.Errorf("Failed to create credential graph: %s", err)
 		return nil, err
 	}
 
+	if pe := keyringPathExp((*t).GetKeyring()); pe != nil {
+		c.cache.invalidate(pe)
+	} else {
+		c.cache.flush()
+	}
+
 	return &resp, nil
 }
 
-// List returns back all segments of the CredentialGraph (Keyring, Keyring
-// Members, and Credentials) that match the given name, path, or path
+// Flush drops every cached credential graph response.
+func (c *CredentialGraphClient) Flush() {
+	c.cache.flush()
+}
+
+// keyringPathExp returns the pathexp of a keyring, or nil if it isn't a
+// known keyring type.
+func keyringPathExp(keyring *envelope.Signed) *pathexp.PathExp {
+	if keyring == nil {
+		return nil
+	}
+
+	switch b := keyring.Body.(type) {
+	case *primitive.Keyring:
+		return b.PathExp
+	case *primitive.KeyringV1:
+		return b.PathExp
+	default:
+		return nil
+	}
+}
+
+// defaultPageSize is the number of CredentialGraphs requested per page by
+// ListAll.
+const defaultPageSize = 100
+
+// List returns back a page of segments of the CredentialGraph (Keyring,
+// Keyring Members, and Credentials) that match the given name, path, or path
 // expression.
+//
+// A limit of 0 lets the registry choose the page size. The returned Cursor
+// can be used to request the next page.
 func (c *CredentialGraphClient) List(ctx context.Context, path string,
-	pathExp *pathexp.PathExp, ownerID *identity.ID) ([]CredentialGraph, error) {
+	pathExp *pathexp.PathExp, ownerID *identity.ID, limit, offset int) ([]CredentialGraph, *pagination.Cursor, error) {
 
 	query := url.Values{}
 
 	if path != "" && pathExp != nil {
-		return nil, errors.New("cannot provide path and pathexp at the same time")
+		return nil, nil, errors.New("cannot provide path and pathexp at the same time")
 	}
 	if path != "" {
 		query.Set("path", path)
@@ -102,8 +147,43 @@ func (c *CredentialGraphClient) List(ctx context.Context, path string,
 	if ownerID != nil {
 		query.Set("owner_id", ownerID.String())
 	}
+	pagination.SetPage(&query, limit, offset)
+
+	graphs, resp, err := c.getGraph(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return graphs, pagination.NewCursor(resp, limit, offset, len(graphs)), nil
+}
+
+// ListAll returns back all segments of the CredentialGraph that match the
+// given name, path, or path expression, paging through the results until
+// they are exhausted.
+func (c *CredentialGraphClient) ListAll(ctx context.Context, path string,
+	pathExp *pathexp.PathExp, ownerID *identity.ID) ([]CredentialGraph, error) {
+
+	var graphs []CredentialGraph
+	offset := 0
+	for {
+		err := ctxutil.ErrIfDone(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		page, cursor, err := c.List(ctx, path, pathExp, ownerID, defaultPageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		graphs = append(graphs, page...)
+		if cursor.Next <= offset || len(page) == 0 {
+			break
+		}
+		offset = cursor.Next
+	}
 
-	return c.getGraph(ctx, query)
+	return graphs, nil
 }
 
 // Search returns back all segments of the CredentialGraph (Keyring, Keyring
@@ -118,14 +198,21 @@ func (c *CredentialGraphClient) Search(ctx context.Context, pathExp string,
 	query.Set("owner_id", ownerID.String())
 	query.Set("mode", "contains")
 
-	return c.getGraph(ctx, query)
+	graphs, _, err := c.getGraph(ctx, query)
+	return graphs, err
 }
 
-func (c *CredentialGraphClient) getGraph(ctx context.Context, query url.Values) ([]CredentialGraph, error) {
+func (c *CredentialGraphClient) getGraph(ctx context.Context, query url.Values) ([]CredentialGraph, *http.Response, error) {
 	req, err := c.client.NewRequest("GET", "/credentialgraph", &query, nil)
 	if err != nil {
-		log.Printf("Error building http request: %s", err)
-		return nil, err
+		logging.Errorf("Error building http request: %s", err)
+		return nil, nil, err
+	}
+
+	key := query.Encode()
+	cached := c.cache.get(key)
+	if cached != nil {
+		req.Header.Set("If-None-Match", cached.etag)
 	}
 
 	resp := []struct {
@@ -135,9 +222,12 @@ func (c *CredentialGraphClient) getGraph(ctx context.Context, query url.Values)
 		Claims      []envelope.Signed `json:"claims"`
 	}{}
 
-	_, err = c.client.Do(ctx, req, &resp)
+	httpResp, err := c.client.Do(ctx, req, &resp)
+	if cached != nil && httpResp != nil && httpResp.StatusCode == http.StatusNotModified {
+		return append([]CredentialGraph(nil), cached.graphs...), httpResp, nil
+	}
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	converted := make([]CredentialGraph, len(resp))
@@ -149,7 +239,7 @@ func (c *CredentialGraphClient) getGraph(ctx context.Context, query url.Values)
 			}
 			err := json.Unmarshal(g.Members, &c.Members)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			converted[i] = &c
 		} else {
@@ -162,11 +252,19 @@ func (c *CredentialGraphClient) getGraph(ctx context.Context, query url.Values)
 			}
 			err := json.Unmarshal(g.Members, &c.Members)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			converted[i] = &c
 		}
 	}
 
-	return converted, nil
+	if etag := httpResp.Header.Get("ETag"); etag != "" {
+		scope := query.Get("pathexp")
+		if scope == "" {
+			scope = query.Get("path")
+		}
+		c.cache.put(key, scope, etag, converted)
+	}
+
+	return append([]CredentialGraph(nil), converted...), httpResp, nil
 }
@@ -2,8 +2,8 @@ package registry
 
 import (
 	"context"
-	"log"
 
+	"github.com/manifoldco/torus-cli/daemon/logging"
 	"github.com/manifoldco/torus-cli/envelope"
 )
 
@@ -17,7 +17,7 @@ type Credentials struct {
 func (c *Credentials) Create(ctx context.Context, credential *envelope.Signed) (*envelope.Signed, error) {
 	req, err := c.client.NewRequest("POST", "/credentials", nil, credential)
 	if err != nil {
-		log.Printf("Error building http request: %s", err)
+		logging.Errorf("Error building http request: %s", err)
 		return nil, err
 	}
 
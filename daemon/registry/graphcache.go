@@ -0,0 +1,103 @@
+package registry
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/manifoldco/torus-cli/pathexp"
+)
+
+// graphCacheSize is the number of credential graph responses kept for
+// revalidation with If-None-Match.
+const graphCacheSize = 64
+
+// graphCache is a bounded, least recently used cache of credential graph
+// responses and the ETags the registry sent with them, keyed by query.
+//
+// Graphs hold only encrypted credentials, as sent by the registry.
+type graphCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // of *graphCacheEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+type graphCacheEntry struct {
+	key    string
+	scope  string // the pathexp or path the query was made for
+	etag   string
+	graphs []CredentialGraph
+}
+
+func newGraphCache(size int) *graphCache {
+	return &graphCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the entry cached for key, or nil if there isn't one.
+func (c *graphCache) get(key string) *graphCacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*graphCacheEntry)
+}
+
+// put caches graphs for key, evicting the least recently used entry if the
+// cache is full.
+func (c *graphCache) put(key, scope, etag string, graphs []CredentialGraph) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &graphCacheEntry{key: key, scope: scope, etag: etag, graphs: graphs}
+	if el, ok := c.entries[key]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+// invalidate drops every entry whose results could include a keyring at pe.
+// Entries whose scope isn't a plain pathexp, such as a path or a search
+// across projects, are always dropped.
+func (c *graphCache) invalidate(pe *pathexp.PathExp) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for el := c.order.Front(); el != nil; {
+		next := el.Next()
+		scope, err := pathexp.Parse(el.Value.(*graphCacheEntry).scope)
+		if err != nil {
+			c.remove(el)
+		} else if _, ok := scope.Intersect(pe); ok {
+			c.remove(el)
+		}
+		el = next
+	}
+}
+
+// flush drops every entry.
+func (c *graphCache) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+}
+
+func (c *graphCache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*graphCacheEntry).key)
+}
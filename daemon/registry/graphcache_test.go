@@ -0,0 +1,60 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/manifoldco/torus-cli/pathexp"
+)
+
+func TestGraphCacheEviction(t *testing.T) {
+	c := newGraphCache(2)
+	c.put("a", "/o/p/e/s/*/*", "1", nil)
+	c.put("b", "/o/p/e/s/*/*", "2", nil)
+
+	// Using a makes b the least recently used.
+	if c.get("a") == nil {
+		t.Fatal("Expected a to be cached")
+	}
+	c.put("c", "/o/p/e/s/*/*", "3", nil)
+
+	if c.get("b") != nil {
+		t.Error("Expected b to be evicted")
+	}
+	if e := c.get("a"); e == nil || e.etag != "1" {
+		t.Errorf("Expected a to be cached with etag 1, got %+v", e)
+	}
+	if e := c.get("c"); e == nil || e.etag != "3" {
+		t.Errorf("Expected c to be cached with etag 3, got %+v", e)
+	}
+
+	c.flush()
+	if c.get("a") != nil || c.get("c") != nil {
+		t.Error("Expected flush to empty the cache")
+	}
+}
+
+func TestGraphCacheInvalidate(t *testing.T) {
+	c := newGraphCache(10)
+	c.put("same", "/o/p/dev/api/*/*", "1", nil)
+	c.put("wider", "/o/p/*/*/*/*", "2", nil)
+	c.put("other-env", "/o/p/prod/api/*/*", "3", nil)
+	c.put("other-project", "/o/q/dev/api/*/*", "4", nil)
+	c.put("path", "/o/p/dev/api/u/1/SECRET", "5", nil)
+
+	pe, err := pathexp.Parse("/o/p/dev/api/*/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.invalidate(pe)
+
+	for _, key := range []string{"same", "wider", "path"} {
+		if c.get(key) != nil {
+			t.Errorf("Expected %s to be invalidated", key)
+		}
+	}
+	for _, key := range []string{"other-env", "other-project"} {
+		if c.get(key) == nil {
+			t.Errorf("Expected %s to still be cached", key)
+		}
+	}
+}
@@ -2,9 +2,9 @@ package registry
 
 import (
 	"context"
-	"log"
 	"net/url"
 
+	"github.com/manifoldco/torus-cli/daemon/logging"
 	"github.com/manifoldco/torus-cli/envelope"
 	"github.com/manifoldco/torus-cli/identity"
 )
@@ -39,14 +39,14 @@ func (k *KeyPairs) Post(ctx context.Context, pubKey, privKey,
 			Claims:     []envelope.Signed{*claim},
 		})
 	if err != nil {
-		log.Printf("Error building http request: %s", err)
+		logging.Errorf("Error building http request: %s", err)
 		return nil, nil, nil, err
 	}
 
 	resp := ClaimedKeyPair{}
 	_, err = k.client.Do(ctx, req, &resp)
 	if err != nil {
-		log.Printf("Failed to create signing keypair: %s", err)
+		logging.Errorf("Failed to create signing keypair: %s", err)
 		return nil, nil, nil, err
 	}
 
@@ -63,14 +63,14 @@ func (k *KeyPairs) List(ctx context.Context, orgID *identity.ID) ([]ClaimedKeyPa
 
 	req, err := k.client.NewRequest("GET", "/keypairs", query, nil)
 	if err != nil {
-		log.Printf("Error building http request: %s", err)
+		logging.Errorf("Error building http request: %s", err)
 		return nil, err
 	}
 
 	resp := []ClaimedKeyPair{}
 	_, err = k.client.Do(ctx, req, &resp)
 	if err != nil {
-		log.Printf("Failed to retrieve keypairs: %s", err)
+		logging.Errorf("Failed to retrieve keypairs: %s", err)
 		return nil, err
 	}
 
@@ -4,9 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"log"
 	"net/url"
+	"time"
 
+	"github.com/manifoldco/torus-cli/daemon/logging"
 	"github.com/manifoldco/torus-cli/envelope"
 	"github.com/manifoldco/torus-cli/identity"
 	"github.com/manifoldco/torus-cli/primitive"
@@ -27,6 +28,9 @@ type KeyringClient struct {
 type KeyringSection interface {
 	GetKeyring() *envelope.Signed
 	FindMember(*identity.ID) (*primitive.KeyringMember, *primitive.MEKShare, error)
+	FindSignedMember(*identity.ID) ([]*envelope.Signed, error)
+	MemberIDs() []*identity.ID
+	SignedMembers() map[identity.ID][]*envelope.Signed
 	HasRevocations() bool
 }
 
@@ -43,13 +47,16 @@ func (k *KeyringSectionV1) GetKeyring() *envelope.Signed {
 }
 
 // FindMember returns the membership and mekshare for the given user id.
-// The data is returned in V2 format.
+// If the user has more than one membership, such as after regenerating their
+// keypairs, the most recent is returned. The data is returned in V2 format.
 func (k *KeyringSectionV1) FindMember(id *identity.ID) (*primitive.KeyringMember, *primitive.MEKShare, error) {
 	var krm *primitive.KeyringMember
 	var mekshare *primitive.MEKShare
+	var created time.Time
 	for _, m := range k.Members {
 		mbody := m.Body.(*primitive.KeyringMemberV1)
-		if *mbody.OwnerID == *id {
+		if *mbody.OwnerID == *id && (krm == nil || !mbody.Created.Before(created)) {
+			created = mbody.Created
 			krm = &primitive.KeyringMember{
 				OrgID:           mbody.OrgID,
 				KeyringID:       mbody.KeyringID,
@@ -61,7 +68,6 @@ func (k *KeyringSectionV1) FindMember(id *identity.ID) (*primitive.KeyringMember
 			mekshare = &primitive.MEKShare{
 				Key: mbody.Key,
 			}
-			break
 		}
 	}
 
@@ -72,6 +78,54 @@ func (k *KeyringSectionV1) FindMember(id *identity.ID) (*primitive.KeyringMember
 	return krm, mekshare, nil
 }
 
+// FindSignedMember returns the signed envelope of the membership FindMember
+// returns for the given user id.
+func (k *KeyringSectionV1) FindSignedMember(id *identity.ID) ([]*envelope.Signed, error) {
+	var member *envelope.Signed
+	var created time.Time
+	for i, m := range k.Members {
+		mbody := m.Body.(*primitive.KeyringMemberV1)
+		if *mbody.OwnerID == *id && (member == nil || !mbody.Created.Before(created)) {
+			created = mbody.Created
+			member = &k.Members[i]
+		}
+	}
+
+	if member == nil {
+		return nil, ErrMemberNotFound
+	}
+
+	return []*envelope.Signed{member}, nil
+}
+
+// SignedMembers returns the signed envelopes of every membership of the
+// keyring, keyed by the ID of the member.
+func (k *KeyringSectionV1) SignedMembers() map[identity.ID][]*envelope.Signed {
+	signed := make(map[identity.ID][]*envelope.Signed)
+	for i, m := range k.Members {
+		ownerID := m.Body.(*primitive.KeyringMemberV1).OwnerID
+		signed[*ownerID] = append(signed[*ownerID], &k.Members[i])
+	}
+
+	return signed
+}
+
+// MemberIDs returns the IDs of the users and machine tokens that can decrypt
+// the keyring's credentials.
+func (k *KeyringSectionV1) MemberIDs() []*identity.ID {
+	var ids []*identity.ID
+	seen := make(map[identity.ID]bool)
+	for _, m := range k.Members {
+		ownerID := m.Body.(*primitive.KeyringMemberV1).OwnerID
+		if !seen[*ownerID] {
+			seen[*ownerID] = true
+			ids = append(ids, ownerID)
+		}
+	}
+
+	return ids
+}
+
 // HasRevocations indicates that a Keyring holds revoked user keys. We don't
 // track in V1 so it is always false.
 func (KeyringSectionV1) HasRevocations() bool {
@@ -91,15 +145,16 @@ func (k *KeyringSectionV2) GetKeyring() *envelope.Signed {
 }
 
 // FindMember returns the membership and mekshare for the given user id.
+// If the user has more than one membership, such as after regenerating their
+// keypairs, the most recent is returned.
 func (k *KeyringSectionV2) FindMember(id *identity.ID) (*primitive.KeyringMember, *primitive.MEKShare, error) {
 	var krm *primitive.KeyringMember
 	var mekshare *primitive.MEKShare
 	for _, m := range k.Members {
 		mbody := m.Member.Body.(*primitive.KeyringMember)
-		if *mbody.OwnerID == *id {
+		if *mbody.OwnerID == *id && (krm == nil || !mbody.Created.Before(krm.Created)) {
 			krm = mbody
 			mekshare = m.MEKShare.Body.(*primitive.MEKShare)
-			break
 		}
 	}
 
@@ -110,6 +165,76 @@ func (k *KeyringSectionV2) FindMember(id *identity.ID) (*primitive.KeyringMember
 	return krm, mekshare, nil
 }
 
+// FindSignedMember returns the signed membership and mekshare envelopes of the
+// membership FindMember returns for the given user id.
+func (k *KeyringSectionV2) FindSignedMember(id *identity.ID) ([]*envelope.Signed, error) {
+	var member *KeyringMember
+	var created time.Time
+	for i, m := range k.Members {
+		mbody := m.Member.Body.(*primitive.KeyringMember)
+		if *mbody.OwnerID == *id && (member == nil || !mbody.Created.Before(created)) {
+			created = mbody.Created
+			member = &k.Members[i]
+		}
+	}
+
+	if member == nil {
+		return nil, ErrMemberNotFound
+	}
+
+	signed := []*envelope.Signed{member.Member}
+	if member.MEKShare != nil {
+		signed = append(signed, member.MEKShare)
+	}
+
+	return signed, nil
+}
+
+// SignedMembers returns the signed membership and mekshare envelopes of every
+// membership of the keyring, keyed by the ID of the member. Unlike MemberIDs,
+// revoked memberships are included.
+func (k *KeyringSectionV2) SignedMembers() map[identity.ID][]*envelope.Signed {
+	signed := make(map[identity.ID][]*envelope.Signed)
+	for _, m := range k.Members {
+		ownerID := m.Member.Body.(*primitive.KeyringMember).OwnerID
+		signed[*ownerID] = append(signed[*ownerID], m.Member)
+		if m.MEKShare != nil {
+			signed[*ownerID] = append(signed[*ownerID], m.MEKShare)
+		}
+	}
+
+	return signed
+}
+
+// MemberIDs returns the IDs of the users and machine tokens that can decrypt
+// the keyring's credentials. Members whose mekshare has been removed, or whose
+// membership has been revoked, are not included.
+func (k *KeyringSectionV2) MemberIDs() []*identity.ID {
+	revoked := make(map[identity.ID]bool)
+	for _, claim := range k.Claims {
+		body := claim.Body.(*primitive.KeyringMemberClaim)
+		if body.ClaimType == primitive.RevocationClaimType {
+			revoked[*body.KeyringMemberID] = true
+		}
+	}
+
+	var ids []*identity.ID
+	seen := make(map[identity.ID]bool)
+	for _, m := range k.Members {
+		if m.MEKShare == nil || revoked[*m.Member.ID] {
+			continue
+		}
+
+		ownerID := m.Member.Body.(*primitive.KeyringMember).OwnerID
+		if !seen[*ownerID] {
+			seen[*ownerID] = true
+			ids = append(ids, ownerID)
+		}
+	}
+
+	return ids
+}
+
 // HasRevocations indicates that a Keyring holds revoked user keys.
 func (k *KeyringSectionV2) HasRevocations() bool {
 	for _, claim := range k.Claims {
@@ -142,7 +267,7 @@ func (k *KeyringClient) List(ctx context.Context, orgID *identity.ID,
 
 	req, err := k.client.NewRequest("GET", "/keyrings", query, nil)
 	if err != nil {
-		log.Printf("Error building http request for GET /keyrings: %s", err)
+		logging.Errorf("Error building http request for GET /keyrings: %s", err)
 		return nil, err
 	}
 
@@ -2,8 +2,8 @@ package registry
 
 import (
 	"context"
-	"log"
 
+	"github.com/manifoldco/torus-cli/daemon/logging"
 	"github.com/manifoldco/torus-cli/envelope"
 	"github.com/manifoldco/torus-cli/primitive"
 )
@@ -20,14 +20,14 @@ func (k *KeyringMemberClientV1) Post(ctx context.Context, members []envelope.Sig
 
 	req, err := k.client.NewRequest("POST", "/keyring-members", nil, members)
 	if err != nil {
-		log.Printf("Error creating POST /keyring-members request: %s", err)
+		logging.Errorf("Error creating POST /keyring-members request: %s", err)
 		return nil, err
 	}
 
 	resp := []envelope.Signed{}
 	_, err = k.client.Do(ctx, req, &resp)
 	if err != nil {
-		log.Printf("Error performing POST /keyring-members request: %s", err)
+		logging.Errorf("Error performing POST /keyring-members request: %s", err)
 		return nil, err
 	}
 
@@ -47,13 +47,13 @@ func (k *KeyringMembersClient) Post(ctx context.Context, member KeyringMember) e
 	members := []KeyringMember{member}
 	req, err := k.client.NewRequest("POST", "/keyrings/"+keyringID.String()+"/members", nil, members)
 	if err != nil {
-		log.Printf("Error creating POST /keyring/:id/members request: %s", err)
+		logging.Errorf("Error creating POST /keyring/:id/members request: %s", err)
 		return err
 	}
 
 	_, err = k.client.Do(ctx, req, nil)
 	if err != nil {
-		log.Printf("Error performing POST /keyring/:id/members request: %s", err)
+		logging.Errorf("Error performing POST /keyring/:id/members request: %s", err)
 		return err
 	}
 
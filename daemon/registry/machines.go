@@ -2,11 +2,14 @@ package registry
 
 import (
 	"context"
-	"log"
+	"errors"
+	"net/url"
 
 	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/daemon/logging"
 	"github.com/manifoldco/torus-cli/envelope"
 	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
 )
 
 // MachinesClient represents the `/machines` registry endpoint, used for
@@ -15,6 +18,13 @@ type MachinesClient struct {
 	client *Client
 }
 
+// MachineListOptions holds the optional filters for listing machines
+type MachineListOptions struct {
+	TeamID *identity.ID
+	State  string
+	Name   string
+}
+
 // MachineCreationSegment represents the request sent to create the registry to
 // create a machine and it's first token
 type MachineCreationSegment struct {
@@ -33,7 +43,7 @@ type MachineTokenCreationSegment struct {
 // Create requests the registry to create a MachineSegment.
 //
 // The MachineSegment includes the Machine, it's Memberships, and authorization
-// tokens.
+// tokens. Like CredentialGraphClient.Post, it is retried on transient errors.
 func (m *MachinesClient) Create(ctx context.Context, machine *envelope.Unsigned,
 	memberships []envelope.Unsigned, token *MachineTokenCreationSegment) (*apitypes.MachineSegment, error) {
 
@@ -43,16 +53,57 @@ func (m *MachinesClient) Create(ctx context.Context, machine *envelope.Unsigned,
 		Tokens:      []MachineTokenCreationSegment{*token},
 	}
 
-	req, err := m.client.NewRequest("POST", "/machines", nil, &segment)
+	req, err := m.client.NewIdempotentRequest("POST", "/machines", nil, &segment)
 	if err != nil {
-		log.Printf("Error building POST Machines Request: %s", err)
+		logging.Errorf("Error building POST Machines Request: %s", err)
 		return nil, err
 	}
 
 	resp := &apitypes.MachineSegment{}
 	_, err = m.client.Do(ctx, req, resp)
 	if err != nil {
-		log.Printf("Failed to create machine: %s", err)
+		logging.Errorf("Failed to create machine: %s", err)
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// List requests all machines in the given org that match the provided
+// filters from the registry.
+func (m *MachinesClient) List(ctx context.Context, orgID *identity.ID,
+	opts *MachineListOptions) ([]apitypes.MachineSegment, error) {
+
+	query := url.Values{}
+	query.Set("org_id", orgID.String())
+
+	if opts != nil {
+		switch opts.State {
+		case "":
+		case primitive.MachineActiveState, primitive.MachineDestroyedState:
+			query.Set("state", opts.State)
+		default:
+			return nil, errors.New("unknown machine state: " + opts.State)
+		}
+
+		if opts.TeamID != nil {
+			query.Set("team_id", opts.TeamID.String())
+		}
+		if opts.Name != "" {
+			query.Set("name", opts.Name)
+		}
+	}
+
+	req, err := m.client.NewRequest("GET", "/machines", &query, nil)
+	if err != nil {
+		logging.Errorf("Error building GET Machines Request: %s", err)
+		return nil, err
+	}
+
+	resp := []apitypes.MachineSegment{}
+	_, err = m.client.Do(ctx, req, &resp)
+	if err != nil {
+		logging.Errorf("Failed to list machines: %s", err)
 		return nil, err
 	}
 
@@ -63,16 +114,57 @@ func (m *MachinesClient) Create(ctx context.Context, machine *envelope.Unsigned,
 func (m *MachinesClient) Get(ctx context.Context, machineID *identity.ID) (*apitypes.MachineSegment, error) {
 	req, err := m.client.NewRequest("GET", "/machines/"+(*machineID).String(), nil, nil)
 	if err != nil {
-		log.Printf("Error building GET Machines Request: %s", err)
+		logging.Errorf("Error building GET Machines Request: %s", err)
 		return nil, err
 	}
 
 	resp := &apitypes.MachineSegment{}
 	_, err = m.client.Do(ctx, req, resp)
 	if err != nil {
-		log.Printf("Failed to retrieve machine: %s", err)
+		logging.Errorf("Failed to retrieve machine: %s", err)
 		return nil, err
 	}
 
 	return resp, nil
 }
+
+// CreateToken requests the registry to create a new token for the given
+// machine.
+func (m *MachinesClient) CreateToken(ctx context.Context, machineID *identity.ID,
+	token *MachineTokenCreationSegment) (*apitypes.MachineTokenSegment, error) {
+
+	path := "/machines/" + machineID.String() + "/tokens"
+	req, err := m.client.NewRequest("POST", path, nil, token)
+	if err != nil {
+		logging.Errorf("Error building POST Machine Tokens Request: %s", err)
+		return nil, err
+	}
+
+	resp := &apitypes.MachineTokenSegment{}
+	_, err = m.client.Do(ctx, req, resp)
+	if err != nil {
+		logging.Errorf("Failed to create machine token: %s", err)
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// DestroyToken requests the registry to destroy the given token of a
+// machine.
+func (m *MachinesClient) DestroyToken(ctx context.Context, machineID, tokenID *identity.ID) error {
+	path := "/machines/" + machineID.String() + "/tokens/" + tokenID.String()
+	req, err := m.client.NewRequest("DELETE", path, nil, nil)
+	if err != nil {
+		logging.Errorf("Error building DELETE Machine Tokens Request: %s", err)
+		return err
+	}
+
+	_, err = m.client.Do(ctx, req, nil)
+	if err != nil {
+		logging.Errorf("Failed to destroy machine token: %s", err)
+		return err
+	}
+
+	return nil
+}
@@ -2,9 +2,9 @@ package registry
 
 import (
 	"context"
-	"log"
 	"net/url"
 
+	"github.com/manifoldco/torus-cli/daemon/logging"
 	"github.com/manifoldco/torus-cli/envelope"
 	"github.com/manifoldco/torus-cli/identity"
 )
@@ -33,14 +33,14 @@ func (m *MembershipsClient) List(ctx context.Context, orgID *identity.ID,
 
 	req, err := m.client.NewRequest("GET", "/memberships", query, nil)
 	if err != nil {
-		log.Printf("could not build GET /memberships request: %s", err)
+		logging.Errorf("could not build GET /memberships request: %s", err)
 		return nil, err
 	}
 
 	memberships := []envelope.Unsigned{}
 	_, err = m.client.Do(ctx, req, &memberships)
 	if err != nil {
-		log.Printf("could not perform GET /memberships: %s", err)
+		logging.Errorf("could not perform GET /memberships: %s", err)
 		return nil, err
 	}
 
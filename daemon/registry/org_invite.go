@@ -3,10 +3,12 @@ package registry
 import (
 	"context"
 	"errors"
-	"log"
+	"net/url"
 
+	"github.com/manifoldco/torus-cli/daemon/logging"
 	"github.com/manifoldco/torus-cli/envelope"
 	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
 )
 
 // OrgInviteClient represents the `/org-invites` registry endpoint, used for
@@ -22,7 +24,7 @@ func (o *OrgInviteClient) Approve(ctx context.Context, inviteID *identity.ID) (*
 	path := "/org-invites/" + inviteID.String() + "/approve"
 	req, err := o.client.NewRequest("POST", path, nil, nil)
 	if err != nil {
-		log.Printf(
+		logging.Errorf(
 			"Error building POST /org-invites/:id/approve api request: %s", err)
 		return nil, err
 	}
@@ -30,7 +32,7 @@ func (o *OrgInviteClient) Approve(ctx context.Context, inviteID *identity.ID) (*
 	invite := envelope.Unsigned{}
 	_, err = o.client.Do(ctx, req, &invite)
 	if err != nil {
-		log.Printf("Error performing POST /org-invites/:id/accept: %s", err)
+		logging.Errorf("Error performing POST /org-invites/:id/accept: %s", err)
 		return nil, err
 	}
 
@@ -46,16 +48,56 @@ func (o *OrgInviteClient) Get(ctx context.Context, inviteID *identity.ID) (*enve
 	path := "/org-invites/" + inviteID.String()
 	req, err := o.client.NewRequest("GET", path, nil, nil)
 	if err != nil {
-		log.Printf("Error building GET /org-invites/:id request: %s", err)
+		logging.Errorf("Error building GET /org-invites/:id request: %s", err)
 		return nil, err
 	}
 
 	invite := envelope.Unsigned{}
 	_, err = o.client.Do(ctx, req, &invite)
 	if err != nil {
-		log.Printf("Error performing GET /org-invites/:id request: %s", err)
+		logging.Errorf("Error performing GET /org-invites/:id request: %s", err)
 		return nil, err
 	}
 
 	return &invite, nil
 }
+
+// List returns the invites for the given org, optionally filtered to those in
+// one of the given states.
+func (o *OrgInviteClient) List(ctx context.Context, orgID *identity.ID,
+	states []string) ([]envelope.Unsigned, error) {
+
+	query := url.Values{}
+	query.Set("org_id", orgID.String())
+	for _, state := range states {
+		query.Add("state", state)
+	}
+
+	req, err := o.client.NewRequest("GET", "/org-invites", &query, nil)
+	if err != nil {
+		logging.Errorf("Error building GET /org-invites request: %s", err)
+		return nil, err
+	}
+
+	resp := []struct {
+		ID      *identity.ID         `json:"id"`
+		Version uint8                `json:"version"`
+		Body    *primitive.OrgInvite `json:"body"`
+	}{}
+	_, err = o.client.Do(ctx, req, &resp)
+	if err != nil {
+		logging.Errorf("Error performing GET /org-invites request: %s", err)
+		return nil, err
+	}
+
+	invites := make([]envelope.Unsigned, len(resp))
+	for i, invite := range resp {
+		invites[i] = envelope.Unsigned{
+			ID:      invite.ID,
+			Version: invite.Version,
+			Body:    invite.Body,
+		}
+	}
+
+	return invites, nil
+}
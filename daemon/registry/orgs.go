@@ -2,9 +2,9 @@ package registry
 
 import (
 	"context"
-	"log"
 	"net/url"
 
+	"github.com/manifoldco/torus-cli/daemon/logging"
 	"github.com/manifoldco/torus-cli/envelope"
 	"github.com/manifoldco/torus-cli/identity"
 )
@@ -25,14 +25,14 @@ func (o *Orgs) List(ctx context.Context, name string) ([]envelope.Unsigned, erro
 
 	req, err := o.client.NewRequest("GET", "/orgs", &v, nil)
 	if err != nil {
-		log.Printf("Error building GET /orgs api request: %s", err)
+		logging.Errorf("Error building GET /orgs api request: %s", err)
 		return nil, err
 	}
 
 	orgs := []envelope.Unsigned{}
 	_, err = o.client.Do(ctx, req, &orgs)
 	if err != nil {
-		log.Printf("Error performing api request: %s", err)
+		logging.Errorf("Error performing api request: %s", err)
 		return nil, err
 	}
 
@@ -43,14 +43,14 @@ func (o *Orgs) List(ctx context.Context, name string) ([]envelope.Unsigned, erro
 func (o *Orgs) Get(ctx context.Context, orgID *identity.ID) (*envelope.Unsigned, error) {
 	req, err := o.client.NewRequest("GET", "/orgs/"+orgID.String(), nil, nil)
 	if err != nil {
-		log.Printf("Error building GET /orgs api request: %s", err)
+		logging.Errorf("Error building GET /orgs api request: %s", err)
 		return nil, err
 	}
 
 	org := envelope.Unsigned{}
 	_, err = o.client.Do(ctx, req, &org)
 	if err != nil {
-		log.Printf("Error performing api request: %s", err)
+		logging.Errorf("Error performing api request: %s", err)
 		return nil, err
 	}
 
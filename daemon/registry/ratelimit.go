@@ -0,0 +1,78 @@
+package registry
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+)
+
+// maxRateLimitWait caps how long a request waits for the registry's rate
+// limit to reset, in case the reported reset time is far off or wrong.
+const maxRateLimitWait = time.Minute
+
+// rateLimit tracks the registry's rate limit, as last reported through the
+// X-RateLimit-Remaining and X-RateLimit-Reset response headers.
+type rateLimit struct {
+	mu        sync.Mutex
+	known     bool
+	remaining int
+	reset     time.Time
+}
+
+// update records the limit reported by resp, if any.
+func (l *rateLimit) update(resp *http.Response, now time.Time) {
+	if resp == nil {
+		return
+	}
+
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+
+	var reset time.Time
+	if secs, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		if secs > apitypes.RateLimitEpochThreshold {
+			reset = time.Unix(secs, 0)
+		} else {
+			reset = now.Add(time.Duration(secs) * time.Second)
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.known = true
+	l.remaining = remaining
+	l.reset = reset
+}
+
+// wait returns how long to hold off before the next request, so as not to
+// exceed the limit. It is zero unless the limit is used up and has not yet
+// reset.
+func (l *rateLimit) wait(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.known || l.remaining > 0 || !l.reset.After(now) {
+		return 0
+	}
+
+	d := l.reset.Sub(now)
+	if d > maxRateLimitWait {
+		d = maxRateLimitWait
+	}
+	return d
+}
+
+// state returns the last reported number of requests remaining and when the
+// limit resets. ok is false if the registry has not reported a limit.
+func (l *rateLimit) state() (remaining int, reset time.Time, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.remaining, l.reset, l.known
+}
@@ -0,0 +1,71 @@
+package registry
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	now := time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	respWith := func(remaining, reset string) *http.Response {
+		h := http.Header{}
+		if remaining != "" {
+			h.Set("X-RateLimit-Remaining", remaining)
+		}
+		if reset != "" {
+			h.Set("X-RateLimit-Reset", reset)
+		}
+		return &http.Response{Header: h}
+	}
+
+	testCases := []struct {
+		name string
+		resp *http.Response
+		wait time.Duration
+	}{
+		{name: "no headers", resp: respWith("", ""), wait: 0},
+		{name: "remaining", resp: respWith("10", "30"), wait: 0},
+		{name: "exhausted, seconds", resp: respWith("0", "30"), wait: 30 * time.Second},
+		{name: "exhausted, unix time",
+			resp: respWith("0", strconv.FormatInt(now.Add(5*time.Second).Unix(), 10)),
+			wait: 5 * time.Second},
+		{name: "exhausted, already reset",
+			resp: respWith("0", strconv.FormatInt(now.Add(-time.Second).Unix(), 10)),
+			wait: 0},
+		{name: "exhausted, capped", resp: respWith("0", "3600"), wait: maxRateLimitWait},
+		{name: "exhausted, no reset", resp: respWith("0", ""), wait: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := &rateLimit{}
+			l.update(tc.resp, now)
+
+			if got := l.wait(now); got != tc.wait {
+				t.Errorf("got wait %s, want %s", got, tc.wait)
+			}
+		})
+	}
+
+	t.Run("state", func(t *testing.T) {
+		l := &rateLimit{}
+		if _, _, ok := l.state(); ok {
+			t.Error("Expected no known limit")
+		}
+
+		l.update(respWith("42", "60"), now)
+		remaining, reset, ok := l.state()
+		if !ok || remaining != 42 || !reset.Equal(now.Add(time.Minute)) {
+			t.Errorf("Unexpected state: %d %s %t", remaining, reset, ok)
+		}
+
+		// A response without the headers leaves the last known limit.
+		l.update(respWith("", ""), now)
+		if remaining, _, _ := l.state(); remaining != 42 {
+			t.Errorf("Expected limit to be kept, got %d remaining", remaining)
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package registry
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+)
+
+// maxRetryDelay caps the delay between attempts, including any delay
+// requested by the registry through Retry-After.
+const maxRetryDelay = 30 * time.Second
+
+// RetryPolicy controls how requests that fail with a transient error are
+// retried. A MaxAttempts of one or less disables retries.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+}
+
+// backoff returns how long to wait before the attempt following the given
+// one. The registry's Retry-After is honored when present, otherwise the
+// delay grows exponentially from BaseDelay, with jitter so that many clients
+// don't retry in lockstep.
+func (p RetryPolicy) backoff(attempt int, resp *http.Response) time.Duration {
+	if d, ok := retryAfter(resp, time.Now()); ok {
+		if d > maxRetryDelay {
+			d = maxRetryDelay
+		}
+		return d
+	}
+
+	d := p.BaseDelay << uint(attempt-1)
+	if d < p.BaseDelay || d > maxRetryDelay {
+		d = maxRetryDelay
+	}
+
+	// Wait somewhere between half and all of the computed delay.
+	half := int64(d / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// retryAfter parses the Retry-After header of resp, which may either be a
+// number of seconds or an HTTP date.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+
+	return 0, false
+}
+
+// isIdempotent returns whether r can safely be sent more than once. Requests
+// that would otherwise mutate state are only idempotent if they carry an
+// idempotency key, allowing the registry to recognize the retry.
+func isIdempotent(r *http.Request) bool {
+	switch r.Method {
+	case "GET", "HEAD":
+		return true
+	}
+
+	return r.Header.Get("Idempotency-Key") != ""
+}
+
+// shouldRetry returns whether the outcome of a request indicates a
+// transient failure. Nothing is retried once ctx is done.
+func shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+
+	if resp != nil {
+		switch resp.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+
+	switch e := err.(type) {
+	case *apitypes.Error:
+		return e.Type == "request_timeout"
+	case net.Error:
+		return e.Timeout() || e.Temporary() || isConnectionError(e)
+	}
+
+	return false
+}
+
+// isConnectionError returns whether err is caused by the registry refusing or
+// resetting the connection, such as while it restarts.
+func isConnectionError(err error) bool {
+	for {
+		switch e := err.(type) {
+		case *url.Error:
+			err = e.Err
+		case *net.OpError:
+			err = e.Err
+		case *os.SyscallError:
+			err = e.Err
+		case syscall.Errno:
+			return e == syscall.ECONNREFUSED || e == syscall.ECONNRESET
+		default:
+			return false
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2017, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		header string
+		delay  time.Duration
+		ok     bool
+	}{
+		{header: "", ok: false},
+		{header: "5", delay: 5 * time.Second, ok: true},
+		{header: "Sun, 01 Jan 2017 12:00:10 GMT", delay: 10 * time.Second, ok: true},
+		{header: "Sun, 01 Jan 2017 11:00:00 GMT", delay: 0, ok: true},
+		{header: "soon", ok: false},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.header, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			resp.Header.Set("Retry-After", test.header)
+
+			delay, ok := retryAfter(resp, now)
+			if ok != test.ok || delay != test.delay {
+				t.Errorf("Expected (%s, %t), got (%s, %t)", test.delay, test.ok, delay, ok)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond}
+
+	for attempt := 1; attempt <= 4; attempt++ {
+		max := p.BaseDelay << uint(attempt-1)
+		for i := 0; i < 20; i++ {
+			d := p.backoff(attempt, nil)
+			if d < max/2 || d > max {
+				t.Errorf("attempt %d: delay %s outside [%s, %s]", attempt, d, max/2, max)
+			}
+		}
+	}
+
+	if d := p.backoff(100, nil); d > maxRetryDelay {
+		t.Errorf("Expected delay to be capped at %s, got %s", maxRetryDelay, d)
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"2"}}}
+	if d := p.backoff(1, resp); d != 2*time.Second {
+		t.Errorf("Expected Retry-After to be honored, got %s", d)
+	}
+}
+
+func TestIsIdempotent(t *testing.T) {
+	get, _ := http.NewRequest("GET", "/", nil)
+	post, _ := http.NewRequest("POST", "/", nil)
+	keyed, _ := http.NewRequest("POST", "/", nil)
+	keyed.Header.Set("Idempotency-Key", "abc")
+
+	if !isIdempotent(get) {
+		t.Error("Expected GET to be idempotent")
+	}
+	if isIdempotent(post) {
+		t.Error("Expected POST without a key not to be idempotent")
+	}
+	if !isIdempotent(keyed) {
+		t.Error("Expected POST with a key to be idempotent")
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	testCases := []struct {
+		name      string
+		status    int
+		err       error
+		cancelled bool
+		retry     bool
+	}{
+		{name: "success", status: 200, err: nil, retry: false},
+		{name: "bad gateway", status: 502, err: errors.New("x"), retry: true},
+		{name: "unavailable", status: 503, err: errors.New("x"), retry: true},
+		{name: "not found", status: 404, err: errors.New("x"), retry: false},
+		{name: "timeout", err: &apitypes.Error{Type: "request_timeout"}, retry: true},
+		{name: "network timeout", err: &net.DNSError{IsTimeout: true}, retry: true},
+		{name: "connection reset", err: &url.Error{Op: "Get", Err: &net.OpError{
+			Op: "read", Err: &os.SyscallError{Syscall: "read", Err: syscall.ECONNRESET}}}, retry: true},
+		{name: "connection refused", err: &net.OpError{
+			Op: "dial", Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}}, retry: true},
+		{name: "other network", err: &net.OpError{Op: "dial", Err: errors.New("no such host")}, retry: false},
+		{name: "other", err: errors.New("malformed"), retry: false},
+		{name: "cancelled", status: 503, err: errors.New("x"), cancelled: true, retry: false},
+		{name: "cancelled network", err: &net.DNSError{IsTimeout: true}, cancelled: true, retry: false},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			var resp *http.Response
+			if test.status != 0 {
+				resp = &http.Response{StatusCode: test.status}
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			if test.cancelled {
+				cancel()
+			}
+			defer cancel()
+
+			if shouldRetry(ctx, resp, test.err) != test.retry {
+				t.Errorf("Expected shouldRetry = %t", test.retry)
+			}
+		})
+	}
+}
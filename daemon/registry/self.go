@@ -2,9 +2,9 @@ package registry
 
 import (
 	"context"
-	"log"
 
 	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/daemon/logging"
 )
 
 // SelfClient represents the registry `/self` endpoints.
@@ -16,7 +16,7 @@ type SelfClient struct {
 func (s *SelfClient) Get(ctx context.Context, token string) (*apitypes.Self, error) {
 	req, err := s.client.NewTokenRequest(token, "GET", "/self", nil, nil)
 	if err != nil {
-		log.Printf("Error making Self request: %s", err)
+		logging.Errorf("Error making Self request: %s", err)
 		return nil, err
 	}
 
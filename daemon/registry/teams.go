@@ -3,9 +3,9 @@ package registry
 import (
 	"context"
 	"errors"
-	"log"
 	"net/url"
 
+	"github.com/manifoldco/torus-cli/daemon/logging"
 	"github.com/manifoldco/torus-cli/envelope"
 	"github.com/manifoldco/torus-cli/identity"
 )
@@ -27,14 +27,14 @@ func (t *TeamsClient) List(ctx context.Context, orgID *identity.ID) ([]envelope.
 
 	req, err := t.client.NewRequest("GET", "/teams", v, nil)
 	if err != nil {
-		log.Printf("Error building GET /teams request: %s", err)
+		logging.Errorf("Error building GET /teams request: %s", err)
 		return nil, err
 	}
 
 	teams := []envelope.Unsigned{}
 	_, err = t.client.Do(ctx, req, &teams)
 	if err != nil {
-		log.Printf("Error performing GET /teams request: %s", err)
+		logging.Errorf("Error performing GET /teams request: %s", err)
 		return nil, err
 	}
 
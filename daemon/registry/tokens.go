@@ -2,10 +2,10 @@ package registry
 
 import (
 	"context"
-	"log"
 
 	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/base64"
+	"github.com/manifoldco/torus-cli/daemon/logging"
 )
 
 // token types that can be requested from the registry
@@ -74,15 +74,15 @@ func (t *Tokens) PostLogin(ctx context.Context, creds apitypes.LoginCredential)
 
 	req, err := t.client.NewRequest("POST", "/tokens", nil, body)
 	if err != nil {
-		log.Printf("Error building http request: %s", err)
+		logging.Errorf("Error building http request: %s", err)
 		return salt.Salt, salt.Token, err
 	}
 
 	resp, err := t.client.Do(ctx, req, &salt)
 	if err != nil && resp != nil && resp.StatusCode != 201 {
-		log.Printf("Failed to get login token from server: %s", err)
+		logging.Errorf("Failed to get login token from server: %s", err)
 	} else if err != nil {
-		log.Printf("Error making api request: %s", err)
+		logging.Errorf("Error making api request: %s", err)
 	}
 
 	return salt.Salt, salt.Token, err
@@ -96,13 +96,13 @@ func (t *Tokens) PostAuth(ctx context.Context, token, hmac string) (string, erro
 	req, err := t.client.NewTokenRequest(token, "POST", "/tokens", nil,
 		&authTokenHMACRequest{Type: tokenTypeAuth, TokenHMAC: hmac})
 	if err != nil {
-		log.Printf("Error building http request: %s", err)
+		logging.Errorf("Error building http request: %s", err)
 		return auth.Token, err
 	}
 
 	_, err = t.client.Do(ctx, req, &auth)
 	if err != nil {
-		log.Printf("Error making api request: %s", err)
+		logging.Errorf("Error making api request: %s", err)
 	}
 
 	return auth.Token, err
@@ -116,13 +116,13 @@ func (t *Tokens) PostPDPKAuth(ctx context.Context, token string, sig *base64.Val
 	req, err := t.client.NewTokenRequest(token, "POST", "/tokens", nil,
 		&authTokenPDPKARequest{Type: tokenTypeAuth, TokenSig: sig})
 	if err != nil {
-		log.Printf("Error building http request: %s", err)
+		logging.Errorf("Error building http request: %s", err)
 		return auth.Token, err
 	}
 
 	_, err = t.client.Do(ctx, req, &auth)
 	if err != nil {
-		log.Printf("Error making api request: %s", err)
+		logging.Errorf("Error making api request: %s", err)
 	}
 
 	return auth.Token, err
@@ -133,13 +133,13 @@ func (t *Tokens) PostPDPKAuth(ctx context.Context, token string, sig *base64.Val
 func (t *Tokens) Delete(ctx context.Context, token string) error {
 	req, err := t.client.NewTokenRequest(token, "DELETE", "/tokens/"+token, nil, nil)
 	if err != nil {
-		log.Printf("Error building http request: %s", err)
+		logging.Errorf("Error building http request: %s", err)
 		return err
 	}
 
 	_, err = t.client.Do(ctx, req, nil)
 	if err != nil {
-		log.Printf("Error making api request: %s", err)
+		logging.Errorf("Error making api request: %s", err)
 	}
 
 	return err
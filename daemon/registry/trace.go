@@ -0,0 +1,92 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Trace records a line describing each request made to the registry with a
+// context carrying it, for debugging slow operations.
+//
+// Only the method, URL, status and elapsed time are recorded. Headers, which
+// include the Authorization token, and bodies, which may contain encrypted
+// credentials, are never recorded, and sensitive query values are redacted.
+type Trace struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+type traceKey struct{}
+
+// WithTrace returns a copy of ctx that records registry requests in t.
+func WithTrace(ctx context.Context, t *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, t)
+}
+
+func traceFrom(ctx context.Context) *Trace {
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
+
+// Lines returns the lines recorded so far, in the order the requests
+// completed.
+func (t *Trace) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]string(nil), t.lines...)
+}
+
+func (t *Trace) record(r *http.Request, resp *http.Response, err error, elapsed time.Duration) {
+	outcome := "error"
+	switch {
+	case resp != nil:
+		outcome = resp.Status
+	case err != nil:
+		outcome = "error: " + err.Error()
+	}
+
+	line := fmt.Sprintf("%s %s %s %s", r.Method, redactURL(r.URL), outcome,
+		elapsed-elapsed%time.Millisecond)
+	if resp != nil {
+		if remaining := resp.Header.Get("X-RateLimit-Remaining"); remaining != "" {
+			line += " (rate limit remaining: " + remaining + ")"
+		}
+	}
+
+	t.note(line)
+}
+
+// note records a line, such as one not tied to a single request.
+func (t *Trace) note(line string) {
+	t.mu.Lock()
+	t.lines = append(t.lines, line)
+	t.mu.Unlock()
+}
+
+// sensitiveParams are query parameters whose values are redacted from
+// traces.
+var sensitiveParams = []string{"token", "code", "secret", "password"}
+
+func redactURL(u *url.URL) string {
+	q := u.Query()
+	for key := range q {
+		for _, s := range sensitiveParams {
+			if strings.Contains(strings.ToLower(key), s) {
+				q.Set(key, "REDACTED")
+				break
+			}
+		}
+	}
+
+	out := u.Path
+	if len(q) > 0 {
+		out += "?" + q.Encode()
+	}
+	return out
+}
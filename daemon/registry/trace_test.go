@@ -0,0 +1,76 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactURL(t *testing.T) {
+	testCases := []struct {
+		url      string
+		redacted string
+	}{
+		{url: "/orgs", redacted: "/orgs"},
+		{url: "/orgs?name=knoxville", redacted: "/orgs?name=knoxville"},
+		{url: "/tokens?token=abc&type=login", redacted: "/tokens?token=REDACTED&type=login"},
+		{url: "/org-invites?code=xyz", redacted: "/org-invites?code=REDACTED"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.url, func(t *testing.T) {
+			u, err := url.Parse(test.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := redactURL(u); got != test.redacted {
+				t.Errorf("got %q, want %q", got, test.redacted)
+			}
+		})
+	}
+}
+
+func TestTraceRecord(t *testing.T) {
+	tr := &Trace{}
+	ctx := WithTrace(context.Background(), tr)
+
+	if traceFrom(context.Background()) != nil {
+		t.Error("Expected no trace on a plain context")
+	}
+	if traceFrom(ctx) != tr {
+		t.Fatal("Expected trace from context")
+	}
+
+	r, err := http.NewRequest("GET", "https://registry.example.com/self", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Authorization", "Bearer secret-token")
+
+	resp := &http.Response{Status: "200 OK", StatusCode: 200}
+	tr.record(r, resp, nil, 1500*time.Microsecond)
+	tr.record(r, nil, errors.New("connection refused"), time.Millisecond)
+
+	lines := tr.Lines()
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+
+	if lines[0] != "GET /self 200 OK 1ms" {
+		t.Errorf("Unexpected line: %q", lines[0])
+	}
+	if lines[1] != "GET /self error: connection refused 1ms" {
+		t.Errorf("Unexpected line: %q", lines[1])
+	}
+
+	for _, line := range lines {
+		if strings.Contains(line, "secret-token") {
+			t.Errorf("Trace leaked the Authorization header: %q", line)
+		}
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"net/url"
 
 	"github.com/manifoldco/torus-cli/apitypes"
@@ -12,6 +11,7 @@ import (
 	"github.com/manifoldco/torus-cli/primitive"
 
 	"github.com/manifoldco/torus-cli/daemon/crypto"
+	"github.com/manifoldco/torus-cli/daemon/logging"
 )
 
 // Users represents the  registry `/users` endpoints.
@@ -33,20 +33,20 @@ func (u *Users) Create(ctx context.Context, userObj Signup, signup apitypes.Sign
 	}
 	req, err := u.client.NewRequest("POST", "/users", v, userObj)
 	if err != nil {
-		log.Printf("Error making api request: %s", err)
+		logging.Errorf("Error making api request: %s", err)
 		return nil, err
 	}
 
 	user := envelope.Unsigned{}
 	_, err = u.client.Do(ctx, req, &user)
 	if err != nil {
-		log.Printf("Error making api request: %s", err)
+		logging.Errorf("Error making api request: %s", err)
 		return nil, err
 	}
 
 	err = validateSelf(&user)
 	if err != nil {
-		log.Printf("Invalid user object: %s", err)
+		logging.Errorf("Invalid user object: %s", err)
 		return nil, err
 	}
 
@@ -8,6 +8,9 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/pathexp"
+
 	"github.com/manifoldco/torus-cli/daemon/logic"
 	"github.com/manifoldco/torus-cli/daemon/observer"
 )
@@ -35,10 +38,11 @@ func credentialsGetRoute(engine *logic.Engine, o *observer.Observer) http.Handle
 		}
 
 		var creds []logic.PlaintextCredentialEnvelope
+		var failures []apitypes.CredentialFailure
 		if path != "" {
-			creds, err = engine.RetrieveCredentials(ctx, n, &path, nil)
+			creds, failures, err = engine.RetrieveCredentials(ctx, n, &path, nil)
 		} else {
-			creds, err = engine.RetrieveCredentials(ctx, n, nil, &pathexp)
+			creds, failures, err = engine.RetrieveCredentials(ctx, n, nil, &pathexp)
 		}
 		if err != nil {
 			// Rely on logs inside engine for debugging
@@ -46,10 +50,25 @@ func credentialsGetRoute(engine *logic.Engine, o *observer.Observer) http.Handle
 			return
 		}
 
+		// Older clients expect a bare list of credentials, and an error rather
+		// than a partial list if any can't be decrypted.
+		var resp interface{} = credentialsResp{Credentials: creds, Failures: failures}
+		if q.Get("failures") != "report" {
+			if len(failures) > 0 {
+				encodeResponseErr(w, &apitypes.Error{
+					StatusCode: http.StatusInternalServerError,
+					Type:       apitypes.InternalServerError,
+					Err:        []string{(&apitypes.DecryptionFailuresError{Failures: failures}).Error()},
+				})
+				return
+			}
+			resp = creds
+		}
+
 		n.Notify(observer.Finished, "Completed Operation", true)
 
 		enc := json.NewEncoder(w)
-		err = enc.Encode(creds)
+		err = enc.Encode(resp)
 		if err != nil {
 			log.Printf("error encoding credentials: %s", err)
 			encodeResponseErr(w, err)
@@ -96,3 +115,126 @@ func credentialsPostRoute(engine *logic.Engine, o *observer.Observer) http.Handl
 		}
 	}
 }
+
+func credentialsBatchPostRoute(engine *logic.Engine, o *observer.Observer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		creds := []*logic.PlaintextCredentialEnvelope{}
+
+		dec := json.NewDecoder(r.Body)
+		err := dec.Decode(&creds)
+		if err != nil {
+			log.Printf("error decoding credentials: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		n, err := o.Notifier(ctx, 1)
+		if err != nil {
+			log.Printf("error constructing Notifier: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		creds, err = engine.AppendCredentials(ctx, n, creds)
+		if err != nil {
+			// Rely on logs inside engine for debugging
+			encodeResponseErr(w, err)
+			return
+		}
+
+		n.Notify(observer.Finished, "Completed Operation", true)
+
+		enc := json.NewEncoder(w)
+		err = enc.Encode(creds)
+		if err != nil {
+			log.Printf("error encoding credentials create resp: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+	}
+}
+
+func credentialsHistoryRoute(engine *logic.Engine, o *observer.Observer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		q := r.URL.Query()
+
+		name := q.Get("name")
+		if name == "" || q.Get("pathexp") == "" {
+			err := errors.New("missing pathexp or name")
+			log.Printf("Error constructing request: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		pe, err := pathexp.Parse(q.Get("pathexp"))
+		if err != nil {
+			log.Printf("Error parsing pathexp: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		n, err := o.Notifier(ctx, 1)
+		if err != nil {
+			log.Printf("error constructing Notifier: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		versions, err := engine.CredentialHistory(ctx, n, pe, name, q.Get("reveal") == "true")
+		if err != nil {
+			// Rely on logs inside engine for debugging
+			encodeResponseErr(w, err)
+			return
+		}
+
+		n.Notify(observer.Finished, "Completed Operation", true)
+
+		enc := json.NewEncoder(w)
+		err = enc.Encode(versions)
+		if err != nil {
+			log.Printf("error encoding credential history: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+	}
+}
+
+func credentialsKeysRoute(engine *logic.Engine) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		pe := r.URL.Query().Get("pathexp")
+		if pe == "" {
+			err := errors.New("missing pathexp")
+			log.Printf("Error constructing request: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		keys, err := engine.CredentialKeys(ctx, pe)
+		if err != nil {
+			// Rely on logs inside engine for debugging
+			encodeResponseErr(w, err)
+			return
+		}
+
+		enc := json.NewEncoder(w)
+		err = enc.Encode(keys)
+		if err != nil {
+			log.Printf("error encoding credential keys: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+	}
+}
+
+// credentialsCacheFlushRoute drops the credential graphs cached by the
+// daemon, and the credentials decrypted from them.
+func credentialsCacheFlushRoute(engine *logic.Engine) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		engine.FlushCache()
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
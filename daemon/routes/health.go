@@ -0,0 +1,61 @@
+package routes
+
+// This file contains routes related to the liveness of the daemon
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/config"
+
+	"github.com/manifoldco/torus-cli/daemon/metrics"
+	"github.com/manifoldco/torus-cli/daemon/session"
+)
+
+// healthRoute reports that the daemon is alive, regardless of whether or not
+// a session exists.
+func healthRoute(c *config.Config, s session.Session, started time.Time) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		enc := json.NewEncoder(w)
+		err := enc.Encode(&apitypes.Health{
+			Status:  "ok",
+			PID:     os.Getpid(),
+			Version: c.Version,
+			Session: s.HasToken() && s.HasPassphrase(),
+			Uptime:  int64(time.Since(started) / time.Second),
+		})
+		if err != nil {
+			encodeResponseErr(w, err)
+		}
+	}
+}
+
+// stopRoute asks the daemon to shut down gracefully. The request returns
+// straight away; the daemon stops once in-flight requests have drained.
+func stopRoute(stop func()) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("Stop requested by client")
+		w.WriteHeader(http.StatusNoContent)
+		stop()
+	}
+}
+
+// metricsRoute exposes the daemon's metrics in the Prometheus text format.
+// The daemon has a single session, so at most one is active.
+func metricsRoute(m *metrics.Metrics, s session.Session) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessions := 0
+		if s.HasToken() && s.HasPassphrase() {
+			sessions = 1
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		if err := m.Expose(w, sessions); err != nil {
+			log.Printf("Error writing metrics: %s", err)
+		}
+	}
+}
@@ -51,3 +51,42 @@ func keypairsGenerateRoute(engine *logic.Engine, o *observer.Observer) http.Hand
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
+
+func keypairsRegenerateRoute(engine *logic.Engine, o *observer.Observer) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		dec := json.NewDecoder(r.Body)
+		genReq := keyPairGenerate{}
+		err := dec.Decode(&genReq)
+		if err != nil {
+			encodeResponseErr(w, err)
+			return
+		}
+
+		if genReq.OrgID == nil {
+			encodeResponseErr(w, &apitypes.Error{
+				Type: apitypes.BadRequestError,
+				Err:  []string{"missing or invalid OrgID provided"},
+			})
+			return
+		}
+
+		n, err := o.Notifier(ctx, 1)
+		if err != nil {
+			log.Printf("Error creating Notifier: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		err = engine.RegenerateKeypair(ctx, n, genReq.OrgID)
+		if err != nil {
+			// Rely on engine for debug logging
+			encodeResponseErr(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,185 @@
+package routes
+
+// This file contains routes related to keyrings
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/daemon/logic"
+	"github.com/manifoldco/torus-cli/daemon/observer"
+	"github.com/manifoldco/torus-cli/identity"
+)
+
+func keyringsRotateRoute(engine *logic.Engine, o *observer.Observer) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		dec := json.NewDecoder(r.Body)
+		rotateReq := keyringRotate{}
+		err := dec.Decode(&rotateReq)
+		if err != nil {
+			encodeResponseErr(w, err)
+			return
+		}
+
+		if rotateReq.OrgID == nil {
+			encodeResponseErr(w, &apitypes.Error{
+				Type: apitypes.BadRequestError,
+				Err:  []string{"missing or invalid OrgID provided"},
+			})
+			return
+		}
+
+		n, err := o.Notifier(ctx, 1)
+		if err != nil {
+			log.Printf("Error creating Notifier: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		rotated, err := engine.RotateKeyrings(ctx, n, rotateReq.OrgID)
+		if err != nil {
+			// Rely on engine for debug logging
+			encodeResponseErr(w, err)
+			return
+		}
+
+		n.Notify(observer.Finished, "Completed Operation", true)
+
+		enc := json.NewEncoder(w)
+		err = enc.Encode(rotated)
+		if err != nil {
+			log.Printf("error encoding rotated keyrings: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+	}
+}
+
+func keyringsRepairRoute(engine *logic.Engine, o *observer.Observer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		dec := json.NewDecoder(r.Body)
+		repairReq := keyringRepair{}
+		err := dec.Decode(&repairReq)
+		if err != nil {
+			encodeResponseErr(w, err)
+			return
+		}
+
+		if repairReq.OrgID == nil || repairReq.ProjectID == nil {
+			encodeResponseErr(w, &apitypes.Error{
+				Type: apitypes.BadRequestError,
+				Err:  []string{"missing or invalid OrgID or ProjectID provided"},
+			})
+			return
+		}
+
+		n, err := o.Notifier(ctx, 1)
+		if err != nil {
+			log.Printf("Error creating Notifier: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		repairs, err := engine.RepairKeyrings(ctx, n, repairReq.OrgID, repairReq.ProjectID)
+		if err != nil {
+			// Rely on engine for debug logging
+			encodeResponseErr(w, err)
+			return
+		}
+
+		n.Notify(observer.Finished, "Completed Operation", true)
+
+		enc := json.NewEncoder(w)
+		err = enc.Encode(repairs)
+		if err != nil {
+			log.Printf("error encoding keyring repairs: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+	}
+}
+
+func keyringsMembersRoute(engine *logic.Engine, o *observer.Observer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		pe := r.URL.Query().Get("pathexp")
+		if pe == "" {
+			err := errors.New("missing pathexp")
+			log.Printf("Error constructing request: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		n, err := o.Notifier(ctx, 1)
+		if err != nil {
+			log.Printf("error constructing Notifier: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		access, err := engine.KeyringAccess(ctx, n, pe)
+		if err != nil {
+			// Rely on logs inside engine for debugging
+			encodeResponseErr(w, err)
+			return
+		}
+
+		n.Notify(observer.Finished, "Completed Operation", true)
+
+		enc := json.NewEncoder(w)
+		err = enc.Encode(access)
+		if err != nil {
+			log.Printf("error encoding keyring members: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+	}
+}
+
+func keyringsVerifyRoute(engine *logic.Engine, o *observer.Observer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		orgID, err := identity.DecodeFromString(r.URL.Query().Get("org_id"))
+		if err != nil {
+			encodeResponseErr(w, &apitypes.Error{
+				Type: apitypes.BadRequestError,
+				Err:  []string{"missing or invalid org_id provided"},
+			})
+			return
+		}
+
+		n, err := o.Notifier(ctx, 1)
+		if err != nil {
+			log.Printf("Error creating Notifier: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		findings, err := engine.VerifyKeyrings(ctx, n, &orgID)
+		if err != nil {
+			// Rely on engine for debug logging
+			encodeResponseErr(w, err)
+			return
+		}
+
+		n.Notify(observer.Finished, "Completed Operation", true)
+
+		enc := json.NewEncoder(w)
+		err = enc.Encode(findings)
+		if err != nil {
+			log.Printf("error encoding keyring findings: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+	}
+}
@@ -85,6 +85,54 @@ func machinesCreateRoute(client *registry.Client, session session.Session,
 	}
 }
 
+func machinesRotateTokenRoute(engine *logic.Engine, o *observer.Observer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		dec := json.NewDecoder(r.Body)
+		req := apitypes.MachineTokenRotateRequest{}
+		err := dec.Decode(&req)
+		if err != nil {
+			log.Printf("Error decoding request: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		if req.MachineID == nil || req.TokenID == nil || req.Secret == nil {
+			encodeResponseErr(w, &apitypes.Error{
+				StatusCode: http.StatusBadRequest,
+				Type:       apitypes.BadRequestError,
+				Err:        []string{"machine_id, token_id, and secret are required"},
+			})
+			return
+		}
+
+		n, err := o.Notifier(ctx, 1)
+		if err != nil {
+			log.Printf("Error creating Notifier: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		token, err := engine.Machine.RotateToken(ctx, n, req.MachineID, req.TokenID, req.Secret)
+		if err != nil {
+			log.Printf("Error rotating machine token: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		n.Notify(observer.Finished, "Machine token rotated", true)
+
+		enc := json.NewEncoder(w)
+		err = enc.Encode(token)
+		if err != nil {
+			log.Printf("Error encoding MachineTokenSegment: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+	}
+}
+
 // createMachine generates a Machine object and associated Membership objects
 // to be uploaded to the registry in the future.
 func createMachine(orgID, teamID, creatorID *identity.ID, name string) (
@@ -3,6 +3,7 @@ package routes
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/go-zoo/bone"
 
@@ -11,15 +12,18 @@ import (
 
 	"github.com/manifoldco/torus-cli/daemon/db"
 	"github.com/manifoldco/torus-cli/daemon/logic"
+	"github.com/manifoldco/torus-cli/daemon/metrics"
 	"github.com/manifoldco/torus-cli/daemon/observer"
 	"github.com/manifoldco/torus-cli/daemon/registry"
 	"github.com/manifoldco/torus-cli/daemon/session"
 )
 
 // NewRouteMux returns a *bone.Mux responsible for handling the cli to daemon
-// http api.
+// http api. stop is called when a client asks the daemon to shut down. The
+// metrics route is only served if m is not nil.
 func NewRouteMux(c *config.Config, s session.Session, db *db.DB,
-	t *http.Transport, o *observer.Observer, client *registry.Client, lEngine *logic.Engine) *bone.Mux {
+	t http.RoundTripper, o *observer.Observer, client *registry.Client, lEngine *logic.Engine,
+	m *metrics.Metrics, stop func()) *bone.Mux {
 
 	mux := bone.New()
 
@@ -32,10 +36,20 @@ func NewRouteMux(c *config.Config, s session.Session, db *db.DB,
 	mux.GetFunc("/self", selfRoute(s))
 
 	mux.PostFunc("/machines", machinesCreateRoute(client, s, lEngine, o))
+	mux.PostFunc("/machines/tokens/rotate", machinesRotateTokenRoute(lEngine, o))
 	mux.PostFunc("/keypairs/generate", keypairsGenerateRoute(lEngine, o))
+	mux.PostFunc("/keypairs/regenerate", keypairsRegenerateRoute(lEngine, o))
+	mux.PostFunc("/keyrings/rotate", keyringsRotateRoute(lEngine, o))
+	mux.PostFunc("/keyrings/repair", keyringsRepairRoute(lEngine, o))
+	mux.GetFunc("/keyrings/members", keyringsMembersRoute(lEngine, o))
+	mux.GetFunc("/keyrings/verify", keyringsVerifyRoute(lEngine, o))
 
 	mux.GetFunc("/credentials", credentialsGetRoute(lEngine, o))
 	mux.PostFunc("/credentials", credentialsPostRoute(lEngine, o))
+	mux.PostFunc("/credentials/batch", credentialsBatchPostRoute(lEngine, o))
+	mux.GetFunc("/credentials/history", credentialsHistoryRoute(lEngine, o))
+	mux.GetFunc("/credentials/keys", credentialsKeysRoute(lEngine))
+	mux.DeleteFunc("/credentials/cache", credentialsCacheFlushRoute(lEngine))
 
 	mux.PostFunc("/org-invites/:id/approve",
 		orgInvitesApproveRoute(lEngine, o))
@@ -44,6 +58,12 @@ func NewRouteMux(c *config.Config, s session.Session, db *db.DB,
 	mux.GetFunc("/worklog/:id", worklogGetRoute(lEngine, o))
 	mux.PostFunc("/worklog/:id", worklogResolveRoute(lEngine, o))
 
+	mux.GetFunc("/health", healthRoute(c, s, time.Now()))
+	mux.PostFunc("/stop", stopRoute(stop))
+	if m != nil {
+		mux.GetFunc("/metrics", metricsRoute(m, s))
+	}
+
 	mux.GetFunc("/version", func(w http.ResponseWriter, r *http.Request) {
 		enc := json.NewEncoder(w)
 		err := enc.Encode(&apitypes.Version{Version: c.Version})
@@ -68,6 +68,7 @@ func logoutRoute(engine *logic.Engine) http.HandlerFunc {
 		if err != nil {
 			log.Printf("Could not complete logout: %s", err)
 			encodeResponseErr(w, err)
+			return
 		}
 
 		w.WriteHeader(http.StatusNoContent)
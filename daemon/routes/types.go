@@ -5,17 +5,35 @@ import (
 
 	"github.com/manifoldco/torus-cli/apitypes"
 	"github.com/manifoldco/torus-cli/identity"
+
+	"github.com/manifoldco/torus-cli/daemon/logic"
 )
 
 type keyPairGenerate struct {
 	OrgID *identity.ID `json:"org_id"`
 }
 
+type keyringRotate struct {
+	OrgID *identity.ID `json:"org_id"`
+}
+
+type keyringRepair struct {
+	OrgID     *identity.ID `json:"org_id"`
+	ProjectID *identity.ID `json:"project_id"`
+}
+
 type machineCreate struct {
 	Name  string       `json:"name"`
 	OrgID *identity.ID `json:"org_id"`
 }
 
+// credentialsResp is the response to GET /credentials for clients that ask for
+// failures to be reported, with ?failures=report.
+type credentialsResp struct {
+	Credentials []logic.PlaintextCredentialEnvelope `json:"credentials"`
+	Failures    []apitypes.CredentialFailure        `json:"failures"`
+}
+
 type errorMsg struct {
 	Type  apitypes.ErrorType `json:"type"`
 	Error []string           `json:"error"`
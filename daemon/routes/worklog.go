@@ -92,13 +92,22 @@ func worklogResolveRoute(engine *logic.Engine, o *observer.Observer) http.Handle
 			return
 		}
 
-		res, err := engine.Worklog.Resolve(ctx, &orgID, &ident)
+		n, err := o.Notifier(ctx, 1)
+		if err != nil {
+			log.Printf("error constructing Notifier: %s", err)
+			encodeResponseErr(w, err)
+			return
+		}
+
+		res, err := engine.Worklog.Resolve(ctx, n, &orgID, &ident)
 		if err != nil {
 			log.Printf("error resolving worklog item: %s", err)
 			encodeResponseErr(w, err)
 			return
 		}
 
+		n.Notify(observer.Finished, "Completed Operation", true)
+
 		if res == nil {
 			encodeResponseErr(w, notFoundError)
 			return
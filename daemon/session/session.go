@@ -21,8 +21,10 @@ type session struct {
 	identity    *envelope.Unsigned
 	auth        *envelope.Unsigned
 
-	// sensitive values
-	token      string
+	// sensitive values, overwritten on logout. This is best-effort: the
+	// token is set and read as a string, and those copies, like any made of
+	// the passphrase by its callers, are left for the garbage collector.
+	token      []byte
 	passphrase []byte
 }
 
@@ -80,12 +82,13 @@ func (s *session) AuthID() *identity.ID {
 	return s.auth.ID
 }
 
-// Token returns the auth token stored in this session.
+// Token returns the auth token stored in this session. The returned string is
+// a copy, which Logout can't overwrite.
 func (s *session) Token() string {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	return s.token
+	return string(s.token)
 }
 
 // Passphrase returns the user's passphrase.
@@ -156,7 +159,7 @@ func (s *session) Set(sessionType string, identity, auth *envelope.Unsigned,
 
 	s.sessionType = sessionType
 	s.passphrase = passphrase
-	s.token = token
+	s.token = []byte(token)
 	s.identity = identity
 	s.auth = auth
 
@@ -195,7 +198,8 @@ func (s *session) Self() *apitypes.Self {
 	}
 }
 
-// Logout resets all values to the logged out state
+// Logout resets all values to the logged out state, overwriting the session's
+// own copies of the token and passphrase.
 func (s *session) Logout() error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
@@ -207,7 +211,19 @@ func (s *session) Logout() error {
 	s.sessionType = apitypes.NotLoggedIn
 	s.identity = nil
 	s.auth = nil
-	s.token = ""
-	s.passphrase = []byte{}
+
+	// Overwrite the secrets, rather than leaving them in memory until they
+	// are garbage collected.
+	zero(s.token)
+	zero(s.passphrase)
+	s.token = nil
+	s.passphrase = nil
+
 	return nil
 }
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,42 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+	"github.com/manifoldco/torus-cli/envelope"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+func TestLogoutZeroesSecrets(t *testing.T) {
+	s := NewSession().(*session)
+
+	user := &envelope.Unsigned{Body: &primitive.User{}}
+	passphrase := []byte("correct horse battery staple")
+
+	err := s.Set(apitypes.UserSession, user, user, passphrase, "sometoken")
+	if err != nil {
+		t.Fatal("Error setting session:", err)
+	}
+	token := s.token
+
+	err = s.Logout()
+	if err != nil {
+		t.Fatal("Error logging out:", err)
+	}
+
+	for name, b := range map[string][]byte{"passphrase": passphrase, "token": token} {
+		for i, c := range b {
+			if c != 0 {
+				t.Errorf("%s byte %d not overwritten: %q", name, i, c)
+			}
+		}
+	}
+
+	if s.HasToken() || s.HasPassphrase() {
+		t.Error("Session still holds secrets after logout")
+	}
+	if s.Type() != apitypes.NotLoggedIn {
+		t.Errorf("Expected type %s, got %s", apitypes.NotLoggedIn, s.Type())
+	}
+}
@@ -0,0 +1,111 @@
+package socket
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/manifoldco/torus-cli/apitypes"
+)
+
+// activeRequests tracks the requests currently being handled by the
+// AuthProxy, so they can be drained on shutdown.
+type activeRequests struct {
+	wg       sync.WaitGroup
+	mutex    sync.Mutex
+	draining bool
+	inflight map[string]int
+}
+
+func newActiveRequests() *activeRequests {
+	return &activeRequests{inflight: make(map[string]int)}
+}
+
+// handler wraps next, recording each request for the duration of its
+// handling. Once draining has begun, new requests are rejected.
+//
+// The observe endpoint is long-lived and closed by the observer itself, so
+// it is not tracked.
+func (a *activeRequests) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := r.URL.Path
+		if p == "/v1/observe" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !a.add(p) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			enc := json.NewEncoder(w)
+			enc.Encode(&apitypes.Error{
+				Type: "service_unavailable",
+				Err:  []string{"Daemon is shutting down"},
+			})
+			return
+		}
+		defer a.done(p)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *activeRequests) add(path string) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.draining {
+		return false
+	}
+
+	a.wg.Add(1)
+	a.inflight[path]++
+	return true
+}
+
+func (a *activeRequests) done(path string) {
+	a.mutex.Lock()
+	a.inflight[path]--
+	if a.inflight[path] == 0 {
+		delete(a.inflight, path)
+	}
+	a.mutex.Unlock()
+
+	a.wg.Done()
+}
+
+// drain stops new requests from being accepted, and waits up to timeout for
+// in-flight requests to finish. It returns false if the timeout elapsed.
+func (a *activeRequests) drain(timeout time.Duration) bool {
+	a.mutex.Lock()
+	a.draining = true
+	a.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// paths returns the sorted paths of all in-flight requests.
+func (a *activeRequests) paths() []string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	paths := make([]string, 0, len(a.inflight))
+	for p := range a.inflight {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	return paths
+}
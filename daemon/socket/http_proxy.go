@@ -0,0 +1,66 @@
+package socket
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// registryProxy returns the proxy selection function for the registry
+// transport. Without a configured proxy, the environment is used.
+func registryProxy(proxy *url.URL) func(*http.Request) (*url.URL, error) {
+	if proxy == nil {
+		return http.ProxyFromEnvironment
+	}
+
+	return func(r *http.Request) (*url.URL, error) {
+		if bypassProxy(r.URL.Host, noProxyEnv()) {
+			return nil, nil
+		}
+
+		return proxy, nil
+	}
+}
+
+func noProxyEnv() string {
+	if v := os.Getenv("NO_PROXY"); v != "" {
+		return v
+	}
+	return os.Getenv("no_proxy")
+}
+
+// bypassProxy returns whether host is matched by the comma separated
+// NO_PROXY list noProxy. As with http.ProxyFromEnvironment, an entry matches
+// the host itself and any of its subdomains, and "*" matches every host.
+func bypassProxy(host, noProxy string) bool {
+	if noProxy == "" {
+		return false
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if h, _, err := net.SplitHostPort(entry); err == nil {
+			entry = h
+		}
+
+		entry = strings.TrimPrefix(entry, ".")
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,86 @@
+package socket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+)
+
+func TestBypassProxy(t *testing.T) {
+	testCases := []struct {
+		host    string
+		noProxy string
+		bypass  bool
+	}{
+		{host: "registry.torus.sh", noProxy: "", bypass: false},
+		{host: "registry.torus.sh", noProxy: "*", bypass: true},
+		{host: "registry.torus.sh", noProxy: "registry.torus.sh", bypass: true},
+		{host: "registry.torus.sh:443", noProxy: "registry.torus.sh", bypass: true},
+		{host: "registry.torus.sh", noProxy: "example.com, torus.sh", bypass: true},
+		{host: "registry.torus.sh", noProxy: ".torus.sh", bypass: true},
+		{host: "registry.torus.sh", noProxy: "REGISTRY.torus.sh:443", bypass: true},
+		{host: "registry.torus.sh", noProxy: "us.sh", bypass: false},
+		{host: "registry.torus.sh", noProxy: "example.com", bypass: false},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.host+" "+test.noProxy, func(t *testing.T) {
+			if got := bypassProxy(test.host, test.noProxy); got != test.bypass {
+				t.Errorf("got %t, want %t", got, test.bypass)
+			}
+		})
+	}
+}
+
+func TestRegistryProxy(t *testing.T) {
+	var proxied, direct int
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer proxy.Close()
+
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		direct++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer registry.Close()
+
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{Proxy: registryProxy(proxyURL)},
+	}
+
+	defer os.Setenv("NO_PROXY", os.Getenv("NO_PROXY"))
+
+	os.Setenv("NO_PROXY", "")
+	resp, err := client.Get(registry.URL + "/v1/self")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if proxied != 1 || direct != 0 {
+		t.Errorf("Expected request through proxy, got %d proxied, %d direct",
+			proxied, direct)
+	}
+
+	os.Setenv("NO_PROXY", "127.0.0.1")
+	resp, err = client.Get(registry.URL + "/v1/self")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if proxied != 1 || direct != 1 {
+		t.Errorf("Expected request to bypass proxy, got %d proxied, %d direct",
+			proxied, direct)
+	}
+}
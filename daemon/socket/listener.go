@@ -0,0 +1,38 @@
+package socket
+
+import (
+	"net"
+
+	"github.com/manifoldco/torus-cli/daemon/logging"
+)
+
+// peerListener wraps the daemon's socket listener, accepting connections only
+// from processes running as uid. Other connections are logged and closed.
+type peerListener struct {
+	net.Listener
+	uid int
+}
+
+// Accept waits for and returns the next connection from the allowed user.
+func (l *peerListener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		uid, err := peerUID(c)
+		if err != nil {
+			logging.Warnf("Rejected connection to daemon socket: %s", err)
+			c.Close()
+			continue
+		}
+		if uid != l.uid {
+			logging.Warnf("Rejected connection to daemon socket from uid %d", uid)
+			c.Close()
+			continue
+		}
+
+		return c, nil
+	}
+}
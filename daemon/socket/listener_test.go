@@ -0,0 +1,92 @@
+package socket
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPeerListener(t *testing.T) {
+	if !peerCredSupported {
+		t.Skip("peer credentials are not supported on this platform")
+	}
+
+	dir, err := ioutil.TempDir("", "torus-socket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socketPath := filepath.Join(dir, "daemon.socket")
+	uid := os.Getuid()
+	other := uid + 1
+
+	t.Run("allowed user", func(t *testing.T) {
+		l, err := makeSocket(socketPath, 0600, &uid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+
+		fi, err := os.Stat(socketPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm() != 0600 {
+			t.Errorf("Expected mode 0600, got %o", fi.Mode().Perm())
+		}
+
+		go func() {
+			c, err := net.Dial("unix", socketPath)
+			if err == nil {
+				defer c.Close()
+				time.Sleep(100 * time.Millisecond)
+			}
+		}()
+
+		c, err := l.Accept()
+		if err != nil {
+			t.Fatal("Expected connection to be accepted:", err)
+		}
+		c.Close()
+	})
+
+	t.Run("other user", func(t *testing.T) {
+		l, err := makeSocket(socketPath, 0600, &other)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		accepted := make(chan struct{})
+		go func() {
+			c, err := l.Accept()
+			if err == nil {
+				c.Close()
+				close(accepted)
+			}
+		}()
+
+		c, err := net.Dial("unix", socketPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+
+		// The daemon closes the rejected connection, so reads see EOF.
+		c.SetReadDeadline(time.Now().Add(time.Second))
+		_, err = c.Read(make([]byte, 1))
+		if err == nil {
+			t.Error("Expected connection to be closed")
+		}
+
+		l.Close()
+		select {
+		case <-accepted:
+			t.Error("Expected connection from another user to be rejected")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+}
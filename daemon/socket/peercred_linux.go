@@ -0,0 +1,33 @@
+package socket
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// peerCredSupported is whether peerUID can identify the user of a connection
+// on this platform.
+const peerCredSupported = true
+
+// peerUID returns the user id of the process on the other end of c, using
+// SO_PEERCRED.
+func peerUID(c net.Conn) (int, error) {
+	uc, ok := c.(*net.UnixConn)
+	if !ok {
+		return 0, errors.New("not a unix socket connection")
+	}
+
+	f, err := uc.File()
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	cred, err := syscall.GetsockoptUcred(int(f.Fd()), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(cred.Uid), nil
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+// +build !linux
+
+package socket
+
+import (
+	"errors"
+	"net"
+)
+
+// peerCredSupported is whether peerUID can identify the user of a connection
+// on this platform.
+const peerCredSupported = false
+
+func peerUID(c net.Conn) (int, error) {
+	return 0, errors.New("peer credentials are not supported on this platform")
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"log"
 	"net"
 	"net/http"
@@ -12,6 +13,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/facebookgo/httpdown"
@@ -23,6 +25,7 @@ import (
 
 	"github.com/manifoldco/torus-cli/daemon/db"
 	"github.com/manifoldco/torus-cli/daemon/logic"
+	"github.com/manifoldco/torus-cli/daemon/metrics"
 	"github.com/manifoldco/torus-cli/daemon/observer"
 	"github.com/manifoldco/torus-cli/daemon/registry"
 	"github.com/manifoldco/torus-cli/daemon/routes"
@@ -41,17 +44,29 @@ type AuthProxy struct {
 	db     *db.DB
 	sess   session.Session
 	o      *observer.Observer
-	t      *http.Transport
+	t      *Transport
 	client *registry.Client
 	logic  *logic.Engine
+	active *activeRequests
+	m      *metrics.Metrics
+
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	graceMu sync.Mutex
+	grace   time.Duration
 }
 
 // NewAuthProxy returns a new AuthProxy. It will return an error if creation
 // of the domain socket fails, or the upstream registry URL is misconfigured.
+//
+// If m is not nil, proxied requests are counted in it, and it is served at
+// /v1/metrics.
 func NewAuthProxy(c *config.Config, sess session.Session, db *db.DB,
-	t *http.Transport, client *registry.Client, logic *logic.Engine) (*AuthProxy, error) {
+	t *Transport, client *registry.Client, logic *logic.Engine,
+	m *metrics.Metrics) (*AuthProxy, error) {
 
-	l, err := makeSocket(c.SocketPath)
+	l, err := makeSocket(c.SocketPath, c.SocketMode, c.SocketUID)
 	if err != nil {
 		return nil, err
 	}
@@ -66,15 +81,38 @@ func NewAuthProxy(c *config.Config, sess session.Session, db *db.DB,
 		t:      t,
 		client: client,
 		logic:  logic,
+		active: newActiveRequests(),
+		m:      m,
+		stop:   make(chan struct{}),
+		grace:  c.GracePeriod,
 	}, nil
 }
 
-// CreateHTTPTransport creates and configures the
+// CreateHTTPTransport creates and configures the transport used for all
+// requests to the registry. Connections are kept alive and pooled, so
+// commands that make many requests only pay for the TLS handshake once.
+//
+// Requests go through the proxy set in the config, or else the one set by the
+// HTTP_PROXY and HTTPS_PROXY environment variables. Hosts listed in NO_PROXY
+// are always reached directly.
 func CreateHTTPTransport(cfg *config.Config) *http.Transport {
-	return &http.Transport{TLSClientConfig: &tls.Config{
-		ServerName: strings.Split(cfg.RegistryURI.Host, ":")[0],
-		RootCAs:    cfg.CABundle,
-	}}
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	return &http.Transport{
+		Proxy:               registryProxy(cfg.Proxy),
+		DialContext:         dialer.DialContext,
+		MaxIdleConns:        cfg.MaxIdleConnsPerHost,
+		MaxIdleConnsPerHost: cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:     cfg.IdleConnTimeout,
+		TLSHandshakeTimeout: 10 * time.Second,
+		TLSClientConfig: &tls.Config{
+			ServerName: strings.Split(cfg.RegistryURI.Host, ":")[0],
+			RootCAs:    cfg.CABundle,
+		},
+	}
 }
 
 // Listen starts the main loop of the AuthProxy. It returns on error, or when
@@ -82,7 +120,7 @@ func CreateHTTPTransport(cfg *config.Config) *http.Transport {
 func (p *AuthProxy) Listen() error {
 	mux := bone.New()
 	proxy := &httputil.ReverseProxy{
-		Transport: p.t,
+		Transport: &metricsTransport{next: p.t, m: p.m},
 		Director: func(r *http.Request) {
 			r.URL.Scheme = p.u.Scheme
 			r.URL.Host = p.u.Host
@@ -102,19 +140,59 @@ func (p *AuthProxy) Listen() error {
 	go p.o.Start()
 
 	mux.HandleFunc("/proxy/", proxyCanceler(proxy))
-	mux.SubRoute("/v1", routes.NewRouteMux(p.c, p.sess, p.db, p.t, p.o, p.client, p.logic))
+	mux.SubRoute("/v1", routes.NewRouteMux(p.c, p.sess, p.db, p.t, p.o, p.client, p.logic,
+		p.m, p.requestStop))
 
-	h := httpdown.HTTP{}
-	p.s = h.Serve(&http.Server{Handler: requestIDHandler(loggingHandler(mux))}, p.l)
+	// In-flight requests are drained by Close before httpdown is stopped, so
+	// anything still open by then is only given a moment before being killed.
+	h := httpdown.HTTP{StopTimeout: time.Second, KillTimeout: time.Second}
+	handler := requestIDHandler(loggingHandler(timeoutHandler(traceHandler(p.active.handler(mux)))))
+	p.s = h.Serve(&http.Server{Handler: handler}, p.l)
 
 	return p.s.Wait()
 }
 
-// Close gracefully closes the socket, ensuring all requests are finished
-// within the timeout.
+// Close gracefully closes the socket. New requests are rejected while those
+// in-flight are given up to the configured grace period to finish, after
+// which the socket is forcibly closed.
 func (p *AuthProxy) Close() error {
+	p.graceMu.Lock()
+	grace := p.grace
+	p.graceMu.Unlock()
+
+	if !p.active.drain(grace) {
+		log.Printf("Grace period of %s elapsed with requests in-flight: %s",
+			grace, strings.Join(p.active.paths(), ", "))
+	}
+
 	p.o.Stop()
-	return p.s.Stop()
+	err := p.s.Stop()
+
+	// Nothing is left to reuse the pooled registry connections.
+	p.t.CloseIdleConnections()
+
+	return err
+}
+
+// Reload applies the settings of cfg that can change while the AuthProxy is
+// running: the registry transport's proxy and idle connection timeout, and
+// the shutdown grace period.
+func (p *AuthProxy) Reload(cfg *config.Config) {
+	p.t.Reload(cfg)
+
+	p.graceMu.Lock()
+	p.grace = cfg.GracePeriod
+	p.graceMu.Unlock()
+}
+
+// StopRequested returns a channel that is closed once a client has asked the
+// daemon to stop.
+func (p *AuthProxy) StopRequested() <-chan struct{} {
+	return p.stop
+}
+
+func (p *AuthProxy) requestStop() {
+	p.stopOnce.Do(func() { close(p.stop) })
 }
 
 // Addr returns the domain socket this proxy is listening on.
@@ -130,6 +208,68 @@ func loggingHandler(next http.Handler) http.Handler {
 	})
 }
 
+// timeoutHandler bounds a request by the time the client has left to wait
+// for it, sent in the X-Torus-Timeout header, so that registry calls made for
+// it use the client's timeout rather than the default.
+func timeoutHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout, err := time.ParseDuration(r.Header.Get("X-Torus-Timeout"))
+		if err != nil || timeout <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancelFunc := context.WithTimeout(r.Context(), timeout)
+		defer cancelFunc()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// traceHandler records the registry requests made while handling a daemon
+// route, for clients that ask for them with the X-Torus-Verbose header. The
+// recorded lines are returned in the X-Torus-Trace trailer.
+//
+// Proxied requests aren't traced; the client can time those itself.
+func traceHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Torus-Verbose") == "" || !strings.HasPrefix(r.URL.Path, "/v1/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		t := &registry.Trace{}
+		w.Header().Set("Trailer", "X-Torus-Trace")
+
+		r = r.WithContext(registry.WithTrace(r.Context(), t))
+		next.ServeHTTP(w, r)
+
+		for _, line := range t.Lines() {
+			w.Header().Add("X-Torus-Trace", line)
+		}
+	})
+}
+
+// metricsTransport counts the requests proxied to the registry in m, timing
+// them as the registry client does its own.
+type metricsTransport struct {
+	next http.RoundTripper
+	m    *metrics.Metrics
+}
+
+func (t *metricsTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(r)
+
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	t.m.Request(status, time.Since(start))
+
+	return resp, err
+}
+
 func requestIDHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		id := r.Header.Get("X-Request-Id")
@@ -143,7 +283,11 @@ func requestIDHandler(next http.Handler) http.Handler {
 	})
 }
 
-func makeSocket(socketPath string) (net.Listener, error) {
+func makeSocket(socketPath string, mode os.FileMode, uid *int) (net.Listener, error) {
+	if uid != nil && !peerCredSupported {
+		return nil, errors.New("socket_uid is not supported on this platform")
+	}
+
 	absPath, err := filepath.Abs(socketPath)
 	if err != nil {
 		return nil, err
@@ -164,18 +308,31 @@ func makeSocket(socketPath string) (net.Listener, error) {
 
 	// Does not guarantee security; BSD ignores file permissions for sockets
 	// see https://github.com/manifoldco/torus-cli/issues/76 for details
-	if err = os.Chmod(socketPath, 0700); err != nil {
+	if err = os.Chmod(socketPath, mode); err != nil {
 		return nil, err
 	}
 
+	if uid != nil {
+		return &peerListener{Listener: l, uid: *uid}, nil
+	}
+
 	return l, nil
 }
 
 // proxyCanceler supports canceling proxied requests via a timeout, and
-// returning a custom error response.
+// returning a custom error response. Requests are bounded by the client's
+// timeout if it sent one, or registry.DefaultTimeout otherwise.
 func proxyCanceler(proxy http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx, cancelFunc := context.WithTimeout(context.Background(), 6*time.Second)
+		// Derive from the request's context, so a client that gives up on
+		// the request also cancels the call to the registry.
+		ctx := r.Context()
+		var cancelFunc context.CancelFunc
+		if _, ok := ctx.Deadline(); ok {
+			ctx, cancelFunc = context.WithCancel(ctx)
+		} else {
+			ctx, cancelFunc = context.WithTimeout(ctx, registry.DefaultTimeout)
+		}
 		defer cancelFunc()
 
 		cw := cancelingProxyResponseWriter{
@@ -0,0 +1,43 @@
+package socket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeoutHandler(t *testing.T) {
+	testCases := []struct {
+		header   string
+		deadline bool
+	}{
+		{header: "", deadline: false},
+		{header: "bogus", deadline: false},
+		{header: "-1s", deadline: false},
+		{header: "30s", deadline: true},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.header, func(t *testing.T) {
+			var deadline time.Time
+			var ok bool
+			h := timeoutHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				deadline, ok = r.Context().Deadline()
+			}))
+
+			r := httptest.NewRequest("GET", "/v1/self", nil)
+			if test.header != "" {
+				r.Header.Set("X-Torus-Timeout", test.header)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if ok != test.deadline {
+				t.Fatalf("got deadline %t, want %t", ok, test.deadline)
+			}
+			if ok && time.Until(deadline) > 30*time.Second {
+				t.Errorf("deadline %s is past the client's timeout", deadline)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package socket
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/manifoldco/torus-cli/config"
+)
+
+// Transport is the http.RoundTripper used for all requests to the registry.
+// It wraps a transport made by CreateHTTPTransport, which is replaced when
+// the daemon's config is reloaded.
+type Transport struct {
+	mu sync.RWMutex
+	t  *http.Transport
+}
+
+// NewTransport returns a Transport configured from cfg.
+func NewTransport(cfg *config.Config) *Transport {
+	return &Transport{t: CreateHTTPTransport(cfg)}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.mu.RLock()
+	next := t.t
+	t.mu.RUnlock()
+
+	return next.RoundTrip(r)
+}
+
+// Reload replaces the underlying transport with one configured from cfg, so
+// that a changed proxy or idle connection timeout is used from the next
+// request on. Requests already in flight finish on the old transport.
+func (t *Transport) Reload(cfg *config.Config) {
+	next := CreateHTTPTransport(cfg)
+
+	t.mu.Lock()
+	old := t.t
+	t.t = next
+	t.mu.Unlock()
+
+	old.CloseIdleConnections()
+}
+
+// CloseIdleConnections closes the pooled connections that aren't in use.
+func (t *Transport) CloseIdleConnections() {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	t.t.CloseIdleConnections()
+}
@@ -0,0 +1,63 @@
+package socket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/manifoldco/torus-cli/config"
+)
+
+func TestTransportReload(t *testing.T) {
+	var proxied, direct int
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer proxy.Close()
+
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		direct++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer registry.Close()
+
+	registryURL, err := url.Parse(registry.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.Setenv("NO_PROXY", os.Getenv("NO_PROXY"))
+	os.Setenv("NO_PROXY", "")
+
+	cfg := &config.Config{RegistryURI: registryURL}
+	transport := NewTransport(cfg)
+	client := &http.Client{Transport: transport}
+
+	get := func() {
+		resp, err := client.Get(registry.URL + "/v1/self")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	get()
+	if proxied != 0 || direct != 1 {
+		t.Errorf("Expected a direct request, got %d proxied, %d direct", proxied, direct)
+	}
+
+	transport.Reload(&config.Config{RegistryURI: registryURL, Proxy: proxyURL})
+	get()
+	if proxied != 1 || direct != 1 {
+		t.Errorf("Expected a request through the reloaded proxy, got %d proxied, %d direct",
+			proxied, direct)
+	}
+}
@@ -11,6 +11,8 @@ import (
 type DirPreferences struct {
 	Organization string `json:"org,omitempty"`
 	Project      string `json:"project,omitempty"`
+	Environment  string `json:"environment,omitempty"`
+	Service      string `json:"service,omitempty"`
 	Path         string `json:"-"`
 }
 
@@ -0,0 +1,95 @@
+package envelope
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+// signatureAlgorithm is the only algorithm used to sign envelopes.
+const signatureAlgorithm = "eddsa"
+
+// VerificationFailure is the reason a signed envelope failed verification.
+type VerificationFailure string
+
+// The reasons a signed envelope can fail verification.
+const (
+	// UnknownSigner means the envelope was not signed by the given key, or
+	// no key was found for its signer.
+	UnknownSigner VerificationFailure = "unknown signer"
+
+	// SignatureMismatch means the signature does not match the envelope's
+	// body, so either has been altered since signing.
+	SignatureMismatch VerificationFailure = "signature mismatch"
+
+	// MalformedEnvelope means the envelope or the key is missing or has
+	// invalid fields, so the signature can't be checked.
+	MalformedEnvelope VerificationFailure = "malformed envelope"
+)
+
+// VerificationError is returned by Verify when an envelope's signature can't
+// be verified.
+type VerificationError struct {
+	Reason VerificationFailure
+	Detail string
+}
+
+func (e *VerificationError) Error() string {
+	return string(e.Reason) + ": " + e.Detail
+}
+
+func verifyErr(reason VerificationFailure, format string, a ...interface{}) error {
+	return &VerificationError{Reason: reason, Detail: fmt.Sprintf(format, a...)}
+}
+
+// Verify checks that the envelope was signed by the signing key held in
+// pubKey, a signed primitive.PublicKey envelope, and that its body has not
+// changed since. pubKey may be nil if the signer's key could not be found.
+// Any failure is returned as a *VerificationError.
+func (e *Signed) Verify(pubKey *Signed) error {
+	if e.Body == nil || e.Signature.PublicKeyID == nil || e.Signature.Value == nil {
+		return verifyErr(MalformedEnvelope, "envelope %s is missing its body or signature", e.ID)
+	}
+	if e.Signature.Algorithm != signatureAlgorithm {
+		return verifyErr(MalformedEnvelope, "envelope %s has unsupported signature algorithm %q",
+			e.ID, e.Signature.Algorithm)
+	}
+
+	if pubKey == nil {
+		return verifyErr(UnknownSigner, "no public key found for %s, which signed envelope %s",
+			e.Signature.PublicKeyID, e.ID)
+	}
+	if pubKey.ID == nil || *pubKey.ID != *e.Signature.PublicKeyID {
+		return verifyErr(UnknownSigner, "envelope %s was signed by %s, not %s",
+			e.ID, e.Signature.PublicKeyID, pubKey.ID)
+	}
+
+	key, ok := pubKey.Body.(*primitive.PublicKey)
+	if !ok || key.Key.Value == nil {
+		return verifyErr(MalformedEnvelope, "envelope %s does not hold a public key", pubKey.ID)
+	}
+	if key.KeyType != "signing" {
+		return verifyErr(UnknownSigner, "%s is a %s key, not a signing key", pubKey.ID, key.KeyType)
+	}
+	if len(*key.Key.Value) != ed25519.PublicKeySize {
+		return verifyErr(MalformedEnvelope, "public key %s is %d bytes, not %d",
+			pubKey.ID, len(*key.Key.Value), ed25519.PublicKeySize)
+	}
+
+	b, err := json.Marshal(&e.Body)
+	if err != nil {
+		return verifyErr(MalformedEnvelope, "could not encode envelope %s: %s", e.ID, err)
+	}
+	b = append([]byte(strconv.Itoa(e.Body.Version())), b...)
+
+	if !ed25519.Verify(ed25519.PublicKey(*key.Key.Value), b, *e.Signature.Value) {
+		return verifyErr(SignatureMismatch, "the signature on envelope %s does not match its contents",
+			e.ID)
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package envelope
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/manifoldco/torus-cli/base64"
+	"github.com/manifoldco/torus-cli/identity"
+	"github.com/manifoldco/torus-cli/primitive"
+)
+
+func mustID(t *testing.T, raw string) *identity.ID {
+	id, err := identity.DecodeFromString(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &id
+}
+
+func signingKey(t *testing.T, id *identity.ID) (*Signed, ed25519.PrivateKey) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Signed{
+		ID:      id,
+		Version: 1,
+		Body: &primitive.PublicKey{
+			Algorithm: "eddsa",
+			Key:       primitive.PublicKeyValue{Value: base64.NewValue(pub)},
+			KeyType:   "signing",
+		},
+	}, priv
+}
+
+func sign(t *testing.T, body identity.Immutable, sigID *identity.ID, priv ed25519.PrivateKey) *Signed {
+	b, err := json.Marshal(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := ed25519.Sign(priv, append([]byte(strconv.Itoa(body.Version())), b...))
+
+	return &Signed{
+		Version: uint8(body.Version()),
+		Body:    body,
+		Signature: primitive.Signature{
+			Algorithm:   "eddsa",
+			PublicKeyID: sigID,
+			Value:       base64.NewValue(sig),
+		},
+	}
+}
+
+func TestVerify(t *testing.T) {
+	keyID := mustID(t, "04100000000000000000000000001")
+	otherID := mustID(t, "04100000000000000000000000010")
+
+	key, priv := signingKey(t, keyID)
+	otherKey, otherPriv := signingKey(t, otherID)
+
+	claim := func() *primitive.Claim {
+		return &primitive.Claim{
+			Created:     time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC),
+			PublicKeyID: keyID,
+			KeyType:     primitive.SignatureClaimType,
+		}
+	}
+
+	tcs := []struct {
+		name   string
+		env    *Signed
+		key    *Signed
+		reason VerificationFailure
+	}{
+		{name: "valid", env: sign(t, claim(), keyID, priv), key: key},
+		{
+			name: "tampered body",
+			env: func() *Signed {
+				env := sign(t, claim(), keyID, priv)
+				env.Body.(*primitive.Claim).KeyType = primitive.RevocationClaimType
+				return env
+			}(),
+			key:    key,
+			reason: SignatureMismatch,
+		},
+		{
+			name:   "wrong key signature",
+			env:    sign(t, claim(), keyID, otherPriv),
+			key:    key,
+			reason: SignatureMismatch,
+		},
+		{
+			name:   "signed by another key",
+			env:    sign(t, claim(), otherID, otherPriv),
+			key:    key,
+			reason: UnknownSigner,
+		},
+		{
+			name:   "missing key",
+			env:    sign(t, claim(), keyID, priv),
+			reason: UnknownSigner,
+		},
+		{
+			name: "missing signature",
+			env: func() *Signed {
+				env := sign(t, claim(), keyID, priv)
+				env.Signature.Value = nil
+				return env
+			}(),
+			key:    key,
+			reason: MalformedEnvelope,
+		},
+		{
+			name: "truncated key",
+			env:  sign(t, claim(), otherID, otherPriv),
+			key: func() *Signed {
+				k := *otherKey
+				pk := *k.Body.(*primitive.PublicKey)
+				pk.Key.Value = base64.NewValue((*pk.Key.Value)[:16])
+				k.Body = &pk
+				return &k
+			}(),
+			reason: MalformedEnvelope,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.env.Verify(tc.key)
+			if tc.reason == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+				return
+			}
+
+			verr, ok := err.(*VerificationError)
+			if !ok {
+				t.Fatalf("Expected a *VerificationError, got %v", err)
+			}
+			if verr.Reason != tc.reason {
+				t.Errorf("Expected reason %q, got %q (%s)", tc.reason, verr.Reason, verr)
+			}
+		})
+	}
+}
@@ -1,11 +1,66 @@
 package errs
 
 import (
+	"net"
+	"net/url"
 	"regexp"
 
 	"github.com/urfave/cli"
 )
 
+// Exit codes returned by the cli, so scripts can tell classes of failure
+// apart. ExitCode maps errors from the daemon and registry onto them:
+//
+//	1  ExitGeneric   any failure without a more specific code
+//	2  ExitUsage     missing or invalid arguments or flags
+//	3  ExitAuth      not logged in, or not allowed to perform the action
+//	                 (unauthorized errors and 401/403 responses)
+//	4  ExitNotFound  the requested resource does not exist
+//	                 (not_found errors and 404 responses)
+//	5  ExitNetwork   the daemon or registry could not be reached
+//	                 (network errors and request timeouts)
+const (
+	ExitGeneric  = 1
+	ExitUsage    = 2
+	ExitAuth     = 3
+	ExitNotFound = 4
+	ExitNetwork  = 5
+)
+
+// apiError is an error returned by the daemon or registry, an
+// *apitypes.Error. It is matched by its methods, as apitypes depends on this
+// package through pathexp.
+type apiError interface {
+	error
+	ErrorType() string
+	HTTPStatus() int
+}
+
+// ExitCode returns the exit code for the class of the given error.
+func ExitCode(err error) int {
+	switch e := err.(type) {
+	case nil:
+		return 0
+	case cli.ExitCoder:
+		return e.ExitCode()
+	case apiError:
+		switch t, status := e.ErrorType(), e.HTTPStatus(); {
+		case t == "unauthorized", status == 401, status == 403:
+			return ExitAuth
+		case t == "not_found", status == 404:
+			return ExitNotFound
+		case t == "request_timeout", status == 408:
+			return ExitNetwork
+		}
+	case *url.Error:
+		return ExitNetwork
+	case net.Error:
+		return ExitNetwork
+	}
+
+	return ExitGeneric
+}
+
 // Word without punctuation or space
 var wordRegex = regexp.MustCompile(`\w`)
 
@@ -14,26 +69,105 @@ func usageString(ctx *cli.Context) string {
 	return "Usage:\n" + spacer + ctx.App.HelpName + " " + ctx.Command.Name + " [command options] " + ctx.Command.ArgsUsage
 }
 
+// exitError is a cli.ExitCoder that keeps the parts of its message apart, so
+// it can also be described by NewJSONError.
+type exitError struct {
+	message string
+	code    int
+	cause   error  // the failure that led to the error, if any
+	usage   string // usage text for the command, if it was misused
+}
+
+func (e *exitError) Error() string {
+	msg := e.message
+	if e.cause != nil {
+		msg += "\n" + e.cause.Error()
+	}
+	if e.usage != "" {
+		msg += "\n" + e.usage
+	}
+	return msg
+}
+
+func (e *exitError) ExitCode() int {
+	return e.code
+}
+
 // NewUsageExitError creates an ExitError with appended usage text
 func NewUsageExitError(message string, ctx *cli.Context) error {
 	if wordRegex.MatchString(message[len(message)-1:]) {
 		message += "."
 	}
-	return cli.NewExitError(message+"\n"+usageString(ctx), -1)
+	return &exitError{message: message, code: ExitUsage, usage: usageString(ctx)}
 }
 
-// NewErrorExitError creates an ExitError with an appended error message
+// NewErrorExitError creates an ExitError with an appended error message, and
+// the exit code for the class of err.
 func NewErrorExitError(message string, err error) error {
 	if wordRegex.MatchString(message[len(message)-1:]) {
 		message += "."
 	}
-	return cli.NewExitError(message+"\n"+err.Error(), -1)
+	return &exitError{message: message, code: ExitCode(err), cause: err}
 }
 
-// NewExitError creates an ExitError with -1
+// NewExitError creates an ExitError with ExitGeneric
 func NewExitError(message string) error {
+	return NewCodedExitError(message, ExitGeneric)
+}
+
+// NewNotFoundExitError creates an ExitError with ExitNotFound
+func NewNotFoundExitError(message string) error {
+	return NewCodedExitError(message, ExitNotFound)
+}
+
+// NewCodedExitError creates an ExitError with the given exit code
+func NewCodedExitError(message string, code int) error {
 	if wordRegex.MatchString(message[len(message)-1:]) {
 		message += "."
 	}
-	return cli.NewExitError(message, -1)
+	return &exitError{message: message, code: code}
+}
+
+// JSONError is an error as written to stderr with --json-errors.
+//
+// Type is the type of the error returned by the daemon or registry, such as
+// not_found or conflict, if the error came from one of them. Otherwise it is
+// named after the class of the error: usage, unauthorized, not_found,
+// network or error. Code is the exit code of the cli.
+type JSONError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+}
+
+// NewJSONError describes err as a JSONError.
+func NewJSONError(err error) *JSONError {
+	cause := err
+	message := err.Error()
+	if e, ok := err.(*exitError); ok {
+		message = e.message
+		if e.cause != nil {
+			cause = e.cause
+			message += " " + e.cause.Error()
+		}
+	}
+
+	code := ExitCode(err)
+	if api, ok := cause.(apiError); ok && api.ErrorType() != "" {
+		return &JSONError{Type: api.ErrorType(), Message: message, Code: code}
+	}
+
+	errType := "error"
+	switch code {
+	case ExitUsage:
+		errType = "usage"
+	case ExitAuth:
+		errType = "unauthorized"
+	case ExitNotFound:
+		errType = "not_found"
+	case ExitNetwork:
+		errType = "network"
+	}
+
+	return &JSONError{Type: errType, Message: message, Code: code}
 }
@@ -0,0 +1,67 @@
+package errs
+
+import (
+	"errors"
+	"flag"
+	"net"
+	"testing"
+
+	"github.com/urfave/cli"
+)
+
+// testAPIError stands in for an *apitypes.Error.
+type testAPIError struct {
+	errType string
+	status  int
+}
+
+func (e *testAPIError) Error() string     { return "Not Found: no such thing" }
+func (e *testAPIError) ErrorType() string { return e.errType }
+func (e *testAPIError) HTTPStatus() int   { return e.status }
+
+func TestNewJSONError(t *testing.T) {
+	app := cli.NewApp()
+	ctx := cli.NewContext(app, flag.NewFlagSet("test", 0), nil)
+	ctx.Command = cli.Command{Name: "test"}
+
+	tcs := []struct {
+		name     string
+		err      error
+		expected JSONError
+	}{
+		{
+			name:     "api error",
+			err:      NewErrorExitError("Could not find it", &testAPIError{errType: "not_found", status: 404}),
+			expected: JSONError{Type: "not_found", Message: "Could not find it. Not Found: no such thing", Code: ExitNotFound},
+		},
+		{
+			name:     "network error",
+			err:      NewErrorExitError("Could not connect", &net.OpError{Op: "dial", Net: "unix", Err: errors.New("refused")}),
+			expected: JSONError{Type: "network", Message: "Could not connect. dial unix: refused", Code: ExitNetwork},
+		},
+		{
+			name:     "usage error",
+			err:      NewUsageExitError("Missing name", ctx),
+			expected: JSONError{Type: "usage", Message: "Missing name.", Code: ExitUsage},
+		},
+		{
+			name:     "not found",
+			err:      NewNotFoundExitError("Project not found"),
+			expected: JSONError{Type: "not_found", Message: "Project not found.", Code: ExitNotFound},
+		},
+		{
+			name:     "plain error",
+			err:      errors.New("boom"),
+			expected: JSONError{Type: "error", Message: "boom", Code: ExitGeneric},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got := NewJSONError(tc.err)
+			if *got != tc.expected {
+				t.Errorf("Expected %+v, got %+v", tc.expected, *got)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/manifoldco/torus-cli/cmd"
 	"github.com/manifoldco/torus-cli/config"
+	"github.com/manifoldco/torus-cli/errs"
 )
 
 func main() {
@@ -17,6 +18,15 @@ func main() {
 	app := cli.NewApp()
 	app.Version = config.Version
 	app.Usage = "A secure, shared workspace for secrets"
+	app.Flags = cmd.GlobalFlags
+	app.Before = cmd.Before
 	app.Commands = cmd.Cmds
-	app.Run(os.Args)
+	cmd.ReportErrors(app)
+
+	// Errors that carry an exit code have already exited; make sure any
+	// other failure still exits non-zero.
+	err := app.Run(os.Args)
+	if err != nil {
+		os.Exit(errs.ExitCode(err))
+	}
 }
@@ -0,0 +1,54 @@
+// Package pagination implements the registry's limit and offset paging
+// convention, shared by the daemon's registry client and the api client.
+package pagination
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Cursor describes where a page of results sits within the full result set.
+type Cursor struct {
+	// Total is the total number of results available, or -1 if the registry
+	// did not report it.
+	Total int
+
+	// Next is the offset to request the following page with. It is 0 once
+	// the results have been exhausted.
+	Next int
+}
+
+// SetPage adds the limit and offset of a page to a query. A limit of 0 lets
+// the registry choose the page size.
+func SetPage(v *url.Values, limit, offset int) {
+	if limit > 0 {
+		v.Set("limit", strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		v.Set("offset", strconv.Itoa(offset))
+	}
+}
+
+// NewCursor returns the Cursor for a page of n results, requested with the
+// given limit and offset. The total comes from the X-Total-Count header; if
+// the registry doesn't send one, a full page is taken to mean there may be
+// more.
+func NewCursor(resp *http.Response, limit, offset, n int) *Cursor {
+	cursor := &Cursor{Total: -1}
+	if resp != nil {
+		if t, err := strconv.Atoi(resp.Header.Get("X-Total-Count")); err == nil {
+			cursor.Total = t
+		}
+	}
+
+	seen := offset + n
+	switch {
+	case cursor.Total >= 0 && seen < cursor.Total:
+		cursor.Next = seen
+	case cursor.Total < 0 && limit > 0 && n == limit:
+		cursor.Next = seen
+	}
+
+	return cursor
+}
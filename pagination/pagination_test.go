@@ -0,0 +1,32 @@
+package pagination
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNewCursor(t *testing.T) {
+	withTotal := func(total string) *http.Response {
+		return &http.Response{Header: http.Header{"X-Total-Count": []string{total}}}
+	}
+
+	tcs := []struct {
+		name                 string
+		resp                 *http.Response
+		limit, offset, n     int
+		expectTotal, expNext int
+	}{
+		{"more remaining", withTotal("250"), 100, 100, 100, 250, 200},
+		{"last page", withTotal("250"), 100, 200, 50, 250, 0},
+		{"no total, full page", &http.Response{Header: http.Header{}}, 100, 0, 100, -1, 100},
+		{"no total, short page", &http.Response{Header: http.Header{}}, 100, 100, 20, -1, 0},
+		{"no limit or total", nil, 0, 0, 30, -1, 0},
+	}
+
+	for _, tc := range tcs {
+		c := NewCursor(tc.resp, tc.limit, tc.offset, tc.n)
+		if c.Total != tc.expectTotal || c.Next != tc.expNext {
+			t.Errorf("%s: expected total %d and next %d, got %+v", tc.name, tc.expectTotal, tc.expNext, c)
+		}
+	}
+}
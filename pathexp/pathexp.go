@@ -25,6 +25,7 @@ package pathexp
 
 import (
 	"errors"
+	"fmt"
 	"regexp"
 	"sort"
 	"strings"
@@ -85,29 +86,30 @@ func (a alternation) String() string {
 	return "[" + strings.Join(strs, "|") + "]"
 }
 
+// segmentRank ranks a segment by its type specificity
+func segmentRank(seg segment) int {
+	switch seg.(type) {
+	case literal:
+		return 3
+	case glob:
+		return 2
+	case alternation:
+		return 1
+	case fullglob:
+		return 0
+	default:
+		panic("Bad type for segment!")
+	}
+}
+
 // compareSegmentType ranks the segments by their type specificity
 func compareSegmentType(a, b segment) int {
-	segs := []segment{a, b}
-	ranks := make([]int, 2)
-	for i, seg := range segs {
-		switch seg.(type) {
-		case literal:
-			ranks[i] = 3
-		case glob:
-			ranks[i] = 2
-		case alternation:
-			ranks[i] = 1
-		case fullglob:
-			ranks[i] = 0
-		default:
-			panic("Bad type for segment!")
-		}
-	}
+	ra, rb := segmentRank(a), segmentRank(b)
 
 	switch {
-	case ranks[0] < ranks[1]:
+	case ra < rb:
 		return -1
-	case ranks[0] > ranks[1]:
+	case ra > rb:
 		return 1
 	default:
 		return 0
@@ -278,37 +280,144 @@ func New(org, project string, envs, services, identities, instances []string) (*
 	return &pe, nil
 }
 
-// Parse parses a string into a path expression. It returns an error if the
-// string is not a valid path expression.
-func Parse(raw string) (*PathExp, error) {
-	parts := strings.Split(raw, "/")
+// segmentNames are the names of the segments of a path expression, in order.
+var segmentNames = []string{"org", "project", "environment", "service", "identity", "instance"}
 
-	if len(parts) != 7 {
-		return nil, errors.New("Wrong number of path segements.")
+// ParseError is returned by Parse for an invalid path expression. Segment is
+// the position of the offending segment, starting from 1 for the org, or 0
+// if the path expression as a whole is malformed.
+type ParseError struct {
+	Path    string
+	Segment int
+	Reason  string
+}
+
+func (e *ParseError) Error() string {
+	if e.Segment == 0 {
+		return fmt.Sprintf("Invalid path expression %q: %s.", e.Path, e.Reason)
 	}
 
-	if parts[0] != "" {
-		return nil, errors.New("Path expressions must start with '/'.")
+	return fmt.Sprintf("Invalid path expression %q: segment %d (%s) %s.",
+		e.Path, e.Segment, segmentNames[e.Segment-1], e.Reason)
+}
+
+// Parse parses a string into a path expression. It returns a *ParseError if
+// the string is not a valid path expression.
+func Parse(raw string) (*PathExp, error) {
+	if !strings.HasPrefix(raw, "/") {
+		return nil, &ParseError{Path: raw, Reason: "must start with '/'"}
 	}
+
 	// remove leading empty section
-	parts = parts[1:]
+	parts := strings.Split(raw[1:], "/")
+	if len(parts) != len(segmentNames) {
+		return nil, &ParseError{Path: raw, Reason: fmt.Sprintf(
+			"has %d segments, not %d", len(parts), len(segmentNames))}
+	}
+
+	for i, part := range parts {
+		if reason := segmentReason(i, part); reason != "" {
+			return nil, &ParseError{Path: raw, Segment: i + 1, Reason: reason}
+		}
+	}
 
 	splitParts := make([][]string, 6)
-	splitNames := []string{"", "", "environment", "service", "identity", "instance"}
 	var err error
 	for i := 2; i < len(splitParts); i++ {
-		splitParts[i], err = Split(splitNames[i], parts[i])
+		splitParts[i], err = Split(segmentNames[i], parts[i])
 		if err != nil {
-			return nil, err
+			return nil, &ParseError{Path: raw, Segment: i + 1, Reason: err.Error()}
 		}
 	}
 
-	return New(parts[orgIdx], parts[projectIdx],
+	pe, err := New(parts[orgIdx], parts[projectIdx],
 		splitParts[envIdx],
 		splitParts[serviceIdx],
 		splitParts[identityIdx],
 		splitParts[instanceIdx],
 	)
+	if err != nil {
+		// The segments were already checked, so this shouldn't happen.
+		return nil, &ParseError{Path: raw, Reason: strings.TrimSuffix(err.Error(), ".")}
+	}
+
+	return pe, nil
+}
+
+// segmentReason describes why the segment at idx is invalid, or returns an
+// empty string if it is valid.
+func segmentReason(idx int, raw string) string {
+	if raw == "" {
+		return "is empty"
+	}
+
+	// The org and project must be names.
+	if idx == orgIdx || idx == projectIdx {
+		if strings.ContainsAny(raw, "*[|]") {
+			return "must be a name, without globs or alternation"
+		}
+		return termReason(raw)
+	}
+
+	if raw == "*" {
+		return ""
+	}
+
+	opens, closes := strings.HasPrefix(raw, "["), strings.HasSuffix(raw, "]")
+	switch {
+	case opens && !closes:
+		return "has a malformed alternation: missing closing ']'"
+	case closes && !opens:
+		return "has a malformed alternation: missing opening '['"
+	case !opens && strings.Contains(raw, "|"):
+		return "has a malformed alternation: options must be wrapped in '[' and ']'"
+	case !opens:
+		return termReason(raw)
+	}
+
+	options := strings.Split(raw[1:len(raw)-1], "|")
+	if len(options) < 2 {
+		return "has a malformed alternation: at least two options are required"
+	}
+	for i, option := range options {
+		switch option {
+		case "":
+			return fmt.Sprintf("has a malformed alternation: option %d is empty", i+1)
+		case "*":
+			return "has a malformed alternation: '*' can't be an option"
+		}
+		if reason := termReason(option); reason != "" {
+			return fmt.Sprintf("has a malformed alternation: option %d %s", i+1, reason)
+		}
+	}
+
+	return ""
+}
+
+// termReason describes why a name, or a glob if it ends in '*', is invalid,
+// or returns an empty string if it is valid.
+func termReason(term string) string {
+	for _, r := range term {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_', r == '*':
+		case r >= 'A' && r <= 'Z':
+			return fmt.Sprintf("contains the uppercase character %q; names are lowercase", r)
+		default:
+			return fmt.Sprintf("contains the illegal character %q", r)
+		}
+	}
+
+	name := strings.TrimSuffix(term, "*")
+	switch {
+	case strings.Contains(name, "*"):
+		return "has a '*' that isn't at the end"
+	case name[0] == '-' || name[0] == '_':
+		return "must start with a letter or number"
+	case len(name) > 64:
+		return "is longer than 64 characters"
+	}
+
+	return ""
 }
 
 // WithInstance clones a PathExp, replacing its instance with the parsed value
@@ -456,6 +565,153 @@ func (pe *PathExp) Equal(other *PathExp) bool {
 	}
 }
 
+// Contains returns a bool indicating if every path matched by the other
+// PathExp is also matched by this PathExp.
+func (pe *PathExp) Contains(other *PathExp) bool {
+	switch {
+	case pe.org != other.org:
+		return false
+	case pe.project != other.project:
+		return false
+	case !segmentContains(pe.envs, other.envs):
+		return false
+	case !segmentContains(pe.services, other.services):
+		return false
+	case !segmentContains(pe.identities, other.identities):
+		return false
+	case !segmentContains(pe.instances, other.instances):
+		return false
+	default:
+		return true
+	}
+}
+
+// Intersect returns a PathExp matching exactly the paths matched by both this
+// PathExp and the other PathExp. The returned bool is false, and the PathExp
+// nil, if no path is matched by both.
+func (pe *PathExp) Intersect(other *PathExp) (*PathExp, bool) {
+	if pe.org != other.org || pe.project != other.project {
+		return nil, false
+	}
+
+	res := PathExp{org: pe.org, project: pe.project}
+	pairs := []struct {
+		dst  *segment
+		a, b segment
+	}{
+		{&res.envs, pe.envs, other.envs},
+		{&res.services, pe.services, other.services},
+		{&res.identities, pe.identities, other.identities},
+		{&res.instances, pe.instances, other.instances},
+	}
+
+	for _, p := range pairs {
+		seg, ok := segmentIntersect(p.a, p.b)
+		if !ok {
+			return nil, false
+		}
+		*p.dst = seg
+	}
+
+	return &res, true
+}
+
+// terms returns the globs and literals that make up a segment. An alternation
+// has one term per option; every other segment is its own single term.
+func terms(s segment) []segment {
+	if a, ok := s.(alternation); ok {
+		return a
+	}
+	return []segment{s}
+}
+
+// termContains reports whether every value matched by term b is also matched
+// by term a.
+func termContains(a, b segment) bool {
+	switch at := a.(type) {
+	case fullglob:
+		return true
+	case glob:
+		switch bt := b.(type) {
+		case literal:
+			return strings.HasPrefix(string(bt), string(at))
+		case glob:
+			return strings.HasPrefix(string(bt), string(at))
+		}
+		return false
+	case literal:
+		bl, ok := b.(literal)
+		return ok && at == bl
+	default:
+		panic("Bad type for segment!")
+	}
+}
+
+// segmentContains reports whether every value matched by segment b is also
+// matched by segment a.
+func segmentContains(a, b segment) bool {
+LoopB:
+	for _, bt := range terms(b) {
+		for _, at := range terms(a) {
+			if termContains(at, bt) {
+				continue LoopB
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// segmentIntersect returns a segment matching the values matched by both a
+// and b, or false if there are none.
+//
+// Two terms either don't overlap, or one contains the other, in which case
+// their intersection is the narrower of the two.
+func segmentIntersect(a, b segment) (segment, bool) {
+	var res alternation
+	for _, at := range terms(a) {
+		for _, bt := range terms(b) {
+			var t segment
+			switch {
+			case termContains(at, bt):
+				t = bt
+			case termContains(bt, at):
+				t = at
+			default:
+				continue
+			}
+
+			res = addTerm(res, t)
+		}
+	}
+
+	switch len(res) {
+	case 0:
+		return nil, false
+	case 1:
+		return res[0], true
+	default:
+		return res, true
+	}
+}
+
+// addTerm adds t to the terms of an alternation, dropping whichever of the
+// terms are made redundant by another.
+func addTerm(a alternation, t segment) alternation {
+	res := alternation{}
+	for _, existing := range a {
+		if termContains(existing, t) {
+			return a
+		}
+		if !termContains(t, existing) {
+			res = append(res, existing)
+		}
+	}
+
+	return append(res, t)
+}
+
 // CompareSpecificity returns an int indicating if this PathExp is more
 // specific than PathExp b.
 //
@@ -463,10 +719,10 @@ func (pe *PathExp) Equal(other *PathExp) bool {
 // PathExp, A's segment is as specific or more specific than B's segment.
 //
 // Segment specificity is, from most to least specific:
-//	- <literal>
-//  - <glob>
-//  - <alternation>
-//  - <fullglob>
+//   - <literal>
+//   - <glob>
+//   - <alternation>
+//   - <fullglob>
 //
 // It is assumed that the provided PathExps are not disjoint.
 func (pe *PathExp) CompareSpecificity(other *PathExp) int {
@@ -485,6 +741,53 @@ func (pe *PathExp) CompareSpecificity(other *PathExp) int {
 	return compareSegmentType(pe.instances, other.instances)
 }
 
+// Specificity returns a score for how specific this PathExp is, for ranking
+// PathExps that match the same path. A higher score is more specific.
+//
+// Each of the environment, service, identity and instance segments is ranked
+// from most to least specific as:
+//   - <literal>      3
+//   - <glob>         2
+//   - <alternation>  1
+//   - <fullglob>     0
+//
+// The segments are compared in that order, so a more specific environment
+// outweighs any difference in service, identity or instance. The score is
+// the segment ranks read as the digits of a base 4 number, which orders
+// PathExps the same way as CompareSpecificity.
+func (pe *PathExp) Specificity() int {
+	score := 0
+	for _, seg := range []segment{pe.envs, pe.services, pe.identities, pe.instances} {
+		score = score*4 + segmentRank(seg)
+	}
+	return score
+}
+
+// MostSpecific returns the index of the most specific of the given PathExps,
+// by Specificity. Rather than silently choosing one, it returns an error if
+// another, different PathExp is just as specific.
+func MostSpecific(pes []*PathExp) (int, error) {
+	if len(pes) == 0 {
+		return -1, errors.New("No path expressions given.")
+	}
+
+	best := 0
+	for i := 1; i < len(pes); i++ {
+		if pes[i].Specificity() > pes[best].Specificity() {
+			best = i
+		}
+	}
+
+	for i, pe := range pes {
+		if i != best && pe.Specificity() == pes[best].Specificity() && !pe.Equal(pes[best]) {
+			return -1, errors.New("Path expressions " + pes[best].String() + " and " +
+				pe.String() + " are equally specific.")
+		}
+	}
+
+	return best, nil
+}
+
 // UnmarshalText implements the encoding.TextUnmarshaler interface.
 // This will be used in json decoding.
 func (pe *PathExp) UnmarshalText(b []byte) error {
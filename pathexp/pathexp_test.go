@@ -131,6 +131,69 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParseErrors(t *testing.T) {
+	testCases := []struct {
+		path    string
+		segment int
+		reason  string
+	}{
+		// malformed as a whole
+		{"org/project/env/service/user/instance", 0, "must start with '/'"},
+		{"/org/project/env/service/user", 0, "has 5 segments, not 6"},
+		{"/org/project/env/service/user/instance/", 0, "has 7 segments, not 6"},
+
+		// empty segments
+		{"//project/env/service/user/instance", 1, "is empty"},
+		{"/org/project/env/service//instance", 5, "is empty"},
+
+		// illegal characters
+		{"/org/project/env/se.rvice/user/instance", 4, `contains the illegal character '.'`},
+		{"/org/project/Env/service/user/instance", 3, `contains the uppercase character 'E'`},
+		{"/org/project/env/service/user/-instance", 6, "must start with a letter or number"},
+		{"/org/project/env/service/user/in*stance", 6, "has a '*' that isn't at the end"},
+		{"/org/project/env/service/" + strings.Repeat("a", 80) + "/instance", 5,
+			"is longer than 64 characters"},
+
+		// org and project must be names
+		{"/org-*/project/env/service/user/instance", 1, "must be a name"},
+		{"/org/[a|b]/env/service/user/instance", 2, "must be a name"},
+
+		// malformed alternations
+		{"/org/project/[a|b/service/user/instance", 3, "missing closing ']'"},
+		{"/org/project/env/a|b]/user/instance", 4, "missing opening '['"},
+		{"/org/project/env/a|b/user/instance", 4, "must be wrapped in '[' and ']'"},
+		{"/org/project/env/service/[a]/instance", 5, "at least two options are required"},
+		{"/org/project/env/service/user/[a||b]", 6, "option 2 is empty"},
+		{"/org/project/env/service/user/[*|b]", 6, "'*' can't be an option"},
+		{"/org/project/env/service/user/[a|B]", 6, "option 2 contains the uppercase character 'B'"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.path, func(t *testing.T) {
+			_, err := Parse(test.path)
+			perr, ok := err.(*ParseError)
+			if !ok {
+				t.Fatalf("Expected a *ParseError, got %#v", err)
+			}
+
+			if perr.Segment != test.segment {
+				t.Errorf("Expected segment %d, got %d", test.segment, perr.Segment)
+			}
+			if !strings.Contains(perr.Reason, test.reason) {
+				t.Errorf("Expected reason containing %q, got %q", test.reason, perr.Reason)
+			}
+		})
+	}
+
+	t.Run("message", func(t *testing.T) {
+		_, err := Parse("/org/project/env/service//instance")
+		expected := `Invalid path expression "/org/project/env/service//instance": segment 5 (identity) is empty.`
+		if err == nil || err.Error() != expected {
+			t.Errorf("Expected %q, got %v", expected, err)
+		}
+	})
+}
+
 func TestPathExpCompareSpecificity(t *testing.T) {
 	type tc struct {
 		a   string
@@ -263,3 +326,199 @@ func TestNormalize(t *testing.T) {
 		t.Errorf("String() %s does not match normalized form %s", out, norm)
 	}
 }
+
+func TestContains(t *testing.T) {
+	type tc struct {
+		a        string
+		b        string
+		contains bool
+	}
+
+	testCases := []tc{
+		{a: "/o/p/e/s/u/i", b: "/o/p/e/s/u/i", contains: true},
+		{a: "/o/p/e/s/u/i", b: "/o1/p/e/s/u/i", contains: false},
+		{a: "/o/p/e/s/u/i", b: "/o/p1/e/s/u/i", contains: false},
+
+		// wildcards
+		{a: "/o/p/*/s/u/i", b: "/o/p/e/s/u/i", contains: true},
+		{a: "/o/p/e/s/u/i", b: "/o/p/*/s/u/i", contains: false},
+		{a: "/o/p/e-*/s/u/i", b: "/o/p/e-1/s/u/i", contains: true},
+		{a: "/o/p/e-*/s/u/i", b: "/o/p/e-1*/s/u/i", contains: true},
+		{a: "/o/p/e-1*/s/u/i", b: "/o/p/e-*/s/u/i", contains: false},
+		{a: "/o/p/e-*/s/u/i", b: "/o/p/f/s/u/i", contains: false},
+
+		// alternation
+		{a: "/o/p/e/[s|b]/u/i", b: "/o/p/e/s/u/i", contains: true},
+		{a: "/o/p/e/[s|b]/u/i", b: "/o/p/e/[b|s]/u/i", contains: true},
+		{a: "/o/p/e/[s|b]/u/i", b: "/o/p/e/[s|c]/u/i", contains: false},
+		{a: "/o/p/e/[s*|b]/u/i", b: "/o/p/e/[s1|s2|b]/u/i", contains: true},
+		{a: "/o/p/e/s/u/i", b: "/o/p/e/[s|b]/u/i", contains: false},
+		{a: "/o/p/e/*/u/i", b: "/o/p/e/[s|b*]/u/i", contains: true},
+
+		// full globs in every multiple segment
+		{a: "/o/p/*/*/*/*", b: "/o/p/e/[s|b]/u*/1", contains: true},
+		{a: "/o/p/*/*/*/*", b: "/o/p/*/*/*/*", contains: true},
+		{a: "/o/p/e/[s|b]/u*/1", b: "/o/p/*/*/*/*", contains: false},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.a+" "+test.b, func(t *testing.T) {
+			a, err := Parse(test.a)
+			if err != nil {
+				t.Fatalf("Failed to parse %s", test.a)
+			}
+
+			b, err := Parse(test.b)
+			if err != nil {
+				t.Fatalf("Failed to parse %s", test.b)
+			}
+
+			if res := a.Contains(b); res != test.contains {
+				t.Errorf("Expected %s Contains %s = %t", test.a, test.b, test.contains)
+			}
+		})
+	}
+}
+
+func TestIntersect(t *testing.T) {
+	type tc struct {
+		a   string
+		b   string
+		res string // empty when the pathexps don't overlap
+	}
+
+	testCases := []tc{
+		{a: "/o/p/e/s/u/i", b: "/o/p/e/s/u/i", res: "/o/p/e/s/u/i"},
+		{a: "/o/p/e/s/u/i", b: "/o1/p/e/s/u/i", res: ""},
+		{a: "/o/p/e/s/u/i", b: "/o/p/f/s/u/i", res: ""},
+
+		// wildcards
+		{a: "/o/p/*/s/u/i", b: "/o/p/e/s/u/i", res: "/o/p/e/s/u/i"},
+		{a: "/o/p/e-*/s/u/i", b: "/o/p/e-1*/s/u/i", res: "/o/p/e-1*/s/u/i"},
+		{a: "/o/p/e-*/s/u/i", b: "/o/p/f*/s/u/i", res: ""},
+		{a: "/o/p/e*/s/u/i", b: "/o/p/*/s*/u/i", res: "/o/p/e*/s/u/i"},
+
+		// alternation
+		{a: "/o/p/e/[s|b]/u/i", b: "/o/p/e/[s|c]/u/i", res: "/o/p/e/s/u/i"},
+		{a: "/o/p/e/[s|b|c]/u/i", b: "/o/p/e/[s|c|d]/u/i", res: "/o/p/e/[c|s]/u/i"},
+		{a: "/o/p/e/[s*|b]/u/i", b: "/o/p/e/[s1|c]/u/i", res: "/o/p/e/s1/u/i"},
+		{a: "/o/p/e/[s*|b*]/u/i", b: "/o/p/e/[s1*|s*]/u/i", res: "/o/p/e/s*/u/i"},
+		{a: "/o/p/e/[s|b]/u/i", b: "/o/p/e/[c|d]/u/i", res: ""},
+
+		// full globs in every multiple segment
+		{a: "/o/p/*/*/*/*", b: "/o/p/e/[s|b]/u*/1", res: "/o/p/e/[b|s]/u*/1"},
+		{a: "/o/p/*/*/*/*", b: "/o/p/*/*/*/*", res: "/o/p/*/*/*/*"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.a+" "+test.b, func(t *testing.T) {
+			a, err := Parse(test.a)
+			if err != nil {
+				t.Fatalf("Failed to parse %s", test.a)
+			}
+
+			b, err := Parse(test.b)
+			if err != nil {
+				t.Fatalf("Failed to parse %s", test.b)
+			}
+
+			for _, order := range [][]*PathExp{{a, b}, {b, a}} {
+				res, ok := order[0].Intersect(order[1])
+				switch {
+				case test.res == "" && ok:
+					t.Errorf("Expected %s and %s not to intersect, got %s",
+						order[0], order[1], res)
+				case test.res != "" && !ok:
+					t.Errorf("Expected %s and %s to intersect", order[0], order[1])
+				case ok && res.String() != test.res:
+					t.Errorf("Expected %s Intersect %s = %s, got %s",
+						order[0], order[1], test.res, res)
+				}
+			}
+		})
+	}
+}
+
+func TestSpecificity(t *testing.T) {
+	// From least to most specific
+	paths := []string{
+		"/o/p/*/*/*/*",
+		"/o/p/*/*/*/i",
+		"/o/p/*/[s|b]/u/i",
+		"/o/p/*/s*/u/i",
+		"/o/p/*/s/u/i",
+		"/o/p/[e|f]/s/u/i",
+		"/o/p/e*/s/u/i",
+		"/o/p/e/*/*/*",
+		"/o/p/e/s/u/i",
+	}
+
+	var prev *PathExp
+	for _, path := range paths {
+		pe, err := Parse(path)
+		if err != nil {
+			t.Fatalf("Failed to parse %s", path)
+		}
+
+		if prev != nil {
+			if pe.Specificity() <= prev.Specificity() {
+				t.Errorf("Expected %s to be more specific than %s", pe, prev)
+			}
+			if pe.CompareSpecificity(prev) != 1 {
+				t.Errorf("Expected %s CompareSpecificity %s = 1", pe, prev)
+			}
+		}
+		prev = pe
+	}
+}
+
+func TestMostSpecific(t *testing.T) {
+	type tc struct {
+		paths []string
+		idx   int // -1 when an error is expected
+	}
+
+	testCases := []tc{
+		{paths: []string{"/o/p/e/s/u/i"}, idx: 0},
+		{paths: []string{"/o/p/*/s/u/i", "/o/p/e/s/u/i", "/o/p/e/*/u/i"}, idx: 1},
+		{paths: []string{"/o/p/e/[s|b]/u/i", "/o/p/e/*/u/i"}, idx: 0},
+
+		// the same pathexp twice is not a collision
+		{paths: []string{"/o/p/e/s/u/i", "/o/p/e/s/u/i"}, idx: 0},
+
+		// different pathexps with equal specificity
+		{paths: []string{"/o/p/e/s/u/*", "/o/p/e/s/u/*", "/o/p/f/s/u/*"}, idx: -1},
+		{paths: []string{"/o/p/[e|f]/s/u/i", "/o/p/[f|g]/s/u/i"}, idx: -1},
+		{paths: []string{"/o/p/e*/s/u/i", "/o/p/f*/s/u/i", "/o/p/*/s/u/i"}, idx: -1},
+
+		// a tie below the most specific pathexp doesn't matter
+		{paths: []string{"/o/p/e*/s/u/i", "/o/p/f*/s/u/i", "/o/p/e/s/u/i"}, idx: 2},
+	}
+
+	for _, test := range testCases {
+		t.Run(strings.Join(test.paths, " "), func(t *testing.T) {
+			pes := make([]*PathExp, len(test.paths))
+			for i, path := range test.paths {
+				pe, err := Parse(path)
+				if err != nil {
+					t.Fatalf("Failed to parse %s", path)
+				}
+				pes[i] = pe
+			}
+
+			idx, err := MostSpecific(pes)
+			switch {
+			case test.idx == -1 && err == nil:
+				t.Errorf("Expected an error, got %d", idx)
+			case test.idx != -1 && err != nil:
+				t.Errorf("Expected %d, got error: %s", test.idx, err)
+			case idx != test.idx:
+				t.Errorf("Expected %d, got %d", test.idx, idx)
+			}
+		})
+	}
+
+	if _, err := MostSpecific(nil); err == nil {
+		t.Error("Expected an error for no pathexps")
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"os/user"
 	"path"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/manifoldco/torus-cli/errs"
@@ -48,6 +50,72 @@ type Core struct {
 	RegistryURI   string `ini:"registry_uri,omitempty"`
 	Context       bool   `ini:"context,omitempty"`
 	AutoConfirm   bool   `ini:"auto_confirm,omitempty"`
+
+	// ExtraCABundleFile is a PEM file of root certificates trusted for the
+	// registry in addition to the CA bundle, such as a private CA for a self
+	// hosted registry. It can be overridden with TORUS_CA_BUNDLE.
+	ExtraCABundleFile string `ini:"extra_ca_bundle_file,omitempty"`
+
+	// DisableAutoOrg stops commands that require an org from using the
+	// user's only org when none is given.
+	DisableAutoOrg bool `ini:"disable_auto_org,omitempty"`
+
+	// ShutdownGracePeriod is the number of seconds the daemon waits for
+	// in-flight requests to finish when shutting down.
+	ShutdownGracePeriod int `ini:"shutdown_grace_period,omitempty"`
+
+	// MaxIdleConnsPerHost and IdleConnTimeout (in seconds) tune how many
+	// connections to the registry the daemon keeps alive for reuse.
+	MaxIdleConnsPerHost int `ini:"max_idle_conns_per_host,omitempty"`
+	IdleConnTimeout     int `ini:"idle_conn_timeout,omitempty"`
+
+	// RetryAttempts is the maximum number of times an idempotent registry
+	// request is attempted, and RetryBaseDelay the delay in milliseconds
+	// before the first retry.
+	RetryAttempts  int `ini:"retry_attempts,omitempty"`
+	RetryBaseDelay int `ini:"retry_base_delay,omitempty"`
+
+	// MaxCredentialSize is the largest secret value, in bytes, the daemon
+	// will store, and MaxKeyringCredentials the most secrets it will store
+	// in a single keyring. Zero, or unset, means the default.
+	MaxCredentialSize     int `ini:"max_credential_size,omitempty"`
+	MaxKeyringCredentials int `ini:"max_keyring_credentials,omitempty"`
+
+	// MaxResponseSize is the largest response body, in bytes, the daemon
+	// will read from the registry.
+	MaxResponseSize int64 `ini:"max_response_size,omitempty"`
+
+	// DecryptWorkers is the number of secrets the daemon decrypts at once.
+	DecryptWorkers int `ini:"decrypt_workers,omitempty"`
+
+	// Metrics enables the daemon's metrics endpoint. It is off by default.
+	Metrics bool `ini:"metrics,omitempty"`
+
+	// SocketMode is the file mode, in octal, of the daemon's socket. If
+	// SocketUID is set, the daemon only accepts connections from processes
+	// running as that user id. Checking the user is only supported on Linux.
+	SocketMode string `ini:"socket_mode,omitempty"`
+	SocketUID  string `ini:"socket_uid,omitempty"`
+
+	// Proxy is the URL of the proxy used for registry requests, overriding
+	// the HTTP_PROXY and HTTPS_PROXY environment variables.
+	Proxy string `ini:"proxy,omitempty"`
+
+	// Profile is the name of the profile used when TORUS_PROFILE is not set.
+	Profile string `ini:"profile,omitempty"`
+
+	// LogFormat is the format of the daemon's log, either "text" or "json".
+	LogFormat string `ini:"log_format,omitempty"`
+
+	// UpdateURL is the releases endpoint queried by 'torus version --check'.
+	UpdateURL string `ini:"update_url,omitempty"`
+
+	// Timeout is how long, as a duration such as "30s", a command waits on
+	// the daemon and registry. It can be overridden with TORUS_TIMEOUT.
+	Timeout string `ini:"timeout,omitempty"`
+
+	// NoColor turns off colored output, like TORUS_NO_COLOR.
+	NoColor bool `ini:"no_color,omitempty"`
 }
 
 // Defaults contains default values for use in command argument flags
@@ -88,6 +156,12 @@ func (prefs Preferences) SetValue(key string, value string) (Preferences, error)
 			v = false
 		}
 		field.SetBool(v)
+	case reflect.TypeOf(0):
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return prefs, errs.NewExitError("error: `" + key + "` must be a number")
+		}
+		field.SetInt(int64(v))
 	default:
 		field.SetString(value)
 	}
@@ -148,3 +222,180 @@ func NewPreferences(useDefaults bool) (*Preferences, error) {
 
 	return prefs, nil
 }
+
+// DefaultProfile is the name of the profile used when no other is selected.
+// Its settings come from the [core] section.
+const DefaultProfile = "default"
+
+const profileSectionPrefix = "profile "
+
+// Profile contains the settings of a named profile, from a [profile <name>]
+// section of the torusrc file. Each profile talks to its own registry, and
+// keeps its own daemon and session.
+type Profile struct {
+	Name         string `ini:"-"`
+	RegistryURI  string `ini:"registry_uri,omitempty"`
+	CABundleFile string `ini:"ca_bundle_file,omitempty"`
+}
+
+// ActiveProfile returns the name of the profile in use: the TORUS_PROFILE
+// environment variable if set, otherwise core.profile, otherwise
+// DefaultProfile.
+func (prefs Preferences) ActiveProfile() string {
+	if name := os.Getenv("TORUS_PROFILE"); name != "" {
+		return name
+	}
+	if prefs.Core.Profile != "" {
+		return prefs.Core.Profile
+	}
+	return DefaultProfile
+}
+
+// LoadProfiles returns the default profile, followed by the profiles defined
+// in the torusrc file sorted by name.
+func LoadProfiles(prefs *Preferences) ([]Profile, error) {
+	profiles := []Profile{{
+		Name:         DefaultProfile,
+		RegistryURI:  prefs.Core.RegistryURI,
+		CABundleFile: prefs.Core.CABundleFile,
+	}}
+
+	f, err := loadRcFile()
+	if err != nil || f == nil {
+		return profiles, err
+	}
+
+	var named []Profile
+	for _, section := range f.Sections() {
+		if !strings.HasPrefix(section.Name(), profileSectionPrefix) {
+			continue
+		}
+
+		profile := Profile{
+			Name:         strings.TrimPrefix(section.Name(), profileSectionPrefix),
+			RegistryURI:  prefs.Core.RegistryURI,
+			CABundleFile: prefs.Core.CABundleFile,
+		}
+		err = section.MapTo(&profile)
+		if err != nil {
+			return nil, err
+		}
+		named = append(named, profile)
+	}
+
+	sort.Sort(profilesByName(named))
+	return append(profiles, named...), nil
+}
+
+// LoadProfile returns the named profile, or an error if it is not defined.
+// Settings not given in the profile's section fall back to [core].
+func LoadProfile(prefs *Preferences, name string) (*Profile, error) {
+	profiles, err := LoadProfiles(prefs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range profiles {
+		if p.Name == name {
+			return &p, nil
+		}
+	}
+
+	return nil, errs.NewExitError("error: unknown profile `" + name + "`")
+}
+
+type profilesByName []Profile
+
+func (p profilesByName) Len() int           { return len(p) }
+func (p profilesByName) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p profilesByName) Less(i, j int) bool { return p[i].Name < p[j].Name }
+
+const serviceTemplateSectionPrefix = "services "
+
+// ServiceTemplate is a named set of services, created together by
+// `torus services create --template`. It is read from a [services <name>]
+// section of the torusrc file, listing the services as names = a, b, c.
+type ServiceTemplate struct {
+	Name  string   `ini:"-"`
+	Names []string `ini:"names,omitempty"`
+}
+
+// LoadServiceTemplates returns the service templates defined in the torusrc
+// file, sorted by name.
+func LoadServiceTemplates() ([]ServiceTemplate, error) {
+	f, err := loadRcFile()
+	if err != nil || f == nil {
+		return nil, err
+	}
+
+	var templates []ServiceTemplate
+	for _, section := range f.Sections() {
+		if !strings.HasPrefix(section.Name(), serviceTemplateSectionPrefix) {
+			continue
+		}
+
+		t := ServiceTemplate{
+			Name: strings.TrimPrefix(section.Name(), serviceTemplateSectionPrefix),
+		}
+		err = section.MapTo(&t)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+
+	sort.Sort(serviceTemplatesByName(templates))
+	return templates, nil
+}
+
+type serviceTemplatesByName []ServiceTemplate
+
+func (t serviceTemplatesByName) Len() int           { return len(t) }
+func (t serviceTemplatesByName) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+func (t serviceTemplatesByName) Less(i, j int) bool { return t[i].Name < t[j].Name }
+
+// Save writes the [core] and [defaults] preferences to the torusrc file,
+// leaving any other sections, such as profiles, as they are.
+func Save(prefs *Preferences) error {
+	f, err := loadRcFile()
+	if err != nil {
+		return err
+	}
+	if f == nil {
+		f = ini.Empty()
+	}
+
+	// Start these sections afresh, so that unset values are removed
+	f.DeleteSection("core")
+	f.DeleteSection("defaults")
+
+	err = ini.ReflectFrom(f, prefs)
+	if err != nil {
+		return err
+	}
+
+	rcPath, err := RcPath()
+	if err != nil {
+		return err
+	}
+
+	return f.SaveTo(rcPath)
+}
+
+// loadRcFile loads the torusrc file, returning nil if it does not exist.
+func loadRcFile() (*ini.File, error) {
+	rcPath, err := RcPath()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = os.Stat(rcPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ini.Load(rcPath)
+}
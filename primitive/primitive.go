@@ -145,6 +145,12 @@ type Credential struct { // type: 0x0b
 	immutable
 	BaseCredential
 	State *string `json:"state"`
+
+	// Description and Tags are optional, unencrypted metadata used to
+	// describe and find credentials. Both are omitted when empty, so
+	// credentials created before they existed keep the same signed body.
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
 }
 
 // CredentialV1 is a secret value shared between a group of services based
@@ -6,6 +6,10 @@ import (
 	"strings"
 )
 
+// NoColor disables the colors and other text styles used in output, leaving
+// only the plain text.
+var NoColor = false
+
 const esc = "\033["
 
 type attribute int
@@ -57,6 +61,10 @@ func styler(attrs ...attribute) func(string) string {
 	seq := strings.Join(attrstrs, ";")
 
 	return func(s string) string {
+		if NoColor {
+			return s
+		}
+
 		end := ""
 		if !strings.HasSuffix(s, resetCode) {
 			end = resetCode
@@ -29,4 +29,14 @@ func TestStyler(t *testing.T) {
 		}
 
 	})
+
+	t.Run("renders plain text when colors are disabled", func(t *testing.T) {
+		NoColor = true
+		defer func() { NoColor = false }()
+
+		plain := styler(fgRed, fgBold)("hi")
+		if plain != "hi" {
+			t.Errorf("style did not match: %s != hi", plain)
+		}
+	})
 }
@@ -63,7 +63,7 @@ func (p *Prompt) Run() (string, error) {
 		suggestedAnswer = " " + faint("[Y/n]")
 	}
 
-	state := iconInitial
+	state := iconInitial()
 	prompt := p.Label + punctuation + suggestedAnswer + " "
 
 	c.Prompt = bold(state) + " " + bold(prompt)
@@ -116,15 +116,15 @@ func (p *Prompt) Run() (string, error) {
 		err := validFn(string(line))
 		if err != nil {
 			if _, ok := err.(*ValidationError); ok {
-				state = iconBad
+				state = iconBad()
 			} else {
 				rl.Close()
 				return nil, 0, false
 			}
 		} else {
-			state = iconGood
+			state = iconGood()
 			if p.IsConfirm {
-				state = iconInitial
+				state = iconInitial()
 			}
 		}
 
@@ -145,14 +145,14 @@ func (p *Prompt) Run() (string, error) {
 			if verr, ok := oerr.(*ValidationError); ok {
 				msg = verr.msg
 				valid = false
-				state = iconBad
+				state = iconBad()
 			} else {
 				return "", oerr
 			}
 		}
 
 		if valid {
-			state = iconGood
+			state = iconGood()
 			break
 		}
 
@@ -197,10 +197,10 @@ func (p *Prompt) Run() (string, error) {
 
 	if p.IsConfirm {
 		if strings.ToLower(echo) != "y" {
-			state = iconBad
+			state = iconBad()
 			err = ErrAbort
 		} else {
-			state = iconGood
+			state = iconGood()
 		}
 	}
 
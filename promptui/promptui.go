@@ -35,10 +35,15 @@ func NewValidationError(msg string) *ValidationError {
 
 // SuccessfulValue returns a value as if it were entered via prompt, valid
 func SuccessfulValue(label, value string) string {
-	return iconGood + " " + label + ": " + faint(value)
+	return iconGood() + " " + label + ": " + faint(value)
 }
 
 // FailedValue returns a value as if it were entered via prompt, invalid
 func FailedValue(label, value string) string {
-	return iconBad + " " + label + ": " + faint(value)
+	return iconBad() + " " + label + ": " + faint(value)
+}
+
+// Warning returns msg marked as a warning.
+func Warning(msg string) string {
+	return styler(fgBold, fgYellow)("Warning:") + " " + msg
 }
@@ -6,11 +6,11 @@ var (
 	underlined = styler(fgUnderline)
 )
 
-var (
-	iconInitial = styler(fgBlue)("?")
-	iconGood    = styler(fgGreen)("✔")
-	iconWarn    = styler(fgYellow)("⚠")
-	iconBad     = styler(fgRed)("✗")
-)
+// Icons are styled when used, rather than once up front, so that they respect
+// NoColor being set after the package is initialized.
+func iconInitial() string { return styler(fgBlue)("?") }
+func iconGood() string    { return styler(fgGreen)("✔") }
+func iconWarn() string    { return styler(fgYellow)("⚠") }
+func iconBad() string     { return styler(fgRed)("✗") }
 
 var red = styler(fgBold, fgRed)